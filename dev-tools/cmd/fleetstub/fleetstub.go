@@ -0,0 +1,101 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// fleetstub runs a local Fleet Server stand-in implementing the enroll,
+// checkin, ack and file upload endpoints, so policy changes, actions and
+// diagnostics uploads can be exercised against a real Elastic Agent with no
+// network. It is a development tool and is not part of the Elastic Agent
+// binary:
+//
+//	go run ./dev-tools/cmd/fleetstub -dir fleet-stub -address localhost:8220
+//
+// The stand-in is backed by a directory:
+//
+//	policy.json         the policy sent to the agent, sent again with a new revision every time the file changes
+//	actions/*.json      actions sent to the agent once, in file name order, then moved to actions/delivered
+//	acks.ndjson         the acks received from the agent, one per line
+//	uploads/<id>/       the files uploaded by the agent, e.g. diagnostics
+//
+// Action files hold a single action as sent by Fleet, e.g.
+// {"type": "UPGRADE", "data": {"version": "9.1.0"}}; the action ID defaults to
+// the file name. The enrolled agent is kept in the directory, restarting the
+// stand-in does not require enrolling again.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/elastic/elastic-agent/testing/fleetservertest"
+)
+
+var (
+	dir     string
+	address string
+)
+
+func init() {
+	flag.StringVar(&dir, "dir", "fleet-stub", "Directory holding the policy, actions, acks and uploads")
+	flag.StringVar(&address, "address", "localhost:8220", "Address to listen on")
+}
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	logFn := func(format string, a ...any) {
+		fmt.Fprintf(os.Stdout, "%s %s\n", time.Now().Format(time.TimeOnly), fmt.Sprintf(format, a...))
+	}
+	stub, err := fleetservertest.NewStub(dir, logFn)
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	server := &http.Server{
+		Handler:           fleetservertest.NewRouter(stub.Handlers()),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Fprintf(os.Stdout, "Fleet stub serving %s on http://%s\n", stub.Dir(), l.Addr())
+	fmt.Fprintf(os.Stdout, "Enroll with: elastic-agent enroll --url=http://%s --enrollment-token=%s --insecure\n",
+		l.Addr(), stub.EnrollmentToken())
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Serve(l)
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to stop the fleet stub: %w", err)
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"syscall"
 	"time"
 
@@ -21,7 +22,9 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics/redaction"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
 	"github.com/elastic/elastic-agent/internal/pkg/otel"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/fleetapi"
@@ -72,20 +75,47 @@ type Diagnostics struct {
 	limiter      *rate.Limiter
 	uploader     Uploader
 	topPath      string
+	redactor     *redaction.Redactor
+	redactorErr  error
+
+	uploadsDir       string
+	onUploadProgress func([]uploader.Progress)
+	uploads          uploadProgress
 }
 
 // NewDiagnostics returns a new Diagnostics handler.
-func NewDiagnostics(log abstractLogger, topPath string, coord diagnosticsProvider, cfg config.Limit, uploader Uploader) *Diagnostics {
+func NewDiagnostics(log abstractLogger, topPath string, coord diagnosticsProvider, cfg config.Limit, uploader Uploader, opts ...DiagnosticsOption) *Diagnostics {
 	if topPath == "" {
 		topPath = paths.Top()
 	}
-	return &Diagnostics{
+	h := &Diagnostics{
 		log:          log,
 		diagProvider: coord,
 		limiter:      rate.NewLimiter(rate.Every(cfg.Interval), cfg.Burst),
 		uploader:     uploader,
 		topPath:      topPath,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// WithRedactor applies the user-supplied redaction rules of r to the
+// diagnostics bundles.
+func WithRedactor(r *redaction.Redactor) DiagnosticsOption {
+	return func(h *Diagnostics) {
+		h.redactor = r
+	}
+}
+
+// WithRedactorError refuses the diagnostics actions because the user-supplied
+// redaction rules failed to load with err, a bundle is never uploaded without
+// them.
+func WithRedactorError(err error) DiagnosticsOption {
+	return func(h *Diagnostics) {
+		h.redactorErr = err
+	}
 }
 
 // Handle processes the passed Diagnostics action asynchronously.
@@ -106,6 +136,9 @@ func (h *Diagnostics) Handle(ctx context.Context, a fleetapi.Action, ack acker.A
 // The bundle is assembled on disk, however if it encounters any errors an in-memory-buffer is used.
 func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDiagnostics, ack acker.Acker) {
 	ts := time.Now().UTC()
+	// skipAck is set when the upload is interrupted by the agent stopping, the
+	// action is acked once the upload is resumed on the next start.
+	skipAck := false
 	defer func() {
 		if r := recover(); r != nil {
 			err := fmt.Errorf("panic detected: %v", r)
@@ -114,18 +147,8 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 		}
 	}()
 	defer func() {
-		err := ack.Ack(ctx, action)
-		if err != nil {
-			h.log.Errorw("failed to ack diagnostics action",
-				"error.message", err,
-				"action", action)
-		}
-		err = ack.Commit(ctx)
-		if err != nil {
-			h.log.Errorw("failed to commit diagnostics action",
-				"error.message", err,
-				"action", action)
-
+		if !skipAck {
+			h.ackAction(ctx, action, ack)
 		}
 	}()
 
@@ -135,8 +158,30 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 		return
 	}
 
+	if h.redactorErr != nil {
+		action.Err = fmt.Errorf("diagnostics redaction rules are invalid: %w", h.redactorErr)
+		h.log.Errorw("diagnostics action handler refused the action, the redaction rules failed to load",
+			"error.message", h.redactorErr,
+			"action", action)
+		return
+	}
+
+	profile, err := diagnostics.GetProfile(action.Data.Profile)
+	if err != nil {
+		action.Err = err
+		h.log.Errorw("diagnostics action handler received an invalid profile",
+			"error.message", err,
+			"action", action)
+		return
+	}
+	var archiveOpts []diagnostics.ArchiveOption
+	if action.Data.Profile != "" || action.Data.MaxSize > 0 {
+		archiveOpts = append(archiveOpts, diagnostics.WithProfile(profile), diagnostics.WithMaxSize(action.Data.MaxSize))
+	}
+	archiveOpts = append(archiveOpts, diagnostics.WithRedactor(h.redactor))
+
 	h.log.Debug("Gathering agent diagnostics.")
-	aDiag, err := h.runHooks(ctx, action)
+	aDiag, err := h.runHooks(ctx, action, profile)
 	if err != nil {
 		action.Err = err
 		h.log.Errorw("diagnostics action handler failed to run diagnostics hooks",
@@ -144,17 +189,23 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 			"action", action)
 		return
 	}
-	h.log.Debug("Gathering unit diagnostics.")
-	uDiag := h.diagUnits(ctx)
+	var uDiag []client.DiagnosticUnitResult
+	if profile.Units {
+		h.log.Debug("Gathering unit diagnostics.")
+		uDiag = h.diagUnits(ctx)
+	}
 
-	h.log.Debug("Gathering component diagnostics.")
-	cDiag := h.diagComponents(ctx, action)
+	var cDiag []client.DiagnosticComponentResult
+	if profile.Components {
+		h.log.Debug("Gathering component diagnostics.")
+		cDiag = h.diagComponents(ctx, action, profile)
+	}
 
 	var r io.Reader
 	// attempt to create a temporary diagnostics file on disk in order to avoid
 	// loading a potentially large file in memory.
 	// if on-disk creation fails an in-memory buffer is used.
-	f, s, err := h.diagFile(aDiag, uDiag, cDiag, action.Data.ExcludeEventsLog)
+	f, s, err := h.diagFile(aDiag, uDiag, cDiag, action.Data.ExcludeEventsLog, archiveOpts...)
 	if err != nil {
 		var b bytes.Buffer
 		h.log.Warnw("Diagnostics action unable to use temporary file, using buffer instead.", "error.message", err)
@@ -164,7 +215,7 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 				h.log.Warn(str)
 			}
 		}()
-		err := diagnostics.ZipArchive(&wBuf, &b, h.topPath, aDiag, uDiag, cDiag, action.Data.ExcludeEventsLog)
+		err := diagnostics.ZipArchive(&wBuf, &b, h.topPath, aDiag, uDiag, cDiag, action.Data.ExcludeEventsLog, archiveOpts...)
 		if err != nil {
 			h.log.Errorw(
 				"diagnostics action handler failed generate zip archive",
@@ -182,6 +233,22 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 			os.Remove(f.Name())
 		}()
 		r = f
+
+		if h.resumableUploader() != nil {
+			pending, err := h.persistBundle(action, ts, f)
+			if err == nil {
+				h.log.Debug("Sending diagnostics archive.")
+				skipAck = !h.uploadPending(ctx, pending)
+				elapsed := time.Since(ts)
+				h.log.Debugw(fmt.Sprintf("Diagnostics action complete. Took %s", elapsed), "action", action, "elapsed", elapsed)
+				return
+			}
+			h.log.Warnw("Diagnostics action unable to persist the diagnostics archive, its upload cannot be resumed.", "error.message", err)
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				action.Err = err
+				return
+			}
+		}
 	}
 	h.log.Debug("Sending diagnostics archive.")
 	uploadID, err := h.uploader.UploadDiagnostics(ctx, action.ActionID, ts.Format("2006-01-02T15-04-05Z07-00"), s, r) // RFC3339 format that uses - instead of : so it works on Windows
@@ -198,21 +265,37 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 	h.log.Debugw(fmt.Sprintf("Diagnostics action complete. Took %s", elapsed), "action", action, "elapsed", elapsed)
 }
 
-// runHooks runs the agent diagnostics hooks.
-func (h *Diagnostics) runHooks(ctx context.Context, action *fleetapi.ActionDiagnostics) ([]client.DiagnosticFileResult, error) {
-	hooks := append(h.diagProvider.DiagnosticHooks(), diagnostics.GlobalHooks()...)
+// ackAction acks and commits the diagnostics action.
+func (h *Diagnostics) ackAction(ctx context.Context, action *fleetapi.ActionDiagnostics, ack acker.Acker) {
+	err := ack.Ack(ctx, action)
+	if err != nil {
+		h.log.Errorw("failed to ack diagnostics action",
+			"error.message", err,
+			"action", action)
+	}
+	err = ack.Commit(ctx)
+	if err != nil {
+		h.log.Errorw("failed to commit diagnostics action",
+			"error.message", err,
+			"action", action)
 
-	// Currently CPU is the only additional metric we can collect.
-	// If this changes we would need to change how we scan AdditionalMetrics.
-	collectCPU := false
-	for _, metric := range action.Data.AdditionalMetrics {
-		if metric == "CPU" {
-			h.log.Debug("Diagnostics will collect CPU profile.")
-			collectCPU = true
-			break
+	}
+}
+
+// runHooks runs the agent diagnostics hooks of the profile.
+func (h *Diagnostics) runHooks(ctx context.Context, action *fleetapi.ActionDiagnostics, profile diagnostics.Profile) ([]client.DiagnosticFileResult, error) {
+	var hooks diagnostics.Hooks
+	for _, hook := range append(h.diagProvider.DiagnosticHooks(), diagnostics.GlobalHooks()...) {
+		if profile.IncludesHook(hook.Name) {
+			hooks = append(hooks, hook)
 		}
 	}
 
+	collectCPU := collectCPUProfile(action, profile)
+	if collectCPU {
+		h.log.Debug("Diagnostics will collect CPU profile.")
+	}
+
 	resultLen := len(hooks)
 	if collectCPU {
 		resultLen++
@@ -311,7 +394,7 @@ func (h *Diagnostics) diagUnits(ctx context.Context) []client.DiagnosticUnitResu
 }
 
 // diagUnits gathers diagnostics from components.
-func (h *Diagnostics) diagComponents(ctx context.Context, action *fleetapi.ActionDiagnostics) []client.DiagnosticComponentResult {
+func (h *Diagnostics) diagComponents(ctx context.Context, action *fleetapi.ActionDiagnostics, profile diagnostics.Profile) []client.DiagnosticComponentResult {
 	cDiag := make([]client.DiagnosticComponentResult, 0)
 	h.log.Debug("Performing component diagnostics")
 	startTime := time.Now()
@@ -319,10 +402,13 @@ func (h *Diagnostics) diagComponents(ctx context.Context, action *fleetapi.Actio
 		h.log.Debugf("Component diagnostics complete. Took: %s", time.Since(startTime))
 	}()
 	additionalMetrics := []cproto.AdditionalDiagnosticRequest{}
-	for _, metric := range action.Data.AdditionalMetrics {
-		if metric == "CPU" {
-			additionalMetrics = append(additionalMetrics, cproto.AdditionalDiagnosticRequest_CPU)
-		}
+	if collectCPUProfile(action, profile) {
+		additionalMetrics = append(additionalMetrics, cproto.AdditionalDiagnosticRequest_CPU)
+	}
+	// the connection request diagnostics are only collected when explicitly
+	// requested by a profile
+	if action.Data.Profile != "" && profile.Conn {
+		additionalMetrics = append(additionalMetrics, cproto.AdditionalDiagnosticRequest_CONN)
 	}
 	rr, err := h.diagProvider.PerformComponentDiagnostics(ctx, additionalMetrics)
 	if err != nil {
@@ -354,12 +440,21 @@ func (h *Diagnostics) diagComponents(ctx context.Context, action *fleetapi.Actio
 	return cDiag
 }
 
+// collectCPUProfile returns true if a CPU profile is requested by the action
+// or is part of the profile.
+func collectCPUProfile(action *fleetapi.ActionDiagnostics, profile diagnostics.Profile) bool {
+	// Currently CPU is the only additional metric we can collect.
+	// If this changes we would need to change how we scan AdditionalMetrics.
+	return profile.CPU || slices.Contains(action.Data.AdditionalMetrics, "CPU")
+}
+
 // diagFile will write the diagnostics to a temporary file and return the file ready to be read
 func (h *Diagnostics) diagFile(
 	aDiag []client.DiagnosticFileResult,
 	uDiag []client.DiagnosticUnitResult,
 	cDiag []client.DiagnosticComponentResult,
-	excludeEvents bool) (*os.File, int64, error) {
+	excludeEvents bool,
+	opts ...diagnostics.ArchiveOption) (*os.File, int64, error) {
 
 	f, err := os.CreateTemp(paths.TempDir(), "elastic-agent-diagnostics")
 	if err != nil {
@@ -373,7 +468,7 @@ func (h *Diagnostics) diagFile(
 			h.log.Warn(str)
 		}
 	}()
-	if err := diagnostics.ZipArchive(&wBuf, f, h.topPath, aDiag, uDiag, cDiag, excludeEvents); err != nil {
+	if err := diagnostics.ZipArchive(&wBuf, f, h.topPath, aDiag, uDiag, cDiag, excludeEvents, opts...); err != nil {
 		os.Remove(name)
 		return nil, 0, err
 	}
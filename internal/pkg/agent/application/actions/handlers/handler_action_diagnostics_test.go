@@ -8,6 +8,7 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"os"
 	"path"
@@ -454,6 +455,79 @@ func TestDiagnosticsHandlerWithEDOT(t *testing.T) {
 	require.True(t, called, "expected the mock diagnostics server to be called")
 }
 
+func TestDiagnosticHandlerWithProfile(t *testing.T) {
+	tempAgentRoot := t.TempDir()
+	paths.SetTop(tempAgentRoot)
+	err := os.MkdirAll(path.Join(tempAgentRoot, "data"), 0755)
+	require.NoError(t, err)
+
+	// the minimal profile skips the unit and component diagnostics
+	mockDiagProvider := newMockDiagnosticsProvider(t)
+	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{hook1})
+
+	mockAcker := acker.NewMockAcker(t)
+	mockAcker.EXPECT().Ack(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, a fleetapi.Action) error {
+		require.IsType(t, new(fleetapi.ActionDiagnostics), a)
+		assert.NoError(t, a.(*fleetapi.ActionDiagnostics).Err)
+		return nil
+	})
+	mockAcker.EXPECT().Commit(mock.Anything).Return(nil)
+
+	mockUploader := NewMockUploader(t)
+	mockUploader.EXPECT().UploadDiagnostics(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, s1, s2 string, i int64, r io.Reader) (string, error) {
+		buf, err := io.ReadAll(r)
+		require.NoError(t, err)
+		zr, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+		require.NoError(t, err)
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		assert.Contains(t, names, diagnostics.ManifestFilename)
+		assert.NotContains(t, names, "hook1.yaml", "hook1 is not part of the minimal profile")
+		assert.NotContains(t, names, "components/")
+		return "upload-id", nil
+	})
+
+	testLogger, _ := loggertest.New("diagnostic-handler-test")
+	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler.collectDiag(t.Context(), &fleetapi.ActionDiagnostics{
+		Data: fleetapi.ActionDiagnosticsData{Profile: diagnostics.ProfileMinimal, MaxSize: 10 * 1024 * 1024},
+	}, mockAcker)
+}
+
+func TestDiagnosticHandlerUnknownProfile(t *testing.T) {
+	mockAcker := acker.NewMockAcker(t)
+	mockAcker.EXPECT().Ack(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, a fleetapi.Action) error {
+		require.IsType(t, new(fleetapi.ActionDiagnostics), a)
+		assert.ErrorContains(t, a.(*fleetapi.ActionDiagnostics).Err, "unknown diagnostics profile")
+		return nil
+	})
+	mockAcker.EXPECT().Commit(mock.Anything).Return(nil)
+
+	testLogger, _ := loggertest.New("diagnostic-handler-test")
+	handler := NewDiagnostics(testLogger, t.TempDir(), newMockDiagnosticsProvider(t), defaultRateLimit, NewMockUploader(t))
+	handler.collectDiag(t.Context(), &fleetapi.ActionDiagnostics{
+		Data: fleetapi.ActionDiagnosticsData{Profile: "unknown"},
+	}, mockAcker)
+}
+
+func TestDiagnosticHandlerInvalidRedactionRules(t *testing.T) {
+	mockAcker := acker.NewMockAcker(t)
+	mockAcker.EXPECT().Ack(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, a fleetapi.Action) error {
+		require.IsType(t, new(fleetapi.ActionDiagnostics), a)
+		assert.ErrorContains(t, a.(*fleetapi.ActionDiagnostics).Err, "diagnostics redaction rules are invalid")
+		return nil
+	})
+	mockAcker.EXPECT().Commit(mock.Anything).Return(nil)
+
+	testLogger, _ := loggertest.New("diagnostic-handler-test")
+	// the provider and the uploader mocks fail the test if the bundle is collected or uploaded
+	handler := NewDiagnostics(testLogger, t.TempDir(), newMockDiagnosticsProvider(t), defaultRateLimit, NewMockUploader(t),
+		WithRedactorError(errors.New(`redaction rule "rule-1" has an invalid value`)))
+	handler.collectDiag(t.Context(), &fleetapi.ActionDiagnostics{}, mockAcker)
+}
+
 func verifyZip(t *testing.T, reader io.Reader, expectedContent map[string][]byte) {
 	// Read all from io.Reader into a buffer
 	buf, err := io.ReadAll(reader)
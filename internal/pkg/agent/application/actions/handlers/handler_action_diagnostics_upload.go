@@ -0,0 +1,304 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
+	"github.com/elastic/elastic-agent/pkg/fleetapi"
+)
+
+// diagnosticsUploadAttempts is the number of times the upload of a diagnostics
+// bundle is attempted before the action is acked with an error.
+const diagnosticsUploadAttempts = 3
+
+// diagnosticsUploadBackoff is the time waited between upload attempts, it is a
+// variable so it can be replaced in unit-tests.
+var diagnosticsUploadBackoff = 5 * time.Second
+
+// ResumableUploader is implemented by the uploaders able to resume an
+// interrupted diagnostics upload from the last chunk confirmed by fleet-server.
+type ResumableUploader interface {
+	UploadDiagnosticsResumable(ctx context.Context, actionID string, state *uploader.UploadState, r io.ReadSeeker, onProgress func(*uploader.UploadState)) (string, error)
+}
+
+// DiagnosticsOption is an option for the Diagnostics handler.
+type DiagnosticsOption func(*Diagnostics)
+
+// WithResumableUploads keeps the diagnostics bundles and the state of their
+// upload in dir until they are uploaded, so an interrupted upload is resumed
+// instead of started over. It has no effect if the uploader does not implement
+// ResumableUploader.
+func WithResumableUploads(dir string) DiagnosticsOption {
+	return func(h *Diagnostics) {
+		h.uploadsDir = dir
+	}
+}
+
+// WithUploadProgress sets the function called with the progress of the
+// ongoing resumable uploads every time it changes.
+func WithUploadProgress(fn func([]uploader.Progress)) DiagnosticsOption {
+	return func(h *Diagnostics) {
+		h.onUploadProgress = fn
+	}
+}
+
+// pendingDiagnosticsUpload is a diagnostics bundle not uploaded yet, it is
+// persisted next to the bundle.
+type pendingDiagnosticsUpload struct {
+	Action *fleetapi.ActionDiagnostics `json:"action"`
+	State  uploader.UploadState        `json:"state"`
+}
+
+// uploadProgress tracks the progress of the ongoing resumable uploads.
+type uploadProgress struct {
+	mx       sync.Mutex
+	progress map[string]uploader.Progress
+}
+
+// resumableUploader returns the uploader to use for resumable uploads, nil if
+// they are disabled.
+func (h *Diagnostics) resumableUploader() ResumableUploader {
+	if h.uploadsDir == "" {
+		return nil
+	}
+	u, _ := h.uploader.(ResumableUploader)
+	return u
+}
+
+// ResumeUploads resumes in the background the uploads of the diagnostics
+// bundles persisted by a previous run, the actions are acked once done.
+func (h *Diagnostics) ResumeUploads(ctx context.Context, ack acker.Acker) {
+	if h.resumableUploader() == nil {
+		return
+	}
+	matches, err := filepath.Glob(filepath.Join(h.uploadsDir, "*.json"))
+	if err != nil {
+		h.log.Errorw("failed to list pending diagnostics uploads", "error.message", err)
+		return
+	}
+	for _, stateFile := range matches {
+		pending, err := readPendingUpload(stateFile)
+		if err != nil {
+			h.log.Errorw("failed to read pending diagnostics upload, discarding it",
+				"error.message", err,
+				"file", stateFile)
+			h.removePendingUpload(strings.TrimSuffix(stateFile, ".json"))
+			continue
+		}
+		h.log.Infof("Resuming upload of diagnostics for action %s", pending.Action.ActionID)
+		go func() {
+			if h.uploadPending(ctx, pending) {
+				h.ackAction(ctx, pending.Action, ack)
+			}
+		}()
+	}
+}
+
+// persistBundle moves the diagnostics bundle f to the uploads directory and
+// persists the state of its upload.
+func (h *Diagnostics) persistBundle(action *fleetapi.ActionDiagnostics, ts time.Time, f *os.File) (*pendingDiagnosticsUpload, error) {
+	if err := os.MkdirAll(h.uploadsDir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create diagnostics uploads directory: %w", err)
+	}
+	base := h.pendingUploadBase(action.ActionID)
+	dst, err := os.OpenFile(base+".zip", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create diagnostics bundle: %w", err)
+	}
+	defer dst.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(dst, hash), f)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return nil, fmt.Errorf("failed to write diagnostics bundle: %w", err)
+	}
+
+	pending := &pendingDiagnosticsUpload{
+		Action: action,
+		State: uploader.UploadState{
+			Name:   fmt.Sprintf("elastic-agent-diagnostics-%s.zip", ts.Format("2006-01-02T15-04-05Z07-00")), // RFC3339 format that uses - instead of : so it works on Windows
+			Size:   size,
+			SHA256: hex.EncodeToString(hash.Sum(nil)),
+		},
+	}
+	if err := h.savePendingUpload(pending); err != nil {
+		os.Remove(dst.Name())
+		return nil, err
+	}
+	return pending, nil
+}
+
+// uploadPending uploads a persisted diagnostics bundle, resuming from the last
+// chunk confirmed by fleet-server. The result of the upload is set in the
+// action, it returns false if ctx is done before the upload completes: the
+// bundle is kept so the upload is resumed on the next start and the action
+// must not be acked.
+func (h *Diagnostics) uploadPending(ctx context.Context, pending *pendingDiagnosticsUpload) bool {
+	action := pending.Action
+	base := h.pendingUploadBase(action.ActionID)
+	progress := uploader.Progress{ActionID: action.ActionID, Size: pending.State.Size}
+	defer h.clearProgress(action.ActionID)
+
+	onProgress := func(state *uploader.UploadState) {
+		if err := h.savePendingUpload(pending); err != nil {
+			h.log.Warnw("failed to persist diagnostics upload state", "error.message", err, "action", action)
+		}
+		progress.UploadID = state.UploadID
+		progress.ChunksSent = len(state.ChunkHashes)
+		progress.Chunks = state.Chunks()
+		progress.BytesSent = state.BytesSent()
+		h.reportProgress(progress)
+	}
+
+	var err error
+	for attempt := 1; attempt <= diagnosticsUploadAttempts; attempt++ {
+		progress.Attempt = attempt
+		onProgress(&pending.State)
+
+		err = h.uploadBundle(ctx, base+".zip", pending, onProgress)
+		action.UploadID = pending.State.UploadID
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			h.log.Infof("Diagnostics upload for action %s interrupted, it will be resumed on the next start", action.ActionID)
+			return false
+		}
+		h.log.Warnw(fmt.Sprintf("Diagnostics upload attempt %d/%d failed", attempt, diagnosticsUploadAttempts),
+			"error.message", err,
+			"action", action)
+		if errors.Is(err, uploader.ErrUploadRejected) {
+			// the upload cannot be resumed, the next attempt starts a new one
+			pending.State.Reset()
+			action.UploadID = ""
+		}
+		progress.LastError = err.Error()
+		if attempt == diagnosticsUploadAttempts {
+			break
+		}
+		onProgress(&pending.State)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(diagnosticsUploadBackoff):
+		}
+	}
+
+	if err != nil {
+		action.Err = err
+		h.log.Errorw(
+			"diagnostics action handler failed to upload diagnostics",
+			"error.message", err,
+			"action", action)
+	}
+	h.removePendingUpload(base)
+	return true
+}
+
+// uploadBundle makes a single attempt to upload the bundle at path.
+func (h *Diagnostics) uploadBundle(ctx context.Context, path string, pending *pendingDiagnosticsUpload, onProgress func(*uploader.UploadState)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open diagnostics bundle: %w", err)
+	}
+	defer f.Close()
+	_, err = h.resumableUploader().UploadDiagnosticsResumable(ctx, pending.Action.ActionID, &pending.State, f, onProgress)
+	return err
+}
+
+func (h *Diagnostics) pendingUploadBase(actionID string) string {
+	return filepath.Join(h.uploadsDir, filepath.Base(actionID))
+}
+
+func (h *Diagnostics) savePendingUpload(pending *pendingDiagnosticsUpload) error {
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("failed to marshal diagnostics upload state: %w", err)
+	}
+	stateFile := h.pendingUploadBase(pending.Action.ActionID) + ".json"
+	tmp := stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write diagnostics upload state: %w", err)
+	}
+	if err := os.Rename(tmp, stateFile); err != nil {
+		return fmt.Errorf("failed to write diagnostics upload state: %w", err)
+	}
+	return nil
+}
+
+func (h *Diagnostics) removePendingUpload(base string) {
+	for _, name := range []string{base + ".json", base + ".zip"} {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			h.log.Warnw("failed to remove pending diagnostics upload", "error.message", err, "file", name)
+		}
+	}
+}
+
+func readPendingUpload(stateFile string) (*pendingDiagnosticsUpload, error) {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	var pending pendingDiagnosticsUpload
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, err
+	}
+	if pending.Action == nil {
+		return nil, fmt.Errorf("no action in %s", stateFile)
+	}
+	return &pending, nil
+}
+
+func (h *Diagnostics) reportProgress(p uploader.Progress) {
+	p.UpdatedAt = time.Now().UTC()
+	h.uploads.mx.Lock()
+	defer h.uploads.mx.Unlock()
+	if h.uploads.progress == nil {
+		h.uploads.progress = make(map[string]uploader.Progress)
+	}
+	h.uploads.progress[p.ActionID] = p
+	h.notifyProgress()
+}
+
+func (h *Diagnostics) clearProgress(actionID string) {
+	h.uploads.mx.Lock()
+	defer h.uploads.mx.Unlock()
+	delete(h.uploads.progress, actionID)
+	h.notifyProgress()
+}
+
+// notifyProgress must be called with h.uploads.mx held.
+func (h *Diagnostics) notifyProgress() {
+	if h.onUploadProgress == nil {
+		return
+	}
+	progress := make([]uploader.Progress, 0, len(h.uploads.progress))
+	for _, p := range h.uploads.progress {
+		progress = append(progress, p)
+	}
+	slices.SortFunc(progress, func(a, b uploader.Progress) int {
+		return strings.Compare(a.ActionID, b.ActionID)
+	})
+	h.onUploadProgress(progress)
+}
@@ -0,0 +1,205 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	"github.com/elastic/elastic-agent/pkg/fleetapi"
+)
+
+// fakeResumableUploader confirms a single chunk and then fails with the next
+// error of errs, once errs is empty it confirms all the remaining chunks.
+type fakeResumableUploader struct {
+	Uploader
+
+	mx      sync.Mutex
+	errs    []error
+	resumed []int
+}
+
+func (u *fakeResumableUploader) UploadDiagnosticsResumable(ctx context.Context, _ string, state *uploader.UploadState, r io.ReadSeeker, onProgress func(*uploader.UploadState)) (string, error) {
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	if state.UploadID == "" {
+		state.UploadID = "upload-id"
+		state.ChunkSize = 4
+		onProgress(state)
+	}
+	u.resumed = append(u.resumed, len(state.ChunkHashes))
+
+	if _, err := r.Seek(int64(len(state.ChunkHashes))*state.ChunkSize, io.SeekStart); err != nil {
+		return state.UploadID, err
+	}
+	chunk := make([]byte, state.ChunkSize)
+	n, err := r.Read(chunk)
+	if err != nil {
+		return state.UploadID, err
+	}
+	hash := sha256.Sum256(chunk[:n])
+	state.ChunkHashes = append(state.ChunkHashes, hex.EncodeToString(hash[:]))
+	onProgress(state)
+
+	if len(u.errs) > 0 {
+		err, u.errs = u.errs[0], u.errs[1:]
+		if errors.Is(err, context.Canceled) {
+			return state.UploadID, ctx.Err()
+		}
+		return state.UploadID, err
+	}
+	for len(state.ChunkHashes) < state.Chunks() {
+		state.ChunkHashes = append(state.ChunkHashes, "")
+		onProgress(state)
+	}
+	return state.UploadID, nil
+}
+
+func newTestBundle(t *testing.T, content string) *os.File {
+	f, err := os.CreateTemp(t.TempDir(), "bundle")
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	return f
+}
+
+func TestDiagnosticsResumableUpload(t *testing.T) {
+	diagnosticsUploadBackoff = time.Millisecond
+	dir := t.TempDir()
+	up := &fakeResumableUploader{errs: []error{errors.New("connection reset")}}
+	var reported [][]uploader.Progress
+	testLogger, _ := loggertest.New("diagnostic-handler-test")
+	handler := NewDiagnostics(testLogger, t.TempDir(), newMockDiagnosticsProvider(t), defaultRateLimit, up,
+		WithResumableUploads(dir),
+		WithUploadProgress(func(p []uploader.Progress) {
+			reported = append(reported, p)
+		}))
+
+	action := &fleetapi.ActionDiagnostics{ActionID: "action-1"}
+	pending, err := handler.persistBundle(action, time.Now(), newTestBundle(t, "0123456789"))
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "action-1.zip"))
+	assert.FileExists(t, filepath.Join(dir, "action-1.json"))
+	assert.Equal(t, int64(10), pending.State.Size)
+
+	require.True(t, handler.uploadPending(context.Background(), pending))
+	require.NoError(t, action.Err)
+	assert.Equal(t, "upload-id", action.UploadID)
+	assert.Equal(t, []int{0, 1}, up.resumed, "the second attempt must resume after the confirmed chunk")
+	assert.NoFileExists(t, filepath.Join(dir, "action-1.zip"))
+	assert.NoFileExists(t, filepath.Join(dir, "action-1.json"))
+
+	require.NotEmpty(t, reported)
+	assert.Empty(t, reported[len(reported)-1], "the progress must be cleared once done")
+	var retried bool
+	for _, p := range reported {
+		if len(p) == 1 && p[0].Attempt == 2 {
+			retried = true
+			assert.Equal(t, "connection reset", p[0].LastError)
+			assert.Equal(t, 3, p[0].Chunks)
+			assert.Equal(t, int64(10), p[0].Size)
+		}
+	}
+	assert.True(t, retried, "the progress of the second attempt must be reported")
+}
+
+func TestDiagnosticsResumableUploadFails(t *testing.T) {
+	diagnosticsUploadBackoff = time.Millisecond
+	uploadErr := errors.New("connection reset")
+	up := &fakeResumableUploader{errs: []error{uploadErr, uploadErr, uploadErr}}
+	testLogger, _ := loggertest.New("diagnostic-handler-test")
+	dir := t.TempDir()
+	handler := NewDiagnostics(testLogger, t.TempDir(), newMockDiagnosticsProvider(t), defaultRateLimit, up, WithResumableUploads(dir))
+
+	action := &fleetapi.ActionDiagnostics{ActionID: "action-1"}
+	pending, err := handler.persistBundle(action, time.Now(), newTestBundle(t, "0123456789"))
+	require.NoError(t, err)
+
+	require.True(t, handler.uploadPending(context.Background(), pending))
+	assert.ErrorIs(t, action.Err, uploadErr)
+	assert.Len(t, up.resumed, diagnosticsUploadAttempts)
+	assert.NoFileExists(t, filepath.Join(dir, "action-1.zip"))
+}
+
+func TestDiagnosticsResumableUploadRejected(t *testing.T) {
+	diagnosticsUploadBackoff = time.Millisecond
+	up := &fakeResumableUploader{errs: []error{fmt.Errorf("%w: status code: 404", uploader.ErrUploadRejected)}}
+	testLogger, _ := loggertest.New("diagnostic-handler-test")
+	dir := t.TempDir()
+	handler := NewDiagnostics(testLogger, t.TempDir(), newMockDiagnosticsProvider(t), defaultRateLimit, up, WithResumableUploads(dir))
+
+	action := &fleetapi.ActionDiagnostics{ActionID: "action-1"}
+	pending, err := handler.persistBundle(action, time.Now(), newTestBundle(t, "0123456789"))
+	require.NoError(t, err)
+
+	require.True(t, handler.uploadPending(context.Background(), pending))
+	require.NoError(t, action.Err)
+	assert.Equal(t, []int{0, 0}, up.resumed, "a rejected upload must be started again")
+}
+
+func TestDiagnosticsResumeUploads(t *testing.T) {
+	dir := t.TempDir()
+	testLogger, _ := loggertest.New("diagnostic-handler-test")
+
+	// the agent stops while uploading
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	up := &fakeResumableUploader{errs: []error{context.Canceled}}
+	handler := NewDiagnostics(testLogger, t.TempDir(), newMockDiagnosticsProvider(t), defaultRateLimit, up, WithResumableUploads(dir))
+	action := &fleetapi.ActionDiagnostics{ActionID: "action-1", ActionType: fleetapi.ActionTypeDiagnostics}
+	pending, err := handler.persistBundle(action, time.Now(), newTestBundle(t, "0123456789"))
+	require.NoError(t, err)
+	require.False(t, handler.uploadPending(ctx, pending), "an interrupted upload must not be acked")
+	assert.FileExists(t, filepath.Join(dir, "action-1.zip"))
+	assert.FileExists(t, filepath.Join(dir, "action-1.json"))
+
+	// and resumes the upload on the next start
+	var acked *fleetapi.ActionDiagnostics
+	committed := make(chan struct{})
+	mockAcker := acker.NewMockAcker(t)
+	mockAcker.EXPECT().Ack(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, a fleetapi.Action) error {
+		acked = a.(*fleetapi.ActionDiagnostics)
+		return nil
+	})
+	mockAcker.EXPECT().Commit(mock.Anything).RunAndReturn(func(ctx context.Context) error {
+		close(committed)
+		return nil
+	})
+
+	up = &fakeResumableUploader{}
+	handler = NewDiagnostics(testLogger, t.TempDir(), newMockDiagnosticsProvider(t), defaultRateLimit, up, WithResumableUploads(dir))
+	handler.ResumeUploads(context.Background(), mockAcker)
+
+	select {
+	case <-committed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the resumed upload was not acked")
+	}
+	assert.Equal(t, "action-1", acked.ActionID)
+	assert.Equal(t, "upload-id", acked.UploadID)
+	assert.NoError(t, acked.Err)
+	assert.Equal(t, []int{1}, up.resumed, "the upload must resume after the confirmed chunk")
+	assert.NoFileExists(t, filepath.Join(dir, "action-1.zip"))
+	assert.NoFileExists(t, filepath.Join(dir, "action-1.json"))
+}
@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
@@ -23,6 +25,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
@@ -32,6 +35,11 @@ import (
 	"github.com/elastic/elastic-agent/pkg/fleetapi"
 )
 
+// policyRollbackSetter reports a policy re-applied from the policy history in the Elastic Agent state.
+type policyRollbackSetter interface {
+	SetPolicyRollback(*coordinator.PolicyRollback)
+}
+
 // PolicyChangeHandler is a handler for POLICY_CHANGE action.
 type PolicyChangeHandler struct {
 	log                   *logger.Logger
@@ -43,6 +51,14 @@ type PolicyChangeHandler struct {
 	setters               []actions.ClientSetter
 	runtimeLogLevelSetter logLevelSetter
 	disableAckFn          func() bool
+
+	// history records the applied policies, a policy from it can be re-applied
+	// with RollbackPolicy. mx serializes policy changes and rollbacks.
+	history        *store.PolicyHistory
+	rollbackSetter policyRollbackSetter
+	rolledBack     bool
+	mx             sync.Mutex
+
 	// Disabled for 8.8.0 release in order to limit the surface
 	// https://github.com/elastic/security-team/issues/6501
 	// // Last known valid signature validation key
@@ -82,6 +98,13 @@ func (h *PolicyChangeHandler) AddSetter(cs actions.ClientSetter) {
 	h.setters = append(h.setters, cs)
 }
 
+// SetPolicyHistory sets the history recording the applied policies. The rollbackSetter is notified
+// when a policy from the history is re-applied and when a new policy replaces it.
+func (h *PolicyChangeHandler) SetPolicyHistory(history *store.PolicyHistory, rollbackSetter policyRollbackSetter) {
+	h.history = history
+	h.rollbackSetter = rollbackSetter
+}
+
 // Handle handles policy change action.
 func (h *PolicyChangeHandler) Handle(ctx context.Context, a fleetapi.Action, acker acker.Acker) error {
 	h.log.Debugf("handlerPolicyChange: action '%+v' received", a)
@@ -90,6 +113,9 @@ func (h *PolicyChangeHandler) Handle(ctx context.Context, a fleetapi.Action, ack
 		return fmt.Errorf("invalid type, expected ActionPolicyChange and received %T", a)
 	}
 
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
 	// Disabled for 8.8.0 release in order to limit the surface
 	// https://github.com/elastic/security-team/issues/6501
 
@@ -113,11 +139,98 @@ func (h *PolicyChangeHandler) Handle(ctx context.Context, a fleetapi.Action, ack
 	if err != nil {
 		return err
 	}
+	h.recordPolicyChange(action)
 
-	h.ch <- newPolicyChange(ctx, h.log, c, a, acker, false, h.disableAckFn())
+	change := newPolicyChange(ctx, h.log, c, a, acker, false, h.disableAckFn())
+	change.history = h.history
+	h.ch <- change
 	return nil
 }
 
+// PolicyHistory returns the applied policies, from the oldest to the latest.
+func (h *PolicyChangeHandler) PolicyHistory() []store.PolicyHistoryEntry {
+	if h.history == nil {
+		return nil
+	}
+	return h.history.Entries()
+}
+
+// RollbackPolicy re-applies the most recently applied policy with the provided policy ID and
+// revision from the policy history, the policy ID defaults to the one of the currently applied
+// policy. The policy goes through the same steps as a policy received from Fleet, except the
+// validation of the Fleet Server hosts as Fleet can be unreachable, and it is persisted so it is
+// restored on start, but it is never acknowledged to Fleet.
+func (h *PolicyChangeHandler) RollbackPolicy(ctx context.Context, policyID string, revision int64) (*coordinator.PolicyRollback, error) {
+	if h.history == nil {
+		return nil, errors.New("policy history is not enabled")
+	}
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	latest, hasLatest := h.history.Latest()
+	if policyID == "" {
+		if !hasLatest {
+			return nil, errors.New("the policy history is empty")
+		}
+		policyID = latest.PolicyID
+	}
+	entry, ok := h.history.FindRevision(policyID, revision)
+	if !ok {
+		return nil, fmt.Errorf("revision %d of policy %s is not in the policy history", revision, policyID)
+	}
+	if hasLatest && latest.ActionID == entry.ActionID {
+		return nil, fmt.Errorf("revision %d of policy %s is the currently applied policy", revision, policyID)
+	}
+
+	action := entry.Action()
+	c, err := config.NewConfigFrom(action.Data.Policy)
+	if err != nil {
+		return nil, errors.New(err, "could not parse the configuration from the policy history", errors.TypeConfig)
+	}
+
+	h.log.Infow("Rolling back to a policy from the policy history",
+		"policy_id", entry.PolicyID, "revision", entry.Revision, "action_id", entry.ActionID)
+	if err := h.applyPolicyChange(ctx, c, action, true); err != nil {
+		return nil, err
+	}
+	applied, err := h.history.AddRollback(entry, time.Now())
+	if err != nil {
+		h.log.Warnf("failed to persist policy history: %v", err)
+	}
+	rollback := coordinator.NewPolicyRollback(applied)
+	h.setPolicyRollback(rollback)
+
+	// the policy was already acknowledged, or not, when it was received from Fleet
+	change := newPolicyChange(ctx, h.log, c, action, nil, false, true)
+	h.ch <- change
+	return rollback, nil
+}
+
+// recordPolicyChange adds the applied policy to the policy history. When the policy is a rollback
+// restored on start it is reported again, otherwise a previously reported rollback is cleared.
+func (h *PolicyChangeHandler) recordPolicyChange(action *fleetapi.ActionPolicyChange) {
+	if h.history == nil {
+		return
+	}
+	entry, err := h.history.Add(action, time.Now())
+	if err != nil {
+		h.log.Warnf("failed to persist policy history: %v", err)
+	}
+	if entry.Rollback {
+		h.setPolicyRollback(coordinator.NewPolicyRollback(entry))
+	} else if h.rolledBack {
+		h.setPolicyRollback(nil)
+	}
+}
+
+func (h *PolicyChangeHandler) setPolicyRollback(rollback *coordinator.PolicyRollback) {
+	h.rolledBack = rollback != nil
+	if h.rollbackSetter != nil {
+		h.rollbackSetter.SetPolicyRollback(rollback)
+	}
+}
+
 // Watch returns the channel for configuration change notifications.
 func (h *PolicyChangeHandler) Watch() <-chan coordinator.ConfigChange {
 	return h.ch
@@ -237,6 +350,12 @@ func emptyCertificateConfig() tlscommon.CertificateConfig {
 }
 
 func (h *PolicyChangeHandler) handlePolicyChange(ctx context.Context, c *config.Config, action *fleetapi.ActionPolicyChange) error {
+	return h.applyPolicyChange(ctx, c, action, false)
+}
+
+// applyPolicyChange applies the policy. On a rollback the Fleet Server hosts of the policy are not
+// validated, nor applied, the current Fleet client configuration is kept.
+func (h *PolicyChangeHandler) applyPolicyChange(ctx context.Context, c *config.Config, action *fleetapi.ActionPolicyChange, rollback bool) error {
 	partialCfg, err := configuration.NewPartialFromConfigNoDefaults(c)
 	if err != nil {
 		return fmt.Errorf("parsing fleet config: %w", err)
@@ -244,9 +363,12 @@ func (h *PolicyChangeHandler) handlePolicyChange(ctx context.Context, c *config.
 
 	// Step 1: Validate policy configuration.
 	var validationErr error
-	validatedFleetConfig, err := h.validateFleetServerHosts(ctx, partialCfg)
-	if err != nil {
-		validationErr = goerrors.Join(validationErr, fmt.Errorf("failed to validate Fleet client config: %w", err))
+	var validatedFleetConfig *remote.Config
+	if !rollback {
+		validatedFleetConfig, err = h.validateFleetServerHosts(ctx, partialCfg)
+		if err != nil {
+			validationErr = goerrors.Join(validationErr, fmt.Errorf("failed to validate Fleet client config: %w", err))
+		}
 	}
 	loggingConfig, err := validateLoggingConfig(partialCfg)
 	if err != nil {
@@ -515,6 +637,7 @@ type policyChange struct {
 	acker      acker.Acker
 	ackWatcher chan struct{}
 	disableAck bool
+	history    *store.PolicyHistory
 }
 
 func newPolicyChange(
@@ -566,6 +689,11 @@ func (l *policyChange) Ack() error {
 		if err := l.acker.Commit(l.ctx); err != nil {
 			return err
 		}
+		if l.history != nil {
+			if err := l.history.SetAcked(l.action.ID()); err != nil {
+				l.log.Warnf("failed to persist policy history: %v", err)
+			}
+		}
 		if l.ackWatcher != nil {
 			close(l.ackWatcher)
 		}
@@ -18,6 +18,7 @@ import (
 	"strconv"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -33,6 +34,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	noopacker "github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/noop"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
@@ -196,6 +198,138 @@ func TestPolicyAcked(t *testing.T) {
 	})
 }
 
+type testPolicyRollbackSetter struct {
+	rollbacks []*coordinator.PolicyRollback
+}
+
+func (s *testPolicyRollbackSetter) SetPolicyRollback(rollback *coordinator.PolicyRollback) {
+	s.rollbacks = append(s.rollbacks, rollback)
+}
+
+func TestPolicyChangeRollback(t *testing.T) {
+	log, _ := loggertest.New("TestPolicyChangeRollback")
+	agentInfo := &info.AgentInfo{}
+	nullStore := &storage.NullStore{}
+
+	historyStore, err := storage.NewDiskStore(filepath.Join(t.TempDir(), "policy_history.enc"))
+	require.NoError(t, err)
+	history := store.NewPolicyHistory(log, historyStore, store.DefaultPolicyHistorySize)
+
+	stateSaver := &mockStateStore{}
+	stateSaver.On("SetAction", mock.Anything).Return()
+	stateSaver.On("Save").Return(nil)
+
+	logLevelSetter := newMockLogLevelSetter(t)
+	logLevelSetter.EXPECT().SetLogLevel(mock.Anything, mock.Anything).Return(nil)
+
+	ch := make(chan coordinator.ConfigChange, 1)
+	handler := NewPolicyChangeHandler(log, agentInfo, configuration.DefaultConfiguration(), nullStore, stateSaver, ch, logLevelSetter)
+	rollbackSetter := &testPolicyRollbackSetter{}
+	handler.SetPolicyHistory(history, rollbackSetter)
+	handler.disableAckFn = func() bool { return false }
+
+	policyAction := func(revision int) *fleetapi.ActionPolicyChange {
+		return &fleetapi.ActionPolicyChange{
+			ActionID:   fmt.Sprintf("action-%d", revision),
+			ActionType: fleetapi.ActionTypePolicyChange,
+			Data: fleetapi.ActionPolicyChangeData{
+				Policy: map[string]interface{}{
+					"id":       "policy-1",
+					"revision": revision,
+					"hello":    fmt.Sprintf("world-%d", revision),
+				},
+			},
+		}
+	}
+
+	tacker := &testAcker{}
+	require.NoError(t, handler.Handle(context.Background(), policyAction(1), tacker))
+	require.NoError(t, (<-ch).Ack())
+	require.NoError(t, handler.Handle(context.Background(), policyAction(2), tacker))
+	<-ch // revision 2 is never acked
+	assert.Equal(t, []string{"action-1"}, tacker.Items())
+
+	entries := handler.PolicyHistory()
+	require.Len(t, entries, 2)
+	assert.True(t, entries[0].Acked)
+	assert.False(t, entries[1].Acked)
+
+	_, err = handler.RollbackPolicy(context.Background(), "", 2)
+	assert.ErrorContains(t, err, "currently applied")
+	_, err = handler.RollbackPolicy(context.Background(), "", 5)
+	assert.ErrorContains(t, err, "not in the policy history")
+	_, err = handler.RollbackPolicy(context.Background(), "policy-2", 1)
+	assert.ErrorContains(t, err, "revision 1 of policy policy-2 is not in the policy history")
+
+	rollback, err := handler.RollbackPolicy(context.Background(), "policy-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "action-1", rollback.ActionID)
+	assert.Equal(t, int64(1), rollback.Revision)
+	assert.Equal(t, int64(2), rollback.FromRevision)
+
+	change := <-ch
+	m, err := change.Config().ToMapStr()
+	require.NoError(t, err)
+	assert.Equal(t, "world-1", m["hello"])
+	require.NoError(t, change.Ack())
+	assert.Equal(t, []string{"action-1"}, tacker.Items(), "a rollback must not be acked to Fleet")
+
+	// a new policy from Fleet clears the rollback
+	require.NoError(t, handler.Handle(context.Background(), policyAction(3), tacker))
+	<-ch
+	require.Len(t, rollbackSetter.rollbacks, 2)
+	assert.Equal(t, rollback, rollbackSetter.rollbacks[0])
+	assert.Nil(t, rollbackSetter.rollbacks[1])
+
+	entries = handler.PolicyHistory()
+	require.Len(t, entries, 4)
+	assert.True(t, entries[2].Rollback)
+	assert.Equal(t, int64(3), entries[3].Revision)
+}
+
+func TestPolicyChangeRollbackFleetUnreachable(t *testing.T) {
+	log, _ := loggertest.New("TestPolicyChangeRollbackFleetUnreachable")
+	historyStore, err := storage.NewDiskStore(filepath.Join(t.TempDir(), "policy_history.enc"))
+	require.NoError(t, err)
+	history := store.NewPolicyHistory(log, historyStore, store.DefaultPolicyHistorySize)
+
+	logLevelSetter := newMockLogLevelSetter(t)
+	logLevelSetter.EXPECT().SetLogLevel(mock.Anything, mock.Anything).Return(nil)
+
+	var setterCalledCount int
+	setter := &testSetter{SetClientFn: func(c client.Sender) {
+		setterCalledCount++
+	}}
+
+	ch := make(chan coordinator.ConfigChange, 1)
+	handler := NewPolicyChangeHandler(log, &info.AgentInfo{}, configuration.DefaultConfiguration(), &storage.NullStore{}, nil, ch, logLevelSetter, setter)
+	handler.SetPolicyHistory(history, &testPolicyRollbackSetter{})
+
+	// the Fleet Server host of the policies is unreachable
+	fleetServer := httptest.NewServer(http.NotFoundHandler())
+	fleetServer.Close()
+	for revision := 1; revision <= 2; revision++ {
+		_, err := history.Add(&fleetapi.ActionPolicyChange{
+			ActionID:   fmt.Sprintf("action-%d", revision),
+			ActionType: fleetapi.ActionTypePolicyChange,
+			Data: fleetapi.ActionPolicyChangeData{
+				Policy: map[string]interface{}{
+					"id":       "policy-1",
+					"revision": revision,
+					"fleet":    map[string]interface{}{"hosts": []interface{}{fleetServer.URL}},
+				},
+			},
+		}, time.Now())
+		require.NoError(t, err)
+	}
+
+	rollback, err := handler.RollbackPolicy(context.Background(), "", 1)
+	require.NoError(t, err, "a rollback must not validate the Fleet Server hosts")
+	assert.Equal(t, int64(1), rollback.Revision)
+	<-ch
+	assert.Zero(t, setterCalledCount, "a rollback must keep the current Fleet client")
+}
+
 func TestPolicyChangeHandler_handlePolicyChange_FleetClientSettings(t *testing.T) {
 	mockProxy := httptest.NewServer(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
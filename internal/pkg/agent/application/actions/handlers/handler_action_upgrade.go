@@ -12,6 +12,7 @@ import (
 
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/maintenance"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/features"
@@ -54,6 +55,19 @@ func (h *Upgrade) Handle(ctx context.Context, a fleetapi.Action, ack acker.Acker
 		return fmt.Errorf("invalid type, expected ActionUpgrade and received %T", a)
 	}
 
+	// upgrades wait for the next maintenance window, the dispatcher queues the deferred action
+	// until the window opens, rollbacks are never deferred
+	if !action.Data.Rollback {
+		deferredUntil, err := h.coord.MaintenanceDeferral()
+		if err != nil {
+			return err
+		}
+		if !deferredUntil.IsZero() {
+			h.log.Infof("Upgrade to version %s deferred until the next maintenance window at %s", action.Data.Version, deferredUntil)
+			return &maintenance.DeferredError{Until: deferredUntil}
+		}
+	}
+
 	asyncCtx, runAsync := h.getAsyncContext(ctx, action, ack)
 	if !runAsync {
 		return nil
@@ -22,6 +22,7 @@ import (
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/maintenance"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
@@ -423,6 +424,7 @@ func TestEndpointPreUpgradeCallback(t *testing.T) {
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			mockCoordinator := newMockUpgradeCoordinator(t)
+			mockCoordinator.EXPECT().MaintenanceDeferral().Return(time.Time{}, nil)
 
 			var coordState coordinator.State
 			if tc.shouldProxyToEndpoint {
@@ -527,3 +529,37 @@ func (f *fakeAcker) Commit(ctx context.Context) error {
 	args := f.Called(ctx)
 	return args.Error(0)
 }
+
+func TestUpgradeHandlerDeferredUntilMaintenanceWindow(t *testing.T) {
+	deferredUntil := time.Now().Add(time.Hour).Truncate(time.Minute)
+	mockCoordinator := newMockUpgradeCoordinator(t)
+	mockCoordinator.EXPECT().MaintenanceDeferral().Return(deferredUntil, nil)
+
+	log, _ := logger.New("", false)
+	u := NewUpgrade(log, mockCoordinator)
+	a := &fleetapi.ActionUpgrade{Data: fleetapi.ActionUpgradeData{
+		Version: "8.3.0", SourceURI: "http://localhost"}}
+
+	err := u.Handle(t.Context(), a, acker.NewMockAcker(t))
+	var deferredErr *maintenance.DeferredError
+	require.ErrorAs(t, err, &deferredErr)
+	assert.Equal(t, deferredUntil, deferredErr.Until)
+	assert.Empty(t, u.bkgActions, "a deferred upgrade must not be started")
+
+	// rollbacks are never deferred
+	rollback := &fleetapi.ActionUpgrade{Data: fleetapi.ActionUpgradeData{
+		Version: "8.2.0", Rollback: true}}
+	upgradeCalled := make(chan struct{})
+	mockCoordinator.EXPECT().Upgrade(mock.Anything, "8.2.0", "", rollback, mock.AnythingOfType("coordinator.UpgradeOpt")).
+		RunAndReturn(func(context.Context, string, string, *fleetapi.ActionUpgrade, ...coordinator.UpgradeOpt) error {
+			close(upgradeCalled)
+			return nil
+		})
+	u.tamperProtectionFn = func() bool { return false }
+	require.NoError(t, u.Handle(t.Context(), rollback, acker.NewMockAcker(t)))
+	select {
+	case <-upgradeCalled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("rollback was not started")
+	}
+}
@@ -30,6 +30,7 @@ type actionCoordinator interface {
 
 type upgradeCoordinator interface {
 	actionCoordinator
+	MaintenanceDeferral() (time.Time, error)
 	Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, opts ...coordinator.UpgradeOpt) error
 }
 
@@ -11,6 +11,7 @@ package handlers
 import (
 	"context"
 	"io"
+	"time"
 
 	mock "github.com/stretchr/testify/mock"
 
@@ -394,6 +395,59 @@ func (_m *mockUpgradeCoordinator) EXPECT() *mockUpgradeCoordinator_Expecter {
 	return &mockUpgradeCoordinator_Expecter{mock: &_m.Mock}
 }
 
+// MaintenanceDeferral provides a mock function for the type mockUpgradeCoordinator
+func (_mock *mockUpgradeCoordinator) MaintenanceDeferral() (time.Time, error) {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MaintenanceDeferral")
+	}
+
+	var r0 time.Time
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() (time.Time, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() time.Time); ok {
+		r0 = returnFunc()
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// mockUpgradeCoordinator_MaintenanceDeferral_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MaintenanceDeferral'
+type mockUpgradeCoordinator_MaintenanceDeferral_Call struct {
+	*mock.Call
+}
+
+// MaintenanceDeferral is a helper method to define mock.On call
+func (_e *mockUpgradeCoordinator_Expecter) MaintenanceDeferral() *mockUpgradeCoordinator_MaintenanceDeferral_Call {
+	return &mockUpgradeCoordinator_MaintenanceDeferral_Call{Call: _e.mock.On("MaintenanceDeferral")}
+}
+
+func (_c *mockUpgradeCoordinator_MaintenanceDeferral_Call) Run(run func()) *mockUpgradeCoordinator_MaintenanceDeferral_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *mockUpgradeCoordinator_MaintenanceDeferral_Call) Return(time1 time.Time, err error) *mockUpgradeCoordinator_MaintenanceDeferral_Call {
+	_c.Call.Return(time1, err)
+	return _c
+}
+
+func (_c *mockUpgradeCoordinator_MaintenanceDeferral_Call) RunAndReturn(run func() (time.Time, error)) *mockUpgradeCoordinator_MaintenanceDeferral_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // PerformAction provides a mock function for the type mockUpgradeCoordinator
 func (_mock *mockUpgradeCoordinator) PerformAction(ctx context.Context, comp component.Component, unit component.Unit, name string, params map[string]interface{}) (map[string]interface{}, error) {
 	ret := _mock.Called(ctx, comp, unit, name, params)
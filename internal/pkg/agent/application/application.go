@@ -217,13 +217,23 @@ func New(
 				return nil, nil, nil, errors.New(err, fmt.Sprintf("fail to read state store '%s'", paths.AgentStateStoreFile()))
 			}
 
+			policyHistory, err := stateStore.NewEncryptedPolicyHistory(ctx, log, paths.AgentPolicyHistoryFile(), stateStore.DefaultPolicyHistorySize)
+			if err != nil {
+				return nil, nil, nil, errors.New(err, fmt.Sprintf("fail to create policy history '%s'", paths.AgentPolicyHistoryFile()))
+			}
+
 			fleetAcker, err := fleet.NewAcker(log, agentInfo, client)
 			if err != nil {
 				return nil, nil, nil, fmt.Errorf("failed to create acker: %w", err)
 			}
 
-			retrier := retrier.New(fleetAcker, log)
-			actionAcker = lazy.NewAcker(fleetAcker, log, lazy.WithRetrier(retrier))
+			ackStore, err := stateStore.NewEncryptedAckStore(ctx, log, paths.AgentAckStoreFile(), stateStore.DefaultAckStoreSize)
+			if err != nil {
+				return nil, nil, nil, errors.New(err, fmt.Sprintf("fail to create ack store '%s'", paths.AgentAckStoreFile()))
+			}
+
+			retrier := retrier.New(fleetAcker, log, retrier.WithStore(ackStore))
+			actionAcker = lazy.NewAcker(fleetAcker, log, lazy.WithRetrier(retrier), lazy.WithStore(ackStore))
 
 			actionQueue, err := queue.NewActionQueue(stateStorage.Queue(), stateStorage)
 			if err != nil {
@@ -241,7 +251,7 @@ func New(
 				return nil, nil, nil, fmt.Errorf("failed to create encrypted disk store: %w", err)
 			}
 			// TODO: stop using global state
-			managed, err = newManagedConfigManager(ctx, log, agentInfo, cfg, store, runtime, fleetInitTimeout, paths.Top(), client, fleetAcker, actionAcker, retrier, stateStorage, actionQueue, availableRollbacksSource, upgrader)
+			managed, err = newManagedConfigManager(ctx, log, agentInfo, cfg, store, runtime, fleetInitTimeout, paths.Top(), client, fleetAcker, actionAcker, retrier, ackStore, stateStorage, policyHistory, actionQueue, availableRollbacksSource, upgrader)
 			if err != nil {
 				return nil, nil, nil, err
 			}
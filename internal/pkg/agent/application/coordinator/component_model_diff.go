@@ -0,0 +1,198 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package coordinator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// unitLogLevelKey is reported as a changed key when only the log level of a unit changed.
+const unitLogLevelKey = "log_level"
+
+// ComponentModelDiff is the difference between the previous and the current component model
+// computed by the Coordinator.
+type ComponentModelDiff struct {
+	// Timestamp is when the current component model was computed.
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	// ComponentsAdded are the IDs of the components only present in the current model.
+	ComponentsAdded []string `json:"components_added,omitempty" yaml:"components_added,omitempty"`
+	// ComponentsRemoved are the IDs of the components only present in the previous model.
+	ComponentsRemoved []string `json:"components_removed,omitempty" yaml:"components_removed,omitempty"`
+	// ComponentsChanged are the components present in both models whose units changed.
+	ComponentsChanged []ComponentDiff `json:"components_changed,omitempty" yaml:"components_changed,omitempty"`
+}
+
+// ComponentDiff is the difference of the units of a component present in both models.
+type ComponentDiff struct {
+	ID           string     `json:"id" yaml:"id"`
+	UnitsAdded   []string   `json:"units_added,omitempty" yaml:"units_added,omitempty"`
+	UnitsRemoved []string   `json:"units_removed,omitempty" yaml:"units_removed,omitempty"`
+	UnitsChanged []UnitDiff `json:"units_changed,omitempty" yaml:"units_changed,omitempty"`
+}
+
+// UnitDiff describes a unit whose configuration changed between both models.
+type UnitDiff struct {
+	ID           string          `json:"id" yaml:"id"`
+	Type         client.UnitType `json:"type" yaml:"type"`
+	PreviousHash string          `json:"previous_hash" yaml:"previous_hash"`
+	CurrentHash  string          `json:"current_hash" yaml:"current_hash"`
+	// ChangedKeys are the flattened keys of the unit configuration that were added, removed or modified.
+	ChangedKeys []string `json:"changed_keys,omitempty" yaml:"changed_keys,omitempty"`
+}
+
+// Empty returns true when both component models were identical.
+func (d *ComponentModelDiff) Empty() bool {
+	return len(d.ComponentsAdded) == 0 && len(d.ComponentsRemoved) == 0 && len(d.ComponentsChanged) == 0
+}
+
+// ComponentModelDiff returns the difference computed on the last change of the component model.
+//
+// Returns nil when the component model has not changed since the Coordinator started.
+func (c *Coordinator) ComponentModelDiff() *ComponentModelDiff {
+	c.componentModelDiffMx.RLock()
+	defer c.componentModelDiffMx.RUnlock()
+	return c.componentModelDiff
+}
+
+// setComponentModelDiff records the difference between the previous and the current model,
+// the difference is only kept when something actually changed so a recomputation of the
+// same model does not hide the last change.
+func (c *Coordinator) setComponentModelDiff(lastComponentModel []component.Component, componentModel []component.Component) {
+	if lastComponentModel == nil {
+		// initial component model, nothing to compare to
+		return
+	}
+	diff := diffComponentModel(lastComponentModel, componentModel)
+	if diff.Empty() {
+		return
+	}
+	diff.Timestamp = time.Now().UTC()
+
+	c.componentModelDiffMx.Lock()
+	defer c.componentModelDiffMx.Unlock()
+	c.componentModelDiff = &diff
+}
+
+// diffComponentModel computes the difference between two component models.
+func diffComponentModel(last, current []component.Component) ComponentModelDiff {
+	var diff ComponentModelDiff
+	lastCompMap := convertComponentListToMap(last)
+	currentCompMap := convertComponentListToMap(current)
+
+	for id, comp := range currentCompMap {
+		lastComp, ok := lastCompMap[id]
+		if !ok {
+			diff.ComponentsAdded = append(diff.ComponentsAdded, id)
+			continue
+		}
+		compDiff := diffComponentUnits(lastComp, comp)
+		if len(compDiff.UnitsAdded) > 0 || len(compDiff.UnitsRemoved) > 0 || len(compDiff.UnitsChanged) > 0 {
+			diff.ComponentsChanged = append(diff.ComponentsChanged, compDiff)
+		}
+	}
+	for id := range lastCompMap {
+		if _, ok := currentCompMap[id]; !ok {
+			diff.ComponentsRemoved = append(diff.ComponentsRemoved, id)
+		}
+	}
+
+	sort.Strings(diff.ComponentsAdded)
+	sort.Strings(diff.ComponentsRemoved)
+	sort.Slice(diff.ComponentsChanged, func(i, j int) bool {
+		return diff.ComponentsChanged[i].ID < diff.ComponentsChanged[j].ID
+	})
+	return diff
+}
+
+func diffComponentUnits(last, current component.Component) ComponentDiff {
+	diff := ComponentDiff{ID: current.ID}
+	lastUnits := convertUnitListToMap(last.Units)
+	currentUnits := convertUnitListToMap(current.Units)
+
+	for id, unit := range currentUnits {
+		lastUnit, ok := lastUnits[id]
+		if !ok {
+			diff.UnitsAdded = append(diff.UnitsAdded, id)
+			continue
+		}
+		lastCfg := unitConfigMap(lastUnit)
+		currentCfg := unitConfigMap(unit)
+		changedKeys := diffConfigKeys(lastCfg, currentCfg)
+		if lastUnit.LogLevel != unit.LogLevel {
+			changedKeys = append(changedKeys, unitLogLevelKey)
+		}
+		if len(changedKeys) > 0 {
+			diff.UnitsChanged = append(diff.UnitsChanged, UnitDiff{
+				ID:           id,
+				Type:         unit.Type,
+				PreviousHash: hashUnitConfig(lastCfg),
+				CurrentHash:  hashUnitConfig(currentCfg),
+				ChangedKeys:  changedKeys,
+			})
+		}
+	}
+	for id := range lastUnits {
+		if _, ok := currentUnits[id]; !ok {
+			diff.UnitsRemoved = append(diff.UnitsRemoved, id)
+		}
+	}
+
+	sort.Strings(diff.UnitsAdded)
+	sort.Strings(diff.UnitsRemoved)
+	sort.Slice(diff.UnitsChanged, func(i, j int) bool {
+		return diff.UnitsChanged[i].ID < diff.UnitsChanged[j].ID
+	})
+	return diff
+}
+
+// unitConfigMap returns the configuration of the unit as a map.
+func unitConfigMap(unit component.Unit) map[string]interface{} {
+	if unit.Config == nil || unit.Config.GetSource() == nil {
+		return map[string]interface{}{}
+	}
+	return unit.Config.GetSource().AsMap()
+}
+
+// diffConfigKeys returns the sorted flattened keys that differ between both configurations.
+func diffConfigKeys(last, current map[string]interface{}) []string {
+	lastFlat := mapstr.M(last).Flatten()
+	currentFlat := mapstr.M(current).Flatten()
+
+	var changed []string
+	for key, value := range currentFlat {
+		lastValue, ok := lastFlat[key]
+		if !ok || !reflect.DeepEqual(lastValue, value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range lastFlat {
+		if _, ok := currentFlat[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// hashUnitConfig returns a SHA-256 hash of the unit configuration. Keys of maps are always
+// serialized in order so the same configuration always produces the same hash.
+func hashUnitConfig(cfg map[string]interface{}) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		// not possible, configuration comes from a structpb.Struct
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
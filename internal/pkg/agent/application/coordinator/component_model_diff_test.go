@@ -0,0 +1,118 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package coordinator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func TestDiffComponentModel(t *testing.T) {
+	inputUnit := func(id string, cfg map[string]interface{}) component.Unit {
+		return component.Unit{
+			ID:       id,
+			Type:     client.UnitTypeInput,
+			LogLevel: client.UnitLogLevelInfo,
+			Config:   component.MustExpectedConfig(cfg),
+		}
+	}
+
+	last := []component.Component{
+		{
+			ID: "filestream-default",
+			Units: []component.Unit{
+				inputUnit("filestream-default-logs", map[string]interface{}{
+					"id":    "logs",
+					"type":  "filestream",
+					"paths": []interface{}{"/var/log/*.log"},
+				}),
+				inputUnit("filestream-default-removed", map[string]interface{}{
+					"id":   "removed",
+					"type": "filestream",
+				}),
+			},
+		},
+		{
+			ID: "system/metrics-default",
+		},
+	}
+	current := []component.Component{
+		{
+			ID: "filestream-default",
+			Units: []component.Unit{
+				inputUnit("filestream-default-logs", map[string]interface{}{
+					"id":    "logs",
+					"type":  "filestream",
+					"paths": []interface{}{"/var/log/*.log", "/var/log/app/*.log"},
+					"parsers": map[string]interface{}{
+						"ndjson": true,
+					},
+				}),
+				inputUnit("filestream-default-added", map[string]interface{}{
+					"id":   "added",
+					"type": "filestream",
+				}),
+			},
+		},
+		{
+			ID: "log-default",
+		},
+	}
+
+	diff := diffComponentModel(last, current)
+	assert.Equal(t, []string{"log-default"}, diff.ComponentsAdded)
+	assert.Equal(t, []string{"system/metrics-default"}, diff.ComponentsRemoved)
+	require.Len(t, diff.ComponentsChanged, 1)
+
+	compDiff := diff.ComponentsChanged[0]
+	assert.Equal(t, "filestream-default", compDiff.ID)
+	assert.Equal(t, []string{"filestream-default-added"}, compDiff.UnitsAdded)
+	assert.Equal(t, []string{"filestream-default-removed"}, compDiff.UnitsRemoved)
+	require.Len(t, compDiff.UnitsChanged, 1)
+
+	unitDiff := compDiff.UnitsChanged[0]
+	assert.Equal(t, "filestream-default-logs", unitDiff.ID)
+	assert.Equal(t, client.UnitTypeInput, unitDiff.Type)
+	assert.Equal(t, []string{"parsers.ndjson", "paths"}, unitDiff.ChangedKeys)
+	assert.NotEmpty(t, unitDiff.PreviousHash)
+	assert.NotEqual(t, unitDiff.PreviousHash, unitDiff.CurrentHash)
+}
+
+func TestDiffComponentModelNoChanges(t *testing.T) {
+	comps := []component.Component{
+		{
+			ID: "filestream-default",
+			Units: []component.Unit{
+				{
+					ID:       "filestream-default-logs",
+					Type:     client.UnitTypeInput,
+					LogLevel: client.UnitLogLevelInfo,
+					Config: component.MustExpectedConfig(map[string]interface{}{
+						"id":   "logs",
+						"type": "filestream",
+					}),
+				},
+			},
+		},
+	}
+
+	diff := diffComponentModel(comps, comps)
+	assert.True(t, diff.Empty())
+
+	changedLevel := []component.Component{comps[0]}
+	changedLevel[0].Units = []component.Unit{comps[0].Units[0]}
+	changedLevel[0].Units[0].LogLevel = client.UnitLogLevelDebug
+	diff = diffComponentModel(comps, changedLevel)
+	require.Len(t, diff.ComponentsChanged, 1)
+	require.Len(t, diff.ComponentsChanged[0].UnitsChanged, 1)
+	unitDiff := diff.ComponentsChanged[0].UnitsChanged[0]
+	assert.Equal(t, []string{unitLogLevelKey}, unitDiff.ChangedKeys)
+	assert.Equal(t, unitDiff.PreviousHash, unitDiff.CurrentHash)
+}
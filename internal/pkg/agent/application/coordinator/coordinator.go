@@ -36,6 +36,7 @@ import (
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/enroll"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/maintenance"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
@@ -43,6 +44,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
@@ -50,6 +52,7 @@ import (
 	internalfleetapi "github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	fleetapiClient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -160,6 +163,9 @@ type RuntimeManager interface {
 	// PerformComponentDiagnostics executes the diagnostic action for the provided components. If no components are provided,
 	// then it performs the diagnostics for all current units.
 	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
+
+	// ResumeComponent releases a quarantined component and starts it again.
+	ResumeComponent(componentID string) error
 }
 
 // OTelManager provides an interface to run components and plain otel configurations in an otel collector.
@@ -381,6 +387,25 @@ type Coordinator struct {
 	// SetUpgradeDetails helper to the Coordinator goroutine.
 	upgradeDetailsChan chan *details.Details
 
+	// policyRollbackChan forwards policy rollback details from the publicly
+	// accessible SetPolicyRollback helper to the Coordinator goroutine.
+	policyRollbackChan chan *PolicyRollback
+
+	// pendingAcksChan forwards the acks not delivered to Fleet yet from the
+	// publicly accessible SetPendingAcks helper to the Coordinator goroutine.
+	// It is buffered so SetPendingAcks never blocks.
+	pendingAcksChan chan []store.PendingAck
+
+	// diagnosticsUploadsChan forwards the progress of the diagnostics uploads
+	// from the publicly accessible SetDiagnosticsUploads helper to the
+	// Coordinator goroutine. It is buffered so SetDiagnosticsUploads never
+	// blocks.
+	diagnosticsUploadsChan chan []uploader.Progress
+
+	// maintenanceRestartChan forwards restarts deferred until the next
+	// maintenance window from Restart to the Coordinator goroutine.
+	maintenanceRestartChan chan struct{}
+
 	// loglevelCh forwards log level changes from the public API (SetLogLevel)
 	// to the run loop in Coordinator's main goroutine.
 	logLevelCh chan logp.Level
@@ -432,6 +457,16 @@ type Coordinator struct {
 	// value that is sent to the runtime manager).
 	componentModel []component.Component
 
+	// The difference computed on the last change of the component model.
+	// Guarded by componentModelDiffMx as it is read by the control protocol.
+	componentModelDiff   *ComponentModelDiff
+	componentModelDiffMx sync.RWMutex
+
+	// policyRollbacker re-applies policies from the policy history, only
+	// registered when the Elastic Agent is managed by Fleet.
+	policyRollbacker   PolicyRollbacker
+	policyRollbackerMx sync.RWMutex
+
 	// pendingTransitions tracks deferred manager updates for components
 	// moving between the process and OTel runtimes, one per direction.
 	pendingTransitions pendingRuntimeTransitions
@@ -442,6 +477,34 @@ type Coordinator struct {
 	// any new model is processed.
 	queuedModel *component.Model
 
+	// maintenance is the schedule of the maintenance windows from the
+	// configuration, nil when maintenance windows are not enabled. It is
+	// replaced on the Coordinator goroutine when the configuration changes
+	// and read from external goroutines.
+	maintenance atomic.Pointer[maintenance.Schedule]
+
+	// policyChangeInProgress is set while a policy change is processed, only
+	// policy changes are deferred until the next maintenance window.
+	policyChangeInProgress bool
+
+	// maintenanceModel holds a component model deferred until the next
+	// maintenance window because it stops running components.
+	maintenanceModel *component.Model
+
+	// maintenanceRestart is set when a restart is deferred until the next
+	// maintenance window.
+	maintenanceRestart bool
+
+	// maintenanceTimer fires when the next maintenance window opens, only
+	// set while operations are deferred.
+	maintenanceTimer *time.Timer
+
+	// timeConditions is set when a condition of the policy depends on the
+	// current time, timeConditionsTimer then fires at the next time boundary
+	// to render the policy again.
+	timeConditions      bool
+	timeConditionsTimer *time.Timer
+
 	// Protection section
 	protection protection.Config
 
@@ -583,6 +646,10 @@ func New(
 		logLevelCh:                 make(chan logp.Level),
 		overrideStateChan:          make(chan *coordinatorOverrideState),
 		upgradeDetailsChan:         make(chan *details.Details),
+		policyRollbackChan:         make(chan *PolicyRollback),
+		pendingAcksChan:            make(chan []store.PendingAck, 1),
+		diagnosticsUploadsChan:     make(chan []uploader.Progress, 1),
+		maintenanceRestartChan:     make(chan struct{}),
 		heartbeatChan:              make(chan struct{}),
 		componentPIDTicker:         time.NewTicker(time.Second * 30),
 		componentPidRequiresUpdate: &atomic.Bool{},
@@ -627,6 +694,9 @@ func New(
 	if upgradeMgr != nil && upgradeMgr.MarkerWatcher() != nil {
 		c.managerChans.upgradeMarkerUpdate = upgradeMgr.MarkerWatcher().Watch()
 	}
+	if cfg != nil && cfg.Settings != nil {
+		c.setMaintenanceSchedule(cfg.Settings.Maintenance)
+	}
 	return c
 }
 
@@ -1026,6 +1096,11 @@ func (c *Coordinator) PerformDiagnostics(ctx context.Context, req ...runtime.Com
 	return diags
 }
 
+// ResumeComponent releases a component quarantined after it crashed too many times and starts it again.
+func (c *Coordinator) ResumeComponent(componentID string) error {
+	return c.runtimeMgr.ResumeComponent(componentID)
+}
+
 // PerformComponentDiagnostics executes the diagnostic action for the provided components.
 func (c *Coordinator) PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
 	var diags []runtime.ComponentDiagnostic
@@ -1421,6 +1496,7 @@ func (c *Coordinator) DiagnosticHooks() diagnostics.Hooks {
 					Components     []StateComponentOutput `yaml:"components"`
 					Collector      *StateCollectorStatus  `yaml:"collector,omitempty"`
 					UpgradeDetails *details.Details       `yaml:"upgrade_details,omitempty"`
+					PendingAcks    []store.PendingAck     `yaml:"pending_acks,omitempty"`
 				}
 
 				var toCollectorStatus func(status *status.AggregateStatus) *StateCollectorStatus
@@ -1464,6 +1540,7 @@ func (c *Coordinator) DiagnosticHooks() diagnostics.Hooks {
 					Components:     compStates,
 					Collector:      collectorStatus,
 					UpgradeDetails: s.UpgradeDetails,
+					PendingAcks:    s.PendingAcks,
 				}
 				o, err := yaml.Marshal(output)
 				if err != nil {
@@ -1534,6 +1611,11 @@ func (c *Coordinator) runner(ctx context.Context) error {
 	defer cancel()
 
 	defer c.componentPIDTicker.Stop()
+	defer func() {
+		if c.timeConditionsTimer != nil {
+			c.timeConditionsTimer.Stop()
+		}
+	}()
 
 	// We run nil checks before starting the various managers so that unit tests
 	// only have to initialize / mock the specific components they're testing.
@@ -1659,6 +1741,24 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 	case upgradeDetails := <-c.upgradeDetailsChan:
 		c.setUpgradeDetails(upgradeDetails)
 
+	case policyRollback := <-c.policyRollbackChan:
+		c.setPolicyRollback(policyRollback)
+
+	case pendingAcks := <-c.pendingAcksChan:
+		c.setPendingAcks(pendingAcks)
+
+	case uploads := <-c.diagnosticsUploadsChan:
+		c.setDiagnosticsUploads(uploads)
+
+	case <-c.maintenanceRestartChan:
+		c.setMaintenanceRestart()
+
+	case <-c.maintenanceWindowC():
+		c.processMaintenanceWindow()
+
+	case <-c.timeConditionsC():
+		c.processTimeConditions(ctx)
+
 	case c.heartbeatChan <- struct{}{}:
 
 	case <-c.componentPIDTicker.C:
@@ -1725,6 +1825,11 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 func (c *Coordinator) processConfigChange(ctx context.Context, change ConfigChange) (err error) {
 	c.migrationProgressWg.Wait()
 
+	c.policyChangeInProgress = true
+	defer func() {
+		c.policyChangeInProgress = false
+	}()
+
 	// processConfig will apply persisted config and set c.otelCfg before calling refreshComponentModel
 	err = c.processConfig(ctx, change.Config())
 	if err != nil {
@@ -1801,6 +1906,7 @@ func (c *Coordinator) processConfig(ctx context.Context, cfg *config.Config) (er
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 	c.currentCfg = currentCfg
+	c.setMaintenanceSchedule(currentCfg.Settings.Maintenance)
 
 	// check if log level has changed for standalone elastic-agent
 	// we'd have to update both the periodic and once config watchers and refactor initialization in application.go to do otherwise.
@@ -1865,6 +1971,8 @@ func (c *Coordinator) generateAST(cfg *config.Config, m map[string]interface{})
 	}
 
 	c.ast = rawAst
+	c.timeConditions = usesTimeConditions(m)
+	c.resetTimeConditionsTimer()
 	return nil
 }
 
@@ -2011,6 +2119,10 @@ func (c *Coordinator) refreshComponentModel(ctx context.Context) (err error) {
 		Signed:     signed,
 	}
 
+	if c.deferModelUpdate(model) {
+		return nil
+	}
+
 	c.logger.Info("Updating running component model")
 	c.logger.With("components", model.Components).Debug("Updating running component model")
 	c.updateManagersWithConfig(model)
@@ -2234,14 +2346,23 @@ func (c *Coordinator) splitModelBetweenManagers(model *component.Model) (runtime
 // Normally, we use the runtime set in the component itself via the configuration, but
 // we may also fall back to the process runtime if the otel runtime is unsupported for
 // some reason. One example is the output using unsupported config options.
+// The otlp output is only implemented by the otel runtime, its components never fall
+// back to the process runtime, they fail with a component error instead.
 func maybeOverrideRuntimeForComponent(logger *logger.Logger, runtimeCfg *component.RuntimeConfig, comp *component.Component) {
 	if comp.RuntimeManager == component.ProcessRuntimeManager {
-		// do nothing, the process runtime can handle any component
+		if comp.OutputType == translate.OTLPOutputType {
+			setOTLPComponentErr(comp, errors.New("the component runs in the process runtime"))
+		}
+		// do nothing, the process runtime can handle any other component
 		return
 	}
 	if comp.RuntimeManager == component.OtelRuntimeManager {
 		// check if the component is actually supported
 		err := translate.VerifyComponentIsOtelSupported(comp)
+		if err != nil && comp.OutputType == translate.OTLPOutputType {
+			setOTLPComponentErr(comp, err)
+			return
+		}
 		if err != nil {
 			logger.Infof("otel runtime is not supported for component %s, switching to process runtime, reason: %v", comp.ID, err)
 			comp.RuntimeManager = component.ProcessRuntimeManager
@@ -2250,13 +2371,24 @@ func maybeOverrideRuntimeForComponent(logger *logger.Logger, runtimeCfg *compone
 		// check if the component is dynamic and use the right runtime
 		// dynamic components can cause problems for the otel collector because of its expensive configuration reloading
 		dynamicRuntimeManager := component.RuntimeManager(runtimeCfg.DynamicInputs)
-		if runtimeCfg.DynamicInputs != "" && comp.Dynamic && comp.RuntimeManager != dynamicRuntimeManager {
+		if runtimeCfg.DynamicInputs != "" && comp.Dynamic && comp.RuntimeManager != dynamicRuntimeManager && comp.OutputType != translate.OTLPOutputType {
 			logger.Warnf("Component %s uses dynamic variable providers, switching to %s runtime", comp.ID, dynamicRuntimeManager)
 			comp.RuntimeManager = dynamicRuntimeManager
 		}
 	}
 }
 
+// setOTLPComponentErr fails the component using the otlp output because it cannot run in
+// the otel runtime. The component is handed to the process runtime that only reports its
+// error, it does not run it.
+func setOTLPComponentErr(comp *component.Component, reason error) {
+	comp.RuntimeManager = component.ProcessRuntimeManager
+	if comp.Err != nil {
+		return
+	}
+	comp.Err = fmt.Errorf("the otlp output is only supported by the components running in the otel runtime: %w", reason)
+}
+
 func (c *Coordinator) isFleetServer() bool {
 	for _, s := range c.state.Components {
 		if s.Component.InputType == fleetServer {
@@ -2371,6 +2503,7 @@ func (c *Coordinator) generateComponentModel() (err error) {
 	c.componentModel = comps
 
 	c.checkAndLogUpdate(lastComponentModel)
+	c.setComponentModelDiff(lastComponentModel, comps)
 
 	return nil
 }
@@ -2510,7 +2643,9 @@ func (c *Coordinator) checkAndLogUpdate(lastComponentModel []component.Component
 }
 
 // Filter any inputs and outputs in the generated component model
-// based on whether they're excluded by the capabilities config
+// based on whether they're excluded by the capabilities config, components
+// and units blocked by the component, processor and output setting rules
+// are kept and marked as failed so their status explains why.
 func (c *Coordinator) filterByCapabilities(comps []component.Component) []component.Component {
 	if c.caps == nil {
 		// No active filters, return unchanged
@@ -2526,6 +2661,9 @@ func (c *Coordinator) filterByCapabilities(comps []component.Component) []compon
 			c.logger.Infof("Component '%v' with output type '%v' filtered by capabilities.yml", component.ID, component.OutputType)
 			continue
 		}
+		if capabilities.ApplyToComponent(c.caps, &component) {
+			c.logger.Infof("Component '%v' or some of its units blocked by capabilities.yml", component.ID)
+		}
 		result = append(result, component)
 	}
 	return result
@@ -12,6 +12,8 @@ import (
 	"go.opentelemetry.io/collector/component/componentstatus"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
 	"github.com/elastic/elastic-agent/pkg/component"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
@@ -43,6 +45,17 @@ type State struct {
 	Collector *status.AggregateStatus
 
 	UpgradeDetails *details.Details `yaml:"upgrade_details,omitempty"`
+
+	PolicyRollback *PolicyRollback `yaml:"policy_rollback,omitempty"`
+
+	Maintenance *MaintenanceState `yaml:"maintenance,omitempty"`
+
+	// PendingAcks are the acks not delivered to Fleet yet.
+	PendingAcks []store.PendingAck `yaml:"pending_acks,omitempty"`
+
+	// DiagnosticsUploads is the progress of the diagnostics bundles being
+	// uploaded to Fleet.
+	DiagnosticsUploads []uploader.Progress `yaml:"diagnostics_uploads,omitempty"`
 }
 
 type coordinatorOverrideState struct {
@@ -72,6 +85,48 @@ func (c *Coordinator) SetUpgradeDetails(upgradeDetails *details.Details) {
 	c.upgradeDetailsChan <- upgradeDetails
 }
 
+// SetPolicyRollback sets the details of the policy re-applied from the policy
+// history, nil clears them once a new policy is applied.
+func (c *Coordinator) SetPolicyRollback(policyRollback *PolicyRollback) {
+	c.policyRollbackChan <- policyRollback
+}
+
+// SetPendingAcks sets the acks not delivered to Fleet yet. It never blocks as
+// it is called while acking, possibly from the Coordinator goroutine, only the
+// latest pending acks are kept until the Coordinator goroutine reads them.
+func (c *Coordinator) SetPendingAcks(pendingAcks []store.PendingAck) {
+	for {
+		select {
+		case c.pendingAcksChan <- pendingAcks:
+			return
+		default:
+		}
+		// drop the pending acks not read yet, they are outdated
+		select {
+		case <-c.pendingAcksChan:
+		default:
+		}
+	}
+}
+
+// SetDiagnosticsUploads sets the progress of the diagnostics uploads. Like
+// SetPendingAcks it never blocks, only the latest progress is kept until the
+// Coordinator goroutine reads it.
+func (c *Coordinator) SetDiagnosticsUploads(uploads []uploader.Progress) {
+	for {
+		select {
+		case c.diagnosticsUploadsChan <- uploads:
+			return
+		default:
+		}
+		// drop the progress not read yet, it is outdated
+		select {
+		case <-c.diagnosticsUploadsChan:
+		default:
+		}
+	}
+}
+
 // setRuntimeUpdateError reports a failed policy update in the runtime manager.
 // Called on the main Coordinator goroutine.
 func (c *Coordinator) setRuntimeUpdateError(err error) {
@@ -140,6 +195,27 @@ func (c *Coordinator) setUpgradeDetails(upgradeDetails *details.Details) {
 	c.logUpgradeDetails(upgradeDetails)
 }
 
+// setPolicyRollback is the internal helper to set the policy rollback details and set stateNeedsRefresh.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) setPolicyRollback(policyRollback *PolicyRollback) {
+	c.state.PolicyRollback = policyRollback
+	c.stateNeedsRefresh = true
+}
+
+// setPendingAcks updates the acks not delivered to Fleet yet.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setPendingAcks(pendingAcks []store.PendingAck) {
+	c.state.PendingAcks = pendingAcks
+	c.stateNeedsRefresh = true
+}
+
+// setDiagnosticsUploads updates the progress of the diagnostics uploads.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setDiagnosticsUploads(uploads []uploader.Progress) {
+	c.state.DiagnosticsUploads = uploads
+	c.stateNeedsRefresh = true
+}
+
 // Forward the current state to the broadcaster and clear the stateNeedsRefresh
 // flag. Must be called on the main Coordinator goroutine.
 func (c *Coordinator) refreshState() {
@@ -217,6 +293,10 @@ func (c *Coordinator) generateReportableState() (s State) {
 	s.FleetMessage = c.state.FleetMessage
 	s.LogLevel = c.state.LogLevel
 	s.UpgradeDetails = c.state.UpgradeDetails
+	s.PolicyRollback = c.state.PolicyRollback
+	s.Maintenance = c.state.Maintenance
+	s.PendingAcks = c.state.PendingAcks
+	s.DiagnosticsUploads = c.state.DiagnosticsUploads
 	s.Components = make([]runtime.ComponentComponentState, len(c.state.Components))
 	copy(s.Components, c.state.Components)
 	if c.state.Collector != nil {
@@ -14,6 +14,8 @@ import (
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent-libs/logp"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
 	pkgcomponent "github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -235,3 +237,35 @@ func TestApplyComponentState_StartingFromNewRuntimeReplacesExisting(t *testing.T
 		"component should now be under process runtime")
 	assert.Equal(t, client.UnitStateStarting, coord.state.Components[0].State.State)
 }
+
+func TestSetPendingAcks_NeverBlocks(t *testing.T) {
+	coord := &Coordinator{
+		pendingAcksChan: make(chan []store.PendingAck, 1),
+	}
+
+	// the Coordinator goroutine is not running, only the latest pending acks are kept
+	coord.SetPendingAcks([]store.PendingAck{{ActionID: "action-1"}})
+	coord.SetPendingAcks([]store.PendingAck{{ActionID: "action-1"}, {ActionID: "action-2"}})
+
+	coord.setPendingAcks(<-coord.pendingAcksChan)
+	assert.True(t, coord.stateNeedsRefresh)
+	require.Len(t, coord.state.PendingAcks, 2)
+	assert.Equal(t, "action-2", coord.state.PendingAcks[1].ActionID)
+	assert.Empty(t, coord.pendingAcksChan)
+}
+
+func TestSetDiagnosticsUploads_NeverBlocks(t *testing.T) {
+	coord := &Coordinator{
+		diagnosticsUploadsChan: make(chan []uploader.Progress, 1),
+	}
+
+	// the Coordinator goroutine is not running, only the latest progress is kept
+	coord.SetDiagnosticsUploads([]uploader.Progress{{ActionID: "action-1", ChunksSent: 1}})
+	coord.SetDiagnosticsUploads([]uploader.Progress{{ActionID: "action-1", ChunksSent: 2}})
+
+	coord.setDiagnosticsUploads(<-coord.diagnosticsUploadsChan)
+	assert.True(t, coord.stateNeedsRefresh)
+	require.Len(t, coord.state.DiagnosticsUploads, 1)
+	assert.Equal(t, 2, coord.state.DiagnosticsUploads[0].ChunksSent)
+	assert.Empty(t, coord.diagnosticsUploadsChan)
+}
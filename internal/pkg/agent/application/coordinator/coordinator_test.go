@@ -1551,6 +1551,21 @@ func (f *fakeCapabilities) AllowOutput(name string) bool {
 	return args.Bool(0)
 }
 
+func (f *fakeCapabilities) AllowProcessor(name string) bool {
+	args := f.Called(name)
+	return args.Bool(0)
+}
+
+func (f *fakeCapabilities) AllowComponent(inputType string, commandName string) bool {
+	args := f.Called(inputType, commandName)
+	return args.Bool(0)
+}
+
+func (f *fakeCapabilities) CheckOutputSettings(outputType string, settings map[string]interface{}) error {
+	args := f.Called(outputType, settings)
+	return args.Error(0)
+}
+
 func (f *fakeCapabilities) AllowFleetOverride() bool {
 	args := f.Called()
 	return args.Bool(0)
@@ -2125,6 +2140,11 @@ func (r *fakeRuntimeManager) PerformComponentDiagnostics(ctx context.Context, ad
 	return nil, nil
 }
 
+// ResumeComponent releases a quarantined component and starts it again.
+func (r *fakeRuntimeManager) ResumeComponent(componentID string) error {
+	return nil
+}
+
 func testBinary(t testing.TB, name string) string {
 	t.Helper()
 
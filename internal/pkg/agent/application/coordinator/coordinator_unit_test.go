@@ -34,6 +34,7 @@ import (
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/status"
 	"go.opentelemetry.io/collector/component/componentstatus"
 	"go.opentelemetry.io/collector/confmap"
+	"google.golang.org/protobuf/types/known/structpb"
 	"gopkg.in/yaml.v3"
 
 	"github.com/stretchr/testify/assert"
@@ -2737,6 +2738,38 @@ func TestMaybeOverrideRuntimeForComponent(t *testing.T) {
 		maybeOverrideRuntimeForComponent(logger, runtimeCfg, &comp)
 		assert.Equal(t, pkgcomponent.OtelRuntimeManager, comp.RuntimeManager)
 	})
+
+	t.Run("dynamic otlp component stays on otel runtime", func(t *testing.T) {
+		runtimeCfg := &pkgcomponent.RuntimeConfig{
+			DynamicInputs: string(pkgcomponent.ProcessRuntimeManager),
+		}
+		comp := otelSupportedComponent(true)
+		comp.OutputType = "otlp"
+		comp.Units[0].Config.Type = "otlp"
+		maybeOverrideRuntimeForComponent(logger, runtimeCfg, &comp)
+		assert.Equal(t, pkgcomponent.OtelRuntimeManager, comp.RuntimeManager)
+		assert.NoError(t, comp.Err)
+	})
+
+	t.Run("unsupported otlp component fails instead of switching to process runtime", func(t *testing.T) {
+		runtimeCfg := pkgcomponent.DefaultRuntimeConfig()
+		comp := otelSupportedComponent(false)
+		comp.OutputType = "otlp"
+		source, err := structpb.NewStruct(map[string]any{"type": "otlp", "hosts": []any{"gateway:4317"}, "proxy_url": "http://proxy:8080"})
+		require.NoError(t, err)
+		comp.Units[0].Config = &proto.UnitExpectedConfig{Type: "otlp", Source: source}
+		maybeOverrideRuntimeForComponent(logger, runtimeCfg, &comp)
+		assert.ErrorContains(t, comp.Err, "the otlp output is only supported by the components running in the otel runtime")
+	})
+
+	t.Run("otlp component in process runtime fails", func(t *testing.T) {
+		runtimeCfg := pkgcomponent.DefaultRuntimeConfig()
+		comp := otelSupportedComponent(false)
+		comp.RuntimeManager = pkgcomponent.ProcessRuntimeManager
+		comp.OutputType = "otlp"
+		maybeOverrideRuntimeForComponent(logger, runtimeCfg, &comp)
+		assert.ErrorContains(t, comp.Err, "the otlp output is only supported by the components running in the otel runtime")
+	})
 }
 
 func TestGetDynamicInputs(t *testing.T) {
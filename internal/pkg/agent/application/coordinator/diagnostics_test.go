@@ -20,6 +20,7 @@ import (
 	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
@@ -595,6 +596,16 @@ func TestDiagnosticState(t *testing.T) {
 				RetryUntil:      &now,
 			},
 		},
+		PendingAcks: []store.PendingAck{
+			{
+				ActionID:      "action-1",
+				ActionType:    "POLICY_CHANGE",
+				Attempts:      2,
+				LastError:     "fleet unreachable",
+				EnqueuedAt:    now,
+				LastAttemptAt: now,
+			},
+		},
 	}
 
 	expected := fmt.Sprintf(`
@@ -624,7 +635,14 @@ upgrade_details:
     scheduled_at: %s
     download_rate: 123.56
     retry_until: %s
-`, now.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano))
+pending_acks:
+  - action_id: action-1
+    action_type: POLICY_CHANGE
+    attempts: 2
+    last_error: fleet unreachable
+    enqueued_at: %s
+    last_attempt_at: %s
+`, now.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano))
 
 	coord := &Coordinator{
 		// This test needs a broadcaster since the components-actual diagnostic
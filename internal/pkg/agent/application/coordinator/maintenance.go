@@ -0,0 +1,190 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package coordinator
+
+import (
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/maintenance"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// MaintenanceState describes the operations deferred until the next maintenance window.
+type MaintenanceState struct {
+	DeferredUntil time.Time `json:"deferred_until" yaml:"deferred_until"`
+	Restart       bool      `json:"restart,omitempty" yaml:"restart,omitempty"`
+	PolicyChange  bool      `json:"policy_change,omitempty" yaml:"policy_change,omitempty"`
+}
+
+// Restart re-executes the Elastic Agent. Outside of the maintenance windows the restart is
+// deferred until the next window opens and the start of that window is returned, unless force
+// is set.
+// Called from external goroutines.
+func (c *Coordinator) Restart(force bool) (time.Time, error) {
+	if force {
+		c.ReExec(nil)
+		return time.Time{}, nil
+	}
+	next, err := c.MaintenanceDeferral()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if next.IsZero() {
+		c.ReExec(nil)
+		return time.Time{}, nil
+	}
+	c.logger.Infof("Restart deferred until the next maintenance window at %s", next)
+	c.maintenanceRestartChan <- struct{}{}
+	return next, nil
+}
+
+// MaintenanceDeferral returns the start of the next maintenance window when a disruptive
+// operation requested now must be deferred, the zero time when it can be performed now.
+// Called from external goroutines.
+func (c *Coordinator) MaintenanceDeferral() (time.Time, error) {
+	return c.maintenance.Load().Deferral(time.Now())
+}
+
+// setMaintenanceSchedule replaces the schedule of the maintenance windows when the configuration
+// changes. Operations deferred under the previous schedule are re-scheduled, or performed on the
+// next iteration when the maintenance windows are disabled.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) setMaintenanceSchedule(cfg *configuration.MaintenanceConfig) {
+	schedule, err := cfg.NewSchedule()
+	if err != nil {
+		// the configuration is validated when it is unpacked
+		c.logger.Errorf("invalid maintenance windows configuration: %v", err)
+		return
+	}
+	c.maintenance.Store(schedule)
+	if c.maintenanceModel != nil || c.maintenanceRestart {
+		c.updateMaintenanceState()
+	}
+}
+
+// deferModelUpdate returns true when the component model must not be applied until the next
+// maintenance window, the model is then kept and applied once the window opens. Only policy
+// changes that stop running components are deferred, once a policy change is deferred every
+// following model is deferred as well until the window opens so the deferred policy is never
+// partially applied.
+// Always called on the main Coordinator goroutine.
+func (c *Coordinator) deferModelUpdate(model *component.Model) bool {
+	schedule := c.maintenance.Load()
+	if schedule == nil && c.maintenanceModel == nil {
+		return false
+	}
+	if !c.policyChangeInProgress && c.maintenanceModel == nil {
+		return false
+	}
+	if !c.stopsRunningComponents(model) {
+		if c.maintenanceModel != nil {
+			c.logger.Info("Component model no longer stops running components, applying it without waiting for the maintenance window")
+			c.maintenanceModel = nil
+			c.updateMaintenanceState()
+		}
+		return false
+	}
+	if schedule.Contains(time.Now()) {
+		c.maintenanceModel = nil
+		c.updateMaintenanceState()
+		return false
+	}
+
+	c.logger.Info("Policy change stops running components, deferring it until the next maintenance window")
+	c.maintenanceModel = model
+	c.updateMaintenanceState()
+	return true
+}
+
+// stopsRunningComponents returns true when applying the model stops a running component, either
+// because it is removed or because it moves to another runtime.
+func (c *Coordinator) stopsRunningComponents(model *component.Model) bool {
+	desired := make(map[string]component.RuntimeManager, len(model.Components))
+	for _, comp := range model.Components {
+		desired[comp.ID] = comp.RuntimeManager
+	}
+	for _, compState := range c.state.Components {
+		runtimeManager, ok := desired[compState.Component.ID]
+		if !ok || runtimeManager != compState.Component.RuntimeManager {
+			return true
+		}
+	}
+	return false
+}
+
+// setMaintenanceRestart defers a restart until the next maintenance window.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) setMaintenanceRestart() {
+	c.maintenanceRestart = true
+	c.updateMaintenanceState()
+}
+
+// processMaintenanceWindow runs the deferred operations when a maintenance window opens.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) processMaintenanceWindow() {
+	if !c.maintenance.Load().Contains(time.Now()) {
+		// woke up early, wait again for the window
+		c.updateMaintenanceState()
+		return
+	}
+
+	model := c.maintenanceModel
+	restart := c.maintenanceRestart
+	c.maintenanceModel = nil
+	c.maintenanceRestart = false
+	c.updateMaintenanceState()
+
+	if restart {
+		// the deferred policy change is applied when the Elastic Agent starts again
+		c.logger.Info("Maintenance window open, performing the deferred restart")
+		go c.ReExec(nil)
+		return
+	}
+	if model != nil {
+		c.logger.Info("Maintenance window open, applying the deferred policy change")
+		c.updateManagersWithConfig(model)
+	}
+}
+
+// updateMaintenanceState reports the deferred operations in the state and arms the timer waiting
+// for the next maintenance window.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) updateMaintenanceState() {
+	if c.maintenanceTimer != nil {
+		c.maintenanceTimer.Stop()
+		c.maintenanceTimer = nil
+	}
+	c.stateNeedsRefresh = true
+	if c.maintenanceModel == nil && !c.maintenanceRestart {
+		c.state.Maintenance = nil
+		return
+	}
+
+	now := time.Now()
+	next, ok := c.maintenance.Load().Next(now)
+	if !ok {
+		// the schedule is validated, only schedules like the 31st of February never open
+		c.logger.Errorf("Deferred operations will never run: %s", maintenance.ErrNoWindow)
+	} else {
+		// fires immediately when the window is already open, or when the maintenance
+		// windows were disabled
+		c.maintenanceTimer = time.NewTimer(next.Sub(now))
+	}
+	c.state.Maintenance = &MaintenanceState{
+		DeferredUntil: next,
+		Restart:       c.maintenanceRestart,
+		PolicyChange:  c.maintenanceModel != nil,
+	}
+}
+
+// maintenanceWindowC returns the channel notified when the next maintenance window opens, nil
+// when no operation is deferred.
+func (c *Coordinator) maintenanceWindowC() <-chan time.Time {
+	if c.maintenanceTimer == nil {
+		return nil
+	}
+	return c.maintenanceTimer.C
+}
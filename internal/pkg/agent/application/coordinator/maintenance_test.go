@@ -0,0 +1,200 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/maintenance"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+// closedMaintenanceSchedule returns a schedule with a single window opening in 2 hours.
+func closedMaintenanceSchedule(t *testing.T) *maintenance.Schedule {
+	start := time.Now().UTC().Add(2 * time.Hour)
+	w, err := maintenance.NewWindow(fmt.Sprintf("%d %d * * *", start.Minute(), start.Hour()), time.Minute)
+	require.NoError(t, err)
+	s, err := maintenance.NewSchedule("UTC", w)
+	require.NoError(t, err)
+	return s
+}
+
+func runningComponentState(id string, runtimeManager component.RuntimeManager) runtime.ComponentComponentState {
+	return runtime.ComponentComponentState{
+		Component: component.Component{ID: id, RuntimeManager: runtimeManager},
+	}
+}
+
+func TestStopsRunningComponents(t *testing.T) {
+	c := &Coordinator{
+		state: State{
+			Components: []runtime.ComponentComponentState{
+				runningComponentState("filestream-default", component.ProcessRuntimeManager),
+			},
+		},
+	}
+
+	testCases := map[string]struct {
+		components []component.Component
+		stops      bool
+	}{
+		"component kept": {
+			components: []component.Component{{ID: "filestream-default", RuntimeManager: component.ProcessRuntimeManager}},
+		},
+		"component added": {
+			components: []component.Component{
+				{ID: "filestream-default", RuntimeManager: component.ProcessRuntimeManager},
+				{ID: "system/metrics-default", RuntimeManager: component.ProcessRuntimeManager},
+			},
+		},
+		"component removed": {
+			components: []component.Component{{ID: "system/metrics-default", RuntimeManager: component.ProcessRuntimeManager}},
+			stops:      true,
+		},
+		"component moved to another runtime": {
+			components: []component.Component{{ID: "filestream-default", RuntimeManager: component.OtelRuntimeManager}},
+			stops:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.stops, c.stopsRunningComponents(&component.Model{Components: tc.components}))
+		})
+	}
+}
+
+func TestDeferModelUpdate(t *testing.T) {
+	log, _ := loggertest.New("maintenance")
+	c := &Coordinator{
+		logger: log,
+		state: State{
+			Components: []runtime.ComponentComponentState{
+				runningComponentState("filestream-default", component.ProcessRuntimeManager),
+			},
+		},
+	}
+	c.maintenance.Store(closedMaintenanceSchedule(t))
+	removed := &component.Model{}
+	kept := &component.Model{Components: []component.Component{{ID: "filestream-default", RuntimeManager: component.ProcessRuntimeManager}}}
+
+	// changes of variables are never deferred
+	assert.False(t, c.deferModelUpdate(removed))
+	assert.Nil(t, c.state.Maintenance)
+
+	// policy changes that do not stop running components are not deferred
+	c.policyChangeInProgress = true
+	assert.False(t, c.deferModelUpdate(kept))
+	assert.Nil(t, c.state.Maintenance)
+
+	// policy changes stopping running components are deferred
+	assert.True(t, c.deferModelUpdate(removed))
+	require.NotNil(t, c.state.Maintenance)
+	assert.True(t, c.state.Maintenance.PolicyChange)
+	assert.False(t, c.state.Maintenance.Restart)
+	assert.True(t, c.state.Maintenance.DeferredUntil.After(time.Now()))
+	assert.NotNil(t, c.maintenanceWindowC())
+
+	// once deferred, changes of variables are deferred as well
+	c.policyChangeInProgress = false
+	assert.True(t, c.deferModelUpdate(removed))
+
+	// the window did not open yet, the operations stay deferred
+	c.processMaintenanceWindow()
+	require.NotNil(t, c.state.Maintenance)
+	assert.Same(t, removed, c.maintenanceModel)
+
+	// a model that no longer stops running components clears the deferral
+	assert.False(t, c.deferModelUpdate(kept))
+	assert.Nil(t, c.state.Maintenance)
+	assert.Nil(t, c.maintenanceModel)
+	assert.Nil(t, c.maintenanceWindowC())
+}
+
+func TestDeferModelUpdateWithoutMaintenanceWindows(t *testing.T) {
+	c := &Coordinator{
+		policyChangeInProgress: true,
+		state: State{
+			Components: []runtime.ComponentComponentState{
+				runningComponentState("filestream-default", component.ProcessRuntimeManager),
+			},
+		},
+	}
+	assert.False(t, c.deferModelUpdate(&component.Model{}))
+}
+
+func TestRestartDeferredUntilMaintenanceWindow(t *testing.T) {
+	log, _ := loggertest.New("maintenance")
+	c := &Coordinator{
+		logger:                 log,
+		maintenanceRestartChan: make(chan struct{}),
+	}
+	c.maintenance.Store(closedMaintenanceSchedule(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.maintenanceRestartChan:
+			c.setMaintenanceRestart()
+			cancel()
+		}
+	}()
+
+	deferredUntil, err := c.Restart(false)
+	require.NoError(t, err)
+	assert.True(t, deferredUntil.After(time.Now()))
+
+	<-ctx.Done()
+	require.NotNil(t, c.state.Maintenance)
+	assert.True(t, c.state.Maintenance.Restart)
+	assert.Equal(t, deferredUntil, c.state.Maintenance.DeferredUntil)
+}
+
+func TestMaintenanceDeferral(t *testing.T) {
+	c := &Coordinator{}
+	deferredUntil, err := c.MaintenanceDeferral()
+	require.NoError(t, err)
+	assert.True(t, deferredUntil.IsZero(), "nothing is deferred without maintenance windows")
+
+	c.maintenance.Store(closedMaintenanceSchedule(t))
+	deferredUntil, err = c.MaintenanceDeferral()
+	require.NoError(t, err)
+	assert.True(t, deferredUntil.After(time.Now()))
+}
+
+func TestSetMaintenanceSchedule(t *testing.T) {
+	log, _ := loggertest.New("maintenance")
+	c := &Coordinator{logger: log}
+
+	cfg := &configuration.MaintenanceConfig{
+		Enabled: true,
+		Windows: []configuration.MaintenanceWindowConfig{{Schedule: "0 0 31 2 *", Duration: time.Hour}},
+	}
+	c.setMaintenanceSchedule(cfg)
+	require.NotNil(t, c.maintenance.Load())
+	c.setMaintenanceRestart()
+	require.NotNil(t, c.state.Maintenance)
+	assert.Nil(t, c.maintenanceWindowC(), "a window that never opens never runs the deferred operations")
+
+	// disabling the maintenance windows runs the deferred operations
+	c.setMaintenanceSchedule(configuration.DefaultMaintenanceConfig())
+	assert.Nil(t, c.maintenance.Load())
+	select {
+	case <-c.maintenanceWindowC():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the deferred restart was not triggered once the maintenance windows were disabled")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+)
+
+// ErrPolicyHistoryNotAvailable is returned when the policy history is requested and the Elastic Agent
+// is not managed by Fleet.
+var ErrPolicyHistoryNotAvailable = errors.New("policy history is only available when the Elastic Agent is managed by Fleet")
+
+// PolicyRollback describes a policy that was re-applied locally from the policy history.
+type PolicyRollback struct {
+	ActionID     string    `json:"action_id" yaml:"action_id"`
+	PolicyID     string    `json:"policy_id" yaml:"policy_id"`
+	Revision     int64     `json:"revision" yaml:"revision"`
+	FromRevision int64     `json:"from_revision" yaml:"from_revision"`
+	RolledBackAt time.Time `json:"rolled_back_at" yaml:"rolled_back_at"`
+}
+
+// NewPolicyRollback returns the policy rollback details of a policy history entry.
+func NewPolicyRollback(entry store.PolicyHistoryEntry) *PolicyRollback {
+	return &PolicyRollback{
+		ActionID:     entry.ActionID,
+		PolicyID:     entry.PolicyID,
+		Revision:     entry.Revision,
+		FromRevision: entry.RollbackFromRevision,
+		RolledBackAt: entry.AppliedAt,
+	}
+}
+
+// PolicyRollbacker provides the history of the applied policies and re-applies a policy from it.
+type PolicyRollbacker interface {
+	// PolicyHistory returns the applied policies, from the oldest to the latest.
+	PolicyHistory() []store.PolicyHistoryEntry
+	// RollbackPolicy re-applies the most recently applied policy with the provided ID and revision,
+	// an empty policy ID selects the currently applied policy.
+	RollbackPolicy(ctx context.Context, policyID string, revision int64) (*PolicyRollback, error)
+}
+
+// RegisterPolicyRollbacker registers the PolicyRollbacker used by PolicyHistory and RollbackPolicy.
+func (c *Coordinator) RegisterPolicyRollbacker(r PolicyRollbacker) {
+	c.policyRollbackerMx.Lock()
+	defer c.policyRollbackerMx.Unlock()
+	c.policyRollbacker = r
+}
+
+// PolicyHistory returns the last applied policies, from the oldest to the latest.
+func (c *Coordinator) PolicyHistory() ([]store.PolicyHistoryEntry, error) {
+	c.policyRollbackerMx.RLock()
+	defer c.policyRollbackerMx.RUnlock()
+	if c.policyRollbacker == nil {
+		return nil, ErrPolicyHistoryNotAvailable
+	}
+	return c.policyRollbacker.PolicyHistory(), nil
+}
+
+// RollbackPolicy re-applies the most recently applied policy with the provided ID and revision from
+// the policy history, an empty policy ID selects the currently applied policy. The rollback is
+// reported in the state until a new policy is received from Fleet.
+func (c *Coordinator) RollbackPolicy(ctx context.Context, policyID string, revision int64) (*PolicyRollback, error) {
+	c.policyRollbackerMx.RLock()
+	r := c.policyRollbacker
+	c.policyRollbackerMx.RUnlock()
+	if r == nil {
+		return nil, ErrPolicyHistoryNotAvailable
+	}
+	return r.RollbackPolicy(ctx, policyID, revision)
+}
@@ -0,0 +1,67 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package coordinator
+
+import (
+	"context"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/eql"
+)
+
+// usesTimeConditions returns true when a condition of the policy calls a function whose result
+// depends on the current time.
+func usesTimeConditions(node interface{}) bool {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			if s, ok := v.(string); ok && k == "condition" && eql.UsesTimeFunctions(s) {
+				return true
+			}
+			if usesTimeConditions(v) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, v := range n {
+			if usesTimeConditions(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resetTimeConditionsTimer arms the timer rendering the policy again at the next time boundary
+// when the policy has time conditions, and stops it otherwise.
+func (c *Coordinator) resetTimeConditionsTimer() {
+	if c.timeConditionsTimer != nil {
+		c.timeConditionsTimer.Stop()
+		c.timeConditionsTimer = nil
+	}
+	if !c.timeConditions {
+		return
+	}
+	now := time.Now()
+	c.timeConditionsTimer = time.NewTimer(eql.NextTimeBoundary(now).Sub(now))
+}
+
+// timeConditionsC returns the channel notified at the next time boundary, nil when the policy has
+// no time conditions.
+func (c *Coordinator) timeConditionsC() <-chan time.Time {
+	if c.timeConditionsTimer == nil {
+		return nil
+	}
+	return c.timeConditionsTimer.C
+}
+
+// processTimeConditions renders the policy again, the result of its time conditions can change
+// without any variable changing.
+func (c *Coordinator) processTimeConditions(ctx context.Context) {
+	c.resetTimeConditionsTimer()
+	if err := c.refreshComponentModel(ctx); err != nil {
+		c.logger.Errorf("error refreshing component model for time conditions: %s", err)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package coordinator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsesTimeConditions(t *testing.T) {
+	policy := func(condition string) map[string]interface{} {
+		return map[string]interface{}{
+			"inputs": []interface{}{
+				map[string]interface{}{
+					"id":   "logs",
+					"type": "filestream",
+					"streams": []interface{}{
+						map[string]interface{}{"id": "stream", "condition": condition},
+					},
+				},
+			},
+		}
+	}
+
+	assert.True(t, usesTimeConditions(policy("timeOfDayBetween('09:00', '17:00')")))
+	assert.False(t, usesTimeConditions(policy("${host.name} == 'web-01'")))
+	assert.False(t, usesTimeConditions(map[string]interface{}{"agent": map[string]interface{}{"condition": 1}}))
+}
+
+func TestTimeConditionsTimer(t *testing.T) {
+	c := &Coordinator{}
+	assert.Nil(t, c.timeConditionsC())
+
+	c.timeConditions = true
+	c.resetTimeConditionsTimer()
+	require.NotNil(t, c.timeConditionsC(), "the timer must be armed when the policy has time conditions")
+
+	c.timeConditions = false
+	c.resetTimeConditionsTimer()
+	assert.Nil(t, c.timeConditionsC(), "the timer must be stopped when the policy has no time conditions")
+}
@@ -0,0 +1,292 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package diagcapture captures a lightweight diagnostics bundle when the
+// Elastic Agent or one of its components becomes degraded or failed, so the
+// state of a transient problem is available after it is gone.
+package diagcapture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics/redaction"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// TriggerFilename is the name of the file describing why a bundle was captured.
+	TriggerFilename = "trigger.yaml"
+
+	// logTailFilename is the name of the file with the last lines of the Elastic Agent log.
+	logTailFilename = "elastic-agent-log-tail.ndjson"
+
+	// agentID is the ID reported for the Elastic Agent itself in a trigger.
+	agentID = "elastic-agent"
+
+	// captureTimeout bounds the time spent capturing a bundle, an unhealthy
+	// component can be slow to answer its diagnostics action.
+	captureTimeout = time.Minute
+
+	// stateBufferLen is the number of state changes buffered while a bundle is
+	// captured, so a short-lived degradation is not missed.
+	stateBufferLen = 32
+)
+
+// captureHooks are the names of the agent diagnostics hooks included in a capture.
+var captureHooks = []string{"version", "goroutine", "heap", "state"}
+
+// captureProfile only includes the component diagnostics, the logs are
+// replaced by the tail of the Elastic Agent log.
+var captureProfile = diagnostics.Profile{
+	Name:        "capture",
+	Description: "automatic capture on health degradation",
+	Components:  true,
+}
+
+// Trigger describes why a bundle was captured.
+type Trigger struct {
+	Time      time.Time   `yaml:"time"`
+	Unhealthy []Unhealthy `yaml:"unhealthy"`
+}
+
+// Unhealthy is the Elastic Agent, or a component, that became degraded or failed.
+type Unhealthy struct {
+	ID      string `yaml:"id"`
+	State   string `yaml:"state"`
+	Message string `yaml:"message,omitempty"`
+}
+
+// coordinatorProvider provides the coordinator state and runs the diagnostics
+// action of the components.
+type coordinatorProvider interface {
+	StateSubscribe(ctx context.Context, bufferLen int) chan coordinator.State
+	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
+}
+
+// Capturer watches the coordinator state and captures a diagnostics bundle
+// every time the Elastic Agent or a component becomes degraded or failed.
+type Capturer struct {
+	log      *logger.Logger
+	cfg      *configuration.DiagnosticsCaptureConfig
+	dir      string
+	logsDir  string
+	hooks    diagnostics.Hooks
+	redactor *redaction.Redactor
+	provider coordinatorProvider
+
+	// unhealthy are the IDs of the Elastic Agent and components unhealthy in
+	// the last state, a capture is only triggered when a new one appears.
+	unhealthy map[string]bool
+	last      time.Time
+	now       func() time.Time
+}
+
+// New returns a Capturer writing the captures to dir. The last lines of the
+// newest Elastic Agent log in logsDir are included in every capture.
+func New(log *logger.Logger, cfg *configuration.DiagnosticsCaptureConfig, dir string, logsDir string, hooks diagnostics.Hooks, redactor *redaction.Redactor, provider coordinatorProvider) *Capturer {
+	var captured diagnostics.Hooks
+	for _, hook := range hooks {
+		if slices.Contains(captureHooks, hook.Name) {
+			captured = append(captured, hook)
+		}
+	}
+	return &Capturer{
+		log:       log,
+		cfg:       cfg,
+		dir:       dir,
+		logsDir:   logsDir,
+		hooks:     captured,
+		redactor:  redactor,
+		provider:  provider,
+		unhealthy: make(map[string]bool),
+		now:       time.Now,
+	}
+}
+
+// Run captures the diagnostics until ctx is done.
+func (c *Capturer) Run(ctx context.Context) {
+	states := c.provider.StateSubscribe(ctx, stateBufferLen)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case state, ok := <-states:
+			if !ok {
+				return
+			}
+			if trigger, comps := c.onState(state); trigger != nil {
+				c.capture(ctx, trigger, comps)
+			}
+		}
+	}
+}
+
+// onState returns the trigger of a capture, and the unhealthy components, if
+// the Elastic Agent or a component became unhealthy since the last state.
+func (c *Capturer) onState(state coordinator.State) (*Trigger, []component.Component) {
+	current := make(map[string]bool)
+	var newly []Unhealthy
+	var comps []component.Component
+	if state.State == agentclient.Degraded || state.State == agentclient.Failed {
+		current[agentID] = true
+		if !c.unhealthy[agentID] {
+			newly = append(newly, Unhealthy{ID: agentID, State: state.State.String(), Message: state.Message})
+		}
+	}
+	for _, comp := range state.Components {
+		if comp.State.State != client.UnitStateDegraded && comp.State.State != client.UnitStateFailed {
+			continue
+		}
+		current[comp.Component.ID] = true
+		if !c.unhealthy[comp.Component.ID] {
+			newly = append(newly, Unhealthy{ID: comp.Component.ID, State: comp.State.State.String(), Message: comp.State.Message})
+			comps = append(comps, comp.Component)
+		}
+	}
+	c.unhealthy = current
+	if len(newly) == 0 {
+		return nil, nil
+	}
+
+	now := c.now()
+	if !c.last.IsZero() && now.Sub(c.last) < c.cfg.MinInterval {
+		c.log.Debugf("Skipping diagnostics capture, the last capture was taken at %s", c.last)
+		return nil, nil
+	}
+	c.last = now
+	return &Trigger{Time: now.UTC(), Unhealthy: newly}, comps
+}
+
+// capture writes a diagnostics bundle and removes the oldest captures.
+func (c *Capturer) capture(ctx context.Context, trigger *Trigger, comps []component.Component) {
+	ctx, cancel := context.WithTimeout(ctx, captureTimeout)
+	defer cancel()
+
+	c.log.Infow("Capturing diagnostics, the Elastic Agent health degraded", "unhealthy", trigger.Unhealthy)
+	path, err := c.write(ctx, trigger, comps)
+	if err != nil {
+		c.log.Errorw("Failed to capture diagnostics", "error.message", err)
+		return
+	}
+	c.log.Infof("Diagnostics captured in %s", path)
+	if err := rotate(c.dir, c.cfg.MaxCaptures); err != nil {
+		c.log.Warnw("Failed to remove old diagnostics captures", "error.message", err)
+	}
+}
+
+func (c *Capturer) write(ctx context.Context, trigger *Trigger, comps []component.Component) (string, error) {
+	triggerContent, err := yaml.Marshal(trigger)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal capture trigger: %w", err)
+	}
+	aDiag := []agentclient.DiagnosticFileResult{{
+		Name:        "trigger",
+		Filename:    TriggerFilename,
+		Description: "why the diagnostics were captured",
+		ContentType: "application/yaml",
+		Content:     triggerContent,
+		Generated:   trigger.Time,
+	}}
+	for _, hook := range c.hooks {
+		aDiag = append(aDiag, agentclient.DiagnosticFileResult{
+			Name:        hook.Name,
+			Filename:    hook.Filename,
+			Description: hook.Description,
+			ContentType: hook.ContentType,
+			Content:     hook.Hook(ctx),
+			Generated:   time.Now().UTC(),
+		})
+	}
+	if c.cfg.LogLines > 0 {
+		tail, err := tailLog(c.logsDir, c.cfg.LogLines)
+		if err != nil {
+			c.log.Warnw("Failed to read the Elastic Agent log for the diagnostics capture", "error.message", err)
+		} else {
+			aDiag = append(aDiag, agentclient.DiagnosticFileResult{
+				Name:        "log-tail",
+				Filename:    logTailFilename,
+				Description: fmt.Sprintf("last %d lines of the Elastic Agent log", c.cfg.LogLines),
+				ContentType: "application/x-ndjson",
+				Content:     tail,
+				Generated:   time.Now().UTC(),
+			})
+		}
+	}
+
+	var cDiag []agentclient.DiagnosticComponentResult
+	if len(comps) > 0 {
+		cDiag = c.diagComponents(ctx, comps)
+	}
+
+	if err := os.MkdirAll(c.dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create diagnostics captures directory: %w", err)
+	}
+	f, err := os.CreateTemp(c.dir, "capture-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create diagnostics capture: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	var errOut bytes.Buffer
+	err = diagnostics.ZipArchive(&errOut, f, "", aDiag, nil, cDiag, true, diagnostics.WithProfile(captureProfile), diagnostics.WithRedactor(c.redactor))
+	if str := errOut.String(); str != "" {
+		c.log.Warn(str)
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write diagnostics capture: %w", err)
+	}
+	path := filepath.Join(c.dir, captureName(trigger.Time))
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write diagnostics capture: %w", err)
+	}
+	return path, nil
+}
+
+// diagComponents runs the diagnostics action of the unhealthy components.
+func (c *Capturer) diagComponents(ctx context.Context, comps []component.Component) []agentclient.DiagnosticComponentResult {
+	rr, err := c.provider.PerformComponentDiagnostics(ctx, nil, comps...)
+	if err != nil {
+		c.log.Warnw("Failed to fetch component diagnostics for the diagnostics capture", "error.message", err)
+	}
+	cDiag := make([]agentclient.DiagnosticComponentResult, 0, len(rr))
+	for _, r := range rr {
+		diag := agentclient.DiagnosticComponentResult{
+			ComponentID: r.Component.ID,
+			Err:         r.Err,
+		}
+		for _, res := range r.Results {
+			diag.Results = append(diag.Results, agentclient.DiagnosticFileResult{
+				Name:        res.Name,
+				Filename:    res.Filename,
+				Description: res.Description,
+				ContentType: res.ContentType,
+				Content:     res.Content,
+				Generated:   res.Generated.AsTime(),
+			})
+		}
+		cDiag = append(cDiag, diag)
+	}
+	return cDiag
+}
@@ -0,0 +1,169 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagcapture
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+type fakeCoordinator struct {
+	states    chan coordinator.State
+	requested [][]string
+}
+
+func (f *fakeCoordinator) StateSubscribe(_ context.Context, _ int) chan coordinator.State {
+	return f.states
+}
+
+func (f *fakeCoordinator) PerformComponentDiagnostics(_ context.Context, _ []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
+	var ids []string
+	var diags []runtime.ComponentDiagnostic
+	for _, comp := range req {
+		ids = append(ids, comp.ID)
+		diags = append(diags, runtime.ComponentDiagnostic{
+			Component: comp,
+			Results: []*proto.ActionDiagnosticUnitResult{{
+				Name:        "comp",
+				Filename:    "comp.txt",
+				ContentType: "text/plain",
+				Content:     []byte(comp.ID),
+				Generated:   timestamppb.Now(),
+			}},
+		})
+	}
+	f.requested = append(f.requested, ids)
+	return diags, nil
+}
+
+func testState(agentState agentclient.State, compStates map[string]client.UnitState) coordinator.State {
+	state := coordinator.State{State: agentState}
+	for id, s := range compStates {
+		state.Components = append(state.Components, runtime.ComponentComponentState{
+			Component: component.Component{ID: id},
+			State:     runtime.ComponentState{State: s, Message: "unit is " + s.String()},
+		})
+	}
+	return state
+}
+
+func newTestCapturer(t *testing.T, cfg *configuration.DiagnosticsCaptureConfig, coord *fakeCoordinator) (*Capturer, string) {
+	log, _ := loggertest.New("diagnostics-capture")
+	dir := t.TempDir()
+	logsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(logsDir, "elastic-agent-20261017.ndjson"), []byte("first\nsecond\nthird\n"), 0o600))
+	hooks := diagnostics.Hooks{
+		{Name: "version", Filename: "version.txt", ContentType: "text/plain", Hook: func(context.Context) []byte { return []byte("9.2.0") }},
+		{Name: "local-config", Filename: "local-config.yaml", ContentType: "application/yaml", Hook: func(context.Context) []byte { return []byte("{}") }},
+	}
+	return New(log, cfg, dir, logsDir, hooks, nil, coord), dir
+}
+
+func TestCapturerOnState(t *testing.T) {
+	cfg := configuration.DefaultDiagnosticsConfig().Capture
+	cfg.MinInterval = time.Hour
+	c, _ := newTestCapturer(t, cfg, &fakeCoordinator{})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	trigger, comps := c.onState(testState(agentclient.Healthy, map[string]client.UnitState{"comp-1": client.UnitStateHealthy}))
+	assert.Nil(t, trigger, "a healthy agent must not be captured")
+	assert.Empty(t, comps)
+
+	trigger, comps = c.onState(testState(agentclient.Degraded, map[string]client.UnitState{"comp-1": client.UnitStateDegraded, "comp-2": client.UnitStateHealthy}))
+	require.NotNil(t, trigger)
+	assert.Equal(t, []Unhealthy{
+		{ID: agentID, State: "DEGRADED"},
+		{ID: "comp-1", State: "DEGRADED", Message: "unit is DEGRADED"},
+	}, trigger.Unhealthy)
+	require.Len(t, comps, 1)
+	assert.Equal(t, "comp-1", comps[0].ID)
+
+	trigger, _ = c.onState(testState(agentclient.Degraded, map[string]client.UnitState{"comp-1": client.UnitStateDegraded}))
+	assert.Nil(t, trigger, "a component staying unhealthy must not be captured again")
+
+	now = now.Add(time.Minute)
+	trigger, _ = c.onState(testState(agentclient.Failed, map[string]client.UnitState{"comp-1": client.UnitStateDegraded, "comp-2": client.UnitStateFailed}))
+	assert.Nil(t, trigger, "no capture must be taken before the minimum interval")
+
+	now = now.Add(time.Hour)
+	trigger, _ = c.onState(testState(agentclient.Failed, map[string]client.UnitState{"comp-1": client.UnitStateDegraded, "comp-2": client.UnitStateFailed}))
+	assert.Nil(t, trigger, "comp-2 was already unhealthy in the previous state")
+
+	c.onState(testState(agentclient.Healthy, nil))
+	trigger, comps = c.onState(testState(agentclient.Healthy, map[string]client.UnitState{"comp-2": client.UnitStateFailed}))
+	require.NotNil(t, trigger, "a component unhealthy again must be captured")
+	assert.Equal(t, []Unhealthy{{ID: "comp-2", State: "FAILED", Message: "unit is FAILED"}}, trigger.Unhealthy)
+	require.Len(t, comps, 1)
+}
+
+func TestCapturerRun(t *testing.T) {
+	cfg := configuration.DefaultDiagnosticsConfig().Capture
+	cfg.MinInterval = 0
+	cfg.MaxCaptures = 2
+	cfg.LogLines = 2
+	coord := &fakeCoordinator{states: make(chan coordinator.State)}
+	c, dir := newTestCapturer(t, cfg, coord)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx)
+	}()
+	for i := 0; i < 3; i++ {
+		coord.states <- testState(agentclient.Degraded, map[string]client.UnitState{"comp-1": client.UnitStateDegraded})
+		coord.states <- testState(agentclient.Healthy, nil)
+		// captures taken within the same millisecond would have the same name
+		time.Sleep(2 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	captures, err := List(dir)
+	require.NoError(t, err)
+	require.Len(t, captures, 2, "the oldest capture must be removed")
+	assert.Equal(t, [][]string{{"comp-1"}, {"comp-1"}, {"comp-1"}}, coord.requested)
+
+	zr, err := zip.OpenReader(captures[0].Path)
+	require.NoError(t, err)
+	defer zr.Close()
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		files[f.Name] = string(content)
+	}
+	assert.Equal(t, "9.2.0", files["version.txt"])
+	assert.NotContains(t, files, "local-config.yaml", "only the capture hooks must be included")
+	assert.Equal(t, "second\nthird\n", files[logTailFilename])
+	assert.Equal(t, "comp-1", files["components/comp-1/comp.txt"])
+	assert.Contains(t, files, TriggerFilename)
+	assert.Contains(t, files, diagnostics.ManifestFilename)
+}
@@ -0,0 +1,182 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagcapture
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	capturePrefix = "elastic-agent-diagnostics-capture-"
+	captureSuffix = ".zip"
+
+	// tailBlockSize is the size of the blocks read from the end of a log file.
+	tailBlockSize = 64 * 1024
+)
+
+// Capture is a diagnostics bundle captured automatically.
+type Capture struct {
+	Name      string      `yaml:"name"`
+	Path      string      `yaml:"path"`
+	Size      int64       `yaml:"size"`
+	Time      time.Time   `yaml:"time"`
+	Unhealthy []Unhealthy `yaml:"unhealthy,omitempty"`
+}
+
+// captureName returns the file name of a capture taken at ts. The names sort
+// in the order the captures are taken.
+func captureName(ts time.Time) string {
+	return capturePrefix + ts.UTC().Format("2006-01-02T15-04-05.000Z") + captureSuffix // RFC3339 format that uses - instead of : so it works on Windows
+}
+
+// captureFiles returns the paths of the captures in dir, the oldest first.
+func captureFiles(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, capturePrefix+"*"+captureSuffix))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(matches)
+	return matches, nil
+}
+
+// rotate removes the oldest captures in dir to keep at most keep of them.
+func rotate(dir string, keep int) error {
+	files, err := captureFiles(dir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for len(files) > keep {
+		if err := os.Remove(files[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		files = files[1:]
+	}
+	return errors.Join(errs...)
+}
+
+// List returns the captures in dir, the newest first.
+func List(dir string) ([]Capture, error) {
+	files, err := captureFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list diagnostics captures: %w", err)
+	}
+	captures := make([]Capture, 0, len(files))
+	for i := len(files) - 1; i >= 0; i-- {
+		fi, err := os.Stat(files[i])
+		if err != nil {
+			// removed by a rotation since listed
+			continue
+		}
+		capture := Capture{
+			Name: filepath.Base(files[i]),
+			Path: files[i],
+			Size: fi.Size(),
+			Time: fi.ModTime().UTC(),
+		}
+		if trigger, err := readTrigger(files[i]); err == nil {
+			capture.Time = trigger.Time
+			capture.Unhealthy = trigger.Unhealthy
+		}
+		captures = append(captures, capture)
+	}
+	return captures, nil
+}
+
+// readTrigger reads the trigger of the capture at path.
+func readTrigger(path string) (*Trigger, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	f, err := zr.Open(TriggerFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	var trigger Trigger
+	if err := yaml.Unmarshal(content, &trigger); err != nil {
+		return nil, err
+	}
+	return &trigger, nil
+}
+
+// tailLog returns the last n lines of the newest Elastic Agent log in dir.
+func tailLog(dir string, n int) ([]byte, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "elastic-agent-*.ndjson"))
+	if err != nil {
+		return nil, err
+	}
+	var newest string
+	var newestTime time.Time
+	for _, match := range matches {
+		fi, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+		if newest == "" || fi.ModTime().After(newestTime) {
+			newest, newestTime = match, fi.ModTime()
+		}
+	}
+	if newest == "" {
+		return nil, fmt.Errorf("no log file found in %s", dir)
+	}
+	return tailLines(newest, n)
+}
+
+// tailLines returns the last n lines of the file at path, reading it from the end.
+func tailLines(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var tail []byte
+	offset := fi.Size()
+	for offset > 0 {
+		size := min(int64(tailBlockSize), offset)
+		offset -= size
+		block := make([]byte, size)
+		if _, err := f.ReadAt(block, offset); err != nil {
+			return nil, err
+		}
+		tail = append(block, tail...)
+		// the last n lines are complete once n line separators are read
+		// before the end of the last line
+		if bytes.Count(bytes.TrimSuffix(tail, []byte("\n")), []byte("\n")) >= n {
+			break
+		}
+	}
+
+	lines := strings.SplitAfter(string(tail), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
@@ -0,0 +1,110 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagcapture
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTailLines(t *testing.T) {
+	var content strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	path := filepath.Join(t.TempDir(), "elastic-agent-1.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte(content.String()), 0o600))
+
+	tail, err := tailLines(path, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "line 9997\nline 9998\nline 9999\n", string(tail))
+
+	// more lines than a block
+	tail, err = tailLines(path, 9000)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(tail), "line 1000\n"))
+	assert.Equal(t, 9000, strings.Count(string(tail), "\n"))
+
+	// more lines than the file
+	tail, err = tailLines(path, 20000)
+	require.NoError(t, err)
+	assert.Equal(t, content.String(), string(tail))
+
+	// the last line is not terminated
+	require.NoError(t, os.WriteFile(path, []byte("a\nb\nc"), 0o600))
+	tail, err = tailLines(path, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "b\nc", string(tail))
+}
+
+func TestTailLog(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"elastic-agent-20260101.ndjson", "elastic-agent-20260102.ndjson", "other.ndjson"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(name+"\n"), 0o600))
+		modTime := now.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	tail, err := tailLog(dir, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "elastic-agent-20260102.ndjson\n", string(tail), "the newest Elastic Agent log must be used")
+
+	_, err = tailLog(t.TempDir(), 10)
+	assert.ErrorContains(t, err, "no log file found")
+}
+
+func writeTestCapture(t *testing.T, dir string, trigger Trigger) string {
+	t.Helper()
+	path := filepath.Join(dir, captureName(trigger.Time))
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	w, err := zw.Create(TriggerFilename)
+	require.NoError(t, err)
+	content, err := yaml.Marshal(trigger)
+	require.NoError(t, err)
+	_, err = w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return path
+}
+
+func TestListAndRotate(t *testing.T) {
+	dir := t.TempDir()
+	captures, err := List(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, captures)
+
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		writeTestCapture(t, dir, Trigger{
+			Time:      start.Add(time.Duration(i) * time.Hour),
+			Unhealthy: []Unhealthy{{ID: fmt.Sprintf("comp-%d", i), State: "DEGRADED"}},
+		})
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unrelated.zip"), nil, 0o600))
+
+	require.NoError(t, rotate(dir, 2))
+	captures, err = List(dir)
+	require.NoError(t, err)
+	require.Len(t, captures, 2)
+	assert.Equal(t, start.Add(3*time.Hour), captures[0].Time, "the newest capture must be listed first")
+	assert.Equal(t, []Unhealthy{{ID: "comp-3", State: "DEGRADED"}}, captures[0].Unhealthy)
+	assert.Equal(t, "elastic-agent-diagnostics-capture-2026-10-17T13-00-00.000Z.zip", captures[0].Name)
+	assert.Equal(t, start.Add(2*time.Hour), captures[1].Time)
+	assert.FileExists(t, filepath.Join(dir, "unrelated.zip"))
+}
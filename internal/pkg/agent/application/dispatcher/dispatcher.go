@@ -16,6 +16,7 @@ import (
 	"go.elastic.co/apm/v2"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/maintenance"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -37,6 +38,9 @@ type priorityQueue interface {
 type Dispatcher interface {
 	Dispatch(context.Context, details.Observer, acker.Acker, ...fleetapi.Action)
 	Errors() <-chan error
+	// NextDispatch returns the start time of the next queued action, the zero time when no queued
+	// action starts after now.
+	NextDispatch(now time.Time) time.Time
 }
 
 // ActionDispatcher processes actions coming from fleet using registered set of handlers.
@@ -83,6 +87,22 @@ func (ad *ActionDispatcher) Errors() <-chan error {
 	return ad.errCh
 }
 
+// NextDispatch returns the start time of the next queued action, the zero time when no queued action
+// starts after now. The actions deferred until a maintenance window start at the start of the window.
+func (ad *ActionDispatcher) NextDispatch(now time.Time) time.Time {
+	var next time.Time
+	for _, action := range ad.queue.Actions() {
+		start, err := action.StartTime()
+		if err != nil || !start.After(now) {
+			continue
+		}
+		if next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+	return next
+}
+
 // Register registers a new handler for action.
 func (ad *ActionDispatcher) Register(a fleetapi.Action, handler actions.Handler) error {
 	k := ad.key(a)
@@ -164,6 +184,11 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, detailsSetter details.
 		}
 
 		if err := ad.dispatchAction(ctx, action, acker); err != nil {
+			var deferredErr *maintenance.DeferredError
+			if rAction, ok := action.(fleetapi.RetryableAction); ok && errors.As(err, &deferredErr) {
+				ad.deferAction(rAction, deferredErr.Until, &upgradeDetailsNeedUpdate)
+				continue
+			}
 			rAction, ok := action.(fleetapi.RetryableAction)
 			if ok {
 				rAction.SetError(err) // set the retryable action error to what the dispatcher returned
@@ -431,6 +456,21 @@ func (ad *ActionDispatcher) scheduleRetry(ctx context.Context, action fleetapi.R
 	}
 }
 
+// deferAction queues the action until the start of the next maintenance window. The action is persisted
+// in the queue so the deferral survives restarts, and a newer upgrade action removes it from the queue.
+// Deferring an action is not a failure, it is neither acked nor counted as a retry attempt.
+func (ad *ActionDispatcher) deferAction(action fleetapi.RetryableAction, until time.Time, upgradeDetailsNeedUpdate *bool) {
+	ad.log.Infof("Deferring action id %q of type %q until the next maintenance window at %s", action.ID(), action.Type(), until)
+	action.SetStartTime(until)
+	ad.queue.Add(action, until.Unix())
+	if err := ad.queue.Save(); err != nil {
+		ad.log.Errorf("deferred action id %s failed to persist action_queue: %v", action.ID(), err)
+	}
+	if action.Type() == fleetapi.ActionTypeUpgrade {
+		*upgradeDetailsNeedUpdate = true
+	}
+}
+
 // GetScheduledUpgradeDetails returns the upgrade details of the next scheduled upgrade action, if any. It also adjusts
 // accordingly the upgrade details if the action has expired.
 func GetScheduledUpgradeDetails(log *logger.Logger, actions []fleetapi.ScheduledAction, ts time.Time) *details.Details {
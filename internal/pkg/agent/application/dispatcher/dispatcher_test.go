@@ -20,6 +20,7 @@ import (
 	api "github.com/elastic/fleet-server/pkg/api"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions/handlers"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/maintenance"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/noop"
 	"github.com/elastic/elastic-agent/internal/pkg/queue"
@@ -398,6 +399,51 @@ func TestActionDispatcher(t *testing.T) {
 		saver.AssertExpectations(t)
 	})
 
+	t.Run("Dispatch of an action deferred until the next maintenance window queues it", func(t *testing.T) {
+		deferredUntil := time.Now().Add(time.Hour).Truncate(time.Second)
+		def := &mockHandler{}
+		def.On("Handle", mock.Anything, mock.Anything, mock.Anything).Return(&maintenance.DeferredError{Until: deferredUntil}).Once()
+
+		saver := &mockSaver{}
+		saver.On("Save").Return(nil).Times(2)
+		saver.On("SetQueue", mock.Anything).Times(2)
+		actionQueue, err := queue.NewActionQueue([]fleetapi.ScheduledAction{}, saver)
+		require.NoError(t, err)
+
+		d, err := New(nil, t.TempDir(), def, actionQueue)
+		require.NoError(t, err)
+		err = d.Register(&mockRetryableAction{}, def)
+		require.NoError(t, err)
+
+		ack := acker.NewMockAcker(t)
+		ack.EXPECT().Commit(mock.Anything).Return(nil).Once()
+
+		action := &mockRetryableAction{}
+		action.On("Type").Return("action")
+		action.On("ID").Return("id")
+		action.On("StartTime").Return(time.Time{}, fleetapi.ErrNoStartTime).Once()
+		action.On("SetStartTime", deferredUntil).Once()
+
+		dispatchCtx, cancelFn := context.WithCancel(context.Background())
+		defer cancelFn()
+		go d.Dispatch(dispatchCtx, detailsSetter, ack, action)
+		if err := <-d.Errors(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		def.AssertExpectations(t)
+		action.AssertExpectations(t)
+		saver.AssertExpectations(t)
+		// neither acked nor counted as a retry attempt
+		action.AssertNotCalled(t, "SetRetryAttempt", mock.Anything)
+		action.AssertNotCalled(t, "SetError", mock.Anything)
+		assert.Len(t, actionQueue.Actions(), 1)
+
+		// the dispatcher is called again when the window opens
+		action.On("StartTime").Return(deferredUntil, nil)
+		assert.Equal(t, deferredUntil, d.NextDispatch(time.Now()))
+		assert.True(t, d.NextDispatch(deferredUntil).IsZero(), "an action already started is dispatched on the next call")
+	})
+
 	t.Run("Dispatch multiple events returns one error", func(t *testing.T) {
 		saver := &mockSaver{}
 		saver.On("Save").Return(nil).Once()
@@ -253,6 +253,16 @@ func enroll(
 		return err
 	}
 
+	// the policies in the policy history belong to the previous enrollment
+	if err := os.Remove(paths.AgentPolicyHistoryFile()); err != nil && !goerrors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// the pending acks are for the actions of the previous enrollment
+	if err := os.Remove(paths.AgentAckStoreFile()); err != nil && !goerrors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
 	return nil
 }
 
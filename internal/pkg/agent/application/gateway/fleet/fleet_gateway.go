@@ -96,6 +96,12 @@ type FleetGateway struct {
 	actionCh           chan []fleetapi.Action
 	rollbackSource     ttl.ReadOnlySource
 	compression        string
+
+	// deltaPolicy is set when the agent offers Fleet to send policies as
+	// deltas, fullPolicyRequired once a delta could not be applied.
+	deltaPolicy        bool
+	fullPolicyRequired bool
+	policyHash         policyHashCache
 }
 
 // New creates a new fleet gateway
@@ -127,6 +133,7 @@ func New(
 		return nil, err
 	}
 	gw.compression = cfg.GetCompression()
+	gw.deltaPolicy = cfg.IsDeltaPolicyEnabled()
 	return gw, nil
 }
 
@@ -199,6 +206,7 @@ func (f *FleetGateway) Run(ctx context.Context) error {
 					f.log.Errorf("failed to unmarshal checkin actions: %v", err)
 					continue
 				}
+				actions = f.rebuildDeltaPolicies(actions)
 			}
 			if len(actions) > 0 {
 				f.actionCh <- actions
@@ -413,10 +421,14 @@ func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 	var (
 		agentPolicyID     string
 		policyRevisionIDX int64
+		policyHash        string
 	)
 	if pc, ok := f.stateStore.Action().(*fleetapi.ActionPolicyChange); ok && pc != nil {
 		agentPolicyID = pc.PolicyID()
 		policyRevisionIDX = pc.PolicyRevisionIDX()
+		if f.deltaPolicy {
+			policyHash = f.policyHash.get(f.log, pc)
+		}
 	}
 
 	// get available rollbacks
@@ -456,6 +468,12 @@ func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 	if len(validRollbacks) > 0 {
 		req.Upgrade.Rollbacks = validRollbacks
 	}
+	if f.deltaPolicy {
+		req.PolicyDelta = &fleetapi.CheckinPolicyDelta{
+			PolicyHash:         policyHash,
+			FullPolicyRequired: f.fullPolicyRequired,
+		}
+	}
 
 	resp, took, err := cmd.Execute(stateCtx, req)
 	f.stateFetcher.Done()
@@ -519,6 +537,8 @@ func agentStateToString(state agentclient.State) string {
 		return fleetStateOnline
 	case agentclient.Rollback:
 		return fleetStateDegraded
+	case agentclient.Quarantined:
+		return fleetStateDegraded
 	case agentclient.Degraded:
 		return fleetStateDegraded
 	// Report Stopping and Stopped as online since Fleet doesn't understand these states yet.
@@ -426,6 +426,107 @@ func TestFleetGateway(t *testing.T) {
 		}
 	})
 
+	t.Run("rebuilds policy deltas and requests the complete policy on mismatch", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
+		scheduler := scheduler.NewStepper()
+		client := newTestingClient()
+
+		log, _ := loggertest.New("fleet_gateway")
+
+		basePolicy := map[string]interface{}{
+			"id":       "test-policy-id",
+			"revision": 1,
+			"inputs":   []interface{}{map[string]interface{}{"id": "logs-1", "type": "logfile"}},
+		}
+		baseHash, err := fleetapi.PolicyHash(basePolicy)
+		require.NoError(t, err)
+		rebuiltPolicy := map[string]interface{}{
+			"id":       "test-policy-id",
+			"revision": 2,
+			"inputs":   []interface{}{map[string]interface{}{"id": "logs-1", "type": "filestream"}},
+		}
+		rebuiltHash, err := fleetapi.PolicyHash(rebuiltPolicy)
+		require.NoError(t, err)
+
+		stateStore := newStateStore(t, log)
+		stateStore.SetAction(&fleetapi.ActionPolicyChange{
+			ActionID:   "test-action-id",
+			ActionType: fleetapi.ActionTypePolicyChange,
+			Data:       fleetapi.ActionPolicyChangeData{Policy: basePolicy},
+		})
+		require.NoError(t, stateStore.Save())
+
+		mockRollbacksSrc := ttl.NewMockReadOnlySource(t)
+		mockRollbacksSrc.EXPECT().GetAll().Return(nil, nil, nil)
+
+		gateway, err := newFleetGatewayWithScheduler(log, settings, agentInfo, client, scheduler, noop.New(), stateStore, NewCheckinStateFetcher(emptyStateFetcher), mockRollbacksSrc)
+		require.NoError(t, err)
+		gateway.deltaPolicy = true
+
+		deltaResp := func(actionID string, hash string) *http.Response {
+			return wrapStrToResp(http.StatusOK, fmt.Sprintf(`{
+				"actions": [{
+					"type": "POLICY_CHANGE",
+					"id": %q,
+					"data": {
+						"policy_delta": {
+							"base_revision_idx": 1,
+							"hash": %q,
+							"set": {"revision": 2},
+							"inputs": [{"id": "logs-1", "type": "filestream"}]
+						}
+					}
+				}]
+			}`, actionID, hash))
+		}
+		readCheckin := func(body io.Reader) fleetapi.CheckinRequest {
+			var checkinRequest fleetapi.CheckinRequest
+			require.NoError(t, json.NewDecoder(body).Decode(&checkinRequest))
+			require.NotNil(t, checkinRequest.PolicyDelta)
+			return checkinRequest
+		}
+
+		mismatchWaitFn := ackSeq(
+			client.Answer(func(_ context.Context, _ http.Header, body io.Reader) (*http.Response, error) {
+				checkinRequest := readCheckin(body)
+				assert.Equal(t, baseHash, checkinRequest.PolicyDelta.PolicyHash)
+				assert.False(t, checkinRequest.PolicyDelta.FullPolicyRequired)
+				return deltaResp("mismatch-action-id", "not-the-hash"), nil
+			}),
+		)
+
+		errCh := runFleetGateway(ctx, gateway)
+
+		scheduler.Next()
+		mismatchWaitFn()
+
+		rebuiltWaitFn := ackSeq(
+			client.Answer(func(_ context.Context, _ http.Header, body io.Reader) (*http.Response, error) {
+				checkinRequest := readCheckin(body)
+				assert.True(t, checkinRequest.PolicyDelta.FullPolicyRequired)
+				return deltaResp("rebuilt-action-id", rebuiltHash), nil
+			}),
+		)
+
+		scheduler.Next()
+		rebuiltWaitFn()
+
+		actions := <-gateway.Actions()
+		require.Len(t, actions, 1, "the action with the mismatching delta must be dropped")
+		pc, ok := actions[0].(*fleetapi.ActionPolicyChange)
+		require.True(t, ok)
+		assert.Equal(t, "rebuilt-action-id", pc.ActionID)
+		assert.Nil(t, pc.Data.PolicyDelta)
+		hash, err := fleetapi.PolicyHash(pc.Data.Policy)
+		require.NoError(t, err)
+		assert.Equal(t, rebuiltHash, hash)
+
+		cancel()
+		require.NoError(t, <-errCh)
+	})
+
 	t.Run("Test cancel checkin on state update", func(t *testing.T) {
 		scheduler := scheduler.NewStepper()
 		client := newTestingClient()
@@ -725,9 +826,13 @@ func TestAgentStateToString(t *testing.T) {
 			agentState:         agentclient.Rollback,
 			expectedFleetState: fleetStateDegraded,
 		},
+		{
+			agentState:         agentclient.Quarantined,
+			expectedFleetState: fleetStateDegraded,
+		},
 		{
 			// Unknown states should map to degraded.
-			agentState:         agentclient.Rollback + 1,
+			agentState:         agentclient.Quarantined + 1,
 			expectedFleetState: fleetStateDegraded,
 		},
 	}
@@ -0,0 +1,87 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleet
+
+import (
+	"fmt"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	fleetapi "github.com/elastic/elastic-agent/pkg/fleetapi"
+)
+
+// policyHashCache keeps the hash of the policy the agent runs, so it is not
+// computed on every checkin.
+type policyHashCache struct {
+	actionID string
+	hash     string
+}
+
+// get returns the hash of the policy of the action.
+func (c *policyHashCache) get(log *logger.Logger, action *fleetapi.ActionPolicyChange) string {
+	if c.actionID == action.ActionID && c.hash != "" {
+		return c.hash
+	}
+	hash, err := fleetapi.PolicyHash(action.Data.Policy)
+	if err != nil {
+		log.Warnf("failed to compute the hash of the policy of action %s: %v", action.ActionID, err)
+		return ""
+	}
+	c.actionID = action.ActionID
+	c.hash = hash
+	return hash
+}
+
+// rebuildDeltaPolicies replaces the policy deltas of the POLICY_CHANGE actions
+// with the complete policies rebuilt from the policy the agent runs. Actions
+// whose policy cannot be rebuilt are dropped without being acked and a
+// complete policy is requested on the next checkin.
+func (f *FleetGateway) rebuildDeltaPolicies(actions []fleetapi.Action) []fleetapi.Action {
+	base, _ := f.stateStore.Action().(*fleetapi.ActionPolicyChange)
+
+	rebuilt := make([]fleetapi.Action, 0, len(actions))
+	for _, action := range actions {
+		pc, ok := action.(*fleetapi.ActionPolicyChange)
+		if !ok {
+			rebuilt = append(rebuilt, action)
+			continue
+		}
+		if pc.Data.PolicyDelta == nil {
+			// complete policy, the base of the following deltas
+			f.fullPolicyRequired = false
+			base = pc
+			rebuilt = append(rebuilt, action)
+			continue
+		}
+
+		policy, err := rebuildPolicy(base, pc.Data.PolicyDelta)
+		if err != nil {
+			f.log.Warnw("Failed to rebuild the policy from the delta sent by Fleet, requesting the complete policy",
+				"action_id", pc.ActionID, "error.message", err)
+			f.fullPolicyRequired = true
+			continue
+		}
+		f.log.Infow("Rebuilt the policy from the delta sent by Fleet",
+			"action_id", pc.ActionID, "base_revision_idx", pc.Data.PolicyDelta.BaseRevisionIDX,
+			"inputs", len(pc.Data.PolicyDelta.Inputs), "removed_inputs", len(pc.Data.PolicyDelta.RemovedInputs),
+			"outputs", len(pc.Data.PolicyDelta.Outputs), "removed_outputs", len(pc.Data.PolicyDelta.RemovedOutputs))
+		pc.Data.Policy = policy
+		pc.Data.PolicyDelta = nil
+		f.fullPolicyRequired = false
+		base = pc
+		rebuilt = append(rebuilt, pc)
+	}
+	return rebuilt
+}
+
+// rebuildPolicy applies the delta on the policy of the base action.
+func rebuildPolicy(base *fleetapi.ActionPolicyChange, delta *fleetapi.PolicyDelta) (map[string]interface{}, error) {
+	if base == nil || base.Data.Policy == nil {
+		return nil, fmt.Errorf("no policy to apply the delta on")
+	}
+	if revision := base.PolicyRevisionIDX(); revision != delta.BaseRevisionIDX {
+		return nil, fmt.Errorf("delta applies to revision %d, the agent runs revision %d", delta.BaseRevisionIDX, revision)
+	}
+	return delta.Apply(base.Data.Policy)
+}
@@ -0,0 +1,149 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package maintenance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var weekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// cronExpr is a parsed 5 field cron expression: minute, hour, day of month, month and day of week.
+type cronExpr struct {
+	minutes  [60]bool
+	hours    [24]bool
+	days     [32]bool
+	months   [13]bool
+	weekdays [7]bool
+
+	// anyDay and anyWeekday are set when the field is a wildcard, when both fields are
+	// restricted a time matches when either of them matches, like in cron.
+	anyDay     bool
+	anyWeekday bool
+}
+
+// parseCron parses a cron expression. Each field accepts a wildcard (*), a value, a range (1-5),
+// a step (*/15 or 0-30/10) and a comma separated list of those. Months and days of the week also
+// accept their three letter English names, Sunday is either 0 or 7.
+func parseCron(expr string) (*cronExpr, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule '%s' must have 5 fields (minute hour day-of-month month day-of-week); received %d", expr, len(fields))
+	}
+
+	c := &cronExpr{
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	if err := parseField(fields[0], 0, 59, nil, c.minutes[:]); err != nil {
+		return nil, fmt.Errorf("schedule '%s' has an invalid minute: %w", expr, err)
+	}
+	if err := parseField(fields[1], 0, 23, nil, c.hours[:]); err != nil {
+		return nil, fmt.Errorf("schedule '%s' has an invalid hour: %w", expr, err)
+	}
+	if err := parseField(fields[2], 1, 31, nil, c.days[:]); err != nil {
+		return nil, fmt.Errorf("schedule '%s' has an invalid day of month: %w", expr, err)
+	}
+	if err := parseField(fields[3], 1, 12, monthNames, c.months[:]); err != nil {
+		return nil, fmt.Errorf("schedule '%s' has an invalid month: %w", expr, err)
+	}
+	var weekdays [8]bool
+	if err := parseField(fields[4], 0, 7, weekdayNames, weekdays[:]); err != nil {
+		return nil, fmt.Errorf("schedule '%s' has an invalid day of week: %w", expr, err)
+	}
+	copy(c.weekdays[:], weekdays[:7])
+	c.weekdays[0] = c.weekdays[0] || weekdays[7]
+	return c, nil
+}
+
+func parseField(field string, min, max int, names map[string]int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step <= 0 {
+				return fmt.Errorf("invalid step '%s'", stepStr)
+			}
+		}
+
+		start, end := min, max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			start, err = parseValue(from, min, max, names)
+			if err != nil {
+				return err
+			}
+			end = start
+			if isRange {
+				end, err = parseValue(to, min, max, names)
+				if err != nil {
+					return err
+				}
+				if end < start {
+					return fmt.Errorf("invalid range '%s'", rng)
+				}
+			} else if hasStep {
+				// a single value with a step starts at the value, like 5/15
+				end = max
+			}
+		}
+
+		for i := start; i <= end; i += step {
+			set[i] = true
+		}
+	}
+	return nil
+}
+
+func parseValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", s)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range [%d-%d]", v, min, max)
+	}
+	return v, nil
+}
+
+// matchDay returns true when the date of t matches the day of month, month and day of week fields.
+func (c *cronExpr) matchDay(t time.Time) bool {
+	if !c.months[t.Month()] {
+		return false
+	}
+	day := c.days[t.Day()]
+	weekday := c.weekdays[t.Weekday()]
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// match returns true when t, truncated to the minute, matches the expression.
+func (c *cronExpr) match(t time.Time) bool {
+	return c.matchDay(t) && c.hours[t.Hour()] && c.minutes[t.Minute()]
+}
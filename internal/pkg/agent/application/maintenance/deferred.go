@@ -0,0 +1,38 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoWindow is returned when an operation must be deferred until the next maintenance window
+// and the maintenance windows never open.
+var ErrNoWindow = errors.New("the configured maintenance windows never open")
+
+// DeferredError is returned when an operation is not performed because it is deferred until the
+// start of the next maintenance window.
+type DeferredError struct {
+	Until time.Time
+}
+
+func (e *DeferredError) Error() string {
+	return fmt.Sprintf("deferred until the next maintenance window at %s", e.Until.Format(time.RFC3339))
+}
+
+// Deferral returns the start of the next maintenance window when an operation requested at t must
+// be deferred, the zero time when t is inside a maintenance window.
+func (s *Schedule) Deferral(t time.Time) (time.Time, error) {
+	next, ok := s.Next(t)
+	if !ok {
+		return time.Time{}, ErrNoWindow
+	}
+	if !next.After(t) {
+		return time.Time{}, nil
+	}
+	return next, nil
+}
@@ -0,0 +1,41 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleDeferral(t *testing.T) {
+	// every night between 02:00 and 03:00
+	nightly, err := NewWindow("0 2 * * *", time.Hour)
+	require.NoError(t, err)
+	s, err := NewSchedule("UTC", nightly)
+	require.NoError(t, err)
+
+	until, err := s.Deferral(time.Date(2026, time.October, 17, 2, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.True(t, until.IsZero(), "nothing is deferred inside a window")
+
+	until, err = s.Deferral(time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, time.October, 18, 2, 0, 0, 0, time.UTC), until)
+
+	var disabled *Schedule
+	until, err = disabled.Deferral(time.Now())
+	require.NoError(t, err)
+	assert.True(t, until.IsZero(), "nothing is deferred without maintenance windows")
+
+	never, err := NewWindow("0 0 31 2 *", time.Hour)
+	require.NoError(t, err)
+	s, err = NewSchedule("UTC", never)
+	require.NoError(t, err)
+	_, err = s.Deferral(time.Now())
+	assert.ErrorIs(t, err, ErrNoWindow)
+}
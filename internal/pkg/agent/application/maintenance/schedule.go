@@ -0,0 +1,128 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package maintenance computes the maintenance windows during which the Elastic Agent is allowed
+// to perform disruptive operations like upgrades and restarts.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// searchLimit bounds the search for the next window start, 4 years covers every combination
+// of days, including the 29th of February.
+const searchLimit = 4 * 366 * 24 * time.Hour
+
+// Window is a recurring maintenance window. It opens at every minute matching its cron
+// schedule and stays open for its duration.
+type Window struct {
+	schedule string
+	expr     *cronExpr
+	duration time.Duration
+}
+
+// NewWindow creates a window from a 5 field cron schedule and a duration.
+func NewWindow(schedule string, duration time.Duration) (Window, error) {
+	if duration <= 0 {
+		return Window{}, fmt.Errorf("window '%s' must have a duration higher than zero", schedule)
+	}
+	expr, err := parseCron(schedule)
+	if err != nil {
+		return Window{}, err
+	}
+	return Window{schedule: schedule, expr: expr, duration: duration}, nil
+}
+
+// String returns the cron schedule of the window.
+func (w Window) String() string {
+	return w.schedule
+}
+
+// Schedule is a set of maintenance windows in a time zone. A nil Schedule has no restriction,
+// every time is inside a maintenance window.
+type Schedule struct {
+	loc     *time.Location
+	windows []Window
+}
+
+// NewSchedule creates a schedule of the windows evaluated in the time zone, an empty time zone
+// is UTC.
+func NewSchedule(timezone string, windows ...Window) (*Schedule, error) {
+	if len(windows) == 0 {
+		return nil, errors.New("at least one maintenance window is required")
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone '%s': %w", timezone, err)
+	}
+	return &Schedule{loc: loc, windows: windows}, nil
+}
+
+// Contains returns true when t is inside a maintenance window.
+func (s *Schedule) Contains(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	t = t.In(s.loc)
+	end := t.Truncate(time.Minute)
+	for _, w := range s.windows {
+		// a window started after t minus its duration is still open at t
+		first := ceilMinute(t.Add(-w.duration).Add(time.Nanosecond))
+		if _, ok := w.expr.next(first, end, s.loc); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns t when t is inside a maintenance window, otherwise it returns the start of the
+// next maintenance window. Returns false when no window ever starts.
+func (s *Schedule) Next(t time.Time) (time.Time, bool) {
+	if s.Contains(t) {
+		return t, true
+	}
+	t = t.In(s.loc)
+	from := ceilMinute(t)
+	var next time.Time
+	for _, w := range s.windows {
+		start, ok := w.expr.next(from, from.Add(searchLimit), s.loc)
+		if ok && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// next returns the first minute in [from, until] matching the expression.
+func (c *cronExpr) next(from, until time.Time, loc *time.Location) (time.Time, bool) {
+	for t := from; !t.After(until); {
+		var skip time.Time
+		switch {
+		case !c.matchDay(t):
+			skip = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !c.hours[t.Hour()]:
+			skip = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !c.minutes[t.Minute()]:
+			skip = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+		if !skip.After(t) {
+			// daylight saving time transitions can map the local time to an earlier instant
+			skip = t.Add(time.Minute)
+		}
+		t = skip
+	}
+	return time.Time{}, false
+}
+
+func ceilMinute(t time.Time) time.Time {
+	truncated := t.Truncate(time.Minute)
+	if truncated.Before(t) {
+		return truncated.Add(time.Minute)
+	}
+	return truncated
+}
@@ -0,0 +1,174 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWindow(t *testing.T) {
+	testCases := map[string]struct {
+		schedule string
+		duration time.Duration
+		err      string
+	}{
+		"valid":                {schedule: "0 22 * * sat,sun", duration: time.Hour},
+		"valid steps":          {schedule: "*/15 0-6/2 1,15 1-12 *", duration: time.Minute},
+		"sunday as 7":          {schedule: "0 0 * * 7", duration: time.Hour},
+		"missing field":        {schedule: "0 22 * *", duration: time.Hour, err: "must have 5 fields"},
+		"minute out of range":  {schedule: "60 22 * * *", duration: time.Hour, err: "invalid minute"},
+		"invalid range":        {schedule: "0 22 * * 5-1", duration: time.Hour, err: "invalid range"},
+		"invalid step":         {schedule: "*/0 22 * * *", duration: time.Hour, err: "invalid step"},
+		"invalid month name":   {schedule: "0 22 * foo *", duration: time.Hour, err: "invalid month"},
+		"zero duration":        {schedule: "0 22 * * *", duration: 0, err: "duration higher than zero"},
+		"negative duration":    {schedule: "0 22 * * *", duration: -time.Hour, err: "duration higher than zero"},
+		"day of month is zero": {schedule: "0 22 0 * *", duration: time.Hour, err: "invalid day of month"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewWindow(tc.schedule, tc.duration)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNewScheduleErrors(t *testing.T) {
+	_, err := NewSchedule("UTC")
+	assert.Error(t, err)
+
+	w, err := NewWindow("0 0 * * *", time.Hour)
+	require.NoError(t, err)
+	_, err = NewSchedule("Not/AZone", w)
+	assert.ErrorContains(t, err, "unknown time zone")
+}
+
+func TestSchedule(t *testing.T) {
+	// weekends, starting Saturday at midnight for 48 hours
+	weekend, err := NewWindow("0 0 * * sat", 48*time.Hour)
+	require.NoError(t, err)
+	// every night between 02:30 and 03:00
+	nightly, err := NewWindow("30 2 * * *", 30*time.Minute)
+	require.NoError(t, err)
+
+	s, err := NewSchedule("Europe/Paris", weekend, nightly)
+	require.NoError(t, err)
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	at := func(day, hour, minute int) time.Time {
+		// October 2026, the 16th is a Friday
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, paris)
+	}
+
+	testCases := map[string]struct {
+		t        time.Time
+		contains bool
+		next     time.Time
+	}{
+		"friday afternoon": {
+			t:    at(16, 15, 0),
+			next: at(17, 0, 0),
+		},
+		"saturday": {
+			t:        at(17, 12, 0),
+			contains: true,
+			next:     at(17, 12, 0),
+		},
+		"sunday end of the day": {
+			t:        at(18, 23, 59),
+			contains: true,
+			next:     at(18, 23, 59),
+		},
+		"monday midnight, window closed": {
+			t:    at(19, 0, 0),
+			next: at(19, 2, 30),
+		},
+		"nightly window": {
+			t:        at(20, 2, 45),
+			contains: true,
+			next:     at(20, 2, 45),
+		},
+		"nightly window end is exclusive": {
+			t:    at(20, 3, 0),
+			next: at(21, 2, 30),
+		},
+		"in the middle of a minute": {
+			t:    at(20, 2, 29).Add(30 * time.Second),
+			next: at(20, 2, 30),
+		},
+		"other time zone": {
+			// 22:00 UTC on Friday is midnight on Saturday in Paris
+			t:        time.Date(2026, time.October, 16, 22, 0, 0, 0, time.UTC),
+			contains: true,
+			next:     time.Date(2026, time.October, 16, 22, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.contains, s.Contains(tc.t))
+			next, ok := s.Next(tc.t)
+			require.True(t, ok)
+			assert.True(t, tc.next.Equal(next), "expected next window at %s, got %s", tc.next, next)
+		})
+	}
+}
+
+func TestScheduleDayOfMonthAndWeek(t *testing.T) {
+	// like cron, when both the day of month and the day of week are restricted either one matches
+	w, err := NewWindow("0 12 1 * mon", time.Hour)
+	require.NoError(t, err)
+	s, err := NewSchedule("", w)
+	require.NoError(t, err)
+
+	// Friday the 16th of October 2026, the next Monday is the 19th
+	next, ok := s.Next(time.Date(2026, time.October, 16, 13, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC), next)
+
+	// Wednesday the 28th, the 1st of November comes before the next Monday
+	next, ok = s.Next(time.Date(2026, time.October, 28, 13, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, time.November, 1, 12, 0, 0, 0, time.UTC), next)
+}
+
+func TestScheduleLeapDay(t *testing.T) {
+	w, err := NewWindow("0 0 29 feb *", time.Hour)
+	require.NoError(t, err)
+	s, err := NewSchedule("UTC", w)
+	require.NoError(t, err)
+
+	next, ok := s.Next(time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC), next)
+}
+
+func TestScheduleNeverStarts(t *testing.T) {
+	w, err := NewWindow("0 0 31 feb *", time.Hour)
+	require.NoError(t, err)
+	s, err := NewSchedule("UTC", w)
+	require.NoError(t, err)
+
+	_, ok := s.Next(time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+}
+
+func TestNilSchedule(t *testing.T) {
+	var s *Schedule
+	now := time.Now()
+	assert.True(t, s.Contains(now))
+	next, ok := s.Next(now)
+	assert.True(t, ok)
+	assert.Equal(t, now, next)
+}
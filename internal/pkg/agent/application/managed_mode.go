@@ -45,8 +45,10 @@ type managedConfigManager struct {
 	client                   *remote.Client
 	store                    storage.Store
 	stateStore               *store.StateStore
+	policyHistory            *store.PolicyHistory
 	actionQueue              *queue.ActionQueue
 	dispatcher               *dispatcher.ActionDispatcher
+	diagnostics              *handlers.Diagnostics
 	runtime                  *runtime.Manager
 	coord                    *coordinator.Coordinator
 	fleetInitTimeout         time.Duration
@@ -54,13 +56,14 @@ type managedConfigManager struct {
 	fleetAcker               *fleet.Acker
 	actionAcker              acker.Acker
 	retrier                  *retrier.Retrier
+	ackStore                 *store.AckStore
 	availableRollbacksSource ttl.ReadOnlySource
 
 	ch    chan coordinator.ConfigChange
 	errCh chan error
 }
 
-func newManagedConfigManager(ctx context.Context, log *logger.Logger, agentInfo info.Agent, cfg *configuration.Configuration, storeSaver storage.Store, runtime *runtime.Manager, fleetInitTimeout time.Duration, topPath string, client *remote.Client, fleetAcker *fleet.Acker, actionAcker acker.Acker, retrier *retrier.Retrier, stateStore *store.StateStore, actionQueue *queue.ActionQueue, source ttl.ReadOnlySource, clientSetters ...actions.ClientSetter) (*managedConfigManager, error) {
+func newManagedConfigManager(ctx context.Context, log *logger.Logger, agentInfo info.Agent, cfg *configuration.Configuration, storeSaver storage.Store, runtime *runtime.Manager, fleetInitTimeout time.Duration, topPath string, client *remote.Client, fleetAcker *fleet.Acker, actionAcker acker.Acker, retrier *retrier.Retrier, ackStore *store.AckStore, stateStore *store.StateStore, policyHistory *store.PolicyHistory, actionQueue *queue.ActionQueue, source ttl.ReadOnlySource, clientSetters ...actions.ClientSetter) (*managedConfigManager, error) {
 	actionDispatcher, err := dispatcher.New(log, topPath, handlers.NewDefault(log), actionQueue)
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize action dispatcher: %w", err)
@@ -73,6 +76,7 @@ func newManagedConfigManager(ctx context.Context, log *logger.Logger, agentInfo
 		client:                   client,
 		store:                    storeSaver,
 		stateStore:               stateStore,
+		policyHistory:            policyHistory,
 		actionQueue:              actionQueue,
 		dispatcher:               actionDispatcher,
 		runtime:                  runtime,
@@ -83,6 +87,7 @@ func newManagedConfigManager(ctx context.Context, log *logger.Logger, agentInfo
 		fleetAcker:               fleetAcker,
 		actionAcker:              actionAcker,
 		retrier:                  retrier,
+		ackStore:                 ackStore,
 		availableRollbacksSource: source,
 	}, nil
 }
@@ -110,12 +115,25 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 
 	// Initialize the actionDispatcher.
 	policyChanger := m.initDispatcher(gatewayCancel)
+	if m.policyHistory != nil {
+		m.coord.RegisterPolicyRollbacker(policyChanger)
+	}
 
 	// Create ackers to enqueue/retry failed acks
 	if err := m.coord.AckUpgrade(ctx, m.actionAcker); err != nil {
 		m.log.Warnf("Failed to ack upgrade: %v", err)
 	}
 
+	if m.ackStore != nil {
+		// report the pending acks and replay the ones not delivered before the restart
+		m.ackStore.OnChange(m.coord.SetPendingAcks)
+		m.coord.SetPendingAcks(m.ackStore.Pending())
+		if pending := m.ackStore.Actions(); len(pending) > 0 {
+			m.log.Infof("Replaying %d pending acks not delivered to Fleet before the restart", len(pending))
+			m.retrier.Enqueue(pending)
+		}
+	}
+
 	// Run the retrier.
 	retrierRun := make(chan bool)
 	retrierCtx, retrierCancel := context.WithCancel(ctx)
@@ -216,29 +234,44 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 		return gateway.Run(ctx)
 	})
 
+	// Resume the diagnostics uploads interrupted by the last stop.
+	m.diagnostics.ResumeUploads(ctx, m.actionAcker)
+
 	go runDispatcher(ctx, m.dispatcher, gateway, m.coord.SetUpgradeDetails, m.actionAcker, dispatchFlushInterval)
 
 	<-ctx.Done()
 	return gatewayRunner.Err()
 }
 
-// runDispatcher passes actions collected from gateway to dispatcher or calls Dispatch with no actions every flushInterval.
+// runDispatcher passes actions collected from gateway to dispatcher or calls Dispatch with no actions every flushInterval,
+// or earlier when a queued action starts before the next flush.
 func runDispatcher(ctx context.Context, actionDispatcher dispatcher.Dispatcher, fleetGateway coordinator.FleetGateway, detailsSetter details.Observer, actionAcker acker.Acker, flushInterval time.Duration) {
-	t := time.NewTimer(flushInterval)
+	t := time.NewTimer(nextDispatchIn(actionDispatcher, flushInterval))
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-t.C: // periodically call the dispatcher to handle scheduled actions.
 			actionDispatcher.Dispatch(ctx, detailsSetter, actionAcker)
-			t.Reset(flushInterval)
+			t.Reset(nextDispatchIn(actionDispatcher, flushInterval))
 		case actions := <-fleetGateway.Actions():
 			actionDispatcher.Dispatch(ctx, detailsSetter, actionAcker, actions...)
-			t.Reset(flushInterval)
+			t.Reset(nextDispatchIn(actionDispatcher, flushInterval))
 		}
 	}
 }
 
+// nextDispatchIn returns the time until the dispatcher is called with no actions, at most flushInterval. A queued
+// action starting before the next flush, like an action deferred until a maintenance window shorter than
+// flushInterval, is dispatched when it starts.
+func nextDispatchIn(actionDispatcher dispatcher.Dispatcher, flushInterval time.Duration) time.Duration {
+	now := time.Now()
+	if next := actionDispatcher.NextDispatch(now); !next.IsZero() && next.Sub(now) < flushInterval {
+		return next.Sub(now)
+	}
+	return flushInterval
+}
+
 // ActionErrors returns the error channel for actions.
 // May return errors for fleet managed errors.
 func (m *managedConfigManager) ActionErrors() <-chan error {
@@ -329,6 +362,9 @@ func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *han
 		m.ch,
 		m.coord,
 	)
+	if m.policyHistory != nil {
+		policyChanger.SetPolicyHistory(m.policyHistory, m.coord)
+	}
 
 	m.dispatcher.MustRegister(
 		&fleetapi.ActionPolicyChange{},
@@ -369,15 +405,30 @@ func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *han
 		),
 	)
 
+	diagOpts := []handlers.DiagnosticsOption{
+		handlers.WithResumableUploads(paths.AgentDiagnosticsUploadsDir()),
+		handlers.WithUploadProgress(m.coord.SetDiagnosticsUploads),
+	}
+	if m.cfg.Settings.Diagnostics != nil {
+		// never upload a bundle without the redaction rules the user asked for
+		if redactor, err := m.cfg.Settings.Diagnostics.Redaction.NewRedactor(); err != nil {
+			m.log.Errorw("Diagnostics actions refused, failed to load the redaction rules", "error.message", err)
+			diagOpts = append(diagOpts, handlers.WithRedactorError(err))
+		} else {
+			diagOpts = append(diagOpts, handlers.WithRedactor(redactor))
+		}
+	}
+	m.diagnostics = handlers.NewDiagnostics(
+		m.log,
+		paths.Top(), // TODO: stop using global state
+		m.coord,
+		m.cfg.Settings.MonitoringConfig.Diagnostics.Limit,
+		uploader.New(m.agentInfo.AgentID(), m.client, m.cfg.Settings.MonitoringConfig.Diagnostics.Uploader),
+		diagOpts...,
+	)
 	m.dispatcher.MustRegister(
 		&fleetapi.ActionDiagnostics{},
-		handlers.NewDiagnostics(
-			m.log,
-			paths.Top(), // TODO: stop using global state
-			m.coord,
-			m.cfg.Settings.MonitoringConfig.Diagnostics.Limit,
-			uploader.New(m.agentInfo.AgentID(), m.client, m.cfg.Settings.MonitoringConfig.Diagnostics.Uploader),
-		),
+		m.diagnostics,
 	)
 
 	m.dispatcher.MustRegister(
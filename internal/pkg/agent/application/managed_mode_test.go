@@ -21,12 +21,20 @@ import (
 
 type mockDispatcher struct {
 	mock.Mock
+	next time.Time
 }
 
 func (m *mockDispatcher) Dispatch(ctx context.Context, detailsSetter details.Observer, ack acker.Acker, actions ...fleetapi.Action) {
 	m.Called(ctx, detailsSetter, ack, actions)
 }
 
+func (m *mockDispatcher) NextDispatch(now time.Time) time.Time {
+	if m.next.After(now) {
+		return m.next
+	}
+	return time.Time{}
+}
+
 func (m *mockDispatcher) Errors() <-chan error {
 	args := m.Called()
 	return args.Get(0).(<-chan error)
@@ -120,6 +128,20 @@ func Test_runDispatcher(t *testing.T) {
 		},
 		flushInterval:  time.Millisecond * 60,
 		contextTimeout: time.Millisecond * 100,
+	}, {
+		name: "queued action starting before the flush, dispatcher is called at its start",
+		mockGateway: func(ch chan []fleetapi.Action) *mockGateway {
+			gateway := &mockGateway{}
+			gateway.On("Actions").Return((<-chan []fleetapi.Action)(ch))
+			return gateway
+		},
+		mockDispatcher: func() *mockDispatcher {
+			dispatcher := &mockDispatcher{next: time.Now().Add(50 * time.Millisecond)}
+			dispatcher.On("Dispatch", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Once()
+			return dispatcher
+		},
+		flushInterval:  time.Hour,
+		contextTimeout: time.Millisecond * 200,
 	}}
 
 	for _, tc := range tests {
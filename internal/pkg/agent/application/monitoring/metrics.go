@@ -0,0 +1,362 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+
+	componentmonitoring "github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring/component"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring/monitoringhelpers"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+const (
+	// prometheusContentType is the content type of the Prometheus text exposition format.
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	// componentStatsTimeout bounds the time spent fetching the stats of a component, so an
+	// unresponsive component does not make the whole scrape time out.
+	componentStatsTimeout = 5 * time.Second
+
+	metricPrefix          = "elastic_agent_"
+	componentMetricPrefix = metricPrefix + "component_"
+
+	counterType = "counter"
+	gaugeType   = "gauge"
+)
+
+// statsFetcher fetches the content of path from the monitoring endpoint of a process.
+type statsFetcher func(ctx context.Context, endpoint, path string) ([]byte, int, error)
+
+// statsMetric maps a value of the Beats-style stats to a Prometheus metric.
+type statsMetric struct {
+	key    string
+	name   string
+	help   string
+	typ    string
+	labels []promLabel
+	scale  float64
+}
+
+// processMetrics are exposed both for the Elastic Agent and for each component.
+var processMetrics = []statsMetric{
+	{key: "beat.cpu.total.time.ms", name: "cpu_seconds_total", help: "Total CPU time consumed by the process, in seconds.", typ: counterType, scale: 0.001},
+	{key: "beat.memstats.rss", name: "memory_rss_bytes", help: "Resident set size of the process, in bytes.", typ: gaugeType},
+	{key: "beat.memstats.memory_alloc", name: "memory_alloc_bytes", help: "Bytes of allocated heap objects.", typ: gaugeType},
+	{key: "beat.runtime.goroutines", name: "goroutines", help: "Number of goroutines.", typ: gaugeType},
+	{key: "beat.handles.open", name: "open_handles", help: "Number of open file handles.", typ: gaugeType},
+	{key: "beat.info.uptime.ms", name: "uptime_seconds", help: "Time since the process started, in seconds.", typ: gaugeType, scale: 0.001},
+}
+
+// pipelineMetrics are exposed for each component, they come from the queue and the output of the Beats.
+var pipelineMetrics = []statsMetric{
+	{key: "libbeat.pipeline.queue.filled.events", name: "queue_events", help: "Number of events in the queue.", typ: gaugeType},
+	{key: "libbeat.pipeline.queue.max_events", name: "queue_max_events", help: "Capacity of the queue, in events.", typ: gaugeType},
+	{key: "libbeat.output.events.acked", name: "output_events_total", help: "Number of events processed by the output, by status.", typ: counterType, labels: []promLabel{{"status", "acked"}}},
+	{key: "libbeat.output.events.failed", name: "output_events_total", help: "Number of events processed by the output, by status.", typ: counterType, labels: []promLabel{{"status", "failed"}}},
+	{key: "libbeat.output.events.dropped", name: "output_events_total", help: "Number of events processed by the output, by status.", typ: counterType, labels: []promLabel{{"status", "dropped"}}},
+	{key: "libbeat.output.write.bytes", name: "output_write_bytes_total", help: "Number of bytes written by the output.", typ: counterType},
+	{key: "libbeat.output.write.errors", name: "output_write_errors_total", help: "Number of errors writing to the output.", typ: counterType},
+}
+
+var agentStates = []agentclient.State{
+	agentclient.Starting,
+	agentclient.Configuring,
+	agentclient.Healthy,
+	agentclient.Degraded,
+	agentclient.Failed,
+	agentclient.Stopping,
+	agentclient.Stopped,
+	agentclient.Upgrading,
+	agentclient.Rollback,
+}
+
+var unitStates = []client.UnitState{
+	client.UnitStateStarting,
+	client.UnitStateConfiguring,
+	client.UnitStateHealthy,
+	client.UnitStateDegraded,
+	client.UnitStateFailed,
+	client.UnitStateStopping,
+	client.UnitStateStopped,
+}
+
+// componentMetrics holds the state and the stats of a component for a scrape.
+type componentMetrics struct {
+	labels []promLabel
+	state  runtime.ComponentState
+	// scraped is true when the stats of the component were fetched, up reports if it succeeded.
+	scraped bool
+	up      bool
+	stats   map[string]float64
+}
+
+// metricsHandler renders the metrics of the Elastic Agent and of its components in the
+// Prometheus text format.
+func metricsHandler(coord CoordinatorState, ns *monitoring.Namespace, fetch statsFetcher) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		state := coord.State()
+
+		agentStats := map[string]float64{}
+		snapshot := monitoring.CollectFlatSnapshot(ns.GetRegistry(), monitoring.Full, false)
+		for k, v := range snapshot.Ints {
+			agentStats[k] = float64(v)
+		}
+		for k, v := range snapshot.Floats {
+			agentStats[k] = v
+		}
+
+		comps := make([]*componentMetrics, 0, len(state.Components))
+		var wg sync.WaitGroup
+		for _, c := range state.Components {
+			cm := &componentMetrics{
+				labels: []promLabel{
+					{"component_id", c.Component.ID},
+					{"input_type", c.Component.InputType},
+					{"output", c.Component.OutputType},
+				},
+				state:   c.State,
+				scraped: exposesStats(c.Component),
+			}
+			comps = append(comps, cm)
+			if !cm.scraped {
+				continue
+			}
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				cm.stats, cm.up = fetchComponentStats(r.Context(), fetch, id)
+			}(c.Component.ID)
+		}
+		wg.Wait()
+		sort.Slice(comps, func(i, j int) bool {
+			return comps[i].labels[0].value < comps[j].labels[0].value
+		})
+
+		pw := &promWriter{}
+
+		pw.family(metricPrefix+"state", "Overall state of the Elastic Agent, 1 for the current state.", gaugeType)
+		for _, s := range agentStates {
+			pw.sample(metricPrefix+"state", []promLabel{{"state", strings.ToLower(s.String())}}, boolValue(state.State == s))
+		}
+		for _, m := range processMetrics {
+			if v, ok := agentStats[m.key]; ok {
+				pw.family(metricPrefix+m.name, m.help, m.typ)
+				pw.sample(metricPrefix+m.name, nil, m.value(v))
+			}
+		}
+
+		pw.componentFamily(comps, "state", "State of the component, 1 for the current state.", gaugeType, func(cm *componentMetrics, emit func([]promLabel, float64)) {
+			for _, s := range unitStates {
+				emit([]promLabel{{"state", strings.ToLower(s.String())}}, boolValue(cm.state.State == s))
+			}
+		})
+		pw.componentFamily(comps, "healthy", "1 when the component is healthy.", gaugeType, func(cm *componentMetrics, emit func([]promLabel, float64)) {
+			emit(nil, boolValue(cm.state.State == client.UnitStateHealthy))
+		})
+		pw.componentFamily(comps, "restarts_total", "Number of times the component process exited unexpectedly and was restarted.", counterType, func(cm *componentMetrics, emit func([]promLabel, float64)) {
+			emit(nil, float64(cm.state.Restarts))
+		})
+		pw.componentFamily(comps, "quarantined", "1 when the component crashed too many times and is no longer restarted.", gaugeType, func(cm *componentMetrics, emit func([]promLabel, float64)) {
+			emit(nil, boolValue(cm.state.Quarantine != nil))
+		})
+		pw.componentFamily(comps, "oom_kills_total", "Number of times the component process was killed for exceeding its memory limit.", counterType, func(cm *componentMetrics, emit func([]promLabel, float64)) {
+			if cm.state.Resources != nil {
+				emit(nil, float64(cm.state.Resources.OOMKills))
+			}
+		})
+		pw.componentFamily(comps, "cpu_throttled_periods_total", "Number of periods the component process was throttled for exceeding its CPU limit.", counterType, func(cm *componentMetrics, emit func([]promLabel, float64)) {
+			if cm.state.Resources != nil {
+				emit(nil, float64(cm.state.Resources.ThrottledPeriods))
+			}
+		})
+		pw.componentFamily(comps, "cpu_throttled_seconds_total", "Time the component process was throttled for exceeding its CPU limit, in seconds.", counterType, func(cm *componentMetrics, emit func([]promLabel, float64)) {
+			if cm.state.Resources != nil {
+				emit(nil, cm.state.Resources.ThrottledTime.Seconds())
+			}
+		})
+		pw.componentFamily(comps, "metrics_up", "1 when the stats of the component could be fetched.", gaugeType, func(cm *componentMetrics, emit func([]promLabel, float64)) {
+			if cm.scraped {
+				emit(nil, boolValue(cm.up))
+			}
+		})
+		for _, group := range [][]statsMetric{processMetrics, pipelineMetrics} {
+			for i, m := range group {
+				if i > 0 && group[i-1].name == m.name {
+					// labelled variants of the same metric are rendered with the first one
+					continue
+				}
+				variants := metricVariants(group, m.name)
+				pw.componentFamily(comps, m.name, m.help, m.typ, func(cm *componentMetrics, emit func([]promLabel, float64)) {
+					for _, variant := range variants {
+						if v, ok := cm.stats[variant.key]; ok {
+							emit(variant.labels, variant.value(v))
+						}
+					}
+				})
+			}
+		}
+
+		w.Header().Set("Content-Type", prometheusContentType)
+		_, err := w.Write(pw.buf.Bytes())
+		return err
+	}
+}
+
+// exposesStats returns true when the component runs in its own process with a monitoring
+// endpoint the stats can be fetched from.
+func exposesStats(comp component.Component) bool {
+	return comp.InputSpec != nil &&
+		comp.InputSpec.Spec.Command != nil &&
+		comp.RuntimeManager != component.OtelRuntimeManager
+}
+
+// fetchComponentStats fetches the stats of the component and flattens them, false is returned
+// when the stats are not available.
+func fetchComponentStats(ctx context.Context, fetch statsFetcher, componentID string) (map[string]float64, bool) {
+	ctx, cancel := context.WithTimeout(ctx, componentStatsTimeout)
+	defer cancel()
+
+	endpoint := componentmonitoring.PrefixedEndpoint(monitoringhelpers.BeatsMonitoringEndpoint(componentID))
+	raw, statusCode, err := fetch(ctx, endpoint, "stats")
+	if err != nil || statusCode != http.StatusOK {
+		return nil, false
+	}
+	var stats map[string]any
+	if err := json.Unmarshal(raw, &stats); err != nil {
+		return nil, false
+	}
+	flat := make(map[string]float64)
+	flattenStats("", stats, flat)
+	return flat, true
+}
+
+func flattenStats(prefix string, obj map[string]any, out map[string]float64) {
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch t := v.(type) {
+		case map[string]any:
+			flattenStats(key, t, out)
+		case float64:
+			out[key] = t
+		}
+	}
+}
+
+// metricVariants returns the metrics of the group sharing the name.
+func metricVariants(group []statsMetric, name string) []statsMetric {
+	var variants []statsMetric
+	for _, m := range group {
+		if m.name == name {
+			variants = append(variants, m)
+		}
+	}
+	return variants
+}
+
+func (m statsMetric) value(v float64) float64 {
+	if m.scale == 0 {
+		return v
+	}
+	return v * m.scale
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type promLabel struct {
+	name  string
+	value string
+}
+
+// promWriter writes metrics in the Prometheus text exposition format.
+type promWriter struct {
+	buf bytes.Buffer
+}
+
+func (p *promWriter) family(name, help, typ string) {
+	fmt.Fprintf(&p.buf, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(&p.buf, "# TYPE %s %s\n", name, typ)
+}
+
+func (p *promWriter) sample(name string, labels []promLabel, value float64) {
+	p.buf.WriteString(name)
+	if len(labels) > 0 {
+		p.buf.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				p.buf.WriteByte(',')
+			}
+			fmt.Fprintf(&p.buf, "%s=\"%s\"", l.name, escapeLabelValue(l.value))
+		}
+		p.buf.WriteByte('}')
+	}
+	p.buf.WriteByte(' ')
+	p.buf.WriteString(formatValue(value))
+	p.buf.WriteByte('\n')
+}
+
+// componentFamily writes a family with a sample for each value emitted for the components, the
+// family is skipped when no value is emitted.
+func (p *promWriter) componentFamily(comps []*componentMetrics, name, help, typ string, values func(*componentMetrics, func([]promLabel, float64))) {
+	name = componentMetricPrefix + name
+	written := false
+	for _, cm := range comps {
+		values(cm, func(labels []promLabel, v float64) {
+			if !written {
+				p.family(name, help, typ)
+				written = true
+			}
+			p.sample(name, append(append([]promLabel{}, cm.labels...), labels...), v)
+		})
+	}
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelReplacer.Replace(s)
+}
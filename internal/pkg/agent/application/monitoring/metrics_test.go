@@ -0,0 +1,136 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	componentmonitoring "github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring/component"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring/monitoringhelpers"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	monitoring.NewInt(reg, "beat.memstats.rss").Set(1024)
+	monitoring.NewInt(reg, "beat.cpu.total.time.ms").Set(1500)
+	ns := &monitoring.Namespace{}
+	ns.SetRegistry(reg)
+
+	command := &component.InputRuntimeSpec{Spec: component.InputSpec{Command: &component.CommandSpec{}}}
+	coord := mockCoordinator{
+		state: coordinator.State{
+			State: agentclient.Degraded,
+			Components: []runtime.ComponentComponentState{
+				{
+					Component: component.Component{ID: "system/metrics-default", InputType: "system/metrics", OutputType: "elasticsearch", InputSpec: command},
+					State: runtime.ComponentState{
+						State:    client.UnitStateHealthy,
+						Restarts: 3,
+						Resources: &runtime.ComponentResources{
+							OOMKills:         1,
+							ThrottledPeriods: 10,
+							ThrottledTime:    1500 * time.Millisecond,
+						},
+					},
+				},
+				{
+					Component: component.Component{ID: "filestream-default", InputType: "filestream", OutputType: "elasticsearch", InputSpec: command},
+					State:     runtime.ComponentState{State: client.UnitStateFailed, Quarantine: &runtime.ComponentQuarantine{}},
+				},
+				{
+					Component: component.Component{ID: "filestream-otel", InputType: "filestream", OutputType: "otlp", InputSpec: command, RuntimeManager: component.OtelRuntimeManager},
+					State:     runtime.ComponentState{State: client.UnitStateHealthy},
+				},
+			},
+		},
+	}
+
+	fetch := func(_ context.Context, endpoint, path string) ([]byte, int, error) {
+		assert.Equal(t, "stats", path)
+		switch endpoint {
+		case endpointFor("system/metrics-default"):
+			return []byte(`{
+				"beat": {"memstats": {"rss": 2048}, "info": {"uptime": {"ms": 60000}}},
+				"libbeat": {
+					"pipeline": {"queue": {"filled": {"events": 12}, "max_events": 3200}},
+					"output": {"events": {"acked": 100, "failed": 2}, "write": {"bytes": 4096}}
+				}
+			}`), http.StatusOK, nil
+		case endpointFor("filestream-default"):
+			return nil, 0, errors.New("connection refused")
+		}
+		t.Errorf("unexpected endpoint %s", endpoint)
+		return nil, 0, errors.New("unexpected endpoint")
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	require.NoError(t, metricsHandler(coord, ns, fetch)(rec, req))
+	assert.Equal(t, prometheusContentType, rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	lines := strings.Split(body, "\n")
+	for _, expected := range []string{
+		`elastic_agent_state{state="degraded"} 1`,
+		`elastic_agent_state{state="healthy"} 0`,
+		`elastic_agent_memory_rss_bytes 1024`,
+		`elastic_agent_cpu_seconds_total 1.5`,
+		`elastic_agent_component_state{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch",state="healthy"} 1`,
+		`elastic_agent_component_state{component_id="filestream-default",input_type="filestream",output="elasticsearch",state="failed"} 1`,
+		`elastic_agent_component_healthy{component_id="filestream-otel",input_type="filestream",output="otlp"} 1`,
+		`elastic_agent_component_restarts_total{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch"} 3`,
+		`elastic_agent_component_quarantined{component_id="filestream-default",input_type="filestream",output="elasticsearch"} 1`,
+		`elastic_agent_component_oom_kills_total{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch"} 1`,
+		`elastic_agent_component_cpu_throttled_seconds_total{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch"} 1.5`,
+		`elastic_agent_component_metrics_up{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch"} 1`,
+		`elastic_agent_component_metrics_up{component_id="filestream-default",input_type="filestream",output="elasticsearch"} 0`,
+		`elastic_agent_component_memory_rss_bytes{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch"} 2048`,
+		`elastic_agent_component_uptime_seconds{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch"} 60`,
+		`elastic_agent_component_queue_events{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch"} 12`,
+		`elastic_agent_component_queue_max_events{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch"} 3200`,
+		`elastic_agent_component_output_events_total{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch",status="acked"} 100`,
+		`elastic_agent_component_output_events_total{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch",status="failed"} 2`,
+		`elastic_agent_component_output_write_bytes_total{component_id="system/metrics-default",input_type="system/metrics",output="elasticsearch"} 4096`,
+	} {
+		assert.Contains(t, lines, expected)
+	}
+
+	// the components without stats are not reported
+	assert.NotContains(t, body, `elastic_agent_component_metrics_up{component_id="filestream-otel"`)
+	assert.NotContains(t, body, "elastic_agent_component_output_write_errors_total")
+
+	// each family is described once, before its samples
+	assert.Equal(t, 1, strings.Count(body, "# TYPE elastic_agent_component_output_events_total counter\n"))
+	assert.Less(t, strings.Index(body, "# TYPE elastic_agent_component_state gauge"), strings.Index(body, "elastic_agent_component_state{"))
+}
+
+func TestPromWriterEscaping(t *testing.T) {
+	pw := &promWriter{}
+	pw.family("test_metric", "help with \\ and\nnewline", gaugeType)
+	pw.sample("test_metric", []promLabel{{"id", "a\"b\\c\nd"}}, 0.25)
+	assert.Equal(t, "# HELP test_metric help with \\\\ and\\nnewline\n"+
+		"# TYPE test_metric gauge\n"+
+		"test_metric{id=\"a\\\"b\\\\c\\nd\"} 0.25\n", pw.buf.String())
+}
+
+func endpointFor(componentID string) string {
+	return componentmonitoring.PrefixedEndpoint(monitoringhelpers.BeatsMonitoringEndpoint(componentID))
+}
@@ -9,6 +9,9 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/limits"
 )
 
 type source struct {
@@ -16,11 +19,19 @@ type source struct {
 	Outputs []string `json:"outputs"`
 }
 
+type resources struct {
+	Limits           limits.ResourceLimits `json:"limits"`
+	OOMKills         uint64                `json:"oom_kills"`
+	ThrottledPeriods uint64                `json:"throttled_periods"`
+	ThrottledTimeMs  int64                 `json:"throttled_time_ms"`
+}
+
 type process struct {
-	ID     string `json:"id"`
-	PID    string `json:"pid,omitempty"`
-	Binary string `json:"binary"`
-	Source source `json:"source"`
+	ID        string     `json:"id"`
+	PID       string     `json:"pid,omitempty"`
+	Binary    string     `json:"binary"`
+	Source    source     `json:"source"`
+	Resources *resources `json:"resources,omitempty"`
 }
 
 func sourceFromComponentID(procID string) source {
@@ -38,6 +49,18 @@ func sourceFromComponentID(procID string) source {
 	return s
 }
 
+func resourcesFromState(r *runtime.ComponentResources) *resources {
+	if r == nil {
+		return nil
+	}
+	return &resources{
+		Limits:           r.Limits,
+		OOMKills:         r.OOMKills,
+		ThrottledPeriods: r.ThrottledPeriods,
+		ThrottledTimeMs:  r.ThrottledTime.Milliseconds(),
+	}
+}
+
 func processesHandler(coord CoordinatorState) func(http.ResponseWriter, *http.Request) error {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -50,10 +73,11 @@ func processesHandler(coord CoordinatorState) func(http.ResponseWriter, *http.Re
 			if c.Component.InputSpec != nil {
 				procs = append(procs, process{
 					// access the components array manually to avoid a memory aliasing error. This is fixed in go 1.22
-					ID:     expectedCloudProcessID(&state.Components[iter].Component),
-					PID:    c.LegacyPID,
-					Binary: c.Component.BinaryName(),
-					Source: sourceFromComponentID(c.Component.ID),
+					ID:        expectedCloudProcessID(&state.Components[iter].Component),
+					PID:       c.LegacyPID,
+					Binary:    c.Component.BinaryName(),
+					Source:    sourceFromComponentID(c.Component.ID),
+					Resources: resourcesFromState(c.State.Resources),
 				})
 			}
 		}
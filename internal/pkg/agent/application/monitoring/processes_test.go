@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/limits"
+)
+
+func TestProcessesHandlerResources(t *testing.T) {
+	coord := mockCoordinator{
+		state: coordinator.State{
+			Components: []runtime.ComponentComponentState{
+				{
+					Component: component.Component{
+						ID:        "system/metrics-default",
+						InputSpec: &component.InputRuntimeSpec{BinaryName: "metricbeat"},
+					},
+					State: runtime.ComponentState{
+						Resources: &runtime.ComponentResources{
+							Limits:           limits.ResourceLimits{Memory: 512 << 20, CPU: 0.5},
+							OOMKills:         2,
+							ThrottledPeriods: 10,
+							ThrottledTime:    1500 * time.Millisecond,
+						},
+					},
+				},
+				{
+					Component: component.Component{
+						ID:        "filestream-default",
+						InputSpec: &component.InputRuntimeSpec{BinaryName: "filebeat"},
+					},
+				},
+			},
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/processes", nil)
+	require.NoError(t, processesHandler(coord)(rec, req))
+
+	var res struct {
+		Processes []process `json:"processes"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res.Processes, 2)
+
+	assert.Equal(t, &resources{
+		Limits:           limits.ResourceLimits{Memory: 512 << 20, CPU: 0.5},
+		OOMKills:         2,
+		ThrottledPeriods: 10,
+		ThrottledTimeMs:  1500,
+	}, res.Processes[0].Resources)
+	assert.Nil(t, res.Processes[1].Resources)
+}
@@ -60,6 +60,8 @@ func NewServer(
 			r.Handle("/processes/{componentID}", createHandler(processHandler(coord, statsHandler)))
 			r.Handle("/processes/{componentID}/", createHandler(processHandler(coord, statsHandler)))
 			r.Handle("/processes/{componentID}/{metricsPath}", createHandler(processHandler(coord, statsHandler)))
+			// the component metrics are scraped like the /processes endpoints, /metrics is enabled with them
+			r.Handle("/metrics", createHandler(metricsHandler(coord, statNs, GetProcessMetrics)))
 		}
 
 		if isPprofEnabled(cfg) {
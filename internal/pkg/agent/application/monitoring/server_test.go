@@ -98,8 +98,10 @@ func TestHTTPReloadEnableBehavior(t *testing.T) {
 			serverReloader.Start()
 			if testCase.httpOnAtInit {
 				waitOnReturnCode(t, http.StatusOK, "processes", "", serverReloader)
+				waitOnReturnCode(t, http.StatusOK, "metrics", "", serverReloader)
 			} else {
 				waitOnReturnCode(t, http.StatusNotFound, "processes", "", serverReloader)
+				waitOnReturnCode(t, http.StatusNotFound, "metrics", "", serverReloader)
 			}
 
 			err = serverReloader.Reload(testCase.secondConfig)
@@ -107,8 +109,10 @@ func TestHTTPReloadEnableBehavior(t *testing.T) {
 
 			if testCase.httpOnAfterReload {
 				waitOnReturnCode(t, http.StatusOK, "processes", "", serverReloader)
+				waitOnReturnCode(t, http.StatusOK, "metrics", "", serverReloader)
 			} else {
 				waitOnReturnCode(t, http.StatusNotFound, "processes", "", serverReloader)
+				waitOnReturnCode(t, http.StatusNotFound, "metrics", "", serverReloader)
 			}
 
 		})
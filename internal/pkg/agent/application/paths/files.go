@@ -39,6 +39,26 @@ const defaultAgentStateStoreYmlFile = "state.yml"
 // store.
 const defaultAgentStateStoreFile = "state.enc"
 
+// defaultAgentPolicyHistoryFile is the file that will contain the encrypted
+// history of the last applied policies.
+const defaultAgentPolicyHistoryFile = "policy_history.enc"
+
+// defaultAgentAckStoreFile is the file that will contain the encrypted acks
+// not delivered to Fleet yet.
+const defaultAgentAckStoreFile = "ack_store.enc"
+
+// defaultAgentDiagnosticsUploadsDir is the directory that will contain the
+// diagnostics bundles not uploaded to Fleet yet and the state of their upload.
+const defaultAgentDiagnosticsUploadsDir = "diagnostics_uploads"
+
+// defaultAgentDiagnosticsCapturesDir is the directory that will contain the
+// diagnostics bundles captured automatically when the agent becomes unhealthy.
+const defaultAgentDiagnosticsCapturesDir = "diagnostics_captures"
+
+// defaultAgentRolloutStateFile is the file that will contain the version the
+// rollout last upgraded to.
+const defaultAgentRolloutStateFile = "rollout_state.yml"
+
 // AgentConfigYmlFile is a name of file used to store agent information
 func AgentConfigYmlFile() string {
 	return filepath.Join(Config(), defaultAgentFleetYmlFile)
@@ -81,3 +101,28 @@ func AgentStateStoreYmlFile() string {
 func AgentStateStoreFile() string {
 	return filepath.Join(Home(), defaultAgentStateStoreFile)
 }
+
+// AgentPolicyHistoryFile is the file that contains the encrypted history of the last applied policies.
+func AgentPolicyHistoryFile() string {
+	return filepath.Join(Config(), defaultAgentPolicyHistoryFile)
+}
+
+// AgentAckStoreFile is the file that contains the encrypted acks not delivered to Fleet yet, they are replayed after restart.
+func AgentAckStoreFile() string {
+	return filepath.Join(Home(), defaultAgentAckStoreFile)
+}
+
+// AgentDiagnosticsUploadsDir is the directory that contains the diagnostics bundles not uploaded to Fleet yet, their upload is resumed after restart or upgrade.
+func AgentDiagnosticsUploadsDir() string {
+	return filepath.Join(Data(), defaultAgentDiagnosticsUploadsDir)
+}
+
+// AgentDiagnosticsCapturesDir is the directory that contains the diagnostics bundles captured automatically when the agent or a component becomes degraded or failed.
+func AgentDiagnosticsCapturesDir() string {
+	return filepath.Join(Home(), defaultAgentDiagnosticsCapturesDir)
+}
+
+// AgentRolloutStateFile is the file that contains the version the rollout last upgraded to, it is kept across upgrades and rollbacks.
+func AgentRolloutStateFile() string {
+	return filepath.Join(Config(), defaultAgentRolloutStateFile)
+}
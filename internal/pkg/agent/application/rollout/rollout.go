@@ -0,0 +1,334 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package rollout upgrades a fleet of standalone Elastic Agents in waves. Every
+// Agent reads the same rollout descriptor, deterministically places itself in a
+// cohort from its agent ID and only upgrades once the wave of its cohort opens.
+package rollout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/pkg/upgrade/details"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+const (
+	// fetchTimeout bounds the time spent reading the descriptor.
+	fetchTimeout = 30 * time.Second
+
+	// maxDescriptorSize is the maximum size of a descriptor, it only holds a handful of waves.
+	maxDescriptorSize = 1 << 20
+
+	// cohortBuckets is the number of buckets the agents are spread in, a wave selects the
+	// buckets with a percentile below its percentage.
+	cohortBuckets = 10000
+
+	waitingReasonPattern = "waiting for rollout wave %d of %d"
+	skippedReasonPattern = "skipped by the rollout: agent percentile %.2f is not selected by any wave"
+)
+
+// Wave opens the upgrade to the agents with a percentile below Percentage once StartTime is
+// reached. The percentages are cumulative, each wave includes the agents of the previous ones.
+type Wave struct {
+	Percentage float64   `json:"percentage"`
+	StartTime  time.Time `json:"start_time"`
+}
+
+// Descriptor is the shared rollout descriptor.
+type Descriptor struct {
+	Version   string `json:"version"`
+	SourceURI string `json:"source_uri,omitempty"`
+	Waves     []Wave `json:"waves"`
+}
+
+// Validate validates the descriptor.
+func (d *Descriptor) Validate() error {
+	if d.Version == "" {
+		return errors.New("version is required")
+	}
+	if _, err := agtversion.ParseVersion(d.Version); err != nil {
+		return fmt.Errorf("invalid version %q: %w", d.Version, err)
+	}
+	if len(d.Waves) == 0 {
+		return errors.New("at least one wave is required")
+	}
+	for i, w := range d.Waves {
+		if w.Percentage <= 0 || w.Percentage > 100 {
+			return fmt.Errorf("wave %d: percentage must be greater than 0 and at most 100", i+1)
+		}
+		if w.StartTime.IsZero() {
+			return fmt.Errorf("wave %d: start_time is required", i+1)
+		}
+		if i == 0 {
+			continue
+		}
+		if w.Percentage <= d.Waves[i-1].Percentage {
+			return fmt.Errorf("wave %d: percentage must be greater than the percentage of the previous wave", i+1)
+		}
+		if w.StartTime.Before(d.Waves[i-1].StartTime) {
+			return fmt.Errorf("wave %d: start_time must not be before the start_time of the previous wave", i+1)
+		}
+	}
+	return nil
+}
+
+// Load reads the descriptor from source, a file path, a file:// URL or an http(s) URL fetched
+// with client.
+func Load(ctx context.Context, client *http.Client, source string) (*Descriptor, error) {
+	var r io.ReadCloser
+	switch {
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating the request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetching the descriptor: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("fetching the descriptor: unexpected status code %d", resp.StatusCode)
+		}
+		r = resp.Body
+	default:
+		f, err := os.Open(strings.TrimPrefix(source, "file://"))
+		if err != nil {
+			return nil, fmt.Errorf("opening the descriptor: %w", err)
+		}
+		r = f
+	}
+	defer r.Close()
+
+	var d Descriptor
+	if err := json.NewDecoder(io.LimitReader(r, maxDescriptorSize)).Decode(&d); err != nil {
+		return nil, fmt.Errorf("decoding the descriptor: %w", err)
+	}
+	if err := d.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid descriptor: %w", err)
+	}
+	return &d, nil
+}
+
+// Percentile places the agent in [0, 100). The version is part of the hash so the same agents
+// are not always the first ones upgraded.
+func Percentile(agentID string, version string) float64 {
+	sum := sha256.Sum256([]byte(agentID + "/" + version))
+	bucket := binary.BigEndian.Uint64(sum[:8]) % cohortBuckets
+	return float64(bucket) * 100 / cohortBuckets
+}
+
+// Wave returns the index of the first wave selecting the agent, false when no wave selects it.
+func (d *Descriptor) Wave(agentID string) (int, bool) {
+	p := Percentile(agentID, d.Version)
+	for i, w := range d.Waves {
+		if p < w.Percentage {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// upgradeCoordinator is the part of the Coordinator used by the Runner.
+type upgradeCoordinator interface {
+	State() coordinator.State
+	SetUpgradeDetails(*details.Details)
+	Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, opts ...coordinator.UpgradeOpt) error
+}
+
+// persistedState is persisted across the upgrades and the rollbacks of the Elastic Agent.
+type persistedState struct {
+	// AttemptedVersion is the version the Runner last upgraded to. While the Elastic Agent runs
+	// an older version the upgrade was rolled back, it is not retried. It is restored when the
+	// upgrade fails before restarting the Elastic Agent, so that upgrade is retried.
+	AttemptedVersion string `yaml:"attempted_version"`
+}
+
+// Runner periodically reads the descriptor and upgrades the Elastic Agent once its wave opens.
+type Runner struct {
+	log       *logger.Logger
+	cfg       *configuration.UpgradeRolloutConfig
+	client    *http.Client
+	statePath string
+	agentID   string
+	coord     upgradeCoordinator
+
+	// reported are the upgrade details set by the Runner, they are only cleared when still set.
+	reported *details.Details
+
+	now            func() time.Time
+	currentVersion func() string
+}
+
+// NewRunner creates a new Runner. The http(s) descriptors are fetched with the proxy and TLS
+// settings of transport, the version the Runner upgrades to is persisted in statePath.
+func NewRunner(log *logger.Logger, cfg *configuration.UpgradeRolloutConfig, transport httpcommon.HTTPTransportSettings, statePath string, agentID string, coord upgradeCoordinator) (*Runner, error) {
+	client, err := transport.Client(httpcommon.WithAPMHTTPInstrumentation())
+	if err != nil {
+		return nil, fmt.Errorf("creating the http client of the rollout: %w", err)
+	}
+	return &Runner{
+		log:            log,
+		cfg:            cfg,
+		client:         client,
+		statePath:      statePath,
+		agentID:        agentID,
+		coord:          coord,
+		now:            time.Now,
+		currentVersion: release.VersionWithSnapshot,
+	}, nil
+}
+
+// Run checks the descriptor until ctx is done.
+func (r *Runner) Run(ctx context.Context) {
+	r.log.Infof("Rollout enabled, reading the descriptor from %s every %s", r.cfg.Source, r.cfg.CheckInterval)
+	t := time.NewTimer(0)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		t.Reset(r.check(ctx))
+	}
+}
+
+// check reads the descriptor and acts on it, it returns the time until the next check.
+func (r *Runner) check(ctx context.Context) time.Duration {
+	next := r.cfg.CheckInterval
+	state := r.coord.State()
+	if state.State == agentclient.Upgrading {
+		r.log.Debug("Upgrade in progress, skipping the rollout check")
+		return next
+	}
+
+	d, err := Load(ctx, r.client, r.cfg.Source)
+	if err != nil {
+		r.log.Errorw("Failed to read the rollout descriptor", "source", r.cfg.Source, "error.message", err)
+		return next
+	}
+
+	target, _ := agtversion.ParseVersion(d.Version)
+	current, err := agtversion.ParseVersion(r.currentVersion())
+	if err != nil {
+		r.log.Errorw("Failed to parse the version of the Elastic Agent", "error.message", err)
+		return next
+	}
+	if !current.Less(*target) {
+		r.clear(state)
+		return next
+	}
+	prev := r.loadState()
+	if prev.AttemptedVersion == d.Version {
+		r.log.Warnf("Upgrade to version %s was rolled back, not retrying it from the rollout", d.Version)
+		return next
+	}
+
+	wave, ok := d.Wave(r.agentID)
+	if !ok {
+		r.report(state, d.Version, details.StateScheduled, nil, fmt.Sprintf(skippedReasonPattern, Percentile(r.agentID, d.Version)))
+		return next
+	}
+
+	now := r.now()
+	start := d.Waves[wave].StartTime
+	if now.Before(start) {
+		r.report(state, d.Version, details.StateScheduled, &start, fmt.Sprintf(waitingReasonPattern, wave+1, len(d.Waves)))
+		return min(next, start.Sub(now))
+	}
+
+	r.log.Infof("Rollout wave %d of %d is open, upgrading to version %s", wave+1, len(d.Waves), d.Version)
+	r.reported = nil
+	// persisted first, the Elastic Agent restarts on the new version or on the old one
+	// when the upgrade is rolled back
+	if err := r.saveState(persistedState{AttemptedVersion: d.Version}); err != nil {
+		r.log.Errorw("Failed to persist the rollout state, not upgrading", "error.message", err)
+		return next
+	}
+	if err := r.coord.Upgrade(ctx, d.Version, d.SourceURI, nil); err != nil {
+		r.log.Errorw("Rollout upgrade failed, retrying it on the next check", "version", d.Version, "error.message", err)
+		// the Elastic Agent did not restart, only a rollback prevents a retry
+		if err := r.saveState(prev); err != nil {
+			r.log.Errorw("Failed to restore the rollout state", "error.message", err)
+		}
+	}
+	return next
+}
+
+// report sets the upgrade details unless they already describe the same state.
+func (r *Runner) report(state coordinator.State, version string, s details.State, scheduledAt *time.Time, reason string) {
+	if ud := state.UpgradeDetails; ud != nil && ud == r.reported && ud.TargetVersion == version && ud.State == s &&
+		ud.Metadata.Reason == reason && equalTime(ud.Metadata.ScheduledAt, scheduledAt) {
+		return
+	}
+	r.log.Infof("Upgrade to version %s: %s", version, reason)
+	det := details.NewDetails(version, s, "")
+	det.Metadata.ScheduledAt = scheduledAt
+	det.Metadata.Reason = reason
+	r.reported = det
+	r.coord.SetUpgradeDetails(det)
+}
+
+// clear removes the upgrade details set by the Runner.
+func (r *Runner) clear(state coordinator.State) {
+	if r.reported != nil && state.UpgradeDetails == r.reported {
+		r.coord.SetUpgradeDetails(nil)
+	}
+	r.reported = nil
+}
+
+// loadState reads the persisted state, the zero state when it cannot be read.
+func (r *Runner) loadState() persistedState {
+	var s persistedState
+	data, err := os.ReadFile(r.statePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			r.log.Warnw("Failed to read the rollout state", "path", r.statePath, "error.message", err)
+		}
+		return s
+	}
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		r.log.Warnw("Failed to parse the rollout state", "path", r.statePath, "error.message", err)
+	}
+	return s
+}
+
+func (r *Runner) saveState(s persistedState) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.statePath, data, 0o600)
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
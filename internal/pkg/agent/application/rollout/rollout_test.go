@@ -0,0 +1,317 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	"github.com/elastic/elastic-agent/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/pkg/upgrade/details"
+)
+
+var rolloutStart = time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+
+type fakeCoordinator struct {
+	state      coordinator.State
+	upgrades   []string
+	upgradeErr error
+}
+
+func (f *fakeCoordinator) State() coordinator.State {
+	return f.state
+}
+
+func (f *fakeCoordinator) SetUpgradeDetails(d *details.Details) {
+	f.state.UpgradeDetails = d
+}
+
+func (f *fakeCoordinator) Upgrade(_ context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, _ ...coordinator.UpgradeOpt) error {
+	if action != nil {
+		return fmt.Errorf("unexpected action")
+	}
+	f.upgrades = append(f.upgrades, version+" "+sourceURI)
+	return f.upgradeErr
+}
+
+func TestLoad(t *testing.T) {
+	descriptor := `{"version":"9.3.0","source_uri":"https://mirror.example.com/downloads/","waves":[` +
+		`{"percentage":10,"start_time":"2026-10-01T08:00:00Z"},{"percentage":100,"start_time":"2026-10-02T08:00:00Z"}]}`
+	expected := &Descriptor{
+		Version:   "9.3.0",
+		SourceURI: "https://mirror.example.com/downloads/",
+		Waves: []Wave{
+			{Percentage: 10, StartTime: rolloutStart},
+			{Percentage: 100, StartTime: rolloutStart.Add(24 * time.Hour)},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "rollout.json")
+	require.NoError(t, os.WriteFile(path, []byte(descriptor), 0o600))
+	d, err := Load(t.Context(), http.DefaultClient, path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, d)
+
+	d, err = Load(t.Context(), http.DefaultClient, "file://"+path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, d)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rollout.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(descriptor))
+	}))
+	defer srv.Close()
+	d, err = Load(t.Context(), http.DefaultClient, srv.URL+"/rollout.json")
+	require.NoError(t, err)
+	assert.Equal(t, expected, d)
+
+	_, err = Load(t.Context(), http.DefaultClient, srv.URL+"/missing.json")
+	assert.ErrorContains(t, err, "unexpected status code 404")
+}
+
+func TestNewRunnerTransport(t *testing.T) {
+	descriptor := `{"version":"9.3.0","waves":[{"percentage":100,"start_time":"2026-10-01T08:00:00Z"}]}`
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(descriptor))
+	}))
+	defer srv.Close()
+	log, _ := loggertest.New(t.Name())
+	cfg := &configuration.UpgradeRolloutConfig{Enabled: true, Source: srv.URL, CheckInterval: time.Hour}
+	statePath := filepath.Join(t.TempDir(), "rollout_state.yml")
+
+	runner, err := NewRunner(log, cfg, httpcommon.DefaultHTTPTransportSettings(), statePath, "agent-1", &fakeCoordinator{})
+	require.NoError(t, err)
+	_, err = Load(t.Context(), runner.client, srv.URL)
+	assert.ErrorContains(t, err, "certificate", "the certificate of the server is not trusted")
+
+	transport := httpcommon.DefaultHTTPTransportSettings()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	transport.TLS = &tlscommon.Config{CAs: []string{string(ca)}}
+	runner, err = NewRunner(log, cfg, transport, statePath, "agent-1", &fakeCoordinator{})
+	require.NoError(t, err)
+	d, err := Load(t.Context(), runner.client, srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "9.3.0", d.Version)
+}
+
+func TestDescriptorValidate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		descriptor Descriptor
+		err        string
+	}{
+		"missing version": {
+			descriptor: Descriptor{Waves: []Wave{{Percentage: 100, StartTime: rolloutStart}}},
+			err:        "version is required",
+		},
+		"invalid version": {
+			descriptor: Descriptor{Version: "latest", Waves: []Wave{{Percentage: 100, StartTime: rolloutStart}}},
+			err:        `invalid version "latest"`,
+		},
+		"no wave": {
+			descriptor: Descriptor{Version: "9.3.0"},
+			err:        "at least one wave is required",
+		},
+		"percentage out of range": {
+			descriptor: Descriptor{Version: "9.3.0", Waves: []Wave{{Percentage: 101, StartTime: rolloutStart}}},
+			err:        "wave 1: percentage must be greater than 0 and at most 100",
+		},
+		"missing start time": {
+			descriptor: Descriptor{Version: "9.3.0", Waves: []Wave{{Percentage: 100}}},
+			err:        "wave 1: start_time is required",
+		},
+		"decreasing percentage": {
+			descriptor: Descriptor{Version: "9.3.0", Waves: []Wave{{Percentage: 50, StartTime: rolloutStart}, {Percentage: 50, StartTime: rolloutStart}}},
+			err:        "wave 2: percentage must be greater",
+		},
+		"decreasing start time": {
+			descriptor: Descriptor{Version: "9.3.0", Waves: []Wave{{Percentage: 50, StartTime: rolloutStart}, {Percentage: 100, StartTime: rolloutStart.Add(-time.Hour)}}},
+			err:        "wave 2: start_time must not be before",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorContains(t, tc.descriptor.Validate(), tc.err)
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, Percentile("agent-1", "9.3.0"), Percentile("agent-1", "9.3.0"), "the percentile is deterministic")
+
+	// the agents are spread evenly across the percentiles
+	const agents = 10000
+	below := 0
+	moved := 0
+	for i := range agents {
+		id := fmt.Sprintf("agent-%d", i)
+		p := Percentile(id, "9.3.0")
+		require.GreaterOrEqual(t, p, 0.0)
+		require.Less(t, p, 100.0)
+		if p < 10 {
+			below++
+		}
+		if (p < 10) != (Percentile(id, "9.4.0") < 10) {
+			moved++
+		}
+	}
+	assert.InDelta(t, agents/10, below, agents/100)
+	assert.Positive(t, moved, "the cohorts change with the version")
+}
+
+func TestRunnerCheck(t *testing.T) {
+	const agentID = "agent-1"
+	percentile := Percentile(agentID, "9.3.0")
+	log, _ := loggertest.New(t.Name())
+
+	// the first wave does not select the agent, the second one does
+	descriptor := Descriptor{
+		Version:   "9.3.0",
+		SourceURI: "https://mirror.example.com/downloads/",
+		Waves: []Wave{
+			{Percentage: percentile / 2, StartTime: rolloutStart},
+			{Percentage: percentile + 1, StartTime: rolloutStart.Add(24 * time.Hour)},
+		},
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rollout.json")
+	statePath := filepath.Join(dir, "rollout_state.yml")
+	writeDescriptor := func(d Descriptor) {
+		data, err := json.Marshal(d)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+	}
+	writeDescriptor(descriptor)
+
+	coord := &fakeCoordinator{state: coordinator.State{State: agentclient.Healthy}}
+	cfg := &configuration.UpgradeRolloutConfig{Enabled: true, Source: path, CheckInterval: time.Hour}
+	now := rolloutStart.Add(12 * time.Hour)
+	newRunner := func() *Runner {
+		runner, err := NewRunner(log, cfg, httpcommon.DefaultHTTPTransportSettings(), statePath, agentID, coord)
+		require.NoError(t, err)
+		runner.currentVersion = func() string { return "9.2.0" }
+		runner.now = func() time.Time { return now }
+		return runner
+	}
+	runner := newRunner()
+
+	// waiting for the second wave, the next check happens when it opens
+	assert.Equal(t, time.Hour, runner.check(t.Context()))
+	ud := coord.state.UpgradeDetails
+	require.NotNil(t, ud)
+	assert.Equal(t, details.StateScheduled, ud.State)
+	assert.Equal(t, "9.3.0", ud.TargetVersion)
+	assert.Equal(t, "waiting for rollout wave 2 of 2", ud.Metadata.Reason)
+	assert.Equal(t, rolloutStart.Add(24*time.Hour), *ud.Metadata.ScheduledAt)
+	now = rolloutStart.Add(23*time.Hour + 30*time.Minute)
+	assert.Equal(t, 30*time.Minute, runner.check(t.Context()))
+	assert.Same(t, ud, coord.state.UpgradeDetails, "unchanged details are not reported again")
+	assert.Empty(t, coord.upgrades)
+
+	// an upgrade in progress is left alone
+	now = rolloutStart.Add(25 * time.Hour)
+	coord.state.State = agentclient.Upgrading
+	runner.check(t.Context())
+	assert.Empty(t, coord.upgrades)
+	coord.state.State = agentclient.Healthy
+
+	// no wave selects the agent
+	writeDescriptor(Descriptor{Version: descriptor.Version, Waves: descriptor.Waves[:1]})
+	runner.check(t.Context())
+	ud = coord.state.UpgradeDetails
+	require.NotNil(t, ud)
+	assert.Equal(t, details.StateScheduled, ud.State)
+	assert.Nil(t, ud.Metadata.ScheduledAt)
+	assert.Contains(t, ud.Metadata.Reason, "skipped by the rollout")
+	assert.Empty(t, coord.upgrades)
+
+	// already running the target version, the details set by the runner are cleared
+	runner.currentVersion = func() string { return "9.3.0" }
+	runner.check(t.Context())
+	assert.Nil(t, coord.state.UpgradeDetails)
+	assert.Empty(t, coord.upgrades)
+
+	// the wave is open
+	runner.currentVersion = func() string { return "9.2.0" }
+	writeDescriptor(descriptor)
+	runner.check(t.Context())
+	assert.Equal(t, []string{"9.3.0 https://mirror.example.com/downloads/"}, coord.upgrades)
+
+	// the upgrade was rolled back, it is not retried after the restart even once the
+	// upgrade details are gone
+	coord.upgrades = nil
+	runner = newRunner()
+	runner.check(t.Context())
+	assert.Empty(t, coord.upgrades)
+
+	// a rollout to another version upgrades again
+	writeDescriptor(Descriptor{Version: "9.3.1", Waves: []Wave{{Percentage: 100, StartTime: rolloutStart}}})
+	runner.check(t.Context())
+	assert.Equal(t, []string{"9.3.1 "}, coord.upgrades)
+
+	// an unreadable descriptor changes nothing
+	coord.upgrades = nil
+	require.NoError(t, os.Remove(path))
+	assert.Equal(t, time.Hour, runner.check(t.Context()))
+	assert.Nil(t, coord.state.UpgradeDetails)
+	assert.Empty(t, coord.upgrades)
+}
+
+func TestRunnerCheckUpgradeFailed(t *testing.T) {
+	log, _ := loggertest.New(t.Name())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rollout.json")
+	statePath := filepath.Join(dir, "rollout_state.yml")
+	data, err := json.Marshal(Descriptor{Version: "9.3.0", Waves: []Wave{{Percentage: 100, StartTime: rolloutStart}}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	coord := &fakeCoordinator{
+		state:      coordinator.State{State: agentclient.Healthy},
+		upgradeErr: errors.New("download failed"),
+	}
+	cfg := &configuration.UpgradeRolloutConfig{Enabled: true, Source: path, CheckInterval: time.Hour}
+	newRunner := func() *Runner {
+		runner, err := NewRunner(log, cfg, httpcommon.DefaultHTTPTransportSettings(), statePath, "agent-1", coord)
+		require.NoError(t, err)
+		runner.currentVersion = func() string { return "9.2.0" }
+		runner.now = func() time.Time { return rolloutStart.Add(time.Hour) }
+		return runner
+	}
+	runner := newRunner()
+
+	// a failed upgrade is retried on the next check
+	runner.check(t.Context())
+	runner.check(t.Context())
+	assert.Equal(t, []string{"9.3.0 ", "9.3.0 "}, coord.upgrades)
+
+	// once the upgrade succeeded, the Elastic Agent still running the old version after
+	// the restart means it was rolled back, it is not retried
+	coord.upgradeErr = nil
+	runner.check(t.Context())
+	coord.upgrades = nil
+	newRunner().check(t.Context())
+	assert.Empty(t, coord.upgrades)
+}
@@ -0,0 +1,210 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package download
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/upgrade/details"
+)
+
+// BundleManifestName is the name of the manifest describing an offline upgrade bundle.
+const BundleManifestName = "manifest.json"
+
+// BundleManifest describes the content of an offline upgrade bundle. A bundle is a tarball,
+// optionally gzipped, containing the manifest, the agent package and its .sha512 and .asc files.
+type BundleManifest struct {
+	// Version is the version of the agent package.
+	Version string `json:"version"`
+	// Package is the file name of the agent package.
+	Package string `json:"package"`
+}
+
+// Validate validates the bundle manifest.
+func (m BundleManifest) Validate() error {
+	if m.Version == "" {
+		return errors.New("bundle manifest has no version")
+	}
+	if m.Package == "" {
+		return errors.New("bundle manifest has no package")
+	}
+	if path.Base(m.Package) != m.Package || m.Package == "." || m.Package == ".." {
+		return fmt.Errorf("bundle manifest package %q must be a file name", m.Package)
+	}
+	return nil
+}
+
+// files returns the files a bundle must contain.
+func (m BundleManifest) files() []string {
+	return []string{m.Package, AddHashExtension(m.Package), m.Package + ascSuffix}
+}
+
+// IsBundle returns true when the source is a local offline upgrade bundle, local sources
+// are otherwise directories.
+func IsBundle(source string) bool {
+	if !IsLocal(source) {
+		return false
+	}
+	info, err := os.Stat(strings.TrimPrefix(source, "file://"))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// ReadBundleManifest reads the manifest of the offline upgrade bundle at `bundlePath`.
+func ReadBundleManifest(bundlePath string) (BundleManifest, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return BundleManifest{}, fmt.Errorf("failed to open bundle %q: %w", bundlePath, err)
+	}
+	defer f.Close()
+
+	tr, err := newBundleReader(f)
+	if err != nil {
+		return BundleManifest{}, fmt.Errorf("failed to read bundle %q: %w", bundlePath, err)
+	}
+	for {
+		hdr, err := tr.Next()
+		if goerrors.Is(err, io.EOF) {
+			return BundleManifest{}, fmt.Errorf("bundle %q has no %s", bundlePath, BundleManifestName)
+		}
+		if err != nil {
+			return BundleManifest{}, fmt.Errorf("failed to read bundle %q: %w", bundlePath, err)
+		}
+		if hdr.Typeflag == tar.TypeReg && path.Clean(hdr.Name) == BundleManifestName {
+			return decodeBundleManifest(tr)
+		}
+	}
+}
+
+// ExtractBundle extracts the offline upgrade bundle at `bundlePath` into `dir` and returns its manifest.
+// Progress is reported to the upgrade details the same way as a download.
+func ExtractBundle(ctx context.Context, log *logger.Logger, timeout time.Duration, upgradeDetails *details.Details, bundlePath, dir string) (_ BundleManifest, err error) {
+	bundlePath = strings.TrimPrefix(bundlePath, "file://")
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return BundleManifest{}, errors.New(err, fmt.Sprintf("opening bundle %s failed", bundlePath), errors.TypeFilesystem, errors.M(errors.MetaKeyPath, bundlePath))
+	}
+	defer f.Close()
+
+	size := -1
+	if info, err := f.Stat(); err == nil {
+		size = int(info.Size())
+	}
+	observers := []progressObserver{
+		newLoggingProgressObserver(log, timeout),
+		newDetailsProgressObserver(upgradeDetails),
+	}
+	dp := newDownloadProgressReporter("file://"+filepath.ToSlash(bundlePath), timeout, size, observers...)
+	dp.Report(ctx)
+	defer func() {
+		if err != nil {
+			dp.ReportFailed(err)
+			return
+		}
+		dp.ReportComplete()
+	}()
+
+	tr, err := newBundleReader(io.TeeReader(f, dp))
+	if err != nil {
+		return BundleManifest{}, fmt.Errorf("failed to read bundle %q: %w", bundlePath, err)
+	}
+
+	extracted := make(map[string]bool)
+	for {
+		hdr, err := tr.Next()
+		if goerrors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return BundleManifest{}, fmt.Errorf("failed to read bundle %q: %w", bundlePath, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return BundleManifest{}, err
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || strings.Contains(name, "/") || name == "." || name == ".." {
+			return BundleManifest{}, fmt.Errorf("bundle %q contains unexpected entry %q", bundlePath, hdr.Name)
+		}
+		if err := extractBundleFile(tr, filepath.Join(dir, name)); err != nil {
+			return BundleManifest{}, err
+		}
+		extracted[name] = true
+	}
+
+	if !extracted[BundleManifestName] {
+		return BundleManifest{}, fmt.Errorf("bundle %q has no %s", bundlePath, BundleManifestName)
+	}
+	mf, err := os.Open(filepath.Join(dir, BundleManifestName))
+	if err != nil {
+		return BundleManifest{}, fmt.Errorf("failed to open bundle manifest: %w", err)
+	}
+	defer mf.Close()
+	manifest, err := decodeBundleManifest(mf)
+	if err != nil {
+		return BundleManifest{}, err
+	}
+	for _, name := range manifest.files() {
+		if !extracted[name] {
+			return BundleManifest{}, fmt.Errorf("bundle %q is missing %s", bundlePath, name)
+		}
+	}
+	return manifest, nil
+}
+
+func extractBundleFile(r io.Reader, dst string) error {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, packagePermissions)
+	if err != nil {
+		return errors.New(err, fmt.Sprintf("creating %s file failed", dst), errors.TypeFilesystem, errors.M(errors.MetaKeyPath, dst))
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", dst, err)
+	}
+	return nil
+}
+
+// newBundleReader returns a tar reader of the bundle, the bundle can be gzipped.
+func newBundleReader(r io.Reader) (*tar.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return tar.NewReader(gz), nil
+	}
+	return tar.NewReader(br), nil
+}
+
+func decodeBundleManifest(r io.Reader) (BundleManifest, error) {
+	var manifest BundleManifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return BundleManifest{}, fmt.Errorf("failed to decode bundle manifest: %w", err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return BundleManifest{}, err
+	}
+	return manifest, nil
+}
@@ -0,0 +1,119 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package download
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	"github.com/elastic/elastic-agent/pkg/upgrade/details"
+)
+
+const testBundlePackage = "elastic-agent-1.2.3-linux-x86_64.tar.gz"
+
+type bundleEntry struct {
+	name    string
+	content string
+}
+
+func writeBundle(t *testing.T, gzipped bool, entries ...bundleEntry) string {
+	t.Helper()
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar")
+	f, err := os.Create(bundlePath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var w io.Writer = f
+	if gzipped {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+	for _, e := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     e.name,
+			Mode:     0o644,
+			Size:     int64(len(e.content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	return bundlePath
+}
+
+func validBundleEntries() []bundleEntry {
+	return []bundleEntry{
+		{name: testBundlePackage, content: "package"},
+		{name: testBundlePackage + ".sha512", content: "hash"},
+		{name: testBundlePackage + ".asc", content: "signature"},
+		{name: BundleManifestName, content: `{"version":"1.2.3","package":"` + testBundlePackage + `"}`},
+	}
+}
+
+func TestIsBundle(t *testing.T) {
+	bundlePath := writeBundle(t, false, validBundleEntries()...)
+
+	assert.True(t, IsBundle(bundlePath))
+	assert.True(t, IsBundle("file://"+filepath.ToSlash(bundlePath)))
+	assert.False(t, IsBundle(filepath.Dir(bundlePath)), "directories are drop paths")
+	assert.False(t, IsBundle("https://artifacts.elastic.co/downloads/"))
+}
+
+func TestReadBundleManifest(t *testing.T) {
+	for _, gzipped := range []bool{false, true} {
+		manifest, err := ReadBundleManifest(writeBundle(t, gzipped, validBundleEntries()...))
+		require.NoError(t, err)
+		assert.Equal(t, BundleManifest{Version: "1.2.3", Package: testBundlePackage}, manifest)
+	}
+
+	_, err := ReadBundleManifest(writeBundle(t, false, validBundleEntries()[:3]...))
+	assert.ErrorContains(t, err, "has no manifest.json")
+
+	_, err = ReadBundleManifest(writeBundle(t, false, bundleEntry{name: BundleManifestName, content: `{"version":"1.2.3","package":"../package.tar.gz"}`}))
+	assert.ErrorContains(t, err, "must be a file name")
+}
+
+func TestExtractBundle(t *testing.T) {
+	log, _ := loggertest.New(t.Name())
+
+	t.Run("extracts the bundle", func(t *testing.T) {
+		dir := t.TempDir()
+		upgradeDetails := details.NewDetails("1.2.3", details.StateRequested, "")
+		manifest, err := ExtractBundle(t.Context(), log, time.Minute, upgradeDetails, writeBundle(t, true, validBundleEntries()...), dir)
+		require.NoError(t, err)
+		assert.Equal(t, testBundlePackage, manifest.Package)
+		assert.Equal(t, details.StateDownloading, upgradeDetails.State)
+		assert.Equal(t, 1.0, upgradeDetails.Metadata.DownloadPercent)
+
+		content, err := os.ReadFile(filepath.Join(dir, testBundlePackage+".asc"))
+		require.NoError(t, err)
+		assert.Equal(t, "signature", string(content))
+	})
+
+	t.Run("fails when a file is missing", func(t *testing.T) {
+		entries := validBundleEntries()
+		entries = append(entries[:2], entries[3])
+		_, err := ExtractBundle(t.Context(), log, time.Minute, details.NewDetails("1.2.3", details.StateRequested, ""), writeBundle(t, false, entries...), t.TempDir())
+		assert.ErrorContains(t, err, "is missing "+testBundlePackage+".asc")
+	})
+
+	t.Run("rejects nested entries", func(t *testing.T) {
+		entries := append(validBundleEntries(), bundleEntry{name: "../escape", content: "x"})
+		_, err := ExtractBundle(t.Context(), log, time.Minute, details.NewDetails("1.2.3", details.StateRequested, ""), writeBundle(t, false, entries...), t.TempDir())
+		assert.ErrorContains(t, err, `unexpected entry "../escape"`)
+	})
+}
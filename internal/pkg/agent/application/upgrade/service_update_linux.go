@@ -56,15 +56,28 @@ func ensureSystemdServiceConfigUpToDate(unitFilePath string) (bool, error) {
 		return false, fmt.Errorf("error opening systemd unit file [%s]: %w", unitFilePath, err)
 	}
 
-	// Check if KillMode= is already present
-	if cfg.Section("Service").HasKey("KillMode") {
+	updated := false
+	section := cfg.Section("Service")
+
+	// If KillMode= is not present, add it and set it to "process"
+	// See https://github.com/elastic/elastic-agent/pull/3220
+	if !section.HasKey("KillMode") {
+		section.Key("KillMode").SetValue("process")
+		updated = true
+	}
+
+	// If Delegate= is not present, delegate the memory and cpu controllers used to enforce the
+	// resource limits of the components. The delegation takes effect when the service restarts.
+	if !section.HasKey("Delegate") {
+		section.Key("Delegate").SetValue("memory cpu")
+		updated = true
+	}
+
+	if !updated {
 		// Nothing more to do
 		return false, nil
 	}
 
-	// If KillMode= is not present, add it and set it to "process"
-	// See https://github.com/elastic/elastic-agent/pull/3220
-	cfg.Section("Service").Key("KillMode").SetValue("process")
 	if err := cfg.SaveTo(unitFilePath); err != nil {
 		return false, fmt.Errorf("error writing updated systemd unit file [%s]: %w", unitFilePath, err)
 	}
@@ -75,13 +75,6 @@ func (a *artifactDownloader) downloadArtifact(ctx context.Context, target artifa
 		}
 	}
 
-	sources := make([]string, 0, 2)
-	if !download.IsLocal(sourceURI) {
-		// remote download should check drop path first
-		sources = append(sources, "file://"+settings.GetDropPath())
-	}
-	sources = append(sources, sourceURI)
-
 	fileName := target.FileName()
 	if target.Version.IsSnapshot() {
 		// Published snapshot artifacts never include the buildID in the file
@@ -96,6 +89,27 @@ func (a *artifactDownloader) downloadArtifact(ctx context.Context, target artifa
 		return "", fmt.Errorf("failed to create target directory %s: %w", settings.TargetDirectory, err)
 	}
 
+	if download.IsBundle(sourceURI) {
+		// an offline bundle is extracted and used as the only local source
+		bundleDir, err := a.extractBundle(ctx, &settings, target, sourceURI, fileName, upgradeDetails)
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			if err := os.RemoveAll(bundleDir); err != nil {
+				a.log.Warnf("failed to cleanup extracted bundle %s: %v", bundleDir, err)
+			}
+		}()
+		sourceURI = "file://" + filepath.ToSlash(bundleDir)
+	}
+
+	sources := make([]string, 0, 2)
+	if !download.IsLocal(sourceURI) {
+		// remote download should check drop path first
+		sources = append(sources, "file://"+settings.GetDropPath())
+	}
+	sources = append(sources, sourceURI)
+
 	var errs []error
 	for _, src := range sources {
 		resolvedSource, err := Resolve(ctx, &settings, target, src, defaultRemoteSourceSubdir, fileName, upgradeDetails)
@@ -152,6 +166,33 @@ func (a *artifactDownloader) downloadArtifact(ctx context.Context, target artifa
 	return targetPath, fmt.Errorf("failed to obtain agent artifact: %w", goerrors.Join(errs...))
 }
 
+// extractBundle extracts the offline upgrade bundle into a temporary directory of the target directory
+// and checks it contains the package of the target version.
+func (a *artifactDownloader) extractBundle(ctx context.Context, settings *artifact.Config, target artifact.Artifact, bundlePath, fileName string, upgradeDetails *details.Details) (string, error) {
+	dir, err := os.MkdirTemp(settings.TargetDirectory, "bundle-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	a.log.Infow("Extracting offline upgrade bundle", "version", target.Version, "bundle", bundlePath, "target_path", dir)
+	manifest, err := download.ExtractBundle(ctx, a.log, settings.Timeout, upgradeDetails, bundlePath, dir)
+	if err == nil {
+		switch {
+		case manifest.Version != target.Version.String() && manifest.Version != target.Version.VersionWithPrerelease():
+			err = fmt.Errorf("bundle contains version %s, expected %s", manifest.Version, target.Version)
+		case manifest.Package != fileName:
+			err = fmt.Errorf("bundle contains package %s, expected %s", manifest.Package, fileName)
+		}
+	}
+	if err != nil {
+		if removeErr := os.RemoveAll(dir); removeErr != nil {
+			a.log.Warnf("failed to cleanup extracted bundle %s: %v", dir, removeErr)
+		}
+		return "", fmt.Errorf("invalid upgrade bundle %s: %w", bundlePath, err)
+	}
+	return dir, nil
+}
+
 // Resolve computes the fully resolved download URI for an artifact.
 func Resolve(ctx context.Context, config *artifact.Config, target artifact.Artifact, sourceURI, sourceSubdir, fileName string, upgradeDetails *details.Details) (string, error) {
 	if target.Version.IsSnapshot() && sourceURI == artifact.DefaultSourceURI {
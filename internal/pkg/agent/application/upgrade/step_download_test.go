@@ -5,7 +5,9 @@
 package upgrade
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"crypto/sha512"
 	"fmt"
 	"io"
@@ -153,6 +155,47 @@ func TestDownloadArtifact(t *testing.T) {
 				require.NoFileExists(t, download.AddHashExtension(artifactPath))
 			},
 		},
+		{
+			name: "bundle sourceURI extracts and verifies the bundle",
+			run: func(t *testing.T, fx *fixture) {
+				bundlePath := writeUpgradeBundle(t, fx.target.Version.String(), fx.target.FileName(), archiveContent, hashFile, signature)
+				fx.settings.DropPath = t.TempDir()
+
+				artifactPath, err := fx.downloader.downloadArtifact(t.Context(), fx.target, bundlePath,
+					fx.upgradeDetails, false, true, pgpSource)
+				require.NoError(t, err)
+				require.Equal(t, filepath.Join(paths.Downloads(), fx.target.FileName()), artifactPath)
+				require.FileExists(t, artifactPath)
+				require.FileExists(t, download.AddHashExtension(artifactPath))
+				require.Equal(t, details.StateDownloading, fx.upgradeDetails.State)
+
+				entries, err := os.ReadDir(paths.Downloads())
+				require.NoError(t, err)
+				for _, e := range entries {
+					require.False(t, e.IsDir(), "extracted bundle %s is cleaned up", e.Name())
+				}
+			},
+		},
+		{
+			name: "bundle sourceURI fails when the bundle contains another version",
+			run: func(t *testing.T, fx *fixture) {
+				bundlePath := writeUpgradeBundle(t, "1.2.4", fx.target.FileName(), archiveContent, hashFile, signature)
+
+				_, err := fx.downloader.downloadArtifact(t.Context(), fx.target, "file://"+filepath.ToSlash(bundlePath),
+					fx.upgradeDetails, false, true, pgpSource)
+				require.ErrorContains(t, err, "bundle contains version 1.2.4, expected 1.2.3")
+			},
+		},
+		{
+			name: "bundle sourceURI fails when bundle verification fails",
+			run: func(t *testing.T, fx *fixture) {
+				bundlePath := writeUpgradeBundle(t, fx.target.Version.String(), fx.target.FileName(), archiveContent, hashFile, []byte("not a valid signature"))
+
+				_, err := fx.downloader.downloadArtifact(t.Context(), fx.target, bundlePath,
+					fx.upgradeDetails, false, true, pgpSource)
+				require.ErrorContains(t, err, "verification failed")
+			},
+		},
 		{
 			name: "remote sourceURI uses remote source when drop path is unset",
 			run: func(t *testing.T, fx *fixture) {
@@ -447,6 +490,36 @@ func TestDownloadArtifact(t *testing.T) {
 	}
 }
 
+// writeUpgradeBundle writes an offline upgrade bundle containing the package, its hash and signature.
+func writeUpgradeBundle(t *testing.T, version, packageName string, content, hash, signature []byte) string {
+	t.Helper()
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	f, err := os.Create(bundlePath)
+	require.NoError(t, err)
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	manifest := fmt.Sprintf(`{"version":%q,"package":%q}`, version, packageName)
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{download.BundleManifestName, []byte(manifest)},
+		{packageName, content},
+		{packageName + ".sha512", hash},
+		{packageName + ".asc", signature},
+	}
+	for _, file := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0o644, Size: int64(len(file.content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(file.content)
+		require.NoError(t, err)
+	}
+	return bundlePath
+}
+
 // mockUpgradeDetails returns a *details.Details value that has an observer registered on it for inspecting
 // certain properties of the object being set and unset.  It also returns:
 // - a *time.Time value, which will be not nil if Metadata.RetryUntil is set on the mock value,
@@ -690,8 +690,8 @@ func rollbackInstall(ctx context.Context, log *logger.Logger, topDirPath, versio
 
 func copyActionStoreProvider(readFile readFileFunc, writeFile writeFileFunc) copyActionStoreFunc {
 	return func(log *logger.Logger, newHome string) error {
-		// copies legacy action_store.yml, state.yml and state.enc encrypted file if exists
-		storePaths := []string{paths.AgentActionStoreFile(), paths.AgentStateStoreYmlFile(), paths.AgentStateStoreFile()}
+		// copies legacy action_store.yml, state.yml, state.enc and ack_store.enc encrypted files if exists
+		storePaths := []string{paths.AgentActionStoreFile(), paths.AgentStateStoreYmlFile(), paths.AgentStateStoreFile(), paths.AgentAckStoreFile()}
 		log.Infow("Copying action store", "new_home_path", newHome)
 
 		for _, currentActionStorePath := range storePaths {
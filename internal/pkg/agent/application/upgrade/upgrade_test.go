@@ -1784,6 +1784,7 @@ func TestCopyActionStore(t *testing.T) {
 	actionStoreContent := "initial agent action_store.yml content"
 	actionStateStoreYamlContent := "initial agent state.yml content"
 	actionStateStoreFileContent := "initial agent state.enc content"
+	ackStoreFileContent := "initial agent ack_store.enc content"
 
 	type testFile struct {
 		name    string
@@ -1803,6 +1804,8 @@ func TestCopyActionStore(t *testing.T) {
 			files: []testFile{
 				{name: "action_store", content: actionStoreContent},
 				{name: "state_yaml", content: actionStateStoreYamlContent},
+				{name: "state_enc", content: actionStateStoreFileContent},
+				{name: "ack_store", content: ackStoreFileContent},
 			},
 			copyActionStore: copyActionStoreProvider(os.ReadFile, os.WriteFile),
 			expectedError:   nil,
@@ -1848,6 +1851,7 @@ func TestCopyActionStore(t *testing.T) {
 			actionStorePath := paths.AgentActionStoreFile()
 			actionStateStoreYamlPath := paths.AgentStateStoreYmlFile()
 			actionStateStoreFilePath := paths.AgentStateStoreFile()
+			ackStoreFilePath := paths.AgentAckStoreFile()
 
 			newActionStorePaths := []string{}
 
@@ -1861,6 +1865,8 @@ func TestCopyActionStore(t *testing.T) {
 					path = actionStateStoreYamlPath
 				case "state_enc":
 					path = actionStateStoreFilePath
+				case "ack_store":
+					path = ackStoreFilePath
 				}
 
 				// Create the action store directories and files
@@ -0,0 +1,245 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	defaultProbeTimeout = 10 * time.Second
+
+	// defaultProbeFailureThreshold tolerates the checks failing while the upgraded Agent starts.
+	defaultProbeFailureThreshold = 3
+
+	// probeOutputLimit is the number of bytes of the output of a command probe kept in the error.
+	probeOutputLimit = 512
+)
+
+// ErrProbePending is returned by a probe check that cannot conclude yet, it does not count as a failure.
+var ErrProbePending = errors.New("probe pending")
+
+// ProbeError is sent to the watcher when a health probe fails.
+type ProbeError struct {
+	Name string
+	Err  error
+}
+
+func (e *ProbeError) Error() string {
+	return fmt.Sprintf("health probe %s failed: %s", e.Name, e.Err)
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// ProbeFetcher fetches path from a monitoring endpoint of the Elastic Agent or of a component and
+// returns the body and the status code of the response.
+type ProbeFetcher func(ctx context.Context, endpoint, path string) ([]byte, int, error)
+
+// ProbeEndpoints resolves the monitoring endpoints queried by the probes.
+type ProbeEndpoints struct {
+	// Agent is the endpoint of the monitoring server of the Elastic Agent, empty when its HTTP
+	// endpoints are disabled.
+	Agent string
+	// Component returns the endpoint of the monitoring server of a component.
+	Component func(componentID string) string
+}
+
+// Probe is a health check run periodically during the watch period.
+type Probe struct {
+	Name             string
+	Interval         time.Duration
+	Timeout          time.Duration
+	FailureThreshold int
+	Check            func(ctx context.Context) error
+}
+
+// NewProbes creates the probes from their configuration, the probes without an interval are
+// run every defaultInterval.
+func NewProbes(cfgs []configuration.UpgradeWatcherProbeConfig, defaultInterval time.Duration, endpoints ProbeEndpoints, fetch ProbeFetcher) ([]Probe, error) {
+	probes := make([]Probe, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		p := Probe{
+			Name:             cfg.Name,
+			Interval:         cfg.Interval,
+			Timeout:          cfg.Timeout,
+			FailureThreshold: cfg.FailureThreshold,
+		}
+		if p.Interval <= 0 {
+			p.Interval = defaultInterval
+		}
+		if p.Timeout <= 0 {
+			p.Timeout = defaultProbeTimeout
+		}
+		if p.FailureThreshold <= 0 {
+			p.FailureThreshold = defaultProbeFailureThreshold
+		}
+
+		switch cfg.Type {
+		case configuration.ProbeTypeEventsPublished:
+			p.Check = eventsPublishedCheck(endpoints.Component(cfg.Component), fetch, time.Now().Add(cfg.Within))
+		case configuration.ProbeTypeHTTP:
+			endpoint, path := cfg.URL, ""
+			if endpoint == "" {
+				if endpoints.Agent == "" {
+					return nil, fmt.Errorf("probe %q requires agent.monitoring.http.enabled to check the component %s", cfg.Name, cfg.Component)
+				}
+				endpoint, path = endpoints.Agent, "processes/"+cfg.Component
+			}
+			status := cfg.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			p.Check = httpCheck(endpoint, path, status, fetch)
+		case configuration.ProbeTypeCommand:
+			p.Check = commandCheck(cfg.Command, cfg.Args)
+		default:
+			return nil, fmt.Errorf("probe %q has an unknown type %q", cfg.Name, cfg.Type)
+		}
+		probes = append(probes, p)
+	}
+	return probes, nil
+}
+
+// RunProbes runs the probes until ctx is done, the first probe reaching its failure threshold is
+// sent to notifyChan as a *ProbeError.
+func RunProbes(ctx context.Context, log *logger.Logger, probes []Probe, notifyChan chan<- error) {
+	for _, probe := range probes {
+		go runProbe(ctx, log, probe, notifyChan)
+	}
+}
+
+func runProbe(ctx context.Context, log *logger.Logger, probe Probe, notifyChan chan<- error) {
+	ticker := time.NewTicker(probe.Interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, probe.Timeout)
+		err := probe.Check(checkCtx)
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err == nil:
+			if failures > 0 {
+				log.Infof("Health probe %s recovered", probe.Name)
+			}
+			failures = 0
+			continue
+		case errors.Is(err, ErrProbePending):
+			log.Debugf("Health probe %s pending: %s", probe.Name, err)
+			continue
+		}
+
+		failures++
+		log.Errorf("Health probe %s failed (%d/%d): %s", probe.Name, failures, probe.FailureThreshold, err)
+		if failures < probe.FailureThreshold {
+			continue
+		}
+		select {
+		case notifyChan <- &ProbeError{Name: probe.Name, Err: err}:
+		case <-ctx.Done():
+		}
+		return
+	}
+}
+
+// eventsPublishedCheck checks the component published events before the deadline, it is pending
+// until then and passes for the rest of the watch once the events are seen.
+func eventsPublishedCheck(endpoint string, fetch ProbeFetcher, deadline time.Time) func(ctx context.Context) error {
+	published := false
+	return func(ctx context.Context) error {
+		if published {
+			return nil
+		}
+		body, status, err := fetch(ctx, endpoint, "stats")
+		if err == nil && status != http.StatusOK {
+			err = fmt.Errorf("unexpected status code %d", status)
+		}
+		var events float64
+		if err == nil {
+			var stats struct {
+				Libbeat struct {
+					Pipeline struct {
+						Events struct {
+							Published float64 `json:"published"`
+						} `json:"events"`
+					} `json:"pipeline"`
+				} `json:"libbeat"`
+			}
+			if err = json.Unmarshal(body, &stats); err == nil {
+				events = stats.Libbeat.Pipeline.Events.Published
+			}
+		}
+		if events > 0 {
+			published = true
+			return nil
+		}
+		if time.Now().Before(deadline) {
+			if err != nil {
+				return fmt.Errorf("%w: fetching the component stats: %w", ErrProbePending, err)
+			}
+			return ErrProbePending
+		}
+		if err != nil {
+			return fmt.Errorf("fetching the component stats: %w", err)
+		}
+		return errors.New("no event published")
+	}
+}
+
+// httpCheck checks the endpoint answers with the expected status code.
+func httpCheck(endpoint, path string, expectedStatus int, fetch ProbeFetcher) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, status, err := fetch(ctx, endpoint, path)
+		if err != nil {
+			return err
+		}
+		if status != expectedStatus {
+			return fmt.Errorf("unexpected status code %d, expected %d", status, expectedStatus)
+		}
+		return nil
+	}
+}
+
+// commandCheck runs the command, an exit code other than 0 is a failure.
+func commandCheck(command string, args []string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var output bytes.Buffer
+		cmd := exec.CommandContext(ctx, command, args...)
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		if err := cmd.Run(); err != nil {
+			out := strings.TrimSpace(output.String())
+			if len(out) > probeOutputLimit {
+				out = out[:probeOutputLimit] + "..."
+			}
+			if out != "" {
+				return fmt.Errorf("%w: %s", err, out)
+			}
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+var testProbeEndpoints = ProbeEndpoints{
+	Agent:     "http://localhost:6791",
+	Component: func(componentID string) string { return "http+unix:///tmp/" + componentID + ".sock" },
+}
+
+func TestNewProbes(t *testing.T) {
+	var fetched []string
+	fetch := func(_ context.Context, endpoint, path string) ([]byte, int, error) {
+		fetched = append(fetched, endpoint+" "+path)
+		return []byte(`{"state":"HEALTHY"}`), http.StatusOK, nil
+	}
+
+	probes, err := NewProbes([]configuration.UpgradeWatcherProbeConfig{
+		{Name: "process", Type: configuration.ProbeTypeHTTP, Component: "filestream-default"},
+		{Name: "custom", Type: configuration.ProbeTypeHTTP, URL: "http://localhost:8080/health", Status: http.StatusNoContent, Interval: time.Minute, Timeout: time.Second, FailureThreshold: 1},
+	}, 30*time.Second, testProbeEndpoints, fetch)
+	require.NoError(t, err)
+	require.Len(t, probes, 2)
+
+	assert.Equal(t, 30*time.Second, probes[0].Interval)
+	assert.Equal(t, defaultProbeTimeout, probes[0].Timeout)
+	assert.Equal(t, defaultProbeFailureThreshold, probes[0].FailureThreshold)
+	assert.NoError(t, probes[0].Check(t.Context()))
+
+	assert.Equal(t, time.Minute, probes[1].Interval)
+	assert.Equal(t, 1, probes[1].FailureThreshold)
+	assert.ErrorContains(t, probes[1].Check(t.Context()), "unexpected status code 200, expected 204")
+
+	assert.Equal(t, []string{"http://localhost:6791 processes/filestream-default", "http://localhost:8080/health "}, fetched)
+
+	_, err = NewProbes([]configuration.UpgradeWatcherProbeConfig{
+		{Name: "process", Type: configuration.ProbeTypeHTTP, Component: "filestream-default"},
+	}, 30*time.Second, ProbeEndpoints{Component: testProbeEndpoints.Component}, fetch)
+	assert.ErrorContains(t, err, "requires agent.monitoring.http.enabled")
+}
+
+func TestEventsPublishedCheck(t *testing.T) {
+	published := 0
+	fetch := func(_ context.Context, endpoint, path string) ([]byte, int, error) {
+		assert.Equal(t, "http+unix:///tmp/filestream-default.sock", endpoint)
+		assert.Equal(t, "stats", path)
+		if published < 0 {
+			return nil, 0, errors.New("connection refused")
+		}
+		return []byte(`{"libbeat":{"pipeline":{"events":{"published":` + strconv.Itoa(published) + `}}}}`), http.StatusOK, nil
+	}
+	endpoint := testProbeEndpoints.Component("filestream-default")
+
+	check := eventsPublishedCheck(endpoint, fetch, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, check(t.Context()), ErrProbePending)
+	published = -1
+	assert.ErrorIs(t, check(t.Context()), ErrProbePending, "fetch errors are pending until the deadline")
+	published = 5
+	assert.NoError(t, check(t.Context()))
+	published = 0
+	assert.NoError(t, check(t.Context()), "passes once the events were seen")
+
+	check = eventsPublishedCheck(endpoint, fetch, time.Now().Add(-time.Second))
+	assert.EqualError(t, check(t.Context()), "no event published")
+	published = -1
+	assert.ErrorContains(t, check(t.Context()), "connection refused")
+}
+
+func TestCommandCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relies on sh")
+	}
+	assert.NoError(t, commandCheck("sh", []string{"-c", "exit 0"})(t.Context()))
+	err := commandCheck("sh", []string{"-c", "echo no data; exit 2"})(t.Context())
+	assert.ErrorContains(t, err, "exit status 2: no data")
+}
+
+func TestRunProbes(t *testing.T) {
+	log, _ := loggertest.New(t.Name())
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	checks := 0
+	errCh := make(chan error)
+	RunProbes(ctx, log, []Probe{
+		{
+			Name:             "flaky",
+			Interval:         time.Millisecond,
+			Timeout:          time.Second,
+			FailureThreshold: 2,
+			Check: func(context.Context) error {
+				checks++
+				switch checks {
+				case 2:
+					// not counted as a failure
+					return ErrProbePending
+				case 3:
+					// resets the consecutive failures
+					return nil
+				}
+				return errors.New("check failed")
+			},
+		},
+		{
+			Name:             "healthy",
+			Interval:         time.Millisecond,
+			Timeout:          time.Second,
+			FailureThreshold: 1,
+			Check:            func(context.Context) error { return nil },
+		},
+	}, errCh)
+
+	select {
+	case err := <-errCh:
+		var probeErr *ProbeError
+		require.ErrorAs(t, err, &probeErr)
+		assert.Equal(t, "flaky", probeErr.Name)
+		assert.EqualError(t, err, "health probe flaky failed: check failed")
+		assert.Equal(t, 5, checks)
+	case <-ctx.Done():
+		t.Fatal("probe did not fail")
+	}
+}
@@ -82,6 +82,7 @@ func NewCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.AddCommand(newUpgradeCommandWithArgs(args, streams))
 	cmd.AddCommand(newEnrollCommandWithArgs(args, streams))
 	cmd.AddCommand(newInspectCommandWithArgs(args, streams))
+	cmd.AddCommand(newPolicyCommandWithArgs(args, streams))
 	cmd.AddCommand(newPrivilegedCommandWithArgs(args, streams))
 	cmd.AddCommand(newUnprivilegedCommandWithArgs(args, streams))
 	cmd.AddCommand(newWatchCommandWithArgs(args, streams))
@@ -92,6 +93,7 @@ func NewCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.AddCommand(newLogsCommandWithArgs(args, streams))
 	cmd.AddCommand(newOtelCommandWithArgs(args, streams))
 	cmd.AddCommand(newApplyFlavorCommandWithArgs(args, streams))
+	cmd.AddCommand(newVaultCommandWithArgs(args, streams))
 
 	// windows special hidden sub-commands (only added on Windows)
 	reexec := newReExecWindowsCommand(args, streams)
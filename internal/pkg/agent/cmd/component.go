@@ -18,6 +18,7 @@ func newComponentCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.C
 	}
 
 	cmd.AddCommand(newComponentSpecCommandWithArgs(args, streams))
+	cmd.AddCommand(newComponentResumeCommandWithArgs(args, streams))
 
 	return cmd
 }
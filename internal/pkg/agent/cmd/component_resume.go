@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func newComponentResumeCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resume <id>",
+		Short: "Resumes a quarantined component",
+		Long: `Releases a component from quarantine and starts it again.
+
+A component is quarantined when it crashes too many times, as configured by agent.process.quarantine. A quarantined
+component is no longer restarted, its last crashes are reported by the status command.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			return componentResumeCmd(streams, args[0])
+		},
+	}
+
+	return cmd
+}
+
+func componentResumeCmd(streams *cli.IOStreams, componentID string) error {
+	ctx := handleSignal(context.Background())
+	daemon := client.New()
+	err := daemon.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	defer daemon.Disconnect()
+
+	if err := daemon.ComponentResume(ctx, componentID); err != nil {
+		return err
+	}
+	fmt.Fprintf(streams.Out, "Component %s resumed.\n", componentID)
+	return nil
+}
@@ -9,14 +9,20 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 	"time"
 
+	"github.com/docker/go-units"
+
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/diagcapture"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 )
@@ -38,10 +44,49 @@ func newDiagnosticsCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.Flags().BoolP("cpu-profile", "p", false, "wait to collect a CPU profile")
 	cmd.Flags().BoolP("skip-conn", "", false, "Skip connection request diagnostics")
 	cmd.Flags().Bool("exclude-events", false, "do not collect events log file")
+	cmd.Flags().String("profile", diagnostics.ProfileDefault, fmt.Sprintf("diagnostics profile selecting the content of the archive, one of: %s", strings.Join(diagnostics.ProfileNames(), ", ")))
+	cmd.Flags().String("max-size", "", "size budget of the archive (e.g. 50MB), the oldest logs are skipped or truncated to fit in it")
+
+	cmd.AddCommand(newDiagnosticsListCommand(streams))
 
 	return cmd
 }
 
+func newDiagnosticsListCommand(streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the diagnostics captured automatically",
+		Long: `List the diagnostics bundles captured automatically when the Elastic Agent or one of its components became
+degraded or failed, the newest first.
+
+The capture is enabled with the agent.diagnostics.capture.enabled setting.`,
+		Args: cobra.ExactArgs(0),
+		Run: func(c *cobra.Command, args []string) {
+			if err := diagnosticsListCmd(streams); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func diagnosticsListCmd(streams *cli.IOStreams) error {
+	captures, err := diagcapture.List(paths.AgentDiagnosticsCapturesDir())
+	if err != nil {
+		return err
+	}
+	if len(captures) == 0 {
+		fmt.Fprintln(streams.Out, "No diagnostics captured.")
+		return nil
+	}
+	data, err := yaml.Marshal(captures)
+	if err != nil {
+		return errors.New(err, "could not marshal to YAML")
+	}
+	_, err = streams.Out.Write(data)
+	return err
+}
+
 func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 	filepath, _ := cmd.Flags().GetString("file")
 	if filepath == "" {
@@ -54,6 +99,31 @@ func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		return fmt.Errorf("cannot get 'exclude-events' flag: %w", err)
 	}
 
+	profileName, _ := cmd.Flags().GetString("profile")
+	profile, err := diagnostics.GetProfile(profileName)
+	if err != nil {
+		return err
+	}
+	var maxSize int64
+	if maxSizeFlag, _ := cmd.Flags().GetString("max-size"); maxSizeFlag != "" {
+		maxSize, err = units.RAMInBytes(maxSizeFlag)
+		if err != nil || maxSize <= 0 {
+			return fmt.Errorf("invalid 'max-size' flag %q", maxSizeFlag)
+		}
+	}
+	// the archive only has a manifest when a profile or a size budget is requested
+	var archiveOpts []diagnostics.ArchiveOption
+	if cmd.Flags().Changed("profile") || maxSize > 0 {
+		archiveOpts = append(archiveOpts, diagnostics.WithProfile(profile), diagnostics.WithMaxSize(maxSize))
+	}
+	if settings := getConfig(streams).Settings; settings != nil && settings.Diagnostics != nil {
+		redactor, err := settings.Diagnostics.Redaction.NewRedactor()
+		if err != nil {
+			return fmt.Errorf("invalid diagnostics redaction rules: %w", err)
+		}
+		archiveOpts = append(archiveOpts, diagnostics.WithRedactor(redactor))
+	}
+
 	ctx := handleSignal(context.Background())
 
 	// 1st create the file to store the diagnostics, if it fails, anything else
@@ -66,12 +136,12 @@ func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 
 	cpuProfile, _ := cmd.Flags().GetBool("cpu-profile")
 	connSkip, _ := cmd.Flags().GetBool("skip-conn")
-	agentDiag, unitDiags, compDiags, err := collectDiagnostics(ctx, streams, cpuProfile, connSkip)
+	agentDiag, unitDiags, compDiags, err := collectDiagnostics(ctx, streams, profile, cpuProfile, connSkip)
 	if err != nil {
 		return fmt.Errorf("failed collecting diagnostics: %w", err)
 	}
 
-	if err := diagnostics.ZipArchive(streams.Err, f, paths.Top(), agentDiag, unitDiags, compDiags, excludeEvents); err != nil {
+	if err := diagnostics.ZipArchive(streams.Err, f, paths.Top(), agentDiag, unitDiags, compDiags, excludeEvents, archiveOpts...); err != nil {
 		return fmt.Errorf("unable to create archive %q: %w", filepath, err)
 	}
 	fmt.Fprintf(streams.Out, "Created diagnostics archive %q\n", filepath)
@@ -79,7 +149,7 @@ func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 	return nil
 }
 
-func collectDiagnostics(ctx context.Context, streams *cli.IOStreams, cpuProfile, connSkip bool) ([]client.DiagnosticFileResult, []client.DiagnosticUnitResult, []client.DiagnosticComponentResult, error) {
+func collectDiagnostics(ctx context.Context, streams *cli.IOStreams, profile diagnostics.Profile, cpuProfile, connSkip bool) ([]client.DiagnosticFileResult, []client.DiagnosticUnitResult, []client.DiagnosticComponentResult, error) {
 	daemon := client.New()
 	err := daemon.Connect(ctx)
 	if err != nil {
@@ -88,10 +158,10 @@ func collectDiagnostics(ctx context.Context, streams *cli.IOStreams, cpuProfile,
 	defer daemon.Disconnect()
 
 	var additionalDiags []cproto.AdditionalDiagnosticRequest
-	if !connSkip {
+	if profile.Conn && !connSkip {
 		additionalDiags = append(additionalDiags, cproto.AdditionalDiagnosticRequest_CONN)
 	}
-	if cpuProfile {
+	if cpuProfile || profile.CPU {
 		// console will just hang while we wait for the CPU profile; print something so user doesn't get confused
 		fmt.Fprintf(streams.Out, "Creating diagnostics archive, waiting for CPU profile...\n")
 		additionalDiags = append(additionalDiags, cproto.AdditionalDiagnosticRequest_CPU)
@@ -102,14 +172,20 @@ func collectDiagnostics(ctx context.Context, streams *cli.IOStreams, cpuProfile,
 		fmt.Fprintf(streams.Err, "[WARNING]: failed to fetch agent diagnostics: %s", err)
 	}
 
-	unitDiags, err := daemon.DiagnosticUnits(ctx)
-	if err != nil {
-		fmt.Fprintf(streams.Err, "[WARNING]: failed to fetch unit diagnostics: %s", err)
+	var unitDiags []client.DiagnosticUnitResult
+	if profile.Units {
+		unitDiags, err = daemon.DiagnosticUnits(ctx)
+		if err != nil {
+			fmt.Fprintf(streams.Err, "[WARNING]: failed to fetch unit diagnostics: %s", err)
+		}
 	}
 
-	compDiags, err := daemon.DiagnosticComponents(ctx, additionalDiags)
-	if err != nil {
-		fmt.Fprintf(streams.Err, "[WARNING]: failed to fetch component diagnostics: %s", err)
+	var compDiags []client.DiagnosticComponentResult
+	if profile.Components {
+		compDiags, err = daemon.DiagnosticComponents(ctx, additionalDiags)
+		if err != nil {
+			fmt.Fprintf(streams.Err, "[WARNING]: failed to fetch component diagnostics: %s", err)
+		}
 	}
 
 	if len(compDiags) == 0 && len(unitDiags) == 0 && len(agentDiag) == 0 {
@@ -30,6 +30,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/config/operations"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/utils"
 )
@@ -45,6 +46,12 @@ first set of computed variables are used when only the --variables flag is defin
 dynamic providers (kubernetes, docker, etc.) from providing all the possible variables it could have discovered if given
 more time. The --variables-wait allows an amount of time to be provided for variable discovery, when set it will
 wait that amount of time before using the variables for the configuration.
+
+The --policy flag allows a policy file to be simulated instead of the current configuration. The policy is rendered
+through the same variable substitution the Elastic Agent performs, without communicating with the running Elastic
+Agent. The --vars flag provides a file with canned values for the composable providers; when not provided the
+providers defined in the policy are used to discover the variables. The inputs and outputs blocked by the capabilities
+are removed, the --capabilities flag allows a different capabilities file to be used.
 `,
 		Args: cobra.ExactArgs(0),
 		Run: func(c *cobra.Command, args []string) {
@@ -55,8 +62,25 @@ wait that amount of time before using the variables for the configuration.
 
 			opts.variables = opts.variables || c.Flags().Changed("variables-wait")
 
+			var simOpts simulatePolicyOpts
+			simOpts.policyPath, _ = c.Flags().GetString("policy")
+			simOpts.varsPath, _ = c.Flags().GetString("vars")
+			simOpts.capsPath, _ = c.Flags().GetString("capabilities")
+			simOpts.variablesWait = opts.variablesWait
+			if err := simOpts.validate(); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
 			ctx, cancel := context.WithCancel(context.Background())
 			service.HandleSignals(func() {}, cancel)
+			if simOpts.policyPath != "" {
+				if err := inspectSimulatedConfig(ctx, simOpts, streams); err != nil {
+					fmt.Fprintf(streams.Err, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				return
+			}
 			if err := inspectConfig(ctx, paths.ConfigFile(), opts, streams); err != nil {
 				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage)
 				os.Exit(1)
@@ -67,8 +91,10 @@ wait that amount of time before using the variables for the configuration.
 	cmd.Flags().Bool("variables", false, "render configuration with variables substituted")
 	cmd.Flags().Bool("monitoring", false, "includes monitoring configuration (implies --variables)")
 	cmd.Flags().Duration("variables-wait", time.Duration(0), "wait this amount of time for variables before performing substitution (implies --variables)")
+	addSimulatePolicyFlags(cmd)
 
 	cmd.AddCommand(newInspectComponentsCommandWithArgs(s, streams))
+	cmd.AddCommand(newInspectDiffCommandWithArgs(s, streams))
 
 	return cmd
 }
@@ -96,6 +122,11 @@ first set of computed variables are used. This can prevent some of the dynamic p
 providing all the possible variables it could have discovered if given more time. The --variables-wait allows an
 amount of time to be provided for variable discovery, when set it will wait that amount of time before using the
 variables for the configuration.
+
+The --policy flag allows the components model of a policy file to be simulated instead of the current configuration.
+The components, units and any runtime preventions are computed the same way the Elastic Agent does when it receives
+the policy, without communicating with the running Elastic Agent. The --vars flag provides a file with canned values
+for the composable providers and the --capabilities flag allows a different capabilities file to be used.
 `,
 		Args: cobra.MaximumNArgs(1),
 		Run: func(c *cobra.Command, args []string) {
@@ -106,6 +137,14 @@ variables for the configuration.
 			opts.showConfig, _ = c.Flags().GetBool("show-config")
 			opts.showSpec, _ = c.Flags().GetBool("show-spec")
 			opts.variablesWait, _ = c.Flags().GetDuration("variables-wait")
+			opts.simulate.policyPath, _ = c.Flags().GetString("policy")
+			opts.simulate.varsPath, _ = c.Flags().GetString("vars")
+			opts.simulate.capsPath, _ = c.Flags().GetString("capabilities")
+			opts.simulate.variablesWait = opts.variablesWait
+			if err := opts.simulate.validate(); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n", err)
+				os.Exit(1)
+			}
 
 			ctx, cancel := context.WithCancel(context.Background())
 			service.HandleSignals(func() {}, cancel)
@@ -120,10 +159,96 @@ variables for the configuration.
 	cmd.Flags().Bool("show-config", false, "show the configuration for all units")
 	cmd.Flags().Bool("show-spec", false, "show the runtime specification for a component")
 	cmd.Flags().Duration("variables-wait", time.Duration(0), "wait this amount of time for variables before performing substitution")
+	addSimulatePolicyFlags(cmd)
 
 	return cmd
 }
 
+func newInspectDiffCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff",
+		Short: "Displays what changed in the components model on the last policy change",
+		Long: `Displays the difference between the previous and the current components model of the running Elastic Agent.
+
+The components that were added or removed are listed, along with the units whose configuration changed. For every
+changed unit the hash of the previous and the current configuration is provided with the exact configuration keys
+that changed. Only the last change of the components model is kept by the Elastic Agent.
+`,
+		Args: cobra.ExactArgs(0),
+		Run: func(c *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			service.HandleSignals(func() {}, cancel)
+
+			if err := inspectDiff(ctx, streams); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func addSimulatePolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().String("policy", "", "simulate the provided policy file instead of using the current configuration")
+	cmd.Flags().String("vars", "", "file with the values of the composable providers used to simulate the policy (requires --policy)")
+	cmd.Flags().String("capabilities", "", "capabilities file used to simulate the policy, defaults to the Elastic Agent capabilities (requires --policy)")
+}
+
+type simulatePolicyOpts struct {
+	policyPath    string
+	varsPath      string
+	capsPath      string
+	variablesWait time.Duration
+}
+
+// validate returns an error when the flags that only apply to a simulated policy are used without --policy.
+func (o simulatePolicyOpts) validate() error {
+	if o.policyPath != "" {
+		return nil
+	}
+	if o.varsPath != "" {
+		return fmt.Errorf("--vars requires --policy")
+	}
+	if o.capsPath != "" {
+		return fmt.Errorf("--capabilities requires --policy")
+	}
+	return nil
+}
+
+// capabilitiesPath returns the capabilities file used to simulate the policy.
+func (o simulatePolicyOpts) capabilitiesPath() string {
+	if o.capsPath != "" {
+		return o.capsPath
+	}
+	return paths.AgentCapabilitiesPath()
+}
+
+func inspectSimulatedConfig(ctx context.Context, opts simulatePolicyOpts, streams *cli.IOStreams) error {
+	l, err := newErrorLogger()
+	if err != nil {
+		return fmt.Errorf("error creating logger: %w", err)
+	}
+
+	result, err := componentvalidation.SimulatePolicy(ctx, l, opts.policyPath, opts.varsPath, opts.variablesWait)
+	if err != nil {
+		return fmt.Errorf("error simulating policy: %w", err)
+	}
+	caps, err := capabilities.LoadFile(opts.capabilitiesPath(), l)
+	if err != nil {
+		return fmt.Errorf("error loading capabilities: %w", err)
+	}
+
+	// Ensure secret markers are injected based on secret_paths before redaction.
+	rawCfg := config.MustNewConfigFrom(componentvalidation.ApplyCapabilities(result.Config, caps))
+	if err := diagnostics.AddSecretMarkers(l, rawCfg); err != nil {
+		fmt.Fprintf(streams.Err, "failed to add secret markers: %v\n", err)
+	}
+	cfg, err := rawCfg.ToMapStr()
+	if err != nil {
+		return fmt.Errorf("failed to convert config with secret markers: %w", err)
+	}
+	return printMapStringConfig(cfg, streams)
+}
+
 type inspectConfigOpts struct {
 	variables         bool
 	includeMonitoring bool
@@ -279,6 +404,7 @@ type inspectComponentsOpts struct {
 	showConfig    bool
 	showSpec      bool
 	variablesWait time.Duration
+	simulate      simulatePolicyOpts
 }
 
 // returns true if the given Capabilities config blocks the given component.
@@ -292,10 +418,21 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 		return err
 	}
 
-	comps, err := componentvalidation.GetComponentsFromPolicy(ctx, l, cfgPath, opts.variablesWait)
-	if err != nil {
-		// error already includes the context
-		return err
+	var comps []component.Component
+	capsPath := paths.AgentCapabilitiesPath()
+	if opts.simulate.policyPath != "" {
+		result, err := componentvalidation.SimulatePolicy(ctx, l, opts.simulate.policyPath, opts.simulate.varsPath, opts.simulate.variablesWait)
+		if err != nil {
+			return fmt.Errorf("error simulating policy: %w", err)
+		}
+		comps = result.Components
+		capsPath = opts.simulate.capabilitiesPath()
+	} else {
+		comps, err = componentvalidation.GetComponentsFromPolicy(ctx, l, cfgPath, opts.variablesWait)
+		if err != nil {
+			// error already includes the context
+			return err
+		}
 	}
 
 	// Hide configuration unless toggled on.
@@ -346,7 +483,7 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 	}
 
 	// Separate any components that are blocked by capabilities config
-	caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), l)
+	caps, err := capabilities.LoadFile(capsPath, l)
 	if err != nil {
 		return err
 	}
@@ -356,6 +493,9 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 		if blockedByCaps(c, caps) {
 			blocked = append(blocked, c)
 		} else {
+			// components and units blocked by the component, processor
+			// and output setting rules report the error
+			capabilities.ApplyToComponent(caps, &c)
 			allowed = append(allowed, c)
 		}
 	}
@@ -363,6 +503,30 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 	return printComponents(allowed, blocked, streams)
 }
 
+func inspectDiff(ctx context.Context, streams *cli.IOStreams) error {
+	daemon := client.New()
+	err := daemon.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	defer daemon.Disconnect()
+
+	diff, err := daemon.PolicyDiff(ctx)
+	if err != nil {
+		return err
+	}
+	if diff == nil {
+		fmt.Fprintln(streams.Out, "The components model has not changed since the Elastic Agent started.")
+		return nil
+	}
+	data, err := yaml.Marshal(diff)
+	if err != nil {
+		return errors.New(err, "could not marshal to YAML")
+	}
+	_, err = streams.Out.Write(data)
+	return err
+}
+
 func printComponents(
 	components []component.Component,
 	blocked []component.Component,
@@ -21,6 +21,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/filelock"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/utils"
@@ -80,6 +81,7 @@ would like the Agent to operate.
 	}
 
 	addEnrollFlags(cmd)
+	addVaultBackendFlags(cmd)
 
 	return cmd
 }
@@ -115,6 +117,22 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		fmt.Fprintln(streams.Out, "Unprivileged installation mode enabled.")
 	}
 
+	vaultBackend, err := vaultBackendFromFlags(cmd)
+	if err != nil {
+		return fmt.Errorf("invalid vault backend: %w", err)
+	}
+	if vaultBackend.Backend != "" && vaultBackend.Backend != vault.BackendFile {
+		if unprivileged {
+			return fmt.Errorf("the %s vault backend is not available in unprivileged mode", vaultBackend.Backend)
+		}
+		// fail before installing when the backend cannot be reached
+		v, err := vault.New(cmd.Context(), vault.WithBackend(vaultBackend))
+		if err != nil {
+			return fmt.Errorf("unable to open the %s vault backend: %w", vaultBackend.Backend, err)
+		}
+		_ = v.Close()
+	}
+
 	isDevelopmentMode, _ := cmd.Flags().GetBool(flagInstallDevelopment)
 	if isDevelopmentMode {
 		fmt.Fprintln(streams.Out, "Installing into development namespace; this is an experimental and currently unsupported feature.")
@@ -298,7 +316,7 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 			}
 		}()
 
-		ownership, err = install.Install(cfgFile, topPath, unprivileged, log, progBar, streams, customUser, customGroup, customPass, flavor)
+		ownership, err = install.Install(cfgFile, topPath, unprivileged, log, progBar, streams, customUser, customGroup, customPass, flavor, vaultBackend)
 		if err != nil {
 			return fmt.Errorf("error installing package: %w", err)
 		}
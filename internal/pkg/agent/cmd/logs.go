@@ -69,6 +69,30 @@ func createComponentFilter(id string) filterFunc {
 	}
 }
 
+// chainFilters returns a filter that lets print only the log lines passing all the given filters.
+func chainFilters(filters ...filterFunc) filterFunc {
+	var chained []filterFunc
+	for _, f := range filters {
+		if f != nil {
+			chained = append(chained, f)
+		}
+	}
+	switch len(chained) {
+	case 0:
+		return nil
+	case 1:
+		return chained[0]
+	}
+	return func(entry []byte) bool {
+		for _, f := range chained {
+			if !f(entry) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
 func addColorModifier(entry []byte) []byte {
 	var e logEntry
 	err := json.Unmarshal(entry, &e)
@@ -167,7 +191,10 @@ func newLogsCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "logs",
 		Short: "Output Elastic Agent logs",
-		Long:  "This command allows to output, watch and filter Elastic Agent logs.",
+		Long: `This command allows to output, watch and filter Elastic Agent logs.
+
+With --since, --until or --json all log files, including rotated and gzipped ones, are read and
+merged with the event logs in a single chronologically ordered stream.`,
 		Run: func(c *cobra.Command, _ []string) {
 			if err := logsCmd(streams, c, logsDir, eventLogsDir); err != nil {
 				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage)
@@ -182,6 +209,11 @@ func newLogsCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.Flags().Bool("exclude-events", false, "Excludes events log files")
 
 	cmd.Flags().StringP("component", "C", "", "Filter logs and output only logs for the given component ID.")
+	cmd.Flags().String("level", "", "Output only logs with the given log level or above (debug, info, warn, error, critical).")
+	cmd.Flags().StringArray("match", nil, "Output only logs where the field matches the value, e.g. --match log.logger=composable. Can be repeated.")
+	cmd.Flags().String("since", "", "Output only logs written after the given RFC3339 time or duration ago, e.g. 2h. Reads all log files including rotated ones.")
+	cmd.Flags().String("until", "", "Output only logs written before the given RFC3339 time or duration ago. Reads all log files including rotated ones.")
+	cmd.Flags().Bool("json", false, "Output the raw NDJSON entries of all log files, including rotated ones, merged in a single chronologically ordered stream.")
 
 	return cmd
 }
@@ -192,19 +224,55 @@ func logsCmd(streams *cli.IOStreams, cmd *cobra.Command, logsDir, eventLogsDir s
 	follow, _ := cmd.Flags().GetBool("follow")
 	noColor, _ := cmd.Flags().GetBool("no-color")
 	excludeEvents, _ := cmd.Flags().GetBool("exclude-events")
+	level, _ := cmd.Flags().GetString("level")
+	matches, _ := cmd.Flags().GetStringArray("match")
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	query, err := parseLogQuery(time.Now(), since, until, level, matches)
+	if err != nil {
+		return err
+	}
 
 	var (
 		filter   filterFunc
 		modifier modifierFunc
 	)
 
-	if component != "" {
-		filter = createComponentFilter(component)
+	if !noColor && !jsonOutput {
+		modifier = addColorModifier
 	}
 
-	if !noColor {
-		modifier = addColorModifier
+	// the query mode reads all the log files from the oldest and merges them in a single stream
+	if jsonOutput || query.timeFiltered() {
+		if follow {
+			return errors.New("--follow cannot be used with --since, --until or --json")
+		}
+		if component != "" {
+			if query.fields == nil {
+				query.fields = make(map[string]string, 1)
+			}
+			query.fields["component.id"] = component
+		}
+		// without an explicit number of lines the query mode outputs all the matching lines
+		if !cmd.Flags().Changed("number") {
+			lines = 0
+		}
+		dirs := []string{logsDir}
+		if !excludeEvents {
+			dirs = append(dirs, eventLogsDir)
+		}
+		if err := queryLogs(streams.Out, dirs, query, lines, modifier); err != nil {
+			return fmt.Errorf("failed to query logs: %w", err)
+		}
+		return nil
+	}
+
+	if component != "" {
+		filter = createComponentFilter(component)
 	}
+	filter = chainFilters(filter, query.filter())
 
 	// uncomment for debugging
 	// fmt.Fprintf(streams.Err, "logs dir: %q", logsDir)
@@ -0,0 +1,363 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"bufio"
+	"compress/gzip"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// maximum size of a single log line read in the query mode
+	maxLogLineSize = 10 * 1024 * 1024
+)
+
+// queryFilePattern matches the log files and their rotated and gzipped copies
+var queryFilePattern = regexp.MustCompile(`(?:elastic-agent|elastic-otel-collector)(-event-log)?-(\d+)(-\d+)?\.ndjson(\.gz)?$`)
+
+// logQuery defines which log lines are printed by the logs command.
+type logQuery struct {
+	since time.Time
+	until time.Time
+	// minimum log level, nil means all levels
+	level *zapcore.Level
+	// fields that must match, the key is the dotted field path
+	fields map[string]string
+}
+
+// parseLogQuery parses the command line representation of a log query.
+func parseLogQuery(now time.Time, since, until, level string, matches []string) (logQuery, error) {
+	var (
+		q   logQuery
+		err error
+	)
+	if since != "" {
+		q.since, err = parseLogTime(now, since)
+		if err != nil {
+			return q, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if until != "" {
+		q.until, err = parseLogTime(now, until)
+		if err != nil {
+			return q, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+	if !q.since.IsZero() && !q.until.IsZero() && q.until.Before(q.since) {
+		return q, errors.New("--until must not be before --since")
+	}
+	if level != "" {
+		lvl, err := parseLogLevel(level)
+		if err != nil {
+			return q, fmt.Errorf("invalid --level: %w", err)
+		}
+		q.level = &lvl
+	}
+	for _, m := range matches {
+		key, value, ok := strings.Cut(m, "=")
+		if !ok || key == "" {
+			return q, fmt.Errorf("invalid --match %q, expected format is field=value", m)
+		}
+		if q.fields == nil {
+			q.fields = make(map[string]string, len(matches))
+		}
+		q.fields[key] = value
+	}
+	return q, nil
+}
+
+// parseLogTime parses either an absolute RFC3339 time or a duration relative to `now`,
+// e.g. `30m` is 30 minutes ago.
+func parseLogTime(now time.Time, value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a duration nor a RFC3339 time", value)
+	}
+	return t, nil
+}
+
+// parseLogLevel parses a log level as it's written in the log entries.
+func parseLogLevel(level string) (zapcore.Level, error) {
+	switch strings.ToLower(level) {
+	case "warning":
+		level = "warn"
+	case "critical":
+		level = "dpanic"
+	}
+	return zapcore.ParseLevel(level)
+}
+
+// timeFiltered returns true when the query has a time range.
+func (q logQuery) timeFiltered() bool {
+	return !q.since.IsZero() || !q.until.IsZero()
+}
+
+// match returns true if the parsed log entry matches the query.
+func (q logQuery) match(entry map[string]interface{}, ts time.Time) bool {
+	if !q.since.IsZero() && ts.Before(q.since) {
+		return false
+	}
+	if !q.until.IsZero() && ts.After(q.until) {
+		return false
+	}
+	if q.level != nil {
+		lvl, err := parseLogLevel(fmt.Sprint(lookupLogField(entry, "log.level")))
+		if err != nil || lvl < *q.level {
+			return false
+		}
+	}
+	for key, value := range q.fields {
+		v := lookupLogField(entry, key)
+		if v == nil || fmt.Sprint(v) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// filter returns a filter for the tail mode, nil if the query does not filter anything.
+func (q logQuery) filter() filterFunc {
+	if !q.timeFiltered() && q.level == nil && len(q.fields) == 0 {
+		return nil
+	}
+	return func(line []byte) bool {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return false
+		}
+		return q.match(entry, logTimestamp(entry))
+	}
+}
+
+// lookupLogField returns the value of the field defined by its dotted path. Log entries
+// mix dotted keys (`log.level`) and nested objects (`component.id`), both are supported.
+func lookupLogField(entry map[string]interface{}, key string) interface{} {
+	if v, ok := entry[key]; ok {
+		return v
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] != '.' {
+			continue
+		}
+		if nested, ok := entry[key[:i]].(map[string]interface{}); ok {
+			if v := lookupLogField(nested, key[i+1:]); v != nil {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// logTimestamp returns the timestamp of the log entry, zero time if it has none.
+func logTimestamp(entry map[string]interface{}) time.Time {
+	s, ok := entry["@timestamp"].(string)
+	if !ok {
+		return time.Time{}
+	}
+	ts, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return ts
+}
+
+// getQueryLogFilenames returns absolute paths to all log files including the rotated and gzipped ones in `dirs`.
+func getQueryLogFilenames(dirs ...string) ([]string, error) {
+	var paths []string
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list logs directory: %w", err)
+		}
+		for _, e := range entries {
+			if e.IsDir() || !queryFilePattern.MatchString(e.Name()) {
+				continue
+			}
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// logSource reads the log entries of a single log file.
+type logSource struct {
+	closer  io.Closer
+	scanner *bufio.Scanner
+
+	// the current entry
+	line []byte
+	ts   time.Time
+}
+
+func openLogSource(filename string) (*logSource, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file %q for reading: %w", filename, err)
+	}
+	var r io.Reader = f
+	if strings.HasSuffix(filename, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to decompress log file %q: %w", filename, err)
+		}
+		r = gz
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, logBufferSize), maxLogLineSize)
+	return &logSource{closer: f, scanner: scanner}, nil
+}
+
+// next reads the next entry matching the query, returns false once the file is consumed.
+func (s *logSource) next(q logQuery) (bool, error) {
+	for s.scanner.Scan() {
+		line := s.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+		ts := logTimestamp(entry)
+		if !q.until.IsZero() && ts.After(q.until) {
+			// lines in a file are ordered, nothing else can match
+			return false, nil
+		}
+		if !q.match(entry, ts) {
+			continue
+		}
+		s.line = append(s.line[:0], line...)
+		s.ts = ts
+		return true, nil
+	}
+	return false, s.scanner.Err()
+}
+
+// logSourceHeap orders the log sources by the timestamp of their current entry.
+type logSourceHeap []*logSource
+
+func (h logSourceHeap) Len() int           { return len(h) }
+func (h logSourceHeap) Less(i, j int) bool { return h[i].ts.Before(h[j].ts) }
+func (h logSourceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *logSourceHeap) Push(x interface{}) {
+	*h = append(*h, x.(*logSource))
+}
+
+func (h *logSourceHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// queryLogs prints all the log lines from the log files in `dirs` matching the query to `w` as a
+// single chronologically ordered stream. When `lines` is positive only the last `lines` lines are printed.
+func queryLogs(w io.Writer, dirs []string, q logQuery, lines int, modifier modifierFunc) error {
+	files, err := getQueryLogFilenames(dirs...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch log filenames: %w", err)
+	}
+
+	h := make(logSourceHeap, 0, len(files))
+	defer func() {
+		for _, s := range h {
+			s.closer.Close()
+		}
+	}()
+	for _, filename := range files {
+		s, err := openLogSource(filename)
+		if err != nil {
+			return err
+		}
+		ok, err := s.next(q)
+		if err != nil || !ok {
+			s.closer.Close()
+			if err != nil {
+				return fmt.Errorf("failed to read log file %q: %w", filename, err)
+			}
+			continue
+		}
+		h = append(h, s)
+	}
+	heap.Init(&h)
+
+	// with a limit we keep only the last `lines` lines in a ring buffer
+	var (
+		tail [][]byte
+		next int
+	)
+	emit := func(line []byte) error {
+		if modifier != nil {
+			line = modifier(line)
+		}
+		if lines > 0 {
+			l := make([]byte, len(line))
+			copy(l, line)
+			if len(tail) < lines {
+				tail = append(tail, l)
+			} else {
+				tail[next] = l
+				next = (next + 1) % lines
+			}
+			return nil
+		}
+		return writeLogLine(w, line)
+	}
+
+	for h.Len() > 0 {
+		s := h[0]
+		if err := emit(s.line); err != nil {
+			return err
+		}
+		ok, err := s.next(q)
+		if err != nil {
+			return fmt.Errorf("failed to read log file: %w", err)
+		}
+		if ok {
+			heap.Fix(&h, 0)
+			continue
+		}
+		heap.Pop(&h)
+		s.closer.Close()
+	}
+
+	for i := range tail {
+		if err := writeLogLine(w, tail[(next+i)%len(tail)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeLogLine(w io.Writer, line []byte) error {
+	if _, err := w.Write(line); err != nil {
+		return fmt.Errorf("failed to print the log line to the writer: %w", err)
+	}
+	if _, err := w.Write([]byte{'\n'}); err != nil {
+		return fmt.Errorf("failed to print the log line to the writer: %w", err)
+	}
+	return nil
+}
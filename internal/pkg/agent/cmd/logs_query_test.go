@@ -0,0 +1,144 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseLogQuery(t *testing.T) {
+	now := time.Date(2023, 5, 30, 12, 0, 0, 0, time.UTC)
+
+	q, err := parseLogQuery(now, "2h", "2023-05-30T11:30:00Z", "warning", []string{"component.id=filestream-default", "log.logger=composable"})
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-2*time.Hour), q.since)
+	assert.Equal(t, time.Date(2023, 5, 30, 11, 30, 0, 0, time.UTC), q.until)
+	require.NotNil(t, q.level)
+	assert.Equal(t, zapcore.WarnLevel, *q.level)
+	assert.Equal(t, map[string]string{"component.id": "filestream-default", "log.logger": "composable"}, q.fields)
+	assert.True(t, q.timeFiltered())
+
+	q, err = parseLogQuery(now, "", "", "", nil)
+	require.NoError(t, err)
+	assert.False(t, q.timeFiltered())
+	assert.Nil(t, q.filter())
+
+	_, err = parseLogQuery(now, "yesterday", "", "", nil)
+	assert.ErrorContains(t, err, "invalid --since")
+	_, err = parseLogQuery(now, "1h", "2h", "", nil)
+	assert.ErrorContains(t, err, "--until must not be before --since")
+	_, err = parseLogQuery(now, "", "", "loud", nil)
+	assert.ErrorContains(t, err, "invalid --level")
+	_, err = parseLogQuery(now, "", "", "", []string{"component.id"})
+	assert.ErrorContains(t, err, "expected format is field=value")
+}
+
+func TestLookupLogField(t *testing.T) {
+	entry := map[string]interface{}{
+		"log.level": "info",
+		"component": map[string]interface{}{
+			"id":     "filestream-default",
+			"binary": "filebeat",
+		},
+		"log": map[string]interface{}{
+			"origin": map[string]interface{}{
+				"file.line": float64(42),
+			},
+		},
+	}
+
+	assert.Equal(t, "info", lookupLogField(entry, "log.level"))
+	assert.Equal(t, "filestream-default", lookupLogField(entry, "component.id"))
+	assert.Equal(t, float64(42), lookupLogField(entry, "log.origin.file.line"))
+	assert.Nil(t, lookupLogField(entry, "component.state"))
+}
+
+func TestLogQueryFilter(t *testing.T) {
+	q, err := parseLogQuery(time.Now(), "", "", "error", []string{"log.origin.file.line=42"})
+	require.NoError(t, err)
+	filter := q.filter()
+	require.NotNil(t, filter)
+
+	assert.True(t, filter([]byte(`{"log.level":"error","log.origin":{"file.line":42}}`)))
+	assert.True(t, filter([]byte(`{"log.level":"critical","log.origin":{"file.line":42}}`)))
+	assert.False(t, filter([]byte(`{"log.level":"info","log.origin":{"file.line":42}}`)), "level below the minimum")
+	assert.False(t, filter([]byte(`{"log.level":"error","log.origin":{"file.line":41}}`)), "field does not match")
+	assert.False(t, filter([]byte(`{"log.level":"error"}`)), "missing field")
+	assert.False(t, filter([]byte(`{"}`)), "invalid JSON")
+}
+
+func TestQueryLogs(t *testing.T) {
+	logsDir := t.TempDir()
+	eventLogsDir := filepath.Join(logsDir, "events")
+	require.NoError(t, os.MkdirAll(eventLogsDir, 0750))
+
+	entry := func(ts, level, component, msg string) string {
+		return `{"@timestamp":"2023-05-30T` + ts + `Z","log.level":"` + level + `","component":{"id":"` + component + `"},"message":"` + msg + `"}` + "\n"
+	}
+
+	// the rotated file is gzipped
+	var gz bytes.Buffer
+	gzw := gzip.NewWriter(&gz)
+	_, err := gzw.Write([]byte(entry("10:00:00", "info", "agent", "a1") + entry("10:00:02", "error", "filestream-default", "a2")))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+	createFileContent(t, logsDir, "elastic-agent-20230530-1.ndjson.gz", &gz)
+	createFileContent(t, logsDir, "elastic-agent-20230530-2.ndjson", strings.NewReader(
+		entry("10:00:03", "info", "agent", "a3")+"not a json line\n"+entry("10:00:05", "warn", "filestream-default", "a5")))
+	createFileContent(t, eventLogsDir, "elastic-agent-event-log-20230530-1.ndjson", strings.NewReader(
+		entry("10:00:01", "error", "filestream-default", "e1")+entry("10:00:04", "info", "agent", "e4")))
+	createFileEmpty(t, logsDir, "excluded.ndjson")
+
+	messages := func(out string) []string {
+		var msgs []string
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			if line == "" {
+				continue
+			}
+			i := strings.Index(line, `"message":"`)
+			require.NotEqual(t, -1, i, line)
+			msgs = append(msgs, strings.TrimSuffix(line[i+len(`"message":"`):], `"}`))
+		}
+		return msgs
+	}
+	dirs := []string{logsDir, eventLogsDir}
+
+	t.Run("merges all files in chronological order", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, queryLogs(&out, dirs, logQuery{}, 0, nil))
+		assert.Equal(t, []string{"a1", "e1", "a2", "a3", "e4", "a5"}, messages(out.String()))
+		assert.Equal(t, entry("10:00:00", "info", "agent", "a1"), strings.SplitAfter(out.String(), "\n")[0], "lines are passed through")
+	})
+
+	t.Run("filters by time range, level and field", func(t *testing.T) {
+		q, err := parseLogQuery(time.Now(), "2023-05-30T10:00:01Z", "2023-05-30T10:00:05Z", "warn", []string{"component.id=filestream-default"})
+		require.NoError(t, err)
+		var out bytes.Buffer
+		require.NoError(t, queryLogs(&out, dirs, q, 0, nil))
+		assert.Equal(t, []string{"e1", "a2", "a5"}, messages(out.String()))
+	})
+
+	t.Run("outputs only the last lines", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, queryLogs(&out, dirs, logQuery{}, 2, nil))
+		assert.Equal(t, []string{"e4", "a5"}, messages(out.String()))
+	})
+
+	t.Run("skips missing directories", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, queryLogs(&out, []string{logsDir, filepath.Join(logsDir, "missing")}, logQuery{}, 0, nil))
+		assert.Equal(t, []string{"a1", "a2", "a3", "a5"}, messages(out.String()))
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func newPolicyCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policy <subcommand>",
+		Short: "Show the policy history and roll back to a previous policy",
+		Long: `Tools to work with the history of the policies applied by the running Elastic Agent.
+
+The Elastic Agent keeps the last policies received from Fleet, with their revision, when they were applied and
+whether they were acknowledged to Fleet. Any policy in the history can be re-applied locally, without communicating
+with Fleet, when a policy breaks ingestion and Fleet itself is unreachable.
+
+Only available when the Elastic Agent is managed by Fleet.`,
+	}
+
+	cmd.AddCommand(newPolicyHistoryCommandWithArgs(args, streams))
+	cmd.AddCommand(newPolicyRollbackCommandWithArgs(args, streams))
+
+	return cmd
+}
+
+func newPolicyHistoryCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "history",
+		Short: "Show the last policies applied by the Elastic Agent",
+		Long:  "Show the last policies applied by the running Elastic Agent, from the oldest to the latest.",
+		Args:  cobra.ExactArgs(0),
+		Run: func(c *cobra.Command, args []string) {
+			if err := policyHistoryCmd(streams); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func newPolicyRollbackCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback <revision>",
+		Short: "Re-apply a policy from the policy history",
+		Long: `Re-apply the most recently applied policy with the provided revision from the policy history. The revision is
+looked up for the currently applied policy unless another policy is selected with --policy-id.
+
+The policy is applied locally and it is kept across restarts until a new policy is received from Fleet. The Fleet
+Server hosts of the policy are not validated and the current Fleet client settings are kept, as Fleet can be
+unreachable. The rollback is reported by the status command while the re-applied policy is running.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			policyID, _ := c.Flags().GetString("policy-id")
+			if err := policyRollbackCmd(streams, policyID, args[0]); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().String("policy-id", "", "ID of the policy to re-apply, defaults to the currently applied policy")
+
+	return cmd
+}
+
+func policyHistoryCmd(streams *cli.IOStreams) error {
+	ctx := handleSignal(context.Background())
+	daemon := client.New()
+	err := daemon.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	defer daemon.Disconnect()
+
+	entries, err := daemon.PolicyHistory(ctx)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(streams.Out, "The policy history is empty.")
+		return nil
+	}
+	data, err := yaml.Marshal(entries)
+	if err != nil {
+		return errors.New(err, "could not marshal to YAML")
+	}
+	_, err = streams.Out.Write(data)
+	return err
+}
+
+func policyRollbackCmd(streams *cli.IOStreams, policyID string, revisionArg string) error {
+	revision, err := strconv.ParseInt(revisionArg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid policy revision %q: %w", revisionArg, err)
+	}
+
+	ctx := handleSignal(context.Background())
+	daemon := client.New()
+	err = daemon.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	defer daemon.Disconnect()
+
+	rollback, err := daemon.PolicyRollback(ctx, policyID, revision)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(streams.Out, "Rolled back policy %s from revision %d to revision %d.\n", rollback.PolicyID, rollback.FromRevision, rollback.Revision)
+	return nil
+}
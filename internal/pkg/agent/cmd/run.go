@@ -39,12 +39,14 @@ import (
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/diagcapture"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/filelock"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring/reload"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/rollout"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
@@ -455,7 +457,7 @@ func runElasticAgent(
 	diagHooks := diagnostics.GlobalHooks()
 	diagHooks = append(diagHooks, coord.DiagnosticHooks()...)
 	controlLog := l.Named("control")
-	control := server.New(controlLog, agentInfo, coord, tracer, diagHooks, cfg.Settings.GRPC, availableRollbacksSource)
+	control := server.New(controlLog, agentInfo, coord, tracer, diagHooks, cfg.Settings.GRPC, cfg.Settings.Control, availableRollbacksSource)
 
 	// if the configMgr implements the TestModeConfigSetter in means that Elastic Agent is in testing mode and
 	// the configuration will come in over the control protocol, so we set the config setting on the control protocol
@@ -521,6 +523,39 @@ func runElasticAgent(
 		l.Warnw("Error calculating relative path for versioned home. Rollback cleanup will not be scheduled ", "topPath", paths.Top(), "homePath", paths.Home(), "error", homePathErr)
 	}
 
+	// Spawn the diagnostics capture goroutine
+	if captureCfg := cfg.Settings.Diagnostics; captureCfg != nil && captureCfg.Capture != nil && captureCfg.Capture.Enabled {
+		logsDir := paths.Logs()
+		if cfg.Settings.LoggingConfig != nil && cfg.Settings.LoggingConfig.Files.Path != "" {
+			logsDir = cfg.Settings.LoggingConfig.Files.Path
+		}
+		// never capture without the redaction rules the user asked for
+		if redactor, err := captureCfg.Redaction.NewRedactor(); err != nil {
+			l.Errorw("Diagnostics capture disabled, failed to load the redaction rules", "error.message", err)
+		} else {
+			capturer := diagcapture.New(l.Named("diagnostics_capture"), captureCfg.Capture, paths.AgentDiagnosticsCapturesDir(), logsDir, diagHooks, redactor, coord)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				capturer.Run(additionalGoroutinesContext)
+			}()
+		}
+	}
+
+	// Spawn the rollout goroutine, Fleet managed Agents are upgraded by Fleet
+	if rolloutCfg := cfg.Settings.Upgrade.Rollout; rolloutCfg != nil && rolloutCfg.Enabled && configuration.IsStandalone(cfg.Fleet) {
+		runner, err := rollout.NewRunner(l.Named("rollout"), rolloutCfg, cfg.Settings.DownloadConfig.HTTPTransportSettings, paths.AgentRolloutStateFile(), agentInfo.AgentID(), coord)
+		if err != nil {
+			l.Errorw("Rollout disabled, failed to create the rollout runner", "error.message", err)
+		} else {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runner.Run(additionalGoroutinesContext)
+			}()
+		}
+	}
+
 	// listen for signals
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
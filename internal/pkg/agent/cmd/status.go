@@ -121,6 +121,7 @@ func listComponentState(l list.Writer, components []client.ComponentState, all b
 		l.AppendItem(c.ID)
 		l.Indent()
 		l.AppendItem(formatStatus(c.State, c.Message))
+		listQuarantine(l, c.Quarantine)
 		l.UnIndent()
 		for _, u := range c.Units {
 			if !all && (u.State == client.Healthy) {
@@ -141,6 +142,25 @@ func listComponentState(l list.Writer, components []client.ComponentState, all b
 	}
 }
 
+func listQuarantine(l list.Writer, quarantine *client.ComponentQuarantine) {
+	if quarantine == nil {
+		return
+	}
+
+	l.AppendItem("quarantine")
+	l.Indent()
+	l.AppendItem("since: " + quarantine.Since.Format(control.TimeFormat()))
+	for _, crash := range quarantine.Crashes {
+		l.AppendItem(fmt.Sprintf("crash: pid '%d' exited with code '%d' at %s", crash.PID, crash.ExitCode, crash.Time.Format(control.TimeFormat())))
+		l.Indent()
+		for _, line := range crash.Logs {
+			l.AppendItem(line)
+		}
+		l.UnIndent()
+	}
+	l.UnIndent()
+}
+
 func listCollectorState(l list.Writer, id string, component *client.CollectorComponent) {
 	if component == nil {
 		return
@@ -194,6 +214,97 @@ func listAgentState(l list.Writer, state *client.AgentState, all bool) {
 
 	// Upgrade details
 	listUpgradeDetails(l, state.UpgradeDetails)
+
+	// Policy rollback
+	listPolicyRollback(l, state.PolicyRollback)
+
+	// Operations deferred until the next maintenance window
+	listMaintenance(l, state.Maintenance)
+
+	// Diagnostics bundles being uploaded to Fleet
+	listDiagnosticsUploads(l, state.DiagnosticsUploads)
+
+	// Acks not delivered to Fleet yet
+	if all {
+		listPendingAcks(l, state.PendingAcks)
+	}
+}
+
+func listDiagnosticsUploads(l list.Writer, uploads []client.DiagnosticsUpload) {
+	if len(uploads) == 0 {
+		return
+	}
+
+	l.AppendItem("diagnostics_uploads")
+	l.Indent()
+	for _, upload := range uploads {
+		l.AppendItem(upload.ActionID)
+		l.Indent()
+		if upload.UploadID != "" {
+			l.AppendItem("upload_id: " + upload.UploadID)
+		}
+		l.AppendItem(fmt.Sprintf("chunks: %d/%d (%d/%d bytes)", upload.ChunksSent, upload.Chunks, upload.BytesSent, upload.Size))
+		l.AppendItem(fmt.Sprintf("attempt: %d", upload.Attempt))
+		if upload.LastError != "" {
+			l.AppendItem("last_error: " + upload.LastError)
+		}
+		l.UnIndent()
+	}
+	l.UnIndent()
+}
+
+func listPendingAcks(l list.Writer, pendingAcks []client.PendingAck) {
+	if len(pendingAcks) == 0 {
+		return
+	}
+
+	l.AppendItem("pending_acks")
+	l.Indent()
+	for _, ack := range pendingAcks {
+		l.AppendItem(fmt.Sprintf("%s (%s)", ack.ActionID, ack.ActionType))
+		l.Indent()
+		l.AppendItem(fmt.Sprintf("attempts: %d", ack.Attempts))
+		l.AppendItem("enqueued_at: " + ack.EnqueuedAt.Format(control.TimeFormat()))
+		if !ack.LastAttemptAt.IsZero() {
+			l.AppendItem("last_attempt_at: " + ack.LastAttemptAt.Format(control.TimeFormat()))
+		}
+		if ack.LastError != "" {
+			l.AppendItem("last_error: " + ack.LastError)
+		}
+		l.UnIndent()
+	}
+	l.UnIndent()
+}
+
+func listMaintenance(l list.Writer, maintenance *client.MaintenanceState) {
+	if maintenance == nil {
+		return
+	}
+
+	l.AppendItem("maintenance")
+	l.Indent()
+	l.AppendItem("deferred_until: " + maintenance.DeferredUntil.Format(control.TimeFormat()))
+	if maintenance.Restart {
+		l.AppendItem("restart: deferred")
+	}
+	if maintenance.PolicyChange {
+		l.AppendItem("policy_change: deferred")
+	}
+	l.UnIndent()
+}
+
+func listPolicyRollback(l list.Writer, rollback *client.PolicyRollback) {
+	if rollback == nil {
+		return
+	}
+
+	l.AppendItem("policy_rollback")
+	l.Indent()
+	l.AppendItem("policy_id: " + rollback.PolicyID)
+	l.AppendItem(fmt.Sprintf("revision: %d", rollback.Revision))
+	l.AppendItem(fmt.Sprintf("from_revision: %d", rollback.FromRevision))
+	l.AppendItem("rolled_back_at: " + rollback.RolledBackAt.Format(control.TimeFormat()))
+	l.UnIndent()
 }
 
 func listUpgradeDetails(l list.Writer, upgradeDetails *cproto.UpgradeDetails) {
@@ -254,6 +254,113 @@ func TestListUpgradeDetails(t *testing.T) {
 	}
 }
 
+func TestListPolicyRollback(t *testing.T) {
+	now := time.Now().UTC()
+
+	l := list.NewWriter()
+	l.SetStyle(list.StyleConnectedLight)
+	listPolicyRollback(l, nil)
+	require.Empty(t, l.Render())
+
+	listPolicyRollback(l, &client.PolicyRollback{
+		ActionID:     "action-1",
+		PolicyID:     "policy-1",
+		Revision:     3,
+		FromRevision: 4,
+		RolledBackAt: now,
+	})
+	require.Equal(t, fmt.Sprintf(`── policy_rollback
+   ├─ policy_id: policy-1
+   ├─ revision: 3
+   ├─ from_revision: 4
+   └─ rolled_back_at: %s`, now.Format(control.TimeFormat())), l.Render())
+}
+
+func TestListMaintenance(t *testing.T) {
+	deferredUntil := time.Now().UTC()
+
+	l := list.NewWriter()
+	l.SetStyle(list.StyleConnectedLight)
+	listMaintenance(l, nil)
+	require.Empty(t, l.Render())
+
+	listMaintenance(l, &client.MaintenanceState{
+		DeferredUntil: deferredUntil,
+		Restart:       true,
+		PolicyChange:  true,
+	})
+	require.Equal(t, fmt.Sprintf(`── maintenance
+   ├─ deferred_until: %s
+   ├─ restart: deferred
+   └─ policy_change: deferred`, deferredUntil.Format(control.TimeFormat())), l.Render())
+}
+
+func TestListPendingAcks(t *testing.T) {
+	enqueuedAt := time.Now().UTC()
+	lastAttemptAt := enqueuedAt.Add(time.Minute)
+
+	l := list.NewWriter()
+	l.SetStyle(list.StyleConnectedLight)
+	listPendingAcks(l, nil)
+	require.Empty(t, l.Render())
+
+	listPendingAcks(l, []client.PendingAck{
+		{ActionID: "action-1", ActionType: "POLICY_CHANGE", EnqueuedAt: enqueuedAt},
+		{ActionID: "action-2", ActionType: "UPGRADE", Attempts: 3, LastError: "fleet unreachable", EnqueuedAt: enqueuedAt, LastAttemptAt: lastAttemptAt},
+	})
+	require.Equal(t, fmt.Sprintf(`── pending_acks
+   ├─ action-1 (POLICY_CHANGE)
+   │  ├─ attempts: 0
+   │  └─ enqueued_at: %[1]s
+   └─ action-2 (UPGRADE)
+      ├─ attempts: 3
+      ├─ enqueued_at: %[1]s
+      ├─ last_attempt_at: %[2]s
+      └─ last_error: fleet unreachable`, enqueuedAt.Format(control.TimeFormat()), lastAttemptAt.Format(control.TimeFormat())), l.Render())
+}
+
+func TestListDiagnosticsUploads(t *testing.T) {
+	l := list.NewWriter()
+	l.SetStyle(list.StyleConnectedLight)
+	listDiagnosticsUploads(l, nil)
+	require.Empty(t, l.Render())
+
+	listDiagnosticsUploads(l, []client.DiagnosticsUpload{
+		{ActionID: "action-1", Size: 10, Attempt: 1},
+		{ActionID: "action-2", UploadID: "upload-2", ChunksSent: 2, Chunks: 5, BytesSent: 8, Size: 18, Attempt: 2, LastError: "connection reset"},
+	})
+	require.Equal(t, `── diagnostics_uploads
+   ├─ action-1
+   │  ├─ chunks: 0/0 (0/10 bytes)
+   │  └─ attempt: 1
+   └─ action-2
+      ├─ upload_id: upload-2
+      ├─ chunks: 2/5 (8/18 bytes)
+      ├─ attempt: 2
+      └─ last_error: connection reset`, l.Render())
+}
+
+func TestListQuarantine(t *testing.T) {
+	since := time.Now().UTC()
+	crashedAt := since.Add(-time.Minute)
+
+	l := list.NewWriter()
+	l.SetStyle(list.StyleConnectedLight)
+	listQuarantine(l, nil)
+	require.Empty(t, l.Render())
+
+	listQuarantine(l, &client.ComponentQuarantine{
+		Since: since,
+		Crashes: []client.ComponentCrash{
+			{Time: crashedAt, PID: 42, ExitCode: 2, Logs: []string{"panic: oops"}},
+		},
+	})
+	require.Equal(t, fmt.Sprintf(`── quarantine
+   ├─ since: %s
+   └─ crash: pid '42' exited with code '2' at %s
+      └─ panic: oops`, since.Format(control.TimeFormat()), crashedAt.Format(control.TimeFormat())), l.Render())
+}
+
 func TestHumanDurationUntil(t *testing.T) {
 	now := time.Now()
 	cases := map[string]struct {
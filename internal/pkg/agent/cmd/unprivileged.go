@@ -16,6 +16,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/install/componentvalidation"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client/wait"
 	"github.com/elastic/elastic-agent/pkg/utils"
@@ -71,6 +72,11 @@ func unprivilegedCmd(streams *cli.IOStreams, cmd *cobra.Command) (err error) {
 		customPass, _ = cmd.Flags().GetString(flagInstallCustomPass)
 	}
 
+	// the token is only reachable by a privileged Elastic Agent
+	if backend, err := vault.LoadBackend(paths.AgentVaultPath()); err == nil && backend.Backend == vault.BackendPKCS11 {
+		return fmt.Errorf("unable to switch to unprivileged mode with the %s vault backend, run 'elastic-agent vault migrate --%s %s' first", backend.Backend, flagVaultBackend, vault.BackendFile)
+	}
+
 	// cannot switch to unprivileged when service components have issues
 	err = componentvalidation.EnsureNoServiceComponentIssues()
 	if err != nil {
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -38,6 +39,7 @@ const (
 	flagPGPBytesURI    = "pgp-uri"
 	flagForce          = "force"
 	flagRollback       = "rollback"
+	flagBundle         = "bundle"
 
 	// list rollbacks subcommand flags
 	flagOutput      = "output"
@@ -49,14 +51,24 @@ var (
 	nonRootExecutionError           = errors.New("upgrade command needs to be executed as root for fleet managed agents")
 	skipVerifyNotAllowedError       = errors.New(fmt.Sprintf("\"%s\" flag is not allowed when upgrading a fleet managed agent using the cli", flagSkipVerify))
 	skipVerifyNotRootError          = errors.New(fmt.Sprintf("user needs to be root to use \"%s\" flag when upgrading standalone agents", flagSkipVerify))
+	skipVerifyBundleError           = errors.New(fmt.Sprintf("\"%s\" flag is not allowed when upgrading from an offline bundle, the bundle signature is always verified", flagSkipVerify))
 )
 
 func newUpgradeCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "upgrade <version>",
 		Short: "Upgrade the currently installed Elastic Agent to the specified version",
-		Long:  "This command upgrades the currently installed Elastic Agent to the specified version.",
-		Args:  cobra.ExactArgs(1),
+		Long: `This command upgrades the currently installed Elastic Agent to the specified version.
+
+With --bundle the agent is upgraded from an offline bundle, a tarball containing the agent package,
+its .sha512 and .asc files and a manifest.json, the version argument is then optional. The signature of
+the bundle is always verified, --skip-verify cannot be used with --bundle.`,
+		Args: func(c *cobra.Command, args []string) error {
+			if bundle, _ := c.Flags().GetString(flagBundle); bundle != "" {
+				return cobra.MaximumNArgs(1)(c, args)
+			}
+			return cobra.ExactArgs(1)(c, args)
+		},
 		Run: func(c *cobra.Command, args []string) {
 			c.SetContext(context.Background())
 			if err := upgradeCmd(streams, c, args); err != nil {
@@ -74,6 +86,7 @@ func newUpgradeCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Com
 	cmd.Flags().String(flagPGPBytesPath, "", "Path to a file containing PGP to use for package verification")
 	cmd.Flags().BoolP(flagForce, "", false, "Advanced option to force an upgrade on a fleet managed agent")
 	cmd.Flags().BoolP(flagRollback, "", false, "Roll back an upgrade")
+	cmd.Flags().String(flagBundle, "", "Path to an offline upgrade bundle to upgrade from")
 	err := cmd.Flags().MarkHidden(flagForce)
 	if err != nil {
 		fmt.Fprintf(streams.Err, "error while setting upgrade force flag attributes: %s", err.Error())
@@ -242,14 +255,36 @@ func upgradeCmd(streams *cli.IOStreams, cmd *cobra.Command, args []string) error
 	return upgradeCmdWithClient(input)
 }
 
+// upgradeBundleSource returns the version and the source URI of an offline upgrade bundle, when `version`
+// is set it must match the version of the bundle.
+func upgradeBundleSource(bundle, version string) (string, string, error) {
+	bundlePath, err := filepath.Abs(bundle)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get absolute path of bundle %q: %w", bundle, err)
+	}
+	manifest, err := download.ReadBundleManifest(bundlePath)
+	if err != nil {
+		return "", "", err
+	}
+	if version != "" && version != manifest.Version {
+		return "", "", fmt.Errorf("bundle %q contains version %s, not %s", bundle, manifest.Version, version)
+	}
+	return manifest.Version, "file://" + filepath.ToSlash(bundlePath), nil
+}
+
 type upgradeCond struct {
 	isManaged  bool
 	force      bool
 	isRoot     bool
 	skipVerify bool
+	bundle     bool
 }
 
 func checkUpgradable(cond upgradeCond) error {
+	if cond.bundle && cond.skipVerify {
+		return skipVerifyBundleError
+	}
+
 	checkManaged := func() error {
 		if !cond.force {
 			return unsupportedUpgradeError
@@ -286,8 +321,12 @@ func upgradeCmdWithClient(input *upgradeInput) error {
 
 	cmd := input.cmd
 	c := input.c
-	version := input.args[0]
+	var version string
+	if len(input.args) > 0 {
+		version = input.args[0]
+	}
 	sourceURI, _ := cmd.Flags().GetString(flagSourceURI)
+	bundle, _ := cmd.Flags().GetString(flagBundle)
 
 	force, err := cmd.Flags().GetBool(flagForce)
 	if err != nil {
@@ -303,6 +342,16 @@ func upgradeCmdWithClient(input *upgradeInput) error {
 		upgradeOperation = "Rollback"
 	}
 
+	if bundle != "" {
+		if rollbackFlag || sourceURI != "" {
+			return fmt.Errorf("%q flag cannot be used with %q or %q", flagBundle, flagRollback, flagSourceURI)
+		}
+		version, sourceURI, err = upgradeBundleSource(bundle, version)
+		if err != nil {
+			return err
+		}
+	}
+
 	skipVerification, err := cmd.Flags().GetBool(flagSkipVerify)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve %s flag information while upgrading the agent: %w", flagSkipVerify, err)
@@ -313,6 +362,7 @@ func upgradeCmdWithClient(input *upgradeInput) error {
 		force:      force,
 		isRoot:     input.isRoot,
 		skipVerify: skipVerification,
+		bundle:     bundle != "",
 	})
 	if err != nil {
 		return fmt.Errorf("aborting upgrade: %w", err)
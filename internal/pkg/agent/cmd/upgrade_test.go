@@ -5,9 +5,13 @@
 package cmd
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"log"
 	"net"
+	"os"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -17,6 +21,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
@@ -261,6 +266,71 @@ func TestUpgradeCmd(t *testing.T) {
 	})
 }
 
+func TestUpgradeCmdBundle(t *testing.T) {
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar")
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	manifest := []byte(`{"version":"8.13.0","package":"elastic-agent-8.13.0-linux-x86_64.tar.gz"}`)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: download.BundleManifestName, Mode: 0o644, Size: int64(len(manifest)), Typeflag: tar.TypeReg}))
+	_, err := tw.Write(manifest)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, os.WriteFile(bundlePath, buf.Bytes(), 0o644))
+
+	t.Run("upgrades to the bundle version from the bundle", func(t *testing.T) {
+		mockClient := client.NewMockClient(t)
+		mockClient.EXPECT().State(mock.Anything).Return(&client.AgentState{State: cproto.State_HEALTHY}, nil)
+		mockClient.EXPECT().Upgrade(mock.Anything, "8.13.0", false, "file://"+filepath.ToSlash(bundlePath), false, false).Return("8.13.0", nil)
+
+		streams := cli.NewIOStreams()
+		cmd := newUpgradeCommandWithArgs(nil, streams)
+		cmd.SetContext(context.Background())
+		require.NoError(t, cmd.Flags().Set(flagBundle, bundlePath))
+
+		err := upgradeCmdWithClient(&upgradeInput{streams, cmd, nil, mockClient, client.AgentStateInfo{IsManaged: false}, true})
+		assert.NoError(t, err)
+	})
+
+	t.Run("fails when the version does not match the bundle", func(t *testing.T) {
+		mockClient := client.NewMockClient(t)
+
+		args := []string{"8.14.0"}
+		streams := cli.NewIOStreams()
+		cmd := newUpgradeCommandWithArgs(args, streams)
+		cmd.SetContext(context.Background())
+		require.NoError(t, cmd.Flags().Set(flagBundle, bundlePath))
+
+		err := upgradeCmdWithClient(&upgradeInput{streams, cmd, args, mockClient, client.AgentStateInfo{IsManaged: false}, true})
+		assert.ErrorContains(t, err, "contains version 8.13.0, not 8.14.0")
+	})
+
+	t.Run("fails when used with a source URI", func(t *testing.T) {
+		mockClient := client.NewMockClient(t)
+
+		streams := cli.NewIOStreams()
+		cmd := newUpgradeCommandWithArgs(nil, streams)
+		cmd.SetContext(context.Background())
+		require.NoError(t, cmd.Flags().Set(flagBundle, bundlePath))
+		require.NoError(t, cmd.Flags().Set(flagSourceURI, "https://artifacts.elastic.co/downloads/"))
+
+		err := upgradeCmdWithClient(&upgradeInput{streams, cmd, nil, mockClient, client.AgentStateInfo{IsManaged: false}, true})
+		assert.ErrorContains(t, err, `"bundle" flag cannot be used`)
+	})
+
+	t.Run("fails when the verification is skipped", func(t *testing.T) {
+		mockClient := client.NewMockClient(t)
+
+		streams := cli.NewIOStreams()
+		cmd := newUpgradeCommandWithArgs(nil, streams)
+		cmd.SetContext(context.Background())
+		require.NoError(t, cmd.Flags().Set(flagBundle, bundlePath))
+		require.NoError(t, cmd.Flags().Set(flagSkipVerify, "true"))
+
+		err := upgradeCmdWithClient(&upgradeInput{streams, cmd, nil, mockClient, client.AgentStateInfo{IsManaged: false}, true})
+		assert.ErrorIs(t, err, skipVerifyBundleError)
+	})
+}
+
 type mockServer struct {
 	cproto.ElasticAgentControlServer
 	upgradeStop <-chan struct{}
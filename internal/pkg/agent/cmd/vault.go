@@ -0,0 +1,157 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+const (
+	flagVaultBackend          = "vault-backend"
+	flagVaultPKCS11Module     = "vault-pkcs11-module"
+	flagVaultPKCS11TokenLabel = "vault-pkcs11-token-label"
+	flagVaultPKCS11PINFile    = "vault-pkcs11-pin-file"
+)
+
+// vaultMigratedKeys are the vault keys moved by the migration.
+var vaultMigratedKeys = []string{secret.AgentSecretKey}
+
+func newVaultCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vault",
+		Short: "Manage the vault storing the Elastic Agent secrets",
+	}
+
+	cmd.AddCommand(newVaultMigrateCommand(streams))
+
+	return cmd
+}
+
+func newVaultMigrateCommand(streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Move the Elastic Agent secrets to another vault backend",
+		Long: `This command moves the secrets of the Elastic Agent, including the key of its encrypted configuration,
+to another vault backend without enrolling the Elastic Agent again. The secrets are removed from the previous
+backend once the Elastic Agent uses the new one. The running Elastic Agent does not need to be restarted.
+
+The pkcs11 backend is only available on Linux to a privileged Elastic Agent.
+`,
+		Args: cobra.ExactArgs(0),
+		Run: func(c *cobra.Command, args []string) {
+			if err := vaultMigrateCmd(streams, c); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage)
+				os.Exit(1)
+			}
+		},
+	}
+
+	addVaultBackendFlags(cmd)
+
+	return cmd
+}
+
+func addVaultBackendFlags(cmd *cobra.Command) {
+	cmd.Flags().String(flagVaultBackend, "", "Vault backend storing the Elastic Agent secrets: file or pkcs11 (Linux)")
+	cmd.Flags().String(flagVaultPKCS11Module, "", "Path of the PKCS#11 library of the token used by the pkcs11 vault backend")
+	cmd.Flags().String(flagVaultPKCS11TokenLabel, "", "Label of the token used by the pkcs11 vault backend")
+	cmd.Flags().String(flagVaultPKCS11PINFile, "", "Path of the file holding the user PIN of the token used by the pkcs11 vault backend")
+}
+
+// vaultBackendFromFlags returns the vault backend selected by the flags, an empty configuration
+// when no backend is selected.
+func vaultBackendFromFlags(cmd *cobra.Command) (vault.BackendConfig, error) {
+	var cfg vault.BackendConfig
+	cfg.Backend, _ = cmd.Flags().GetString(flagVaultBackend)
+	cfg.PKCS11.Module, _ = cmd.Flags().GetString(flagVaultPKCS11Module)
+	cfg.PKCS11.TokenLabel, _ = cmd.Flags().GetString(flagVaultPKCS11TokenLabel)
+	cfg.PKCS11.PINFile, _ = cmd.Flags().GetString(flagVaultPKCS11PINFile)
+	if cfg.Backend == "" {
+		if cfg.PKCS11 != (vault.PKCS11Config{}) {
+			return cfg, fmt.Errorf("--%s=%s is required by the pkcs11 flags", flagVaultBackend, vault.BackendPKCS11)
+		}
+		return cfg, nil
+	}
+	if cfg.Backend != vault.BackendPKCS11 {
+		cfg.PKCS11 = vault.PKCS11Config{}
+	}
+	return cfg, cfg.Validate()
+}
+
+func vaultMigrateCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
+	isRoot, err := utils.HasRoot()
+	if err != nil {
+		return fmt.Errorf("unable to perform vault migrate command while checking for root/Administrator rights: %w", err)
+	}
+	if !isRoot {
+		return fmt.Errorf("unable to perform vault migrate command, not executed with %s permissions", utils.PermissionUser)
+	}
+
+	target, err := vaultBackendFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if target.Backend == "" {
+		return fmt.Errorf("--%s is required", flagVaultBackend)
+	}
+
+	ctx := handleSignal(context.Background())
+	state, stateErr := getDaemonState(ctx)
+	if target.Backend != vault.BackendFile && stateErr == nil && state.Info.Unprivileged {
+		return fmt.Errorf("the %s vault backend is not available to an unprivileged Elastic Agent", target.Backend)
+	}
+
+	vaultPath := paths.AgentVaultPath()
+	current, err := vault.LoadBackend(vaultPath)
+	if err != nil {
+		return err
+	}
+	if current.Backend == "" {
+		unprivileged := stateErr == nil && state.Info.Unprivileged
+		if stateErr != nil {
+			// the Elastic Agent is not running, an unprivileged install is detected from its vault
+			unprivileged, err = install.CheckForUnprivilegedVault(ctx, vault.WithVaultPath(vaultPath))
+			if err != nil {
+				return err
+			}
+		}
+		current.Backend = vault.DefaultBackend(unprivileged)
+	}
+	if current == target {
+		return fmt.Errorf("the vault already uses the %s backend", target.Backend)
+	}
+
+	src, err := vault.New(ctx, vault.WithVaultPath(vaultPath), vault.WithBackend(current))
+	if err != nil {
+		return fmt.Errorf("failed to open the %s vault: %w", current.Backend, err)
+	}
+	defer src.Close()
+	dst, err := vault.New(ctx, vault.WithVaultPath(vaultPath), vault.WithBackend(target))
+	if err != nil {
+		return fmt.Errorf("failed to open the %s vault: %w", target.Backend, err)
+	}
+	defer dst.Close()
+
+	err = vault.Migrate(ctx, src, dst, vaultMigratedKeys, func() error {
+		return vault.SaveBackend(vaultPath, target)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate the vault from the %s backend to the %s backend: %w", current.Backend, target.Backend, err)
+	}
+
+	fmt.Fprintf(streams.Out, "Vault migrated from the %s backend to the %s backend.\n", current.Backend, target.Backend)
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func TestVaultBackendFromFlags(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args     []string
+		expected vault.BackendConfig
+		err      string
+	}{
+		"no backend": {},
+		"keyring": {
+			args: []string{"--vault-backend", "keyring"},
+			err:  `unknown vault backend "keyring"`,
+		},
+		"pkcs11": {
+			args: []string{"--vault-backend", "pkcs11", "--vault-pkcs11-module", "/usr/lib/softhsm/libsofthsm2.so", "--vault-pkcs11-token-label", "agent", "--vault-pkcs11-pin-file", "/etc/agent.pin"},
+			expected: vault.BackendConfig{Backend: vault.BackendPKCS11, PKCS11: vault.PKCS11Config{
+				Module:     "/usr/lib/softhsm/libsofthsm2.so",
+				TokenLabel: "agent",
+				PINFile:    "/etc/agent.pin",
+			}},
+		},
+		"pkcs11 without token": {
+			args: []string{"--vault-backend", "pkcs11", "--vault-pkcs11-module", "/usr/lib/softhsm/libsofthsm2.so"},
+			err:  "requires the module, the token label and the PIN file",
+		},
+		"pkcs11 flags without backend": {
+			args: []string{"--vault-pkcs11-module", "/usr/lib/softhsm/libsofthsm2.so"},
+			err:  "--vault-backend=pkcs11 is required",
+		},
+		"unknown backend": {
+			args: []string{"--vault-backend", "tpm"},
+			err:  `unknown vault backend "tpm"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cmd := newVaultMigrateCommand(cli.NewIOStreams())
+			require.NoError(t, cmd.Flags().Parse(tc.args))
+			cfg, err := vaultBackendFromFlags(cmd)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg)
+		})
+	}
+}
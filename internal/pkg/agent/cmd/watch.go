@@ -106,8 +106,14 @@ func newWatchCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command
 				return
 			}
 
+			watcher := new(upgradeAgentWatcher)
+			watcher.probes, err = newWatcherProbes(cfg)
+			if err != nil {
+				log.Errorw("Health probes disabled, failed to create them", "error.message", err)
+			}
+
 			if err = withAppLocker(log, func() error {
-				return watchCmd(log, paths.Top(), cfg.Settings.Upgrade.Watcher, new(upgradeAgentWatcher), new(upgradeInstallationModifier))
+				return watchCmd(log, paths.Top(), cfg.Settings.Upgrade.Watcher, watcher, new(upgradeInstallationModifier))
 			}); err != nil {
 				log.Errorw("Watch command failed", "error.message", err)
 				fmt.Fprintf(streams.Err, "Watch command failed: %v\n%s\n", err, troubleshootMessage)
@@ -239,7 +245,12 @@ func watchCmd(log *logp.Logger, topDir string, cfg *configuration.UpgradeWatcher
 
 		log.Error("Error detected, proceeding to rollback: %v", err)
 
-		upgradeDetails.SetStateWithReason(details.StateRollback, details.ReasonWatchFailed)
+		reason := details.ReasonWatchFailed
+		var probeErr *upgrade.ProbeError
+		if errors.As(err, &probeErr) {
+			reason = fmt.Sprintf(details.ReasonProbeFailedPattern, probeErr.Name, probeErr.Err)
+		}
+		upgradeDetails.SetStateWithReason(details.StateRollback, reason)
 
 		// by default remove marker (backward compatible behaviour)
 		removeMarker := true
@@ -12,15 +12,40 @@ import (
 	"time"
 
 	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring"
+	componentmonitoring "github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring/component"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring/monitoringhelpers"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
-type upgradeAgentWatcher struct{}
+type upgradeAgentWatcher struct {
+	probes []upgrade.Probe
+}
 
 func (a upgradeAgentWatcher) Watch(ctx context.Context, tilGrace, errorCheckInterval time.Duration, log *logp.Logger) error {
-	return watch(ctx, tilGrace, errorCheckInterval, log)
+	return watch(ctx, tilGrace, errorCheckInterval, log, a.probes)
+}
+
+// newWatcherProbes creates the health probes run during the watch period.
+func newWatcherProbes(cfg *configuration.Configuration) ([]upgrade.Probe, error) {
+	watcherCfg := cfg.Settings.Upgrade.Watcher
+	if len(watcherCfg.Probes) == 0 {
+		return nil, nil
+	}
+
+	endpoints := upgrade.ProbeEndpoints{
+		Component: func(componentID string) string {
+			return componentmonitoring.PrefixedEndpoint(monitoringhelpers.BeatsMonitoringEndpoint(componentID))
+		},
+	}
+	// the /processes endpoints are only served when the HTTP endpoints of the monitoring server are enabled
+	if mcfg := cfg.Settings.MonitoringConfig; mcfg != nil && mcfg.HTTP != nil && mcfg.HTTP.Enabled {
+		endpoints.Agent = componentmonitoring.PrefixedEndpoint(componentmonitoring.AgentMonitoringEndpoint(mcfg))
+	}
+	return upgrade.NewProbes(watcherCfg.Probes, watcherCfg.ErrorCheck.Interval, endpoints, monitoring.GetProcessMetrics)
 }
 
 type upgradeInstallationModifier struct{}
@@ -39,7 +64,7 @@ func (a upgradeInstallationModifier) Rollback(ctx context.Context, log *logger.L
 	return upgrade.RollbackWithOpts(ctx, log, c, topDirPath, prevVersionedHome, prevHash, actualOpts...)
 }
 
-func watch(ctx context.Context, tilGrace time.Duration, errorCheckInterval time.Duration, log *logger.Logger) error {
+func watch(ctx context.Context, tilGrace time.Duration, errorCheckInterval time.Duration, log *logger.Logger, probes []upgrade.Probe) error {
 	errChan := make(chan error)
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -52,6 +77,7 @@ func watch(ctx context.Context, tilGrace time.Duration, errorCheckInterval time.
 
 	agtWatcher := upgrade.NewAgentWatcher(errChan, log, errorCheckInterval)
 	go agtWatcher.Run(ctx)
+	upgrade.RunProbes(ctx, log, probes, errChan)
 
 	// Allow for signals to interrupt the watch
 	signals := make(chan os.Signal, 1)
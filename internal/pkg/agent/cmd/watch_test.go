@@ -471,6 +471,40 @@ func Test_watchCmd_MarkerPointsToSelf(t *testing.T) {
 	}
 }
 
+func Test_watchCmd_ProbeFailureReason(t *testing.T) {
+	log, _ := loggertest.New(t.Name())
+	topDir := t.TempDir()
+	dataDir := paths.DataFrom(topDir)
+	require.NoError(t, os.MkdirAll(dataDir, 0o755))
+	previousVersionedHome := filepath.Join("data", "elastic-agent-9.3.0-prvver")
+	require.NoError(t, upgrade.SaveMarker(dataDir, &upgrade.UpdateMarker{
+		Version:           "9.4.0",
+		Hash:              "newver",
+		VersionedHome:     filepath.Join("data", "elastic-agent-9.4.0-newver"),
+		UpdatedOn:         time.Now(),
+		PrevVersion:       "9.3.0",
+		PrevHash:          "prvver",
+		PrevVersionedHome: previousVersionedHome,
+	}, true))
+
+	mockWatcher := newMockAgentWatcher(t)
+	mockWatcher.EXPECT().
+		Watch(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&upgrade.ProbeError{Name: "publishing", Err: errors.New("no event published")})
+	mockInstallModifier := newMockInstallationModifier(t)
+	mockInstallModifier.EXPECT().
+		Rollback(mock.Anything, mock.Anything, mock.Anything, paths.Top(), previousVersionedHome, "prvver", mock.Anything, mock.Anything).
+		Return(nil)
+
+	require.NoError(t, watchCmd(log, topDir, configuration.DefaultUpgradeConfig().Watcher, mockWatcher, mockInstallModifier))
+
+	marker, err := upgrade.LoadMarker(dataDir)
+	require.NoError(t, err)
+	require.NotNil(t, marker.Details)
+	assert.Equal(t, details.StateRollback, marker.Details.State)
+	assert.Equal(t, "watch failed: health probe publishing failed: no event published", marker.Details.Metadata.Reason)
+}
+
 // writeFakeInstallForWatcherTest builds the on-disk shape that step_unpack
 // produces for a single install: data/elastic-agent-<version>-<hash>/ plus
 // the binary placeholder and components/logs/run subdirectories. Returns
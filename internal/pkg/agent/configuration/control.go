@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"errors"
+)
+
+// ControlConfig is the configuration of the control protocol server.
+type ControlConfig struct {
+	Access *ControlAccessConfig `yaml:"access" config:"access" json:"access"`
+}
+
+// ControlAccessConfig is the access control of the control protocol. When enabled, the callers
+// with the read_only role can only read the state and the version of the Elastic Agent, the
+// other calls require the admin role. The root user and the user running the Elastic Agent
+// always have the admin role, the other callers without a role are denied.
+type ControlAccessConfig struct {
+	Enabled  bool              `yaml:"enabled" config:"enabled" json:"enabled"`
+	ReadOnly ControlRoleConfig `yaml:"read_only" config:"read_only" json:"read_only"`
+	Admin    ControlRoleConfig `yaml:"admin" config:"admin" json:"admin"`
+}
+
+// ControlRoleConfig selects the callers granted a role, either by the user or group of the peer of
+// the Unix domain socket (Linux and macOS) or by the token the caller presents.
+type ControlRoleConfig struct {
+	UIDs []uint32 `yaml:"uids" config:"uids" json:"uids"`
+	GIDs []uint32 `yaml:"gids" config:"gids" json:"gids"`
+	// TokenFile is the path of the file holding the token of the role.
+	TokenFile string `yaml:"token_file" config:"token_file" json:"token_file"`
+}
+
+// Validate validates settings of configuration.
+func (c *ControlAccessConfig) Validate() error {
+	if c.Enabled && c.ReadOnly.TokenFile != "" && c.ReadOnly.TokenFile == c.Admin.TokenFile {
+		return errors.New("the read_only and admin roles cannot share a token file")
+	}
+	return nil
+}
+
+// DefaultControlConfig creates a config with the access control disabled, every caller able to
+// open the control socket has the admin role.
+func DefaultControlConfig() *ControlConfig {
+	return &ControlConfig{
+		Access: &ControlAccessConfig{
+			Enabled: false,
+		},
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+func TestParseControlConfig(t *testing.T) {
+	tests := map[string]struct {
+		cfg      map[string]any
+		expected ControlAccessConfig
+		err      string
+	}{
+		"default": {
+			cfg:      map[string]any{},
+			expected: ControlAccessConfig{},
+		},
+		"roles": {
+			cfg: map[string]any{
+				"access": map[string]any{
+					"enabled": true,
+					"read_only": map[string]any{
+						"uids":       []any{1000, 1001},
+						"gids":       []any{2000},
+						"token_file": "/etc/elastic-agent/control-read-only.token",
+					},
+					"admin": map[string]any{
+						"uids": []any{1002},
+					},
+				},
+			},
+			expected: ControlAccessConfig{
+				Enabled: true,
+				ReadOnly: ControlRoleConfig{
+					UIDs:      []uint32{1000, 1001},
+					GIDs:      []uint32{2000},
+					TokenFile: "/etc/elastic-agent/control-read-only.token",
+				},
+				Admin: ControlRoleConfig{
+					UIDs: []uint32{1002},
+				},
+			},
+		},
+		"shared token file": {
+			cfg: map[string]any{
+				"access": map[string]any{
+					"enabled":   true,
+					"read_only": map[string]any{"token_file": "/etc/elastic-agent/control.token"},
+					"admin":     map[string]any{"token_file": "/etc/elastic-agent/control.token"},
+				},
+			},
+			err: "cannot share a token file",
+		},
+		"negative uid": {
+			cfg: map[string]any{
+				"access": map[string]any{
+					"read_only": map[string]any{"uids": []any{-1}},
+				},
+			},
+			err: "can not convert 'int' into 'uint'",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := DefaultControlConfig()
+			cfg := config.MustNewConfigFrom(test.cfg)
+			err := cfg.UnpackTo(c)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, *c.Access)
+		})
+	}
+}
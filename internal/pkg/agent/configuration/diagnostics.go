@@ -0,0 +1,89 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"errors"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics/redaction"
+)
+
+const (
+	// defaultCaptureMaxCaptures is the number of automatic captures kept on disk.
+	defaultCaptureMaxCaptures = 5
+
+	// defaultCaptureLogLines is the number of log lines included in an automatic capture.
+	defaultCaptureLogLines = 1000
+
+	// defaultCaptureMinInterval is the minimum time between two automatic captures, so a
+	// flapping component does not fill the disk with captures of the same problem.
+	defaultCaptureMinInterval = 10 * time.Minute
+)
+
+// DiagnosticsConfig is the configuration of the diagnostics collected by the Elastic Agent.
+type DiagnosticsConfig struct {
+	Capture   *DiagnosticsCaptureConfig   `yaml:"capture" config:"capture" json:"capture"`
+	Redaction *DiagnosticsRedactionConfig `yaml:"redaction" config:"redaction" json:"redaction"`
+}
+
+// DiagnosticsCaptureConfig is the configuration of the automatic capture of a lightweight
+// diagnostics bundle when the Elastic Agent or one of its components becomes degraded or failed.
+type DiagnosticsCaptureConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// MaxCaptures is the number of captures kept on disk, the oldest are removed first.
+	MaxCaptures int `yaml:"max_captures" config:"max_captures" json:"max_captures"`
+	// LogLines is the number of lines of the Elastic Agent log included in a capture.
+	LogLines int `yaml:"log_lines" config:"log_lines" json:"log_lines"`
+	// MinInterval is the minimum time between two captures.
+	MinInterval time.Duration `yaml:"min_interval" config:"min_interval" json:"min_interval"`
+}
+
+// Validate validates settings of configuration.
+func (c *DiagnosticsCaptureConfig) Validate() error {
+	if c.MaxCaptures < 1 {
+		return errors.New("max_captures must be at least 1")
+	}
+	if c.LogLines < 0 {
+		return errors.New("log_lines must not be negative")
+	}
+	if c.MinInterval < 0 {
+		return errors.New("min_interval must not be negative")
+	}
+	return nil
+}
+
+// DiagnosticsRedactionConfig is the configuration of the user-supplied redaction rules applied
+// to the diagnostics bundles, on top of the secrets redacted by default.
+type DiagnosticsRedactionConfig struct {
+	Rules []redaction.Rule `yaml:"rules" config:"rules" json:"rules"`
+}
+
+// Validate validates settings of configuration.
+func (c *DiagnosticsRedactionConfig) Validate() error {
+	_, err := redaction.New(c.Rules...)
+	return err
+}
+
+// NewRedactor compiles the redaction rules, nil when there is no rule.
+func (c *DiagnosticsRedactionConfig) NewRedactor() (*redaction.Redactor, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return redaction.New(c.Rules...)
+}
+
+// DefaultDiagnosticsConfig creates a config with the automatic capture disabled.
+func DefaultDiagnosticsConfig() *DiagnosticsConfig {
+	return &DiagnosticsConfig{
+		Capture: &DiagnosticsCaptureConfig{
+			Enabled:     false,
+			MaxCaptures: defaultCaptureMaxCaptures,
+			LogLines:    defaultCaptureLogLines,
+			MinInterval: defaultCaptureMinInterval,
+		},
+		Redaction: &DiagnosticsRedactionConfig{},
+	}
+}
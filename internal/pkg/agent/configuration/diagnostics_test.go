@@ -0,0 +1,109 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics/redaction"
+)
+
+func TestParseDiagnosticsConfig(t *testing.T) {
+	tests := map[string]struct {
+		cfg      map[string]any
+		expected DiagnosticsCaptureConfig
+		err      string
+	}{
+		"default": {
+			cfg: map[string]any{},
+			expected: DiagnosticsCaptureConfig{
+				MaxCaptures: defaultCaptureMaxCaptures,
+				LogLines:    defaultCaptureLogLines,
+				MinInterval: defaultCaptureMinInterval,
+			},
+		},
+		"enabled": {
+			cfg: map[string]any{
+				"capture": map[string]any{
+					"enabled":      true,
+					"max_captures": 3,
+					"log_lines":    0,
+					"min_interval": "1m",
+				},
+			},
+			expected: DiagnosticsCaptureConfig{
+				Enabled:     true,
+				MaxCaptures: 3,
+				MinInterval: time.Minute,
+			},
+		},
+		"no captures kept": {
+			cfg: map[string]any{
+				"capture": map[string]any{
+					"max_captures": 0,
+				},
+			},
+			err: "max_captures must be at least 1",
+		},
+		"negative log lines": {
+			cfg: map[string]any{
+				"capture": map[string]any{
+					"log_lines": -1,
+				},
+			},
+			err: "log_lines must not be negative",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := DefaultDiagnosticsConfig()
+			cfg := config.MustNewConfigFrom(test.cfg)
+			err := cfg.UnpackTo(c)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, *c.Capture)
+		})
+	}
+}
+
+func TestParseDiagnosticsRedactionConfig(t *testing.T) {
+	c := DefaultDiagnosticsConfig()
+	redactor, err := c.Redaction.NewRedactor()
+	require.NoError(t, err)
+	assert.Nil(t, redactor, "no rule is set by default")
+
+	cfg := config.MustNewConfigFrom(map[string]any{
+		"redaction": map[string]any{
+			"rules": []any{
+				map[string]any{"name": "scripts", "key": "inputs.*.processors.**.source"},
+				map[string]any{"name": "customer", "value": "cust-[0-9]+", "hash": true},
+			},
+		},
+	})
+	require.NoError(t, cfg.UnpackTo(c))
+	assert.Equal(t, []redaction.Rule{
+		{Name: "scripts", Key: "inputs.*.processors.**.source"},
+		{Name: "customer", Value: "cust-[0-9]+", Hash: true},
+	}, c.Redaction.Rules)
+	redactor, err = c.Redaction.NewRedactor()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"scripts", "customer"}, redactor.Names())
+
+	cfg = config.MustNewConfigFrom(map[string]any{
+		"redaction": map[string]any{
+			"rules": []any{map[string]any{"name": "invalid", "value": "("}},
+		},
+	})
+	assert.ErrorContains(t, cfg.UnpackTo(DefaultDiagnosticsConfig()), `redaction rule "invalid" has an invalid value`)
+}
@@ -77,6 +77,9 @@ type FleetCheckin struct {
 	// Compression controls how checkin request bodies are encoded.
 	// Accepted values: "none" (no compression) or "gzip" (gzip compression).
 	Compression string `config:"compression" yaml:"compression,omitempty"`
+	// DeltaPolicy controls whether the agent offers Fleet to send policies as
+	// the changes relative to the policy it runs. Disabled when not set.
+	DeltaPolicy *bool `config:"delta_policy" yaml:"delta_policy,omitempty"`
 }
 
 // DefaultFleetCheckin returns a FleetCheckin with default values.
@@ -125,3 +128,12 @@ func (f *FleetCheckin) GetCompression() string {
 	}
 	return f.Compression
 }
+
+// IsDeltaPolicyEnabled returns true when the agent offers to receive policies
+// as deltas, defaulting to false.
+func (f *FleetCheckin) IsDeltaPolicyEnabled() bool {
+	if f == nil || f.DeltaPolicy == nil {
+		return false
+	}
+	return *f.DeltaPolicy
+}
@@ -0,0 +1,59 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/maintenance"
+)
+
+// MaintenanceConfig is the configuration of the maintenance windows. When enabled, upgrades
+// requested by Fleet, policy changes that stop running components and restarts are deferred
+// until the next maintenance window.
+type MaintenanceConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Timezone the window schedules are evaluated in, UTC when empty.
+	Timezone string                    `yaml:"timezone" config:"timezone" json:"timezone"`
+	Windows  []MaintenanceWindowConfig `yaml:"windows" config:"windows" json:"windows"`
+}
+
+// MaintenanceWindowConfig is a recurring maintenance window.
+type MaintenanceWindowConfig struct {
+	// Schedule is a 5 field cron expression (minute hour day-of-month month day-of-week) matching
+	// the start of the window.
+	Schedule string        `yaml:"schedule" config:"schedule" json:"schedule"`
+	Duration time.Duration `yaml:"duration" config:"duration" json:"duration"`
+}
+
+// Validate validates settings of configuration.
+func (c *MaintenanceConfig) Validate() error {
+	_, err := c.NewSchedule()
+	return err
+}
+
+// NewSchedule creates the schedule of the maintenance windows, nil when maintenance windows
+// are not enabled.
+func (c *MaintenanceConfig) NewSchedule() (*maintenance.Schedule, error) {
+	if c == nil || !c.Enabled {
+		return nil, nil
+	}
+	windows := make([]maintenance.Window, 0, len(c.Windows))
+	for _, w := range c.Windows {
+		window, err := maintenance.NewWindow(w.Schedule, w.Duration)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return maintenance.NewSchedule(c.Timezone, windows...)
+}
+
+// DefaultMaintenanceConfig creates a config with maintenance windows disabled.
+func DefaultMaintenanceConfig() *MaintenanceConfig {
+	return &MaintenanceConfig{
+		Enabled: false,
+	}
+}
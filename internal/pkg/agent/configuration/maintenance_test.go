@@ -0,0 +1,112 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+func TestParseMaintenanceConfig(t *testing.T) {
+	tests := map[string]struct {
+		cfg      map[string]any
+		expected MaintenanceConfig
+		schedule bool
+		err      string
+	}{
+		"default": {
+			cfg:      map[string]any{},
+			expected: MaintenanceConfig{},
+		},
+		"weekends": {
+			cfg: map[string]any{
+				"enabled":  true,
+				"timezone": "America/New_York",
+				"windows": []any{
+					map[string]any{
+						"schedule": "0 0 * * sat",
+						"duration": "48h",
+					},
+				},
+			},
+			expected: MaintenanceConfig{
+				Enabled:  true,
+				Timezone: "America/New_York",
+				Windows: []MaintenanceWindowConfig{
+					{Schedule: "0 0 * * sat", Duration: 48 * time.Hour},
+				},
+			},
+			schedule: true,
+		},
+		"disabled with invalid windows": {
+			cfg: map[string]any{
+				"windows": []any{
+					map[string]any{
+						"schedule": "invalid",
+					},
+				},
+			},
+			expected: MaintenanceConfig{
+				Windows: []MaintenanceWindowConfig{
+					{Schedule: "invalid"},
+				},
+			},
+		},
+		"enabled without windows": {
+			cfg: map[string]any{
+				"enabled": true,
+			},
+			err: "at least one maintenance window is required",
+		},
+		"invalid schedule": {
+			cfg: map[string]any{
+				"enabled": true,
+				"windows": []any{
+					map[string]any{
+						"schedule": "0 0 * *",
+						"duration": "1h",
+					},
+				},
+			},
+			err: "must have 5 fields",
+		},
+		"invalid time zone": {
+			cfg: map[string]any{
+				"enabled":  true,
+				"timezone": "Not/AZone",
+				"windows": []any{
+					map[string]any{
+						"schedule": "0 0 * * *",
+						"duration": "1h",
+					},
+				},
+			},
+			err: "unknown time zone",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := DefaultMaintenanceConfig()
+			cfg := config.MustNewConfigFrom(test.cfg)
+			err := cfg.UnpackTo(c)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, *c)
+
+			schedule, err := c.NewSchedule()
+			require.NoError(t, err)
+			assert.Equal(t, test.schedule, schedule != nil)
+		})
+	}
+}
@@ -18,10 +18,13 @@ type SettingsConfig struct {
 	DownloadConfig     *artifact.Config                `yaml:"download" config:"download" json:"download"`
 	ProcessConfig      *process.Config                 `yaml:"process" config:"process" json:"process"`
 	GRPC               *GRPCConfig                     `yaml:"grpc" config:"grpc" json:"grpc"`
+	Control            *ControlConfig                  `yaml:"control" config:"control" json:"control"`
 	MonitoringConfig   *monitoringCfg.MonitoringConfig `yaml:"monitoring" config:"monitoring" json:"monitoring"`
 	LoggingConfig      *logger.Config                  `yaml:"logging,omitempty" config:"logging,omitempty" json:"logging,omitempty"`
 	EventLoggingConfig *logger.Config                  `yaml:"logging.event_data,omitempty" config:"logging.event_data,omitempty" json:"logging.event_data,omitempty"`
 	Upgrade            *UpgradeConfig                  `yaml:"upgrade" config:"upgrade" json:"upgrade"`
+	Maintenance        *MaintenanceConfig              `yaml:"maintenance" config:"maintenance" json:"maintenance"`
+	Diagnostics        *DiagnosticsConfig              `yaml:"diagnostics" config:"diagnostics" json:"diagnostics"`
 	Collector          *CollectorConfig                `yaml:"collector" config:"collector" json:"collector"`
 	Internal           *InternalConfig                 `yaml:"internal" config:"internal" json:"internal"`
 
@@ -40,7 +43,10 @@ func DefaultSettingsConfig() *SettingsConfig {
 		EventLoggingConfig:  logger.DefaultEventLoggingConfig(),
 		MonitoringConfig:    monitoringCfg.DefaultConfig(),
 		GRPC:                DefaultGRPCConfig(),
+		Control:             DefaultControlConfig(),
 		Upgrade:             DefaultUpgradeConfig(),
+		Maintenance:         DefaultMaintenanceConfig(),
+		Diagnostics:         DefaultDiagnosticsConfig(),
 		Collector:           DefaultCollectorConfig(),
 		Internal:            DefaultInternalConfig(),
 		Reload:              DefaultReloadConfig(),
@@ -4,7 +4,11 @@
 
 package configuration
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 const (
 	// period during which we monitor for failures resulting in a rollback.
@@ -20,27 +24,132 @@ const (
 
 	// defaultRollbackCleanupInterval represents the interval between runs to cleanup available rollbacks
 	defaultRollbackCleanupInterval = 10 * time.Minute
+
+	// defaultRolloutCheckInterval is the interval between two reads of the rollout descriptor.
+	defaultRolloutCheckInterval = 10 * time.Minute
+
+	// ProbeTypeEventsPublished is the type of the probes checking a component publishes events.
+	ProbeTypeEventsPublished = "events_published"
+	// ProbeTypeHTTP is the type of the probes checking the status of an HTTP endpoint.
+	ProbeTypeHTTP = "http"
+	// ProbeTypeCommand is the type of the probes running a command.
+	ProbeTypeCommand = "command"
 )
 
 // UpgradeConfig is the configuration related to Agent upgrades.
 type UpgradeConfig struct {
 	Watcher  *UpgradeWatcherConfig  `yaml:"watcher" config:"watcher" json:"watcher"`
 	Rollback *UpgradeRollbackConfig `yaml:"rollback" config:"rollback" json:"rollback"`
+	Rollout  *UpgradeRolloutConfig  `yaml:"rollout" config:"rollout" json:"rollout"`
 }
 
 type UpgradeWatcherConfig struct {
 	GracePeriod time.Duration             `yaml:"grace_period" config:"grace_period" json:"grace_period"`
 	ErrorCheck  UpgradeWatcherCheckConfig `yaml:"error_check" config:"error_check" json:"error_check"`
+	// Probes are the health probes run during the watch period, on top of the state
+	// reported by the upgraded Agent. A failing probe rolls the upgrade back.
+	Probes []UpgradeWatcherProbeConfig `yaml:"probes,omitempty" config:"probes" json:"probes,omitempty"`
+}
+
+// Validate validates settings of configuration.
+func (c *UpgradeWatcherConfig) Validate() error {
+	names := make(map[string]bool, len(c.Probes))
+	for _, probe := range c.Probes {
+		if names[probe.Name] {
+			return fmt.Errorf("probe %q is defined more than once", probe.Name)
+		}
+		names[probe.Name] = true
+	}
+	return nil
 }
+
 type UpgradeWatcherCheckConfig struct {
 	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
 }
 
+// UpgradeWatcherProbeConfig is the configuration of a health probe run by the upgrade watcher.
+type UpgradeWatcherProbeConfig struct {
+	// Name identifies the probe in the logs and in the upgrade details.
+	Name string `yaml:"name" config:"name" json:"name"`
+	// Type is one of events_published, http or command.
+	Type string `yaml:"type" config:"type" json:"type"`
+	// Component is the ID of the component checked by the events_published and http probes.
+	Component string `yaml:"component,omitempty" config:"component" json:"component,omitempty"`
+	// Within is the time after the start of the watch in which the component must have
+	// published events, for the events_published probes.
+	Within time.Duration `yaml:"within,omitempty" config:"within" json:"within,omitempty"`
+	// URL is the endpoint checked by the http probes, the /processes/{component} endpoint
+	// of the monitoring server by default.
+	URL string `yaml:"url,omitempty" config:"url" json:"url,omitempty"`
+	// Status is the status code expected by the http probes, 200 by default.
+	Status int `yaml:"status,omitempty" config:"status" json:"status,omitempty"`
+	// Command is run by the command probes, an exit code other than 0 is a failed check.
+	Command string   `yaml:"command,omitempty" config:"command" json:"command,omitempty"`
+	Args    []string `yaml:"args,omitempty" config:"args" json:"args,omitempty"`
+	// Interval is the time between two checks, the error check interval by default.
+	Interval time.Duration `yaml:"interval,omitempty" config:"interval" json:"interval,omitempty"`
+	// Timeout bounds the time of a check, 10 seconds by default.
+	Timeout time.Duration `yaml:"timeout,omitempty" config:"timeout" json:"timeout,omitempty"`
+	// FailureThreshold is the number of consecutive failed checks failing the probe, 3 by default.
+	FailureThreshold int `yaml:"failure_threshold,omitempty" config:"failure_threshold" json:"failure_threshold,omitempty"`
+}
+
+// Validate validates settings of configuration.
+func (c *UpgradeWatcherProbeConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("probe must have a name")
+	}
+	switch c.Type {
+	case ProbeTypeEventsPublished:
+		if c.Component == "" {
+			return fmt.Errorf("probe %q must have a component", c.Name)
+		}
+		if c.Within <= 0 {
+			return fmt.Errorf("probe %q must have a positive within", c.Name)
+		}
+	case ProbeTypeHTTP:
+		if c.Component == "" && c.URL == "" {
+			return fmt.Errorf("probe %q must have a component or a url", c.Name)
+		}
+	case ProbeTypeCommand:
+		if c.Command == "" {
+			return fmt.Errorf("probe %q must have a command", c.Name)
+		}
+	default:
+		return fmt.Errorf("probe %q has an unknown type %q, must be one of %s, %s or %s", c.Name, c.Type, ProbeTypeEventsPublished, ProbeTypeHTTP, ProbeTypeCommand)
+	}
+	if c.Interval < 0 || c.Timeout < 0 || c.FailureThreshold < 0 || c.Status < 0 {
+		return fmt.Errorf("probe %q must not have negative settings", c.Name)
+	}
+	return nil
+}
+
 type UpgradeRollbackConfig struct {
 	Window          time.Duration `yaml:"window" config:"window" json:"window"`
 	CleanupInterval time.Duration `yaml:"cleanup_interval" config:"cleanup_interval" json:"cleanup_interval"`
 }
 
+// UpgradeRolloutConfig is the configuration of the staged upgrades of standalone Agents, the
+// Agents read a shared rollout descriptor and upgrade when the wave of their cohort opens.
+type UpgradeRolloutConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Source is the path or the http(s) URL of the rollout descriptor.
+	Source string `yaml:"source" config:"source" json:"source"`
+	// CheckInterval is the time between two reads of the rollout descriptor.
+	CheckInterval time.Duration `yaml:"check_interval" config:"check_interval" json:"check_interval"`
+}
+
+// Validate validates settings of configuration.
+func (c *UpgradeRolloutConfig) Validate() error {
+	if c.Enabled && c.Source == "" {
+		return errors.New("source must be set when the rollout is enabled")
+	}
+	if c.CheckInterval <= 0 {
+		return errors.New("check_interval must be greater than 0")
+	}
+	return nil
+}
+
 func DefaultUpgradeConfig() *UpgradeConfig {
 	return &UpgradeConfig{
 		Watcher: &UpgradeWatcherConfig{
@@ -53,5 +162,9 @@ func DefaultUpgradeConfig() *UpgradeConfig {
 			Window:          defaultRollbackWindowDuration,
 			CleanupInterval: defaultRollbackCleanupInterval,
 		},
+		Rollout: &UpgradeRolloutConfig{
+			Enabled:       false,
+			CheckInterval: defaultRolloutCheckInterval,
+		},
 	}
 }
@@ -31,6 +31,7 @@ func TestParseUpgradeConfig(t *testing.T) {
 					Window:          defaultRollbackWindowDuration,
 					CleanupInterval: defaultRollbackCleanupInterval,
 				},
+				Rollout: defaultRolloutConfig(),
 			},
 		},
 		"watcher_grace_period": {
@@ -50,6 +51,7 @@ func TestParseUpgradeConfig(t *testing.T) {
 					Window:          defaultRollbackWindowDuration,
 					CleanupInterval: defaultRollbackCleanupInterval,
 				},
+				Rollout: defaultRolloutConfig(),
 			},
 		},
 		"watcher_error_check_interval": {
@@ -71,6 +73,7 @@ func TestParseUpgradeConfig(t *testing.T) {
 					Window:          defaultRollbackWindowDuration,
 					CleanupInterval: defaultRollbackCleanupInterval,
 				},
+				Rollout: defaultRolloutConfig(),
 			},
 		},
 		"rollback_window": {
@@ -88,6 +91,7 @@ func TestParseUpgradeConfig(t *testing.T) {
 					Window:          8 * time.Hour,
 					CleanupInterval: defaultRollbackCleanupInterval,
 				},
+				Rollout: defaultRolloutConfig(),
 			},
 		},
 		"cleanup_interval": {
@@ -105,6 +109,59 @@ func TestParseUpgradeConfig(t *testing.T) {
 					Window:          defaultRollbackWindowDuration,
 					CleanupInterval: 1 * time.Minute,
 				},
+				Rollout: defaultRolloutConfig(),
+			},
+		},
+		"rollout": {
+			cfg: map[string]any{
+				"rollout": map[string]any{
+					"enabled": true,
+					"source":  "https://artifacts.example.com/rollout.json",
+				},
+			},
+			expected: UpgradeConfig{
+				Watcher: &UpgradeWatcherConfig{
+					GracePeriod: defaultGracePeriodDuration,
+					ErrorCheck: UpgradeWatcherCheckConfig{
+						Interval: defaultStatusCheckInterval,
+					},
+				},
+				Rollback: &UpgradeRollbackConfig{
+					Window:          defaultRollbackWindowDuration,
+					CleanupInterval: defaultRollbackCleanupInterval,
+				},
+				Rollout: &UpgradeRolloutConfig{
+					Enabled:       true,
+					Source:        "https://artifacts.example.com/rollout.json",
+					CheckInterval: defaultRolloutCheckInterval,
+				},
+			},
+		},
+		"watcher_probes": {
+			cfg: map[string]any{
+				"watcher": map[string]any{
+					"probes": []any{
+						map[string]any{"name": "publishing", "type": "events_published", "component": "filestream-default", "within": "5m"},
+						map[string]any{"name": "script", "type": "command", "command": "/usr/local/bin/check", "args": []any{"--quiet"}, "failure_threshold": 3},
+					},
+				},
+			},
+			expected: UpgradeConfig{
+				Watcher: &UpgradeWatcherConfig{
+					GracePeriod: defaultGracePeriodDuration,
+					ErrorCheck: UpgradeWatcherCheckConfig{
+						Interval: defaultStatusCheckInterval,
+					},
+					Probes: []UpgradeWatcherProbeConfig{
+						{Name: "publishing", Type: ProbeTypeEventsPublished, Component: "filestream-default", Within: 5 * time.Minute},
+						{Name: "script", Type: ProbeTypeCommand, Command: "/usr/local/bin/check", Args: []string{"--quiet"}, FailureThreshold: 3},
+					},
+				},
+				Rollback: &UpgradeRollbackConfig{
+					Window:          defaultRollbackWindowDuration,
+					CleanupInterval: defaultRollbackCleanupInterval,
+				},
+				Rollout: defaultRolloutConfig(),
 			},
 		},
 	}
@@ -118,3 +175,42 @@ func TestParseUpgradeConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestParseUpgradeWatcherProbesInvalid(t *testing.T) {
+	for name, probes := range map[string][]any{
+		"no name":                    {map[string]any{"type": "command", "command": "check"}},
+		"unknown type":               {map[string]any{"name": "p", "type": "ping"}},
+		"events without component":   {map[string]any{"name": "p", "type": "events_published", "within": "5m"}},
+		"events without within":      {map[string]any{"name": "p", "type": "events_published", "component": "filestream-default"}},
+		"http without target":        {map[string]any{"name": "p", "type": "http"}},
+		"command without command":    {map[string]any{"name": "p", "type": "command"}},
+		"negative failure threshold": {map[string]any{"name": "p", "type": "command", "command": "check", "failure_threshold": -1}},
+		"duplicated name": {
+			map[string]any{"name": "p", "type": "command", "command": "check"},
+			map[string]any{"name": "p", "type": "http", "url": "http://localhost:6791/processes/filestream-default"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := DefaultUpgradeConfig()
+			cfg := config.MustNewConfigFrom(map[string]any{"watcher.probes": probes})
+			require.Error(t, cfg.UnpackTo(c))
+		})
+	}
+}
+
+func TestParseUpgradeRolloutInvalid(t *testing.T) {
+	for name, rollout := range map[string]map[string]any{
+		"enabled without source": {"enabled": true},
+		"zero check interval":    {"check_interval": "0s"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := DefaultUpgradeConfig()
+			cfg := config.MustNewConfigFrom(map[string]any{"rollout": rollout})
+			require.Error(t, cfg.UnpackTo(c))
+		})
+	}
+}
+
+func defaultRolloutConfig() *UpgradeRolloutConfig {
+	return &UpgradeRolloutConfig{CheckInterval: defaultRolloutCheckInterval}
+}
@@ -0,0 +1,275 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package componentvalidation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vars"
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// defaultSimulationProvider is the default provider used for the canned variables when
+// the variables file does not define one. It matches the default of the composable controller.
+const defaultSimulationProvider = "env"
+
+// SimulationVars is the format of the file containing the canned composable provider values
+// used when simulating a policy.
+//
+// Example:
+//
+//	default_provider: env
+//	context:
+//	  host:
+//	    name: web-01
+//	dynamic:
+//	  - provider: docker
+//	    id: container-1
+//	    mapping:
+//	      container:
+//	        name: nginx
+//	    processors:
+//	      - add_fields:
+//	          target: container
+//	          fields:
+//	            name: nginx
+type SimulationVars struct {
+	DefaultProvider string                     `yaml:"default_provider"`
+	Context         map[string]interface{}     `yaml:"context"`
+	Dynamic         []SimulationDynamicMapping `yaml:"dynamic"`
+}
+
+// SimulationDynamicMapping is a single mapping provided by a dynamic provider.
+type SimulationDynamicMapping struct {
+	Provider   string                 `yaml:"provider"`
+	ID         string                 `yaml:"id"`
+	Mapping    map[string]interface{} `yaml:"mapping"`
+	Processors transpiler.Processors  `yaml:"processors"`
+}
+
+// SimulationResult is the outcome of running a policy through the component model generation.
+type SimulationResult struct {
+	// Config is the policy with all variables substituted.
+	Config map[string]interface{}
+	// Components are the components computed from the policy, before capabilities are applied.
+	Components []component.Component
+}
+
+// simulatedHeaders is used in place of the agent information so the simulation does not depend
+// on the state of an installed Elastic Agent.
+type simulatedHeaders struct{}
+
+func (simulatedHeaders) Headers() map[string]string {
+	return nil
+}
+
+// LoadSimulationVars reads the canned composable provider values from the provided path and
+// converts them to the same set of variables the composable controller would produce.
+func LoadSimulationVars(path string) ([]*transpiler.Vars, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variables file %s: %w", path, err)
+	}
+	var sv SimulationVars
+	if err := yaml.Unmarshal(data, &sv); err != nil {
+		return nil, fmt.Errorf("failed to parse variables file %s: %w", path, err)
+	}
+	return sv.toVars()
+}
+
+func (sv SimulationVars) toVars() ([]*transpiler.Vars, error) {
+	defaultProvider := sv.DefaultProvider
+	if defaultProvider == "" {
+		defaultProvider = defaultSimulationProvider
+	}
+	contextMapping := sv.Context
+	if contextMapping == nil {
+		contextMapping = map[string]interface{}{}
+	}
+	mapping, err := transpiler.NewAST(contextMapping)
+	if err != nil {
+		return nil, fmt.Errorf("invalid context variables: %w", err)
+	}
+
+	result := make([]*transpiler.Vars, 0, len(sv.Dynamic)+1)
+	result = append(result, transpiler.NewVarsFromAst("", mapping, nil, defaultProvider))
+	for i, d := range sv.Dynamic {
+		if d.Provider == "" {
+			return nil, fmt.Errorf("dynamic mapping %d is missing the provider name", i)
+		}
+		dynamicMapping, err := transpiler.NewAST(d.Mapping)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mapping for dynamic provider %q: %w", d.Provider, err)
+		}
+		local := mapping.ShallowClone()
+		if err := local.Insert(dynamicMapping, d.Provider); err != nil {
+			return nil, fmt.Errorf("failed to insert mapping for dynamic provider %q: %w", d.Provider, err)
+		}
+		result = append(result, transpiler.NewVarsWithProcessorsFromAst(
+			fmt.Sprintf("%s-%s", d.Provider, d.ID),
+			local,
+			d.Provider,
+			d.Processors,
+			nil,
+			defaultProvider,
+			d.Provider,
+		))
+	}
+	return result, nil
+}
+
+// SimulatePolicy runs the policy at policyPath through the same steps the coordinator performs
+// when a new policy is received: variable substitution and component model generation, including
+// the runtime preventions of the component specifications. It never communicates with a running
+// Elastic Agent.
+//
+// When varsPath is empty the composable providers defined in the policy are started to gather
+// the variables, waiting up to variablesWait for them to be discovered.
+func SimulatePolicy(ctx context.Context, l *logger.Logger, policyPath string, varsPath string, variablesWait time.Duration, platformModifiers ...component.PlatformModifier) (*SimulationResult, error) {
+	platform, err := component.LoadPlatformDetail(platformModifiers...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather system information: %w", err)
+	}
+	specs, err := component.LoadRuntimeSpecs(paths.Components(), platform)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect inputs and outputs: %w", err)
+	}
+
+	rawCfg, err := config.LoadFile(policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policy %s: %w", policyPath, err)
+	}
+	if err := info.InjectAgentConfig(rawCfg); err != nil {
+		return nil, fmt.Errorf("failed to inject agent configuration: %w", err)
+	}
+	cfg, err := configuration.NewFromConfig(rawCfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", policyPath, err)
+	}
+	lvl := logger.DefaultLogLevel
+	if cfg.Settings.LoggingConfig != nil {
+		lvl = cfg.Settings.LoggingConfig.Level
+	}
+
+	var policyVars []*transpiler.Vars
+	if varsPath != "" {
+		policyVars, err = LoadSimulationVars(varsPath)
+	} else {
+		policyVars, err = vars.WaitForVariables(ctx, l, rawCfg, variablesWait)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := rawCfg.ToMapStr()
+	if err != nil {
+		return nil, fmt.Errorf("could not create the map from the policy: %w", err)
+	}
+	rendered, dynamicInputs, err := renderPolicy(m, policyVars)
+	if err != nil {
+		return nil, err
+	}
+
+	comps, err := specs.ToComponents(
+		rendered,
+		cfg.Settings.Internal.Runtime,
+		nil,
+		nil,
+		lvl,
+		simulatedHeaders{},
+		map[string]uint64{},
+		dynamicInputs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render components: %w", err)
+	}
+
+	return &SimulationResult{
+		Config:     rendered,
+		Components: comps,
+	}, nil
+}
+
+// ApplyCapabilities removes the inputs and the outputs of the rendered policy that are blocked by
+// the capabilities, the same way the Elastic Agent blocks the components using them.
+func ApplyCapabilities(rendered map[string]interface{}, caps capabilities.Capabilities) map[string]interface{} {
+	if inputs, ok := rendered["inputs"].([]interface{}); ok {
+		allowed := make([]interface{}, 0, len(inputs))
+		for _, input := range inputs {
+			inputMap, ok := input.(map[string]interface{})
+			if ok && !caps.AllowInput(typeOf(inputMap)) {
+				continue
+			}
+			allowed = append(allowed, input)
+		}
+		rendered["inputs"] = allowed
+	}
+	if outputs, ok := rendered["outputs"].(map[string]interface{}); ok {
+		for name, output := range outputs {
+			outputMap, ok := output.(map[string]interface{})
+			if ok && !caps.AllowOutput(typeOf(outputMap)) {
+				delete(outputs, name)
+			}
+		}
+	}
+	return rendered
+}
+
+func typeOf(m map[string]interface{}) string {
+	t, _ := m["type"].(string)
+	return t
+}
+
+// renderPolicy performs the variable substitution for the inputs and the outputs of the policy.
+func renderPolicy(m map[string]interface{}, policyVars []*transpiler.Vars) (map[string]interface{}, map[string]bool, error) {
+	ast, err := transpiler.NewAST(m)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create the AST from the policy: %w", err)
+	}
+
+	dynamicInputs := map[string]bool{}
+	inputs, ok := transpiler.Lookup(ast, "inputs")
+	if ok {
+		renderedInputs, inputToDynamicProvider, err := transpiler.RenderInputs(inputs, policyVars)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rendering inputs failed: %w", err)
+		}
+		if err := transpiler.Insert(ast, renderedInputs, "inputs"); err != nil {
+			return nil, nil, fmt.Errorf("inserting rendered inputs failed: %w", err)
+		}
+		for inputID := range inputToDynamicProvider {
+			dynamicInputs[inputID] = true
+		}
+	}
+
+	outputs, ok := transpiler.Lookup(ast, "outputs")
+	if ok {
+		renderedOutputs, err := transpiler.RenderOutputs(outputs, policyVars)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rendering outputs failed: %w", err)
+		}
+		if err := transpiler.Insert(ast, renderedOutputs, "outputs"); err != nil {
+			return nil, nil, fmt.Errorf("inserting rendered outputs failed: %w", err)
+		}
+	}
+
+	rendered, err := ast.Map()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert ast to map[string]interface{}: %w", err)
+	}
+	return rendered, dynamicInputs, nil
+}
@@ -0,0 +1,113 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package componentvalidation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestLoadSimulationVars(t *testing.T) {
+	varsPath := filepath.Join(t.TempDir(), "vars.yml")
+	require.NoError(t, os.WriteFile(varsPath, []byte(`
+context:
+  host:
+    name: web-01
+dynamic:
+  - provider: docker
+    id: c1
+    mapping:
+      container:
+        id: abcd
+`), 0o600))
+
+	vars, err := LoadSimulationVars(varsPath)
+	require.NoError(t, err)
+	require.Len(t, vars, 2)
+	assert.Equal(t, "", vars[0].ID())
+	assert.Equal(t, "docker-c1", vars[1].ID())
+
+	hostName, ok := vars[1].Lookup("host.name")
+	require.True(t, ok, "context variables must be available to the dynamic mappings")
+	assert.Equal(t, "web-01", hostName)
+
+	policy := map[string]interface{}{
+		"inputs": []interface{}{
+			map[string]interface{}{
+				"id":    "logs",
+				"type":  "filestream",
+				"paths": []interface{}{"/var/log/${host.name}.log"},
+			},
+			map[string]interface{}{
+				"id":    "container",
+				"type":  "filestream",
+				"paths": []interface{}{"/var/lib/docker/${docker.container.id}.log"},
+			},
+		},
+	}
+	rendered, dynamicInputs, err := renderPolicy(policy, vars)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"container-docker-c1": true}, dynamicInputs)
+
+	inputs, ok := rendered["inputs"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, inputs, 2)
+	assert.Equal(t, []interface{}{"/var/log/web-01.log"}, inputs[0].(map[string]interface{})["paths"])
+	assert.Equal(t, "container-docker-c1", inputs[1].(map[string]interface{})["id"])
+	assert.Equal(t, []interface{}{"/var/lib/docker/abcd.log"}, inputs[1].(map[string]interface{})["paths"])
+}
+
+func TestLoadSimulationVarsMissingProvider(t *testing.T) {
+	varsPath := filepath.Join(t.TempDir(), "vars.yml")
+	require.NoError(t, os.WriteFile(varsPath, []byte(`
+dynamic:
+  - id: c1
+    mapping:
+      container:
+        id: abcd
+`), 0o600))
+
+	_, err := LoadSimulationVars(varsPath)
+	assert.ErrorContains(t, err, "missing the provider name")
+}
+
+func TestApplyCapabilities(t *testing.T) {
+	log, _ := loggertest.New(t.Name())
+	caps, err := capabilities.Load(strings.NewReader(`
+version: 0.1.0
+capabilities:
+  - rule: deny
+    input: system/metrics
+  - rule: deny
+    output: kafka
+`), log)
+	require.NoError(t, err)
+
+	rendered := ApplyCapabilities(map[string]interface{}{
+		"inputs": []interface{}{
+			map[string]interface{}{"id": "logs", "type": "filestream"},
+			map[string]interface{}{"id": "system", "type": "system/metrics"},
+		},
+		"outputs": map[string]interface{}{
+			"default": map[string]interface{}{"type": "elasticsearch"},
+			"events":  map[string]interface{}{"type": "kafka"},
+		},
+	}, caps)
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": "logs", "type": "filestream"},
+	}, rendered["inputs"])
+	assert.Equal(t, map[string]interface{}{
+		"default": map[string]interface{}{"type": "elasticsearch"},
+	}, rendered["outputs"])
+}
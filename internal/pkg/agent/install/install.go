@@ -23,6 +23,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/perms"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	v1 "github.com/elastic/elastic-agent/pkg/api/v1"
@@ -43,7 +44,7 @@ const (
 )
 
 // Install installs Elastic Agent persistently on the system including creating and starting its service.
-func Install(cfgFile, topPath string, unprivileged bool, log *logp.Logger, pt ProgressDescriber, streams *cli.IOStreams, customUser, customGroup, userPassword string, flavor string) (utils.FileOwner, error) {
+func Install(cfgFile, topPath string, unprivileged bool, log *logp.Logger, pt ProgressDescriber, streams *cli.IOStreams, customUser, customGroup, userPassword string, flavor string, vaultBackend vault.BackendConfig) (utils.FileOwner, error) {
 	dir, err := findDirectory()
 	if err != nil {
 		return utils.FileOwner{}, errors.New(err, "failed to discover the source directory for installation", errors.TypeFilesystem)
@@ -103,6 +104,13 @@ func Install(cfgFile, topPath string, unprivileged bool, log *logp.Logger, pt Pr
 
 	pt.Describe("Successfully copied files")
 
+	// record the vault backend before the secrets are created
+	if vaultBackend.Backend != "" {
+		if err := vault.SaveBackend(filepath.Join(topPath, paths.DefaultAgentVaultPath), vaultBackend); err != nil {
+			return utils.FileOwner{}, fmt.Errorf("failed to record the vault backend: %w", err)
+		}
+	}
+
 	// Check if standalone-agent install needs to encrypt config.
 	rawConfig, err := config.LoadFile(cfgFile)
 	if err != nil {
@@ -66,7 +66,7 @@ func Uninstall(ctx context.Context, cfgFile, topPath, uninstallToken string, log
 	}
 
 	// check if the agent was installed using --unprivileged by checking the file vault for the agent secret (needed on darwin to correctly load the vault)
-	unprivileged, err := checkForUnprivilegedVault(ctx)
+	unprivileged, err := CheckForUnprivilegedVault(ctx)
 	if err != nil {
 		return fmt.Errorf("error checking for unprivileged vault: %w", err)
 	}
@@ -167,6 +167,9 @@ func Uninstall(ctx context.Context, cfgFile, topPath, uninstallToken string, log
 	// of RemovePath is more complex. See https://github.com/elastic/elastic-agent/issues/14142 and https://github.com/elastic/elastic-agent/issues/8428.
 	notifyFleetIfNeeded(ctx, log, pt, cfg, agentID, notifyFleet, localFleet, skipFleetAudit, notifyFleetAuditUninstall)
 
+	// the token outlives the install directory
+	removeVaultSecrets(ctx, pt)
+
 	// remove existing directory
 	pt.Describe("Removing install directory")
 	err = RemovePath(log, topPath)
@@ -265,7 +268,9 @@ func EnsureStoppedService(topPath string, pt ProgressDescriber) (service.Status,
 	return status, nil
 }
 
-func checkForUnprivilegedVault(ctx context.Context, opts ...vault.OptionFunc) (bool, error) {
+// CheckForUnprivilegedVault reports if the file vault holds the agent secret, the vault of an
+// unprivileged Elastic Agent.
+func CheckForUnprivilegedVault(ctx context.Context, opts ...vault.OptionFunc) (bool, error) {
 	// check if we have a file vault to detect if we have to use it for reading config
 	opts = append(opts, vault.WithReadonly(true))
 	vaultOpts, err := vault.ApplyOptions(opts...)
@@ -286,6 +291,20 @@ func checkForUnprivilegedVault(ctx context.Context, opts ...vault.OptionFunc) (b
 	return false, nil
 }
 
+// removeVaultSecrets removes the agent secret from the pkcs11 vault backend, the token stores it
+// outside of the install directory.
+func removeVaultSecrets(ctx context.Context, pt ProgressDescriber) {
+	backend, err := vault.LoadBackend(paths.AgentVaultPath())
+	if err != nil || backend.Backend != vault.BackendPKCS11 {
+		return
+	}
+	if err := secret.Remove(ctx, secret.AgentSecretKey); err != nil {
+		pt.Describe(fmt.Sprintf("Failed to remove the agent secret from the %s vault: %s", backend.Backend, err))
+		return
+	}
+	pt.Describe(fmt.Sprintf("Removed the agent secret from the %s vault", backend.Backend))
+}
+
 // RemovePath helps with removal of a path where there is a probability of
 // running into a running executable that might prevent removal on Windows.
 //
@@ -30,7 +30,7 @@ import (
 	pkgfleetapi "github.com/elastic/elastic-agent/pkg/fleetapi"
 )
 
-func Test_checkForUnprivilegedVault(t *testing.T) {
+func Test_CheckForUnprivilegedVault(t *testing.T) {
 	type postVaultInit func(t *testing.T, vaultPath string)
 
 	type setup struct {
@@ -105,11 +105,11 @@ func Test_checkForUnprivilegedVault(t *testing.T) {
 				}
 			}
 
-			got, err := checkForUnprivilegedVault(ctx, vault.WithVaultPath(testVaultPath))
-			if !tt.wantErr(t, err, fmt.Sprintf("checkForUnprivilegedVault(ctx, vault.WithVaultPath(%q))", testVaultPath)) {
+			got, err := CheckForUnprivilegedVault(ctx, vault.WithVaultPath(testVaultPath))
+			if !tt.wantErr(t, err, fmt.Sprintf("CheckForUnprivilegedVault(ctx, vault.WithVaultPath(%q))", testVaultPath)) {
 				return
 			}
-			assert.Equalf(t, tt.want, got, "checkForUnprivilegedVault(ctx, vault.WithVaultPath(%q))", testVaultPath)
+			assert.Equalf(t, tt.want, got, "CheckForUnprivilegedVault(ctx, vault.WithVaultPath(%q))", testVaultPath)
 		})
 	}
 }
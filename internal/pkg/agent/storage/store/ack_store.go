@@ -0,0 +1,379 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/fleetapi"
+)
+
+// AckStoreVersion is the current AckStore version. If any breaking change is
+// introduced, it should be increased.
+const AckStoreVersion = "1"
+
+// DefaultAckStoreSize is the default number of pending acks kept in the store.
+const DefaultAckStoreSize = 1000
+
+// PendingAck is an action acknowledgement that has not been delivered to Fleet
+// yet.
+type PendingAck struct {
+	ActionID   string `json:"action_id" yaml:"action_id"`
+	ActionType string `json:"action_type" yaml:"action_type"`
+	// Attempts is the number of failed attempts to deliver the ack.
+	Attempts      int       `json:"attempts" yaml:"attempts"`
+	LastError     string    `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+	EnqueuedAt    time.Time `json:"enqueued_at" yaml:"enqueued_at"`
+	LastAttemptAt time.Time `json:"last_attempt_at,omitempty" yaml:"last_attempt_at,omitempty"`
+
+	// ActionError, RetryAttempt, UploadID and the input action fields are the
+	// fields of the action reported in its ack. The action itself is not
+	// persisted, it can hold secrets like a policy or an enrollment token.
+	ActionError  string `json:"action_error,omitempty" yaml:"-"`
+	RetryAttempt int    `json:"retry_attempt,omitempty" yaml:"-"`
+	UploadID     string `json:"upload_id,omitempty" yaml:"-"`
+
+	InputType   string                 `json:"input_type,omitempty" yaml:"-"`
+	Data        json.RawMessage        `json:"data,omitempty" yaml:"-"`
+	Response    map[string]interface{} `json:"response,omitempty" yaml:"-"`
+	StartedAt   string                 `json:"started_at,omitempty" yaml:"-"`
+	CompletedAt string                 `json:"completed_at,omitempty" yaml:"-"`
+}
+
+// AckStore persists the acks that have not been delivered to Fleet yet so
+// they can be replayed after a restart. The oldest ack is discarded once the
+// store is full.
+type AckStore struct {
+	log      *logger.Logger
+	store    saveLoader
+	size     int
+	entries  []PendingAck
+	onChange func([]PendingAck)
+
+	mx sync.Mutex
+}
+
+type ackStoreState struct {
+	Version string       `json:"version"`
+	Entries []PendingAck `json:"entries,omitempty"`
+}
+
+// NewEncryptedAckStore creates a new ack store persisted to an encrypted disk
+// store at path.
+func NewEncryptedAckStore(
+	ctx context.Context,
+	log *logger.Logger,
+	path string,
+	size int,
+	storageOpts ...storage.EncryptedOptionFunc) (*AckStore, error) {
+	diskStore, err := storage.NewEncryptedDiskStore(ctx, path, storageOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create EncryptedDiskStore for the ack store: %w", err)
+	}
+	return NewAckStore(log, diskStore, size), nil
+}
+
+// NewAckStore creates a new ack store keeping up to size pending acks. If the
+// persisted acks cannot be read an empty store is returned.
+func NewAckStore(log *logger.Logger, store saveLoader, size int) *AckStore {
+	if size <= 0 {
+		size = DefaultAckStoreSize
+	}
+	s := &AckStore{
+		log:   log,
+		store: store,
+		size:  size,
+	}
+
+	reader, err := store.Load()
+	if err != nil {
+		log.Warnf("failed to load ack store, starting with no pending acks: %v", err)
+		return s
+	}
+	defer reader.Close()
+
+	st, err := readAckStore(reader)
+	if err != nil {
+		log.Warnf("failed to parse ack store, starting with no pending acks: %v", err)
+		return s
+	}
+	if st.Version != AckStoreVersion {
+		log.Warnf("invalid ack store version, current version is %q loaded version is %q, starting with no pending acks",
+			AckStoreVersion, st.Version)
+		return s
+	}
+	s.entries = st.Entries
+	s.trim()
+	return s
+}
+
+func readAckStore(reader io.Reader) (ackStoreState, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return ackStoreState{}, fmt.Errorf("could not read ack store: %w", err)
+	}
+	if len(data) == 0 {
+		// empty file
+		return ackStoreState{Version: AckStoreVersion}, nil
+	}
+
+	var st ackStoreState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return ackStoreState{}, fmt.Errorf("could not parse JSON: %w", err)
+	}
+	return st, nil
+}
+
+// OnChange registers fn to be called with the pending acks every time they
+// change. fn is called with the lock of the store held and must not block.
+func (s *AckStore) OnChange(fn func([]PendingAck)) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.onChange = fn
+}
+
+// Add records the acks of the actions as pending and persists the store.
+// Adding an action that is already pending updates its content and keeps its
+// attempts.
+func (s *AckStore) Add(actions ...fleetapi.Action) error {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now().UTC()
+	for _, action := range actions {
+		entry := newPendingAck(action, now)
+		if i := s.find(action.ID()); i != -1 {
+			entry.EnqueuedAt = s.entries[i].EnqueuedAt
+			entry.Attempts = s.entries[i].Attempts
+			entry.LastError = s.entries[i].LastError
+			entry.LastAttemptAt = s.entries[i].LastAttemptAt
+			s.entries[i] = entry
+			continue
+		}
+		s.entries = append(s.entries, entry)
+	}
+	s.trim()
+	return s.save()
+}
+
+// Acked removes the acks of the actions delivered to Fleet and persists the
+// store.
+func (s *AckStore) Acked(actions ...fleetapi.Action) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	changed := false
+	for _, action := range actions {
+		if i := s.find(action.ID()); i != -1 {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// Failed records a failed attempt to deliver the acks of the actions and
+// persists the store. Actions that are not pending are added.
+func (s *AckStore) Failed(ackErr error, actions ...fleetapi.Action) error {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now().UTC()
+	for _, action := range actions {
+		i := s.find(action.ID())
+		if i == -1 {
+			s.entries = append(s.entries, newPendingAck(action, now))
+			i = len(s.entries) - 1
+		}
+		s.entries[i].Attempts++
+		s.entries[i].LastAttemptAt = now
+		if ackErr != nil {
+			s.entries[i].LastError = ackErr.Error()
+		}
+	}
+	s.trim()
+	return s.save()
+}
+
+// Pending returns a copy of the pending acks, from the oldest to the latest.
+func (s *AckStore) Pending() []PendingAck {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.pending()
+}
+
+// Actions rebuilds the actions of the pending acks so they can be acked
+// again. The actions only hold the fields reported in their ack.
+func (s *AckStore) Actions() []fleetapi.Action {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	actions := make([]fleetapi.Action, 0, len(s.entries))
+	for _, entry := range s.entries {
+		action, err := entry.action()
+		if err != nil {
+			s.log.Warnf("failed to restore pending ack of action %s, skipping it: %v", entry.ActionID, err)
+			continue
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+func newPendingAck(action fleetapi.Action, now time.Time) PendingAck {
+	entry := PendingAck{
+		ActionID:   action.ID(),
+		ActionType: action.Type(),
+		EnqueuedAt: now,
+	}
+	if err := actionError(action); err != nil {
+		entry.ActionError = err.Error()
+	}
+	switch a := action.(type) {
+	case fleetapi.RetryableAction:
+		entry.RetryAttempt = a.RetryAttempt()
+	case *fleetapi.ActionDiagnostics:
+		entry.UploadID = a.UploadID
+	case *fleetapi.ActionApp:
+		entry.InputType = a.InputType
+		entry.Data = a.Data
+		entry.Response = a.Response
+		entry.StartedAt = a.StartedAt
+		entry.CompletedAt = a.CompletedAt
+	}
+	return entry
+}
+
+// action rebuilds the action of the pending ack from its ID, type and the
+// fields reported in its ack.
+func (e PendingAck) action() (fleetapi.Action, error) {
+	// every action decodes its ID and type from the "id" and "type" keys
+	data, err := json.Marshal(map[string]string{"id": e.ActionID, "type": e.ActionType})
+	if err != nil {
+		return nil, err
+	}
+	action := fleetapi.NewAction(e.ActionType)
+	if err := json.Unmarshal(data, action); err != nil {
+		return nil, fmt.Errorf("could not decode %s action: %w", e.ActionType, err)
+	}
+	if e.ActionError != "" {
+		setActionError(action, errors.New(e.ActionError))
+	}
+	switch a := action.(type) {
+	case fleetapi.RetryableAction:
+		a.SetRetryAttempt(e.RetryAttempt)
+	case *fleetapi.ActionDiagnostics:
+		a.UploadID = e.UploadID
+	case *fleetapi.ActionApp:
+		a.InputType = e.InputType
+		a.Data = e.Data
+		a.Response = e.Response
+		a.StartedAt = e.StartedAt
+		a.CompletedAt = e.CompletedAt
+	}
+	return action, nil
+}
+
+// actionError returns the error reported in the ack of the action, it is not
+// part of the JSON encoding of the action.
+func actionError(action fleetapi.Action) error {
+	switch a := action.(type) {
+	case fleetapi.RetryableAction:
+		return a.GetError()
+	case *fleetapi.ActionDiagnostics:
+		return a.Err
+	case *fleetapi.ActionMigrate:
+		return a.Err
+	case *fleetapi.ActionPrivilegeLevelChange:
+		return a.Err
+	case *fleetapi.ActionApp:
+		if a.Error != "" {
+			return errors.New(a.Error)
+		}
+	}
+	return nil
+}
+
+func setActionError(action fleetapi.Action, err error) {
+	switch a := action.(type) {
+	case fleetapi.RetryableAction:
+		a.SetError(err)
+	case *fleetapi.ActionDiagnostics:
+		a.Err = err
+	case *fleetapi.ActionMigrate:
+		a.Err = err
+	case *fleetapi.ActionPrivilegeLevelChange:
+		a.Err = err
+	case *fleetapi.ActionApp:
+		a.Error = err.Error()
+	}
+}
+
+// find returns the index of the pending ack of the action or -1.
+// Must be called with the lock held.
+func (s *AckStore) find(actionID string) int {
+	for i := range s.entries {
+		if s.entries[i].ActionID == actionID {
+			return i
+		}
+	}
+	return -1
+}
+
+// pending returns a copy of the pending acks. Must be called with the lock held.
+func (s *AckStore) pending() []PendingAck {
+	entries := make([]PendingAck, len(s.entries))
+	copy(entries, s.entries)
+	return entries
+}
+
+// trim drops the oldest pending acks exceeding the size of the store.
+// Must be called with the lock held.
+func (s *AckStore) trim() {
+	if len(s.entries) > s.size {
+		dropped := len(s.entries) - s.size
+		s.log.Warnf("ack store is full, dropping the %d oldest pending acks", dropped)
+		s.entries = append([]PendingAck(nil), s.entries[dropped:]...)
+	}
+}
+
+// save persists the store and notifies the change. Must be called with the
+// lock held.
+func (s *AckStore) save() error {
+	if s.onChange != nil {
+		s.onChange(s.pending())
+	}
+	reader, err := jsonToReader(&ackStoreState{
+		Version: AckStoreVersion,
+		Entries: s.entries,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.store.Save(reader); err != nil {
+		return fmt.Errorf("failed to persist ack store: %w", err)
+	}
+	return nil
+}
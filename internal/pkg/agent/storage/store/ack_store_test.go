@@ -0,0 +1,135 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	"github.com/elastic/elastic-agent/pkg/fleetapi"
+)
+
+func TestAckStore(t *testing.T) {
+	log, _ := loggertest.New("TestAckStore")
+	storePath := filepath.Join(t.TempDir(), "ack_store.enc")
+	s, err := storage.NewDiskStore(storePath)
+	require.NoError(t, err)
+
+	acks := NewAckStore(log, s, 3)
+	var notified []PendingAck
+	acks.OnChange(func(pending []PendingAck) {
+		notified = pending
+	})
+
+	upgrade := &fleetapi.ActionUpgrade{
+		ActionID:   "action-upgrade",
+		ActionType: fleetapi.ActionTypeUpgrade,
+		Data:       fleetapi.ActionUpgradeData{Version: "9.0.0", Retry: 1},
+		Err:        errors.New("download failed"),
+	}
+	diagnostics := &fleetapi.ActionDiagnostics{
+		ActionID:   "action-diagnostics",
+		ActionType: fleetapi.ActionTypeDiagnostics,
+		UploadID:   "upload-1",
+	}
+	require.NoError(t, acks.Add(upgrade, diagnostics))
+	for i := 1; i <= 2; i++ {
+		require.NoError(t, acks.Add(policyChangeAction(fmt.Sprintf("action-%d", i), i)))
+	}
+
+	pending := acks.Pending()
+	require.Len(t, pending, 3, "the oldest ack must be discarded")
+	assert.Equal(t, []string{"action-diagnostics", "action-1", "action-2"},
+		[]string{pending[0].ActionID, pending[1].ActionID, pending[2].ActionID})
+	assert.Equal(t, pending, notified)
+
+	require.NoError(t, acks.Failed(errors.New("fleet unreachable"), diagnostics, policyChangeAction("action-1", 1)))
+	require.NoError(t, acks.Failed(errors.New("status 503"), diagnostics))
+	require.NoError(t, acks.Acked(policyChangeAction("action-2", 2)))
+
+	pending = acks.Pending()
+	require.Len(t, pending, 2)
+	assert.Equal(t, 2, pending[0].Attempts)
+	assert.Equal(t, "status 503", pending[0].LastError)
+	assert.False(t, pending[0].LastAttemptAt.IsZero())
+	assert.Equal(t, 1, pending[1].Attempts)
+	assert.Equal(t, "fleet unreachable", pending[1].LastError)
+
+	// adding a pending action again keeps its attempts
+	require.NoError(t, acks.Add(policyChangeAction("action-1", 1)))
+	assert.Equal(t, 1, acks.Pending()[1].Attempts)
+
+	// an action that is not pending is added on failure
+	require.NoError(t, acks.Failed(errors.New("fleet unreachable"), upgrade))
+	pending = acks.Pending()
+	require.Len(t, pending, 3)
+	assert.Equal(t, "action-upgrade", pending[2].ActionID)
+	assert.Equal(t, 1, pending[2].Attempts)
+
+	// only the fields of the ack are persisted
+	data, err := os.ReadFile(storePath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "policy-1")
+	assert.NotContains(t, string(data), "9.0.0")
+
+	// the pending acks are persisted and the actions restored on start
+	reloaded := NewAckStore(log, s, 3)
+	assert.Equal(t, len(pending), len(reloaded.Pending()))
+	actions := reloaded.Actions()
+	require.Len(t, actions, 3)
+
+	restoredDiagnostics, ok := actions[0].(*fleetapi.ActionDiagnostics)
+	require.True(t, ok)
+	assert.Equal(t, "upload-1", restoredDiagnostics.UploadID)
+
+	policyChange, ok := actions[1].(*fleetapi.ActionPolicyChange)
+	require.True(t, ok)
+	assert.Equal(t, "action-1", policyChange.ID())
+	assert.Equal(t, fleetapi.ActionTypePolicyChange, policyChange.Type())
+	assert.Nil(t, policyChange.Data.Policy)
+
+	restoredUpgrade, ok := actions[2].(*fleetapi.ActionUpgrade)
+	require.True(t, ok)
+	assert.Equal(t, "action-upgrade", restoredUpgrade.ID())
+	assert.Equal(t, 1, restoredUpgrade.RetryAttempt())
+	assert.EqualError(t, restoredUpgrade.GetError(), "download failed")
+}
+
+func TestAckStoreInputAction(t *testing.T) {
+	log, _ := loggertest.New("TestAckStoreInputAction")
+	s, err := storage.NewDiskStore(filepath.Join(t.TempDir(), "ack_store.enc"))
+	require.NoError(t, err)
+
+	action := &fleetapi.ActionApp{
+		ActionID:    "action-input",
+		ActionType:  fleetapi.ActionTypeInputAction,
+		InputType:   "osquery",
+		Data:        []byte(`{"query":"select * from osquery_info"}`),
+		Response:    map[string]interface{}{"count": float64(1)},
+		StartedAt:   "2024-01-02T03:04:05.000000006Z",
+		CompletedAt: "2024-01-02T03:04:06.000000007Z",
+		Error:       "query failed",
+	}
+	acks := NewAckStore(log, s, 3)
+	require.NoError(t, acks.Add(action))
+
+	// the input action is restored with the fields of its ack
+	actions := NewAckStore(log, s, 3).Actions()
+	require.Len(t, actions, 1)
+	restored, ok := actions[0].(*fleetapi.ActionApp)
+	require.True(t, ok)
+
+	ts := time.Now()
+	assert.Equal(t, action.AckEvent("agent-id", ts), restored.AckEvent("agent-id", ts))
+}
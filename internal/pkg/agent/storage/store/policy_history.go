@@ -0,0 +1,253 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/fleetapi"
+)
+
+// PolicyHistoryVersion is the current PolicyHistory version. If any breaking
+// change is introduced, it should be increased.
+const PolicyHistoryVersion = "1"
+
+// DefaultPolicyHistorySize is the default number of policies kept in the history.
+const DefaultPolicyHistorySize = 5
+
+// PolicyHistoryEntry is a policy applied by the Elastic Agent.
+type PolicyHistoryEntry struct {
+	ActionID  string    `json:"action_id" yaml:"action_id"`
+	PolicyID  string    `json:"policy_id" yaml:"policy_id"`
+	Revision  int64     `json:"revision" yaml:"revision"`
+	AppliedAt time.Time `json:"applied_at" yaml:"applied_at"`
+	// Acked is true once the POLICY_CHANGE action was acknowledged to Fleet.
+	Acked bool `json:"acked" yaml:"acked"`
+	// Rollback is true when the policy was re-applied locally from the history,
+	// RollbackFromRevision is the revision that was replaced by the rollback.
+	Rollback             bool  `json:"rollback,omitempty" yaml:"rollback,omitempty"`
+	RollbackFromRevision int64 `json:"rollback_from_revision,omitempty" yaml:"rollback_from_revision,omitempty"`
+
+	Policy map[string]interface{} `json:"policy" yaml:"-"`
+}
+
+// Action returns the POLICY_CHANGE action that applies the policy of the entry.
+func (e PolicyHistoryEntry) Action() *fleetapi.ActionPolicyChange {
+	return &fleetapi.ActionPolicyChange{
+		ActionID:   e.ActionID,
+		ActionType: fleetapi.ActionTypePolicyChange,
+		Data: fleetapi.ActionPolicyChangeData{
+			Policy: e.Policy,
+		},
+	}
+}
+
+// PolicyHistory keeps a bounded history of the last applied policies. The
+// oldest policy is discarded once the history is full.
+type PolicyHistory struct {
+	log     *logger.Logger
+	store   saveLoader
+	size    int
+	entries []PolicyHistoryEntry
+
+	mx sync.RWMutex
+}
+
+type policyHistoryState struct {
+	Version string               `json:"version"`
+	Entries []PolicyHistoryEntry `json:"entries,omitempty"`
+}
+
+// NewEncryptedPolicyHistory creates a new policy history persisted to an
+// encrypted disk store at path.
+func NewEncryptedPolicyHistory(
+	ctx context.Context,
+	log *logger.Logger,
+	path string,
+	size int,
+	storageOpts ...storage.EncryptedOptionFunc) (*PolicyHistory, error) {
+	diskStore, err := storage.NewEncryptedDiskStore(ctx, path, storageOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create EncryptedDiskStore for the policy history: %w", err)
+	}
+	return NewPolicyHistory(log, diskStore, size), nil
+}
+
+// NewPolicyHistory creates a new policy history keeping up to size policies.
+// If the persisted history cannot be read an empty history is returned, the
+// history is not required for the Elastic Agent to run.
+func NewPolicyHistory(log *logger.Logger, store saveLoader, size int) *PolicyHistory {
+	if size <= 0 {
+		size = DefaultPolicyHistorySize
+	}
+	h := &PolicyHistory{
+		log:   log,
+		store: store,
+		size:  size,
+	}
+
+	reader, err := store.Load()
+	if err != nil {
+		log.Warnf("failed to load policy history, starting with an empty history: %v", err)
+		return h
+	}
+	defer reader.Close()
+
+	st, err := readPolicyHistory(reader)
+	if err != nil {
+		log.Warnf("failed to parse policy history, starting with an empty history: %v", err)
+		return h
+	}
+	if st.Version != PolicyHistoryVersion {
+		log.Warnf("invalid policy history version, current version is %q loaded version is %q, starting with an empty history",
+			PolicyHistoryVersion, st.Version)
+		return h
+	}
+	h.entries = st.Entries
+	h.trim()
+	return h
+}
+
+func readPolicyHistory(reader io.Reader) (policyHistoryState, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return policyHistoryState{}, fmt.Errorf("could not read policy history: %w", err)
+	}
+	if len(data) == 0 {
+		// empty file
+		return policyHistoryState{Version: PolicyHistoryVersion}, nil
+	}
+
+	var st policyHistoryState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return policyHistoryState{}, fmt.Errorf("could not parse JSON: %w", err)
+	}
+	return st, nil
+}
+
+// Add records the policy of the action as the latest applied policy and
+// persists the history. Adding the action of the latest entry again, as it
+// happens when the persisted policy is restored on start, does not create a
+// new entry. The latest entry is returned.
+func (h *PolicyHistory) Add(action *fleetapi.ActionPolicyChange, appliedAt time.Time) (PolicyHistoryEntry, error) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1].ActionID == action.ActionID {
+		return h.entries[len(h.entries)-1], nil
+	}
+
+	entry := PolicyHistoryEntry{
+		ActionID:  action.ActionID,
+		PolicyID:  action.PolicyID(),
+		Revision:  action.PolicyRevisionIDX(),
+		AppliedAt: appliedAt.UTC(),
+		Policy:    action.Data.Policy,
+	}
+	h.entries = append(h.entries, entry)
+	h.trim()
+	return entry, h.save()
+}
+
+// AddRollback records the entry as the latest applied policy after it was
+// re-applied locally and persists the history.
+func (h *PolicyHistory) AddRollback(entry PolicyHistoryEntry, appliedAt time.Time) (PolicyHistoryEntry, error) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	entry.Rollback = true
+	if len(h.entries) > 0 {
+		entry.RollbackFromRevision = h.entries[len(h.entries)-1].Revision
+	}
+	entry.AppliedAt = appliedAt.UTC()
+	h.entries = append(h.entries, entry)
+	h.trim()
+	return entry, h.save()
+}
+
+// SetAcked marks the entries of the action as acknowledged and persists the
+// history.
+func (h *PolicyHistory) SetAcked(actionID string) error {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	changed := false
+	for i := range h.entries {
+		if h.entries[i].ActionID == actionID && !h.entries[i].Acked {
+			h.entries[i].Acked = true
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return h.save()
+}
+
+// Entries returns a copy of the history, from the oldest to the latest applied
+// policy.
+func (h *PolicyHistory) Entries() []PolicyHistoryEntry {
+	h.mx.RLock()
+	defer h.mx.RUnlock()
+
+	entries := make([]PolicyHistoryEntry, len(h.entries))
+	copy(entries, h.entries)
+	return entries
+}
+
+// Latest returns the latest applied policy.
+func (h *PolicyHistory) Latest() (PolicyHistoryEntry, bool) {
+	h.mx.RLock()
+	defer h.mx.RUnlock()
+
+	if len(h.entries) == 0 {
+		return PolicyHistoryEntry{}, false
+	}
+	return h.entries[len(h.entries)-1], true
+}
+
+// FindRevision returns the most recently applied entry of the given policy
+// with the given revision.
+func (h *PolicyHistory) FindRevision(policyID string, revision int64) (PolicyHistoryEntry, bool) {
+	h.mx.RLock()
+	defer h.mx.RUnlock()
+
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if h.entries[i].PolicyID == policyID && h.entries[i].Revision == revision {
+			return h.entries[i], true
+		}
+	}
+	return PolicyHistoryEntry{}, false
+}
+
+// trim drops the oldest entries exceeding the size of the history.
+// Must be called with the lock held.
+func (h *PolicyHistory) trim() {
+	if len(h.entries) > h.size {
+		h.entries = append([]PolicyHistoryEntry(nil), h.entries[len(h.entries)-h.size:]...)
+	}
+}
+
+// save persists the history. Must be called with the lock held.
+func (h *PolicyHistory) save() error {
+	reader, err := jsonToReader(&policyHistoryState{
+		Version: PolicyHistoryVersion,
+		Entries: h.entries,
+	})
+	if err != nil {
+		return err
+	}
+	if err := h.store.Save(reader); err != nil {
+		return fmt.Errorf("failed to persist policy history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	"github.com/elastic/elastic-agent/pkg/fleetapi"
+)
+
+func policyChangeAction(actionID string, revision int) *fleetapi.ActionPolicyChange {
+	return &fleetapi.ActionPolicyChange{
+		ActionID:   actionID,
+		ActionType: fleetapi.ActionTypePolicyChange,
+		Data: fleetapi.ActionPolicyChangeData{
+			Policy: map[string]interface{}{
+				"id":       "policy-1",
+				"revision": revision,
+			},
+		},
+	}
+}
+
+func TestPolicyHistory(t *testing.T) {
+	log, _ := loggertest.New("TestPolicyHistory")
+	storePath := filepath.Join(t.TempDir(), "policy_history.enc")
+	s, err := storage.NewDiskStore(storePath)
+	require.NoError(t, err)
+
+	history := NewPolicyHistory(log, s, 3)
+	now := time.Now()
+	for i := 1; i <= 4; i++ {
+		entry, err := history.Add(policyChangeAction(fmt.Sprintf("action-%d", i), i), now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(i), entry.Revision)
+		assert.Equal(t, "policy-1", entry.PolicyID)
+	}
+
+	// adding the latest action again, as it happens on restore, does not create an entry
+	_, err = history.Add(policyChangeAction("action-4", 4), now)
+	require.NoError(t, err)
+
+	entries := history.Entries()
+	require.Len(t, entries, 3, "the oldest entry must be discarded")
+	assert.Equal(t, []int64{2, 3, 4}, []int64{entries[0].Revision, entries[1].Revision, entries[2].Revision})
+
+	require.NoError(t, history.SetAcked("action-3"))
+
+	entry, ok := history.FindRevision("policy-1", 3)
+	require.True(t, ok)
+	assert.True(t, entry.Acked)
+	_, ok = history.FindRevision("policy-1", 1)
+	assert.False(t, ok)
+	_, ok = history.FindRevision("policy-2", 3)
+	assert.False(t, ok, "the revision of another policy must not be found")
+
+	rollback, err := history.AddRollback(entry, now)
+	require.NoError(t, err)
+	assert.True(t, rollback.Rollback)
+	assert.Equal(t, int64(4), rollback.RollbackFromRevision)
+	assert.Equal(t, "action-3", rollback.Action().ID())
+
+	// the history is persisted and loaded on start
+	reloaded := NewPolicyHistory(log, s, 3)
+	latest, ok := reloaded.Latest()
+	require.True(t, ok)
+	assert.True(t, latest.Rollback)
+	assert.Equal(t, int64(3), latest.Revision)
+	assert.Equal(t, "policy-1", latest.Action().PolicyID())
+	assert.Equal(t, int64(3), latest.Action().PolicyRevisionIDX())
+	assert.Len(t, reloaded.Entries(), 3)
+}
+
+func TestPolicyHistoryEmptyStore(t *testing.T) {
+	log, _ := loggertest.New("TestPolicyHistoryEmptyStore")
+	s, err := storage.NewDiskStore(filepath.Join(t.TempDir(), "policy_history.enc"))
+	require.NoError(t, err)
+
+	history := NewPolicyHistory(log, s, 0)
+	assert.Empty(t, history.Entries())
+	_, ok := history.Latest()
+	assert.False(t, ok)
+}
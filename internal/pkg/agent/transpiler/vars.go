@@ -17,7 +17,17 @@ import (
 
 const varsSeparator = "."
 
-var varsRegex = regexp.MustCompile(`\$\$?{([\p{L}\d\s\\\-_|.'":\/]*)}`)
+// varsChars are the characters allowed inside a variable reference.
+const varsChars = `\p{L}\d\s\\\-_|.'":\/(),`
+
+// secretVarsPrefix is the prefix of the secret references, the only variables where '#' is
+// allowed, it separates the path of the secret from its field.
+const secretVarsPrefix = "secret."
+
+var (
+	varsRegex            = regexp.MustCompile(`\$\$?{([` + varsChars + `#]*)}`)
+	varsInvalidCharRegex = regexp.MustCompile(`[^` + varsChars + `]`)
+)
 
 // ErrNoMatch is return when the replace didn't fail, just that no vars match to perform the replace.
 var ErrNoMatch = errors.New("no matching vars")
@@ -150,8 +160,8 @@ func (v *Vars) lookupNode(name string) (Node, bool) {
 func replaceVars(value string, replacer func(variable string) (Node, Processors, bool), reqMatch bool, defaultProvider string) (Node, error) {
 	var processors Processors
 	matchIdxs := varsRegex.FindAllSubmatchIndex([]byte(value), -1)
-	if !validBrackets(value, matchIdxs) {
-		return nil, fmt.Errorf("starting ${ is missing ending }")
+	if err := checkUnmatchedVars(value, matchIdxs); err != nil {
+		return nil, err
 	}
 	result := ""
 	lastIndex := 0
@@ -164,7 +174,7 @@ func replaceVars(value string, replacer func(variable string) (Node, Processors,
 				continue
 			}
 			// match on a non-escaped var
-			vars, err := extractVars(value[r[i+2]:r[i+3]], defaultProvider)
+			vars, funcs, err := extractVars(value[r[i+2]:r[i+3]], defaultProvider)
 			if err != nil {
 				return nil, fmt.Errorf(`error parsing variable "%s": %w`, value[r[i]:r[i+1]], err)
 			}
@@ -172,12 +182,19 @@ func replaceVars(value string, replacer func(variable string) (Node, Processors,
 			for _, val := range vars {
 				switch val.(type) {
 				case *constString:
-					result += value[lastIndex:r[0]] + val.Value()
+					node, err := applyVarFuncs(NewStrVal(val.Value()), funcs)
+					if err != nil {
+						return nil, fmt.Errorf(`error evaluating variable "%s": %w`, value[r[i]:r[i+1]], err)
+					}
+					result += value[lastIndex:r[0]] + node.String()
 					set = true
 				case *varString:
 					node, nodeProcessors, ok := replacer(val.Value())
 					if ok {
-						node := nodeToValue(node)
+						node, err := applyVarFuncs(nodeToValue(node), funcs)
+						if err != nil {
+							return nil, fmt.Errorf(`error evaluating variable "%s": %w`, value[r[i]:r[i+1]], err)
+						}
 						if nodeProcessors != nil {
 							processors = nodeProcessors
 						}
@@ -232,22 +249,52 @@ func nodeToValue(node Node) Node {
 	return node
 }
 
-// validBrackets returns true when all starting {$ have a matching ending }.
-func validBrackets(s string, matchIdxs [][]int) bool {
-	result := ""
+// checkUnmatchedVars returns an error when a starting ${ is not part of a matched variable reference,
+// either because it is missing its ending } or because the reference contains a character that is
+// not supported in variable references, so the reference is never silently left unresolved.
+func checkUnmatchedVars(s string, matchIdxs [][]int) error {
 	lastIndex := 0
-	match := false
 	for _, r := range matchIdxs {
-		match = true
-		for i := 0; i < len(r); i += 4 {
-			result += s[lastIndex:r[0]]
-			lastIndex = r[1]
+		if err := checkUnmatchedVar(s[lastIndex:r[0]]); err != nil {
+			return err
 		}
+		lastIndex = r[1]
 	}
-	if !match {
-		return !strings.Contains(s, "${")
+	return checkUnmatchedVar(s[lastIndex:])
+}
+
+func checkUnmatchedVar(s string) error {
+	start := strings.Index(s, "${")
+	if start == -1 {
+		return nil
 	}
-	return !strings.Contains(result, "${")
+	end := strings.Index(s[start:], "}")
+	if end == -1 {
+		return errors.New("starting ${ is missing ending }")
+	}
+	ref := s[start : start+end+1]
+	if invalid := varsInvalidCharRegex.FindString(ref[2 : len(ref)-1]); invalid != "" {
+		return fmt.Errorf(`error parsing variable "%s": unsupported character %q`, ref, invalid)
+	}
+	return fmt.Errorf(`error parsing variable "%s"`, ref)
+}
+
+// checkSecretVars returns an error when '#' is used outside of a secret reference.
+func checkSecretVars(i string) error {
+	if !strings.Contains(i, "#") {
+		return nil
+	}
+	segments, err := splitVarSegments(i)
+	if err != nil {
+		return err
+	}
+	for _, seg := range segments {
+		segment := strings.TrimSpace(i[seg[0]:seg[1]])
+		if strings.Contains(segment, "#") && !strings.HasPrefix(segment, secretVarsPrefix) {
+			return fmt.Errorf(`unsupported character "#" in %q, it is only allowed in the %s references`, segment, strings.TrimSuffix(secretVarsPrefix, "."))
+		}
+	}
+	return nil
 }
 
 type varI interface {
@@ -270,7 +317,24 @@ func (v *constString) Value() string {
 	return v.value
 }
 
-func extractVars(i string, defaultProvider string) ([]varI, error) {
+// extractVars returns the variables and constants of a variable reference in their fallback order and
+// the functions applied on the resolved value.
+func extractVars(i string, defaultProvider string) ([]varI, []*varFunc, error) {
+	if err := checkSecretVars(i); err != nil {
+		return nil, nil, err
+	}
+	i, funcs, err := splitVarFuncs(i)
+	if err != nil {
+		return nil, nil, err
+	}
+	vars, err := extractFallbacks(i, defaultProvider)
+	if err != nil {
+		return nil, nil, err
+	}
+	return vars, funcs, nil
+}
+
+func extractFallbacks(i string, defaultProvider string) ([]varI, error) {
 	const out = rune(0)
 
 	quote := out
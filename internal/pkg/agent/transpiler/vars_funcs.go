@@ -0,0 +1,311 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// varFuncRegex matches a function call in a variable reference, e.g. `lower()` or `substr(0, 5)`.
+// Parentheses are required, a bare name like `lower` is always a variable.
+var varFuncRegex = regexp.MustCompile(`^([a-z][a-z0-9_]*)\s*\((.*)\)$`)
+
+// varFuncSpec defines a function that transforms the value of a variable.
+type varFuncSpec struct {
+	// minimum and maximum number of arguments
+	minArgs int
+	maxArgs int
+	fn      func(node Node, args []string) (Node, error)
+}
+
+// varFuncs are the functions that can be applied to the value of a variable, e.g. `${host.name | lower()}`.
+var varFuncs = map[string]varFuncSpec{
+	"lower":        {0, 0, stringFunc(func(s string, _ []string) (string, error) { return strings.ToLower(s), nil })},
+	"upper":        {0, 0, stringFunc(func(s string, _ []string) (string, error) { return strings.ToUpper(s), nil })},
+	"trim":         {0, 0, stringFunc(func(s string, _ []string) (string, error) { return strings.TrimSpace(s), nil })},
+	"replace":      {2, 2, stringFunc(func(s string, args []string) (string, error) { return strings.ReplaceAll(s, args[0], args[1]), nil })},
+	"substr":       {1, 2, stringFunc(substrFunc)},
+	"base64encode": {0, 0, stringFunc(func(s string, _ []string) (string, error) { return base64.StdEncoding.EncodeToString([]byte(s)), nil })},
+	"base64decode": {0, 0, stringFunc(base64DecodeFunc)},
+	"join":         {1, 1, joinFunc},
+	"split":        {1, 1, splitFunc},
+}
+
+// varFunc is a function call in a variable reference.
+type varFunc struct {
+	name string
+	args []string
+}
+
+// apply applies the function on the node.
+func (f *varFunc) apply(node Node) (Node, error) {
+	res, err := varFuncs[f.name].fn(node, f.args)
+	if err != nil {
+		return nil, fmt.Errorf("function %s failed: %w", f.name, err)
+	}
+	return res, nil
+}
+
+// applyVarFuncs applies the functions in order on the node.
+func applyVarFuncs(node Node, funcs []*varFunc) (Node, error) {
+	var err error
+	for _, f := range funcs {
+		node, err = f.apply(node)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// splitVarFuncs splits the content of a variable reference into the variables with their fallbacks
+// and the functions applied on the resolved value. Functions come after all the fallbacks and are
+// always called with parentheses, so a variable named like a function stays a variable.
+func splitVarFuncs(i string) (string, []*varFunc, error) {
+	segments, err := splitVarSegments(i)
+	if err != nil {
+		return "", nil, err
+	}
+	var funcs []*varFunc
+	end := len(i)
+	for idx := 1; idx < len(segments); idx++ {
+		f, ok, err := parseVarFunc(i[segments[idx][0]:segments[idx][1]])
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			if len(funcs) > 0 {
+				return "", nil, fmt.Errorf("%q cannot follow a function; functions must come after all the fallbacks", strings.TrimSpace(i[segments[idx][0]:segments[idx][1]]))
+			}
+			continue
+		}
+		if len(funcs) == 0 {
+			// the pipe before the first function
+			end = segments[idx][0] - 1
+		}
+		funcs = append(funcs, f)
+	}
+	return i[:end], funcs, nil
+}
+
+// splitVarSegments returns the start and end indexes of the pipe separated segments of a variable
+// reference, pipes inside quotes or function arguments do not separate segments.
+func splitVarSegments(i string) ([][2]int, error) {
+	var (
+		segments [][2]int
+		quote    rune
+		escape   bool
+		depth    int
+		start    int
+	)
+	for idx, r := range i {
+		switch {
+		case escape:
+			escape = false
+		case r == '\\':
+			escape = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth == 0 {
+				return nil, fmt.Errorf("unexpected ')'")
+			}
+			depth--
+		case r == '|' && depth == 0:
+			segments = append(segments, [2]int{start, idx})
+			start = idx + 1
+		}
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("starting ( is missing ending )")
+	}
+	return append(segments, [2]int{start, len(i)}), nil
+}
+
+// parseVarFunc parses a function call, returns false when the segment is not a function call.
+func parseVarFunc(segment string) (*varFunc, bool, error) {
+	segment = strings.TrimSpace(segment)
+	m := varFuncRegex.FindStringSubmatch(segment)
+	if m == nil {
+		if idx := strings.Index(segment, "("); idx != -1 && !strings.ContainsAny(segment[:idx], `'"`) {
+			return nil, false, fmt.Errorf("invalid function %q", segment)
+		}
+		return nil, false, nil
+	}
+	name := m[1]
+	spec, ok := varFuncs[name]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown function %q", name)
+	}
+	args, err := parseVarFuncArgs(m[2])
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid arguments for function %s: %w", name, err)
+	}
+	if len(args) < spec.minArgs || len(args) > spec.maxArgs {
+		if spec.minArgs == spec.maxArgs {
+			return nil, false, fmt.Errorf("function %s expects %d argument(s), got %d", name, spec.minArgs, len(args))
+		}
+		return nil, false, fmt.Errorf("function %s expects %d to %d arguments, got %d", name, spec.minArgs, spec.maxArgs, len(args))
+	}
+	return &varFunc{name: name, args: args}, true, nil
+}
+
+// parseVarFuncArgs parses the comma separated arguments of a function, arguments are either quoted
+// strings or bare words like numbers.
+func parseVarFuncArgs(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var (
+		args   []string
+		arg    []rune
+		quote  rune
+		quoted bool
+		escape bool
+	)
+	finish := func() error {
+		if !quoted && len(arg) == 0 {
+			return fmt.Errorf("empty argument")
+		}
+		args = append(args, string(arg))
+		arg = arg[:0]
+		quoted = false
+		return nil
+	}
+	for _, r := range s {
+		switch {
+		case escape:
+			arg = append(arg, r)
+			escape = false
+		case r == '\\':
+			escape = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			arg = append(arg, r)
+		case r == '\'' || r == '"':
+			if quoted || len(arg) > 0 {
+				return nil, fmt.Errorf("unexpected quote")
+			}
+			quote = r
+			quoted = true
+		case r == ',':
+			if err := finish(); err != nil {
+				return nil, err
+			}
+		case unicode.IsSpace(r):
+			// spaces are only kept inside quotes
+		default:
+			if quoted {
+				return nil, fmt.Errorf("unexpected %q after quoted argument", r)
+			}
+			arg = append(arg, r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf(`starting %s is missing ending %s`, string(quote), string(quote))
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// stringFunc wraps a function on a string value, dictionaries and lists are rejected.
+func stringFunc(fn func(s string, args []string) (string, error)) func(Node, []string) (Node, error) {
+	return func(node Node, args []string) (Node, error) {
+		s, err := scalarString(node)
+		if err != nil {
+			return nil, err
+		}
+		res, err := fn(s, args)
+		if err != nil {
+			return nil, err
+		}
+		return NewStrVal(res), nil
+	}
+}
+
+func scalarString(node Node) (string, error) {
+	switch node.(type) {
+	case *Dict:
+		return "", fmt.Errorf("expects a string, got a dictionary")
+	case *List:
+		return "", fmt.Errorf("expects a string, got a list")
+	}
+	return node.String(), nil
+}
+
+func substrFunc(s string, args []string) (string, error) {
+	start, err := strconv.Atoi(args[0])
+	if err != nil || start < 0 {
+		return "", fmt.Errorf("start must be a positive integer, got %q", args[0])
+	}
+	runes := []rune(s)
+	if start > len(runes) {
+		start = len(runes)
+	}
+	end := len(runes)
+	if len(args) > 1 {
+		length, err := strconv.Atoi(args[1])
+		if err != nil || length < 0 {
+			return "", fmt.Errorf("length must be a positive integer, got %q", args[1])
+		}
+		if start+length < end {
+			end = start + length
+		}
+	}
+	return string(runes[start:end]), nil
+}
+
+func base64DecodeFunc(s string, _ []string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 value: %w", err)
+	}
+	return string(decoded), nil
+}
+
+func joinFunc(node Node, args []string) (Node, error) {
+	l, ok := node.(*List)
+	if !ok {
+		return nil, fmt.Errorf("expects a list")
+	}
+	values := make([]string, 0, len(l.value))
+	for _, item := range l.value {
+		s, err := scalarString(nodeToValue(item))
+		if err != nil {
+			return nil, fmt.Errorf("list items: %w", err)
+		}
+		values = append(values, s)
+	}
+	return NewStrVal(strings.Join(values, args[0])), nil
+}
+
+func splitFunc(node Node, args []string) (Node, error) {
+	s, err := scalarString(node)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(s, args[0])
+	nodes := make([]Node, len(parts))
+	for i, part := range parts {
+		nodes[i] = NewStrVal(part)
+	}
+	return NewList(nodes), nil
+}
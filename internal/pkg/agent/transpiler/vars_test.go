@@ -307,6 +307,80 @@ func TestVars_Replace(t *testing.T) {
 	}
 }
 
+func TestVars_ReplaceFunctions(t *testing.T) {
+	vars := mustMakeVarsWithDefault(map[string]interface{}{
+		"kubernetes": map[string]interface{}{
+			"labels": map[string]interface{}{
+				"app": "My-App",
+			},
+			"secret": "c2VjcmV0LXZhbHVl",
+			"list": []string{
+				"a",
+				"b",
+				"c",
+			},
+			"dict": map[string]interface{}{
+				"key": "value",
+			},
+		},
+		"host": map[string]interface{}{
+			"name": "  web-01.example.com  ",
+		},
+		"other": map[string]interface{}{
+			"lower": "a variable named lower",
+		},
+	}, "other")
+	tests := []struct {
+		Input  string
+		Result Node
+		Error  string
+	}{
+		{Input: "${kubernetes.labels.app | lower()}", Result: NewStrVal("my-app")},
+		{Input: "${kubernetes.labels.app|upper()}", Result: NewStrVal("MY-APP")},
+		{Input: "${kubernetes.secret | base64decode()}", Result: NewStrVal("secret-value")},
+		{Input: "${kubernetes.labels.app | base64encode() | base64decode()}", Result: NewStrVal("My-App")},
+		{Input: "${kubernetes.list | join(',')}", Result: NewStrVal("a,b,c")},
+		{Input: `${kubernetes.list | join(" | ")}`, Result: NewStrVal("a | b | c")},
+		{Input: "${host.name | trim() | substr(0, 6)}", Result: NewStrVal("web-01")},
+		{Input: "${host.name | trim() | substr(7)}", Result: NewStrVal("example.com")},
+		{Input: "${host.name | trim() | substr(20, 5)}", Result: NewStrVal("")},
+		{Input: "${host.name | trim() | replace('.', '_')}", Result: NewStrVal("web-01_example_com")},
+		{Input: "${host.name | trim() | split('.')}", Result: NewList([]Node{NewStrVal("web-01"), NewStrVal("example"), NewStrVal("com")})},
+		{Input: "name: ${host.name | trim() | upper()}!", Result: NewStrVal("name: WEB-01.EXAMPLE.COM!")},
+		{Input: "${kubernetes.missing | kubernetes.labels.app | lower()}", Result: NewStrVal("my-app")},
+		{Input: "${kubernetes.missing | 'Fallback' | lower()}", Result: NewStrVal("fallback")},
+		{Input: "${kubernetes.missing:Constant|upper()}", Result: NewStrVal("CONSTANT")},
+		{Input: "${lower}", Result: NewStrVal("a variable named lower")},
+		{Input: "${kubernetes.missing | lower}", Result: NewStrVal("a variable named lower")},
+		{Input: "${kubernetes.missing | lower | upper()}", Result: NewStrVal("A VARIABLE NAMED LOWER")},
+		{Input: "${kubernetes.labels.app | unknown(1)}", Error: `unknown function "unknown"`},
+		{Input: "${kubernetes.labels.app | substr()}", Error: "function substr expects 1 to 2 arguments, got 0"},
+		{Input: "${kubernetes.labels.app | lower(1)}", Error: "function lower expects 0 argument(s), got 1"},
+		{Input: "${kubernetes.labels.app | substr(a)}", Error: `start must be a positive integer, got "a"`},
+		{Input: "${kubernetes.labels.app | lower() | kubernetes.secret}", Error: "cannot follow a function"},
+		{Input: "${kubernetes.labels.app | join(',')}", Error: "function join failed: expects a list"},
+		{Input: "${kubernetes.dict | upper()}", Error: "function upper failed: expects a string, got a dictionary"},
+		{Input: "${kubernetes.labels.app | base64decode()}", Error: "invalid base64 value"},
+		{Input: "${kubernetes.labels.app | substr(0, 2}", Error: "starting ( is missing ending )"},
+		{Input: "${kubernetes.labels.app | replace(';', '_')}", Error: `error parsing variable "${kubernetes.labels.app | replace(';', '_')}": unsupported character ";"`},
+		{Input: "ok ${kubernetes.labels.app} ${kubernetes.labels.app | replace('@', '_')}", Error: `unsupported character "@"`},
+		{Input: "${kubernetes.labels.app", Error: "starting ${ is missing ending }"},
+		{Input: "${kubernetes.labels#app}", Error: `unsupported character "#" in "kubernetes.labels#app"`},
+		{Input: "${kubernetes.labels.app | replace('#', '_')}", Error: `unsupported character "#" in "replace('#', '_')"`},
+	}
+	for _, test := range tests {
+		t.Run(test.Input, func(t *testing.T) {
+			res, err := vars.Replace(test.Input)
+			if test.Error != "" {
+				assert.ErrorContains(t, err, test.Error)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Result, res)
+		})
+	}
+}
+
 func TestVars_ReplaceWithProcessors(t *testing.T) {
 	processers := Processors{
 		{
@@ -385,6 +459,7 @@ func TestVars_ReplaceWithFetchContextProvider(t *testing.T) {
 
 	fetchContextProviders := mapstr.M{
 		"kubernetes_secrets": mockFetchProvider,
+		"secret":             mockFetchProvider,
 	}
 	vars, err := NewVarsWithProcessors(
 		"id",
@@ -440,6 +515,14 @@ func TestVars_ReplaceWithFetchContextProvider(t *testing.T) {
 	res, err = vars.Replace("${kubernetes_secrets.test_namespace.testing_secret.secret_value}")
 	require.NoError(t, err)
 	assert.Equal(t, NewStrVal("mockedFetchContent"), res)
+
+	res, err = vars.Replace("${secret.vault/db/production#password}")
+	require.NoError(t, err)
+	assert.Equal(t, NewStrVal("mockedFetchContent"), res)
+
+	res, err = vars.Replace("${testing.missing|secret.vault/db/production#password}")
+	require.NoError(t, err)
+	assert.Equal(t, NewStrVal("mockedFetchContent"), res)
 }
 
 type contextProviderMock struct {
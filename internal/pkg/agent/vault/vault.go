@@ -6,6 +6,8 @@ package vault
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"runtime"
 	"time"
 )
@@ -18,6 +20,9 @@ const (
 	lockFile = `.lock`
 )
 
+// ErrBackendNotAvailable is returned when the selected vault backend is not supported on this platform.
+var ErrBackendNotAvailable = errors.New("vault backend is not available on this platform")
+
 type Vault interface {
 	Exists(ctx context.Context, key string) (bool, error)
 	Get(ctx context.Context, key string) (dec []byte, err error)
@@ -32,9 +37,35 @@ func New(ctx context.Context, opts ...OptionFunc) (Vault, error) {
 		return nil, err
 	}
 
-	if runtime.GOOS == "darwin" && !options.unprivileged {
+	// use the backend recorded at install or by the last migration
+	if options.backend == "" {
+		cfg, err := LoadBackend(options.vaultPath)
+		if err != nil {
+			return nil, err
+		}
+		WithBackend(cfg)(&options)
+	}
+	if options.backend == "" {
+		options.backend = DefaultBackend(options.unprivileged)
+	}
+
+	switch options.backend {
+	case BackendFile:
+		return NewFileVault(ctx, options)
+	case BackendKeychain:
 		return NewDarwinKeyChainVault(ctx, options)
+	case BackendPKCS11:
+		return NewPKCS11Vault(ctx, options)
+	default:
+		return nil, fmt.Errorf("unknown vault backend %q", options.backend)
 	}
+}
 
-	return NewFileVault(ctx, options)
+// DefaultBackend returns the backend used when none is selected or recorded, the keychain for
+// a privileged Elastic Agent on Darwin and the file vault otherwise.
+func DefaultBackend(unprivileged bool) string {
+	if runtime.GOOS == "darwin" && !unprivileged {
+		return BackendKeychain
+	}
+	return BackendFile
 }
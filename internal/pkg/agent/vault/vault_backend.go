@@ -0,0 +1,144 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// BackendFile stores the secrets encrypted on disk, under the vault path.
+	BackendFile = "file"
+	// BackendKeychain stores the secrets in the Darwin keychain.
+	BackendKeychain = "keychain"
+	// BackendPKCS11 stores the secrets as data objects of a PKCS#11 token.
+	BackendPKCS11 = "pkcs11"
+
+	// backendFileName is the name of the file recording the backend, under the vault path.
+	backendFileName = "backend.yml"
+)
+
+// BackendConfig selects the backend storing the secrets.
+type BackendConfig struct {
+	Backend string       `yaml:"backend"`
+	PKCS11  PKCS11Config `yaml:"pkcs11,omitempty"`
+}
+
+// PKCS11Config is the configuration of the PKCS#11 backend.
+type PKCS11Config struct {
+	// Module is the path of the PKCS#11 library of the token.
+	Module string `yaml:"module,omitempty"`
+	// TokenLabel is the label of the token storing the secrets.
+	TokenLabel string `yaml:"token_label,omitempty"`
+	// PINFile is the path of the file holding the user PIN of the token.
+	PINFile string `yaml:"pin_file,omitempty"`
+}
+
+// Validate validates the backend configuration.
+func (c BackendConfig) Validate() error {
+	switch c.Backend {
+	case BackendFile, BackendKeychain:
+	case BackendPKCS11:
+		if c.PKCS11.Module == "" || c.PKCS11.TokenLabel == "" || c.PKCS11.PINFile == "" {
+			return errors.New("the pkcs11 vault backend requires the module, the token label and the PIN file")
+		}
+	default:
+		return fmt.Errorf("unknown vault backend %q", c.Backend)
+	}
+	return nil
+}
+
+// LoadBackend reads the backend recorded under the vault path, an empty configuration is
+// returned when none was recorded.
+func LoadBackend(vaultPath string) (BackendConfig, error) {
+	var cfg BackendConfig
+	path, err := resolveVaultPath(vaultPath)
+	if err != nil {
+		return cfg, err
+	}
+	b, err := os.ReadFile(filepath.Join(path, backendFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read the vault backend: %w", err)
+	}
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse the vault backend: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid vault backend in %s: %w", filepath.Join(path, backendFileName), err)
+	}
+	return cfg, nil
+}
+
+// SaveBackend records the backend under the vault path, vault.New then uses it when no backend
+// is selected.
+func SaveBackend(vaultPath string, cfg BackendConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	path, err := resolveVaultPath(vaultPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path, 0750); err != nil {
+		return fmt.Errorf("failed to create vault path: %v, err: %w", path, err)
+	}
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the vault backend: %w", err)
+	}
+	return writeFile(filepath.Join(path, backendFileName), b)
+}
+
+// Migrate copies the keys from src to dst, calls activate once they are all copied and then
+// removes them from src. The keys missing from src are skipped.
+func Migrate(ctx context.Context, src Vault, dst Vault, keys []string, activate func() error) error {
+	var migrated []string
+	for _, key := range keys {
+		exists, err := src.Exists(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to check for key %s: %w", key, err)
+		}
+		if !exists {
+			continue
+		}
+		data, err := src.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to read key %s: %w", key, err)
+		}
+		if err := dst.Set(ctx, key, data); err != nil {
+			return fmt.Errorf("failed to write key %s: %w", key, err)
+		}
+		stored, err := dst.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to read back key %s: %w", key, err)
+		}
+		if !bytes.Equal(data, stored) {
+			return fmt.Errorf("key %s read back from the destination differs", key)
+		}
+		migrated = append(migrated, key)
+	}
+	if err := activate(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, key := range migrated {
+		if err := src.Remove(ctx, key); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove key %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,165 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendConfigValidate(t *testing.T) {
+	assert.NoError(t, BackendConfig{Backend: BackendFile}.Validate())
+	assert.NoError(t, BackendConfig{Backend: BackendPKCS11, PKCS11: PKCS11Config{Module: "/usr/lib/softhsm/libsofthsm2.so", TokenLabel: "agent", PINFile: "/etc/agent.pin"}}.Validate())
+	assert.ErrorContains(t, BackendConfig{Backend: BackendPKCS11, PKCS11: PKCS11Config{Module: "/usr/lib/softhsm/libsofthsm2.so"}}.Validate(), "requires the module, the token label and the PIN file")
+	assert.ErrorContains(t, BackendConfig{Backend: "tpm"}.Validate(), `unknown vault backend "tpm"`)
+	assert.ErrorContains(t, BackendConfig{Backend: "keyring"}.Validate(), `unknown vault backend "keyring"`)
+}
+
+func TestSaveLoadBackend(t *testing.T) {
+	vaultPath := getTestFileVaultPath(t)
+
+	cfg, err := LoadBackend(vaultPath)
+	require.NoError(t, err)
+	assert.Equal(t, BackendConfig{}, cfg, "no backend recorded")
+
+	expected := BackendConfig{Backend: BackendPKCS11, PKCS11: PKCS11Config{Module: "/usr/lib/softhsm/libsofthsm2.so", TokenLabel: "agent", PINFile: "/etc/agent.pin"}}
+	require.NoError(t, SaveBackend(vaultPath, expected))
+	cfg, err = LoadBackend(vaultPath)
+	require.NoError(t, err)
+	assert.Equal(t, expected, cfg)
+
+	assert.Error(t, SaveBackend(vaultPath, BackendConfig{Backend: "tpm"}))
+}
+
+func TestNewWithBackend(t *testing.T) {
+	ctx := t.Context()
+	vaultPath := getTestFileVaultPath(t)
+
+	// the recorded backend is used
+	require.NoError(t, SaveBackend(vaultPath, BackendConfig{Backend: BackendFile}))
+	v, err := New(ctx, WithVaultPath(vaultPath))
+	require.NoError(t, err)
+	assert.IsType(t, &FileVault{}, v)
+	require.NoError(t, v.Close())
+
+	// unless a backend is selected
+	_, err = New(ctx, WithVaultPath(vaultPath), WithBackend(BackendConfig{Backend: "tpm"}))
+	assert.ErrorContains(t, err, `unknown vault backend "tpm"`)
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := t.Context()
+	src := newMapVault()
+	dst := newMapVault()
+	require.NoError(t, src.Set(ctx, "secret", []byte("value")))
+
+	activated := false
+	err := Migrate(ctx, src, dst, []string{"secret", "missing"}, func() error {
+		activated = true
+		// the keys are still in the source when the destination is activated
+		exists, err := src.Exists(ctx, "secret")
+		require.NoError(t, err)
+		assert.True(t, exists)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, activated)
+	assert.Equal(t, map[string][]byte{"secret": []byte("value")}, dst.data)
+	assert.Empty(t, src.data)
+
+	// a failed activation keeps the keys in the source
+	src = newMapVault()
+	require.NoError(t, src.Set(ctx, "secret", []byte("value")))
+	err = Migrate(ctx, src, newMapVault(), []string{"secret"}, func() error { return errors.New("activation failed") })
+	assert.EqualError(t, err, "activation failed")
+	assert.Contains(t, src.data, "secret")
+}
+
+func TestMigrateFileVaults(t *testing.T) {
+	ctx := t.Context()
+	src, err := New(ctx, WithVaultPath(getTestFileVaultPath(t)), WithBackend(BackendConfig{Backend: BackendFile}))
+	require.NoError(t, err)
+	dst, err := New(ctx, WithVaultPath(getTestFileVaultPath(t)), WithBackend(BackendConfig{Backend: BackendFile}))
+	require.NoError(t, err)
+	require.NoError(t, src.Set(ctx, "secret", []byte("value")))
+
+	require.NoError(t, Migrate(ctx, src, dst, []string{"secret"}, func() error { return nil }))
+	data, err := dst.Get(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), data)
+	exists, err := src.Exists(ctx, "secret")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+// testVaultOperations checks the operations of a vault backend.
+func testVaultOperations(t *testing.T, v Vault) {
+	ctx := t.Context()
+
+	exists, err := v.Exists(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	_, err = v.Get(ctx, "key")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.NoError(t, v.Remove(ctx, "key"), "removing a missing key is not an error")
+
+	require.NoError(t, v.Set(ctx, "key", []byte("value")))
+	exists, err = v.Exists(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	data, err := v.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), data)
+
+	require.NoError(t, v.Set(ctx, "key", []byte("updated value")))
+	data, err = v.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("updated value"), data)
+
+	require.NoError(t, v.Remove(ctx, "key"))
+	exists, err = v.Exists(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+type mapVault struct {
+	data map[string][]byte
+}
+
+func newMapVault() *mapVault {
+	return &mapVault{data: map[string][]byte{}}
+}
+
+func (m *mapVault) Exists(_ context.Context, key string) (bool, error) {
+	_, ok := m.data[key]
+	return ok, nil
+}
+
+func (m *mapVault) Get(_ context.Context, key string) ([]byte, error) {
+	data, ok := m.data[key]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return data, nil
+}
+
+func (m *mapVault) Set(_ context.Context, key string, data []byte) error {
+	m.data[key] = data
+	return nil
+}
+
+func (m *mapVault) Remove(_ context.Context, key string) error {
+	delete(m.data, key)
+	return nil
+}
+
+func (m *mapVault) Close() error {
+	return nil
+}
@@ -29,17 +29,9 @@ type FileVault struct {
 
 // NewFileVault creates the file-based vault store
 func NewFileVault(ctx context.Context, options Options) (v *FileVault, err error) {
-	path := options.vaultPath
-	dir := filepath.Dir(path)
-
-	// If there is no specific path then get the executable directory
-	if dir == "." {
-		exefp, err := os.Executable()
-		if err != nil {
-			return nil, fmt.Errorf("could not get executable path: %w", err)
-		}
-		dir = filepath.Dir(exefp)
-		path = filepath.Join(dir, path)
+	path, err := resolveVaultPath(options.vaultPath)
+	if err != nil {
+		return nil, err
 	}
 
 	if options.readonly {
@@ -164,6 +156,23 @@ func (v *FileVault) Close() error {
 	return nil
 }
 
+// resolveVaultPath returns the vault path, a path without a directory is relative to the
+// directory of the executable.
+func resolveVaultPath(path string) (string, error) {
+	dir := filepath.Dir(path)
+
+	// If there is no specific path then get the executable directory
+	if dir == "." {
+		exefp, err := os.Executable()
+		if err != nil {
+			return "", fmt.Errorf("could not get executable path: %w", err)
+		}
+		dir = filepath.Dir(exefp)
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
 // fileNameFromKey returns the filename as a hash of the vault seed combined with the key
 // This ties the key with the vault seed eliminating the chance of attempting
 // to decrypt the key for the wrong vault seed value.
@@ -29,10 +29,16 @@ type KeychainVaultOptions struct {
 	entryName string
 }
 
+type BackendVaultOptions struct {
+	backend string
+	pkcs11  PKCS11Config
+}
+
 type Options struct {
 	CommonVaultOptions
 	FileVaultOptions
 	KeychainVaultOptions
+	BackendVaultOptions
 }
 
 // WithReadonly opens storage for read-only access only, noop for Darwin
@@ -63,6 +69,15 @@ func WithUnprivileged(unprivileged bool) OptionFunc {
 	}
 }
 
+// WithBackend selects the vault backend instead of the one persisted next to the vault path or
+// the default one of the platform
+func WithBackend(cfg BackendConfig) OptionFunc {
+	return func(o *Options) {
+		o.backend = cfg.Backend
+		o.pkcs11 = cfg.PKCS11
+	}
+}
+
 // ApplyOptions applies options for Windows, Linux and Mac, not all the options may be used
 func ApplyOptions(opts ...OptionFunc) (Options, error) {
 	ownership, err := utils.CurrentFileOwner()
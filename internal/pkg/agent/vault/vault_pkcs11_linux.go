@@ -0,0 +1,444 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux && (amd64 || arm64)
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+)
+
+// The PKCS#11 module is loaded with dlopen and called without cgo, the Elastic Agent is built
+// with CGO_ENABLED=0 on Linux. ckULong is the CK_ULONG of the PKCS#11 interface, an unsigned
+// long of the platform.
+type ckULong = uintptr
+
+type ckRV = ckULong
+
+const (
+	ckrOK                         ckRV = 0x0
+	ckrSlotIDInvalid              ckRV = 0x3
+	ckrUserAlreadyLoggedIn        ckRV = 0x100
+	ckrCryptokiAlreadyInitialized ckRV = 0x191
+
+	ckfOSLockingOK   ckULong = 0x2
+	ckfRWSession     ckULong = 0x2
+	ckfSerialSession ckULong = 0x4
+	ckuUser          ckULong = 1
+	ckoData          ckULong = 0x0
+	ckaClass         ckULong = 0x0
+	ckaToken         ckULong = 0x1
+	ckaPrivate       ckULong = 0x2
+	ckaLabel         ckULong = 0x3
+	ckaApplication   ckULong = 0x10
+	ckaValue         ckULong = 0x11
+
+	// the label is the first field of CK_TOKEN_INFO, the buffer is larger than the structure
+	tokenInfoSize  = 512
+	tokenLabelSize = 32
+)
+
+// ckFunctionList is the subset of the PKCS#11 v2.40 function list used by the vault, its layout
+// follows the specification up to C_FindObjectsFinal.
+type ckFunctionList struct {
+	version           [2]byte
+	initialize        uintptr
+	finalize          uintptr
+	getInfo           uintptr
+	getFunctionList   uintptr
+	getSlotList       uintptr
+	getSlotInfo       uintptr
+	getTokenInfo      uintptr
+	getMechanismList  uintptr
+	getMechanismInfo  uintptr
+	initToken         uintptr
+	initPIN           uintptr
+	setPIN            uintptr
+	openSession       uintptr
+	closeSession      uintptr
+	closeAllSessions  uintptr
+	getSessionInfo    uintptr
+	getOperationState uintptr
+	setOperationState uintptr
+	login             uintptr
+	logout            uintptr
+	createObject      uintptr
+	copyObject        uintptr
+	destroyObject     uintptr
+	getObjectSize     uintptr
+	getAttributeValue uintptr
+	setAttributeValue uintptr
+	findObjectsInit   uintptr
+	findObjects       uintptr
+	findObjectsFinal  uintptr
+}
+
+type ckInitArgs struct {
+	createMutex  uintptr
+	destroyMutex uintptr
+	lockMutex    uintptr
+	unlockMutex  uintptr
+	flags        ckULong
+	reserved     uintptr
+}
+
+type ckAttribute struct {
+	typ   ckULong
+	value unsafe.Pointer
+	len   ckULong
+}
+
+// template is a CK_ATTRIBUTE template, the values are pinned until unpin is called as the
+// module reads them through the template.
+type template struct {
+	attrs  []ckAttribute
+	pinner runtime.Pinner
+}
+
+func (t *template) add(typ ckULong, value []byte) *template {
+	var p unsafe.Pointer
+	if len(value) > 0 {
+		p = unsafe.Pointer(&value[0])
+		t.pinner.Pin(p)
+	}
+	t.attrs = append(t.attrs, ckAttribute{typ: typ, value: p, len: ckULong(len(value))})
+	return t
+}
+
+func (t *template) unpin() {
+	t.pinner.Unpin()
+}
+
+// ulongValue returns the value of a CK_ULONG attribute.
+func ulongValue(v ckULong) []byte {
+	b := make([]byte, unsafe.Sizeof(v))
+	*(*ckULong)(unsafe.Pointer(&b[0])) = v
+	return b
+}
+
+// call calls a function of the module and returns its CK_RV.
+func call(fn uintptr, args ...uintptr) ckRV {
+	rv, _, _ := purego.SyscallN(fn, args...)
+	return rv
+}
+
+// pkcs11Errors names the return values a misconfigured token commonly returns.
+var pkcs11Errors = map[ckRV]string{
+	0x03:  "token not found",
+	0x05:  "general error",
+	0x06:  "function failed",
+	0xA0:  "PIN incorrect",
+	0xA4:  "PIN locked",
+	0xB5:  "session read only",
+	0xE0:  "token not present",
+	0x101: "user not logged in",
+}
+
+func pkcs11Error(op string, rv ckRV) error {
+	if name, ok := pkcs11Errors[rv]; ok {
+		return fmt.Errorf("pkcs11 %s failed: %s (0x%x)", op, name, uint64(rv))
+	}
+	return fmt.Errorf("pkcs11 %s failed: 0x%x", op, uint64(rv))
+}
+
+// pkcs11Module is a loaded PKCS#11 library, it is shared by the vaults of the process as it can
+// only be initialized once.
+type pkcs11Module struct {
+	handle   uintptr
+	fl       *ckFunctionList
+	finalize bool
+	refs     int
+}
+
+var (
+	pkcs11ModulesMx sync.Mutex
+	pkcs11Modules   = map[string]*pkcs11Module{}
+)
+
+func openPKCS11Module(path string) (*pkcs11Module, error) {
+	pkcs11ModulesMx.Lock()
+	defer pkcs11ModulesMx.Unlock()
+
+	if m, ok := pkcs11Modules[path]; ok {
+		m.refs++
+		return m, nil
+	}
+
+	handle, err := purego.Dlopen(path, purego.RTLD_NOW|purego.RTLD_LOCAL)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the pkcs11 module %s: %w", path, err)
+	}
+	getFunctionList, err := purego.Dlsym(handle, "C_GetFunctionList")
+	if err != nil {
+		_ = purego.Dlclose(handle)
+		return nil, fmt.Errorf("could not load the pkcs11 module %s: %w", path, err)
+	}
+	// the function list is owned by the module
+	var fl *ckFunctionList
+	if rv := call(getFunctionList, uintptr(unsafe.Pointer(&fl))); rv != ckrOK {
+		_ = purego.Dlclose(handle)
+		return nil, pkcs11Error("get function list", rv)
+	}
+	args := &ckInitArgs{flags: ckfOSLockingOK}
+	rv := call(fl.initialize, uintptr(unsafe.Pointer(args)))
+	if rv != ckrOK && rv != ckrCryptokiAlreadyInitialized {
+		_ = purego.Dlclose(handle)
+		return nil, pkcs11Error("initialize", rv)
+	}
+	m := &pkcs11Module{
+		handle: handle,
+		fl:     fl,
+		// a module initialized by another user of the process is left initialized
+		finalize: rv == ckrOK,
+		refs:     1,
+	}
+	pkcs11Modules[path] = m
+	return m, nil
+}
+
+func closePKCS11Module(path string, m *pkcs11Module) {
+	pkcs11ModulesMx.Lock()
+	defer pkcs11ModulesMx.Unlock()
+
+	m.refs--
+	if m.refs > 0 {
+		return
+	}
+	delete(pkcs11Modules, path)
+	if m.finalize {
+		call(m.fl.finalize, 0)
+	}
+	_ = purego.Dlclose(m.handle)
+}
+
+// findSlot returns the slot of the token with the label, the label of a token is padded with
+// spaces.
+func (m *pkcs11Module) findSlot(label string) (ckULong, ckRV) {
+	var count ckULong
+	if rv := call(m.fl.getSlotList, 1, 0, uintptr(unsafe.Pointer(&count))); rv != ckrOK {
+		return 0, rv
+	}
+	if count == 0 {
+		return 0, ckrSlotIDInvalid
+	}
+	slots := make([]ckULong, count)
+	if rv := call(m.fl.getSlotList, 1, uintptr(unsafe.Pointer(&slots[0])), uintptr(unsafe.Pointer(&count))); rv != ckrOK {
+		return 0, rv
+	}
+	padded := bytes.Repeat([]byte{' '}, tokenLabelSize)
+	copy(padded, label)
+	info := make([]byte, tokenInfoSize)
+	for _, slot := range slots[:min(count, ckULong(len(slots)))] {
+		if call(m.fl.getTokenInfo, slot, uintptr(unsafe.Pointer(&info[0]))) != ckrOK {
+			continue
+		}
+		if bytes.Equal(info[:tokenLabelSize], padded) {
+			return slot, ckrOK
+		}
+	}
+	return 0, ckrSlotIDInvalid
+}
+
+func (m *pkcs11Module) openSession(slot ckULong, readonly bool, pin []byte) (ckULong, ckRV) {
+	flags := ckfSerialSession
+	if !readonly {
+		flags |= ckfRWSession
+	}
+	var session ckULong
+	if rv := call(m.fl.openSession, slot, flags, 0, 0, uintptr(unsafe.Pointer(&session))); rv != ckrOK {
+		return 0, rv
+	}
+	rv := call(m.fl.login, session, ckuUser, uintptr(unsafe.Pointer(&pin[0])), ckULong(len(pin)))
+	if rv != ckrOK && rv != ckrUserAlreadyLoggedIn {
+		call(m.fl.closeSession, session)
+		return 0, rv
+	}
+	return session, ckrOK
+}
+
+func (m *pkcs11Module) closeSession(session ckULong) {
+	call(m.fl.logout, session)
+	call(m.fl.closeSession, session)
+}
+
+// PKCS11Vault represents storage using data objects of a PKCS#11 token, the objects are private
+// so they can only be read once logged in the token.
+type PKCS11Vault struct {
+	app     string
+	path    string
+	module  *pkcs11Module
+	session ckULong
+	mx      sync.Mutex
+}
+
+// NewPKCS11Vault opens a session on the token
+// Call Close when done to release the resources
+func NewPKCS11Vault(ctx context.Context, opts Options) (*PKCS11Vault, error) {
+	cfg := opts.pkcs11
+	if err := (BackendConfig{Backend: BackendPKCS11, PKCS11: cfg}).Validate(); err != nil {
+		return nil, err
+	}
+	pin, err := os.ReadFile(cfg.PINFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the PIN file: %w", err)
+	}
+	pin = []byte(strings.TrimSpace(string(pin)))
+	if len(pin) == 0 {
+		return nil, fmt.Errorf("the PIN file %s is empty", cfg.PINFile)
+	}
+
+	m, err := openPKCS11Module(cfg.Module)
+	if err != nil {
+		return nil, err
+	}
+
+	slot, rv := m.findSlot(cfg.TokenLabel)
+	if rv != ckrOK {
+		closePKCS11Module(cfg.Module, m)
+		return nil, fmt.Errorf("token %q: %w", cfg.TokenLabel, pkcs11Error("find token", rv))
+	}
+	session, rv := m.openSession(slot, opts.readonly, pin)
+	if rv != ckrOK {
+		closePKCS11Module(cfg.Module, m)
+		return nil, fmt.Errorf("token %q: %w", cfg.TokenLabel, pkcs11Error("open session", rv))
+	}
+
+	return &PKCS11Vault{
+		app:     opts.entryName,
+		path:    cfg.Module,
+		module:  m,
+		session: session,
+	}, nil
+}
+
+// Close closes the session on the token
+func (v *PKCS11Vault) Close() error {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+
+	if v.module == nil {
+		return nil
+	}
+	v.module.closeSession(v.session)
+	closePKCS11Module(v.path, v.module)
+	v.module = nil
+	return nil
+}
+
+// Set stores the key in the token, replacing the previous value
+func (v *PKCS11Vault) Set(ctx context.Context, key string, data []byte) error {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+
+	if err := v.destroy(key); err != nil {
+		return err
+	}
+
+	yes := []byte{1}
+	tmpl := new(template).
+		add(ckaClass, ulongValue(ckoData)).
+		add(ckaToken, yes).
+		add(ckaPrivate, yes).
+		add(ckaApplication, []byte(v.app)).
+		add(ckaLabel, []byte(key)).
+		add(ckaValue, data)
+	defer tmpl.unpin()
+	var object ckULong
+	if rv := call(v.module.fl.createObject, v.session, uintptr(unsafe.Pointer(&tmpl.attrs[0])), ckULong(len(tmpl.attrs)), uintptr(unsafe.Pointer(&object))); rv != ckrOK {
+		return pkcs11Error("create object", rv)
+	}
+	return nil
+}
+
+// Get retrieves the key from the token
+func (v *PKCS11Vault) Get(ctx context.Context, key string) ([]byte, error) {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+
+	object, found, err := v.find(key)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("key %s: %w", key, fs.ErrNotExist)
+	}
+
+	// the length of the value is returned when the attribute has no buffer
+	attr := &ckAttribute{typ: ckaValue}
+	if rv := call(v.module.fl.getAttributeValue, v.session, object, uintptr(unsafe.Pointer(attr)), 1); rv != ckrOK {
+		return nil, pkcs11Error("get object size", rv)
+	}
+	if attr.len == 0 {
+		return []byte{}, nil
+	}
+	value := make([]byte, attr.len)
+	tmpl := new(template).add(ckaValue, value)
+	defer tmpl.unpin()
+	if rv := call(v.module.fl.getAttributeValue, v.session, object, uintptr(unsafe.Pointer(&tmpl.attrs[0])), 1); rv != ckrOK {
+		return nil, pkcs11Error("get object value", rv)
+	}
+	return value[:min(tmpl.attrs[0].len, ckULong(len(value)))], nil
+}
+
+// Exists checks if the key exists
+func (v *PKCS11Vault) Exists(ctx context.Context, key string) (bool, error) {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+
+	_, found, err := v.find(key)
+	return found, err
+}
+
+// Remove removes the key
+func (v *PKCS11Vault) Remove(ctx context.Context, key string) error {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+
+	return v.destroy(key)
+}
+
+func (v *PKCS11Vault) find(key string) (ckULong, bool, error) {
+	tmpl := new(template).
+		add(ckaClass, ulongValue(ckoData)).
+		add(ckaToken, []byte{1}).
+		add(ckaApplication, []byte(v.app)).
+		add(ckaLabel, []byte(key))
+	defer tmpl.unpin()
+	fl := v.module.fl
+	if rv := call(fl.findObjectsInit, v.session, uintptr(unsafe.Pointer(&tmpl.attrs[0])), ckULong(len(tmpl.attrs))); rv != ckrOK {
+		return 0, false, pkcs11Error("find object", rv)
+	}
+	var object, found ckULong
+	rv := call(fl.findObjects, v.session, uintptr(unsafe.Pointer(&object)), 1, uintptr(unsafe.Pointer(&found)))
+	finalRV := call(fl.findObjectsFinal, v.session)
+	if rv == ckrOK {
+		rv = finalRV
+	}
+	if rv != ckrOK {
+		return 0, false, pkcs11Error("find object", rv)
+	}
+	return object, found > 0, nil
+}
+
+func (v *PKCS11Vault) destroy(key string) error {
+	object, found, err := v.find(key)
+	if err != nil || !found {
+		return err
+	}
+	if rv := call(v.module.fl.destroyObject, v.session, object); rv != ckrOK {
+		return pkcs11Error("destroy object", rv)
+	}
+	return nil
+}
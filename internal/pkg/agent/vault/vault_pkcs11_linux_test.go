@@ -0,0 +1,88 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux && (amd64 || arm64)
+
+package vault
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// softHSMModules are the usual locations of the SoftHSM module, SOFTHSM2_MODULE overrides them.
+var softHSMModules = []string{
+	"/usr/lib/softhsm/libsofthsm2.so",
+	"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/lib/aarch64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/lib64/pkcs11/libsofthsm2.so",
+}
+
+// newSoftHSMToken initializes a SoftHSM token in a temporary directory and returns the vault
+// backend configuration using it.
+func newSoftHSMToken(t *testing.T) PKCS11Config {
+	module := os.Getenv("SOFTHSM2_MODULE")
+	if module == "" {
+		for _, m := range softHSMModules {
+			if _, err := os.Stat(m); err == nil {
+				module = m
+				break
+			}
+		}
+	}
+	util, err := exec.LookPath("softhsm2-util")
+	if module == "" || err != nil {
+		t.Skip("SoftHSM is not installed")
+	}
+
+	dir := t.TempDir()
+	tokens := filepath.Join(dir, "tokens")
+	require.NoError(t, os.Mkdir(tokens, 0700))
+	conf := filepath.Join(dir, "softhsm2.conf")
+	require.NoError(t, os.WriteFile(conf, []byte("directories.tokendir = "+tokens+"\n"), 0600))
+	t.Setenv("SOFTHSM2_CONF", conf)
+
+	out, err := exec.Command(util, "--init-token", "--free", "--label", "agent", "--pin", "1234", "--so-pin", "5678").CombinedOutput()
+	require.NoError(t, err, string(out))
+	pinFile := filepath.Join(dir, "pin")
+	require.NoError(t, os.WriteFile(pinFile, []byte("1234\n"), 0600))
+
+	return PKCS11Config{Module: module, TokenLabel: "agent", PINFile: pinFile}
+}
+
+func TestPKCS11Vault(t *testing.T) {
+	cfg := newSoftHSMToken(t)
+	ctx := t.Context()
+
+	options, err := ApplyOptions(WithBackend(BackendConfig{Backend: BackendPKCS11, PKCS11: cfg}))
+	require.NoError(t, err)
+	v, err := NewPKCS11Vault(ctx, options)
+	require.NoError(t, err)
+	testVaultOperations(t, v)
+
+	// the objects are stored on the token
+	require.NoError(t, v.Set(ctx, "key", []byte("value")))
+	require.NoError(t, v.Close())
+	v, err = NewPKCS11Vault(ctx, options)
+	require.NoError(t, err)
+	defer v.Close()
+	data, err := v.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), data)
+
+	// a wrong PIN or token is reported
+	require.NoError(t, os.WriteFile(cfg.PINFile, []byte("0000"), 0600))
+	_, err = NewPKCS11Vault(ctx, options)
+	assert.ErrorContains(t, err, "PIN incorrect")
+	cfg.TokenLabel = "missing"
+	options, err = ApplyOptions(WithBackend(BackendConfig{Backend: BackendPKCS11, PKCS11: cfg}))
+	require.NoError(t, err)
+	_, err = NewPKCS11Vault(ctx, options)
+	assert.ErrorContains(t, err, "token not found")
+}
@@ -0,0 +1,39 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux || !(amd64 || arm64)
+
+package vault
+
+import (
+	"context"
+)
+
+// Empty PKCS11Vault implementation for non-linux OSes and architectures
+type PKCS11Vault struct {
+}
+
+func (v *PKCS11Vault) Exists(ctx context.Context, key string) (bool, error) {
+	return false, ErrBackendNotAvailable
+}
+
+func (v *PKCS11Vault) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, ErrBackendNotAvailable
+}
+
+func (v *PKCS11Vault) Set(ctx context.Context, key string, data []byte) error {
+	return ErrBackendNotAvailable
+}
+
+func (v *PKCS11Vault) Remove(ctx context.Context, key string) error {
+	return ErrBackendNotAvailable
+}
+
+func (v *PKCS11Vault) Close() error {
+	return ErrBackendNotAvailable
+}
+
+func NewPKCS11Vault(ctx context.Context, opts Options) (*PKCS11Vault, error) {
+	return nil, ErrBackendNotAvailable
+}
@@ -6,6 +6,8 @@ package restart
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/elastic/elastic-agent/pkg/control"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -18,21 +20,31 @@ import (
 
 // NewCommandWithArgs returns a new version command.
 func NewCommandWithArgs(streams *cli.IOStreams) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "restart",
 		Short: "Restart the currently running Elastic Agent daemon",
+		Long: `Restart the currently running Elastic Agent daemon.
+
+When maintenance windows are configured the restart is deferred until the next maintenance window,
+use --force to restart immediately.`,
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			force, _ := cmd.Flags().GetBool("force")
 			c := client.New()
 			err := c.Connect(context.Background())
 			if err != nil {
 				return errors.New(err, "Failed communicating to running daemon", errors.TypeNetwork, errors.M("socket", control.Address()))
 			}
 			defer c.Disconnect()
-			err = c.Restart(context.Background())
+			deferredUntil, err := c.RestartOrDefer(context.Background(), force)
 			if err != nil {
 				return errors.New(err, "Failed trigger restart of daemon")
 			}
+			if !deferredUntil.IsZero() {
+				fmt.Fprintf(streams.Out, "Restart deferred until the next maintenance window at %s, use --force to restart immediately\n", deferredUntil.Local().Format(time.RFC3339))
+			}
 			return nil
 		},
 	}
+	cmd.Flags().Bool("force", false, "Restart immediately even outside of the maintenance windows")
+	return cmd
 }
@@ -19,6 +19,11 @@ type Capabilities interface {
 	AllowUpgrade(version string, sourceURI string) bool
 	AllowInput(name string) bool
 	AllowOutput(name string) bool
+	AllowProcessor(name string) bool
+	// AllowComponent returns true when the first component rule matching either the input type
+	// of the component (endpoint) or the name of its command (endpoint-security) allows it.
+	AllowComponent(inputType string, commandName string) bool
+	CheckOutputSettings(outputType string, settings map[string]interface{}) error
 	AllowFleetOverride() bool
 }
 
@@ -26,6 +31,9 @@ type capabilitiesManager struct {
 	log               *logger.Logger
 	inputChecks       []*stringMatcher
 	outputChecks      []*stringMatcher
+	processorChecks   []*stringMatcher
+	componentChecks   []*stringMatcher
+	outputSettingCaps []*outputSettingCapability
 	upgradeCaps       []*upgradeCapability
 	fleetOverrideCaps []*fleetOverrideCapability
 }
@@ -38,6 +46,18 @@ func (cm *capabilitiesManager) AllowOutput(outputType string) bool {
 	return matchString(outputType, cm.outputChecks)
 }
 
+func (cm *capabilitiesManager) AllowProcessor(processorType string) bool {
+	return matchString(processorType, cm.processorChecks)
+}
+
+func (cm *capabilitiesManager) AllowComponent(inputType string, commandName string) bool {
+	return matchAnyString([]string{inputType, commandName}, cm.componentChecks)
+}
+
+func (cm *capabilitiesManager) CheckOutputSettings(outputType string, settings map[string]interface{}) error {
+	return checkOutputSettings(outputType, settings, cm.outputSettingCaps)
+}
+
 func (cm *capabilitiesManager) AllowUpgrade(version string, uri string) bool {
 	return allowUpgrade(cm.log, version, uri, cm.upgradeCaps)
 }
@@ -77,6 +97,9 @@ func Load(capsReader io.Reader, log *logger.Logger) (Capabilities, error) {
 		log:               log,
 		inputChecks:       caps.inputChecks,
 		outputChecks:      caps.outputChecks,
+		processorChecks:   caps.processorChecks,
+		componentChecks:   caps.componentChecks,
+		outputSettingCaps: caps.outputSettingChecks,
 		upgradeCaps:       caps.upgradeChecks,
 		fleetOverrideCaps: caps.fleetOverrideChecks,
 	}, nil
@@ -112,3 +112,70 @@ func TestNoCaps(t *testing.T) {
 	assert.True(t, caps.AllowInput("system/logs"))
 	assert.True(t, caps.AllowOutput("elasticsearch"))
 }
+
+func TestDenyProcessorsAndComponents(t *testing.T) {
+	yml := `
+capabilities:
+- rule: deny
+  processor: script
+- rule: allow
+  component: filebeat
+- rule: deny
+  component: "*"
+`
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+
+	assert.False(t, caps.AllowProcessor("script"))
+	assert.True(t, caps.AllowProcessor("add_fields"))
+	assert.True(t, caps.AllowComponent("filestream", "filebeat"))
+	assert.False(t, caps.AllowComponent("endpoint", "endpoint-security"))
+	assert.True(t, caps.AllowInput("endpoint"), "component rules must not affect input rules")
+}
+
+func TestOutputSettings(t *testing.T) {
+	yml := `
+capabilities:
+- output_setting: worker
+  max: 4
+- output_setting: bulk_max_size
+  output: elasticsearch
+  max: 1600
+- output_setting: queue.mem.events
+  max: 8192
+`
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+
+	assert.NoError(t, caps.CheckOutputSettings("elasticsearch", map[string]interface{}{
+		"worker":        4,
+		"bulk_max_size": 1600.0,
+	}))
+	assert.NoError(t, caps.CheckOutputSettings("logstash", map[string]interface{}{
+		"bulk_max_size": 3200,
+	}), "bulk_max_size is only capped for the elasticsearch output")
+	assert.ErrorContains(t, caps.CheckOutputSettings("logstash", map[string]interface{}{
+		"worker": "8",
+	}), "'worker' value 8 exceeds the maximum of 4")
+	assert.ErrorContains(t, caps.CheckOutputSettings("elasticsearch", map[string]interface{}{
+		"bulk_max_size": 3200,
+	}), "'bulk_max_size' value 3200 exceeds the maximum of 1600")
+	assert.Error(t, caps.CheckOutputSettings("kafka", map[string]interface{}{
+		"queue": map[string]interface{}{"mem": map[string]interface{}{"events": 10000}},
+	}))
+	assert.Error(t, caps.CheckOutputSettings("kafka", map[string]interface{}{
+		"queue.mem.events": 10000,
+	}))
+	assert.Error(t, caps.CheckOutputSettings("kafka", map[string]interface{}{
+		"worker": "many",
+	}), "settings that are not numbers cannot be checked")
+}
+
+func TestOutputSettingsRequiresMax(t *testing.T) {
+	yml := `
+capabilities:
+- output_setting: worker
+`
+	_, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.Error(t, err)
+}
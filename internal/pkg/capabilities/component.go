@@ -0,0 +1,121 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package capabilities
+
+import (
+	"fmt"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// ApplyToComponent enforces the component, processor and output setting rules on the component.
+//
+// A component whose type is denied is marked as failed and never started. Units using a denied
+// processor, and all the units of a component whose output exceeds an output setting maximum or
+// uses a denied processor, are marked as failed and not sent to the component. In both cases the
+// status of the component reports the rule that blocked it. Returns true when anything was blocked.
+//
+// The processors at the top level of the policy are not sent to the components, only the processors
+// of the inputs, of their streams and of the outputs run and are checked.
+func ApplyToComponent(caps Capabilities, comp *component.Component) bool {
+	if caps == nil {
+		return false
+	}
+
+	if comp.Err == nil && comp.InputSpec != nil {
+		if !caps.AllowComponent(comp.InputType, comp.InputSpec.CommandName()) {
+			comp.Err = fmt.Errorf("component type '%s' (%s) is denied by capabilities", comp.InputType, comp.InputSpec.CommandName())
+			return true
+		}
+	}
+
+	blocked := false
+	units := make([]component.Unit, len(comp.Units))
+	copy(units, comp.Units)
+
+	var outputErr error
+	for _, unit := range units {
+		if unit.Type == client.UnitTypeOutput && unit.Err == nil {
+			cfg := unit.Config.GetSource().AsMap()
+			outputErr = caps.CheckOutputSettings(comp.OutputType, cfg)
+			if outputErr == nil {
+				outputErr = deniedProcessor(caps, cfg, "output processor")
+			}
+		}
+	}
+
+	for i, unit := range units {
+		if unit.Err != nil {
+			continue
+		}
+		if outputErr != nil {
+			units[i].Err = outputErr
+			blocked = true
+			continue
+		}
+		if unit.Type != client.UnitTypeInput {
+			continue
+		}
+		if err := deniedProcessor(caps, unit.Config.GetSource().AsMap(), "processor"); err != nil {
+			units[i].Err = err
+			blocked = true
+		}
+	}
+	comp.Units = units
+	return blocked
+}
+
+// deniedProcessor returns an error naming the first processor of the unit configuration that is
+// denied by the capabilities.
+func deniedProcessor(caps Capabilities, cfg map[string]interface{}, kind string) error {
+	for _, processorType := range processorTypes(cfg) {
+		if !caps.AllowProcessor(processorType) {
+			return fmt.Errorf("%s '%s' is denied by capabilities", kind, processorType)
+		}
+	}
+	return nil
+}
+
+// processorTypes returns the type of the processors defined on the unit and on its streams.
+func processorTypes(cfg map[string]interface{}) []string {
+	types := collectProcessorTypes(nil, cfg["processors"])
+	streams, _ := cfg["streams"].([]interface{})
+	for _, s := range streams {
+		stream, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		types = collectProcessorTypes(types, stream["processors"])
+	}
+	return types
+}
+
+func collectProcessorTypes(types []string, processors interface{}) []string {
+	var list []interface{}
+	switch p := processors.(type) {
+	case []interface{}:
+		list = p
+	case map[string]interface{}:
+		// the branches of a conditional processor can hold a single processor
+		list = []interface{}{p}
+	}
+	for _, p := range list {
+		processor, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for name := range processor {
+			if name == "if" || name == "then" || name == "else" {
+				// conditional processor, the processors are in the then and else branches
+				continue
+			}
+			types = append(types, name)
+		}
+		types = collectProcessorTypes(types, processor["then"])
+		types = collectProcessorTypes(types, processor["else"])
+	}
+	return types
+}
@@ -0,0 +1,132 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package capabilities
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestApplyToComponent(t *testing.T) {
+	yml := `
+capabilities:
+- rule: deny
+  processor: script
+- rule: deny
+  component: endpoint
+- rule: deny
+  component: osquerybeat
+- output_setting: worker
+  max: 4
+`
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+
+	newComponent := func(inputType string, binaryName string, output map[string]interface{}, inputs ...map[string]interface{}) component.Component {
+		comp := component.Component{
+			ID:         "comp",
+			InputType:  inputType,
+			OutputType: "elasticsearch",
+			InputSpec:  &component.InputRuntimeSpec{BinaryName: binaryName},
+		}
+		for _, input := range inputs {
+			comp.Units = append(comp.Units, component.Unit{
+				ID:     input["id"].(string),
+				Type:   client.UnitTypeInput,
+				Config: component.MustExpectedConfig(input),
+			})
+		}
+		comp.Units = append(comp.Units, component.Unit{
+			ID:     "comp-default",
+			Type:   client.UnitTypeOutput,
+			Config: component.MustExpectedConfig(output),
+		})
+		return comp
+	}
+	output := map[string]interface{}{"type": "elasticsearch", "worker": 2}
+
+	t.Run("component type denied by input type", func(t *testing.T) {
+		comp := newComponent("endpoint", "endpoint-security", output, map[string]interface{}{"id": "endpoint"})
+		assert.True(t, ApplyToComponent(caps, &comp))
+		assert.EqualError(t, comp.Err, "component type 'endpoint' (endpoint-security) is denied by capabilities")
+	})
+
+	t.Run("component type denied by command name", func(t *testing.T) {
+		comp := newComponent("osquery", "osquerybeat", output, map[string]interface{}{"id": "osquery"})
+		assert.True(t, ApplyToComponent(caps, &comp))
+		assert.EqualError(t, comp.Err, "component type 'osquery' (osquerybeat) is denied by capabilities")
+	})
+
+	t.Run("processor denied", func(t *testing.T) {
+		comp := newComponent("filestream", "filebeat", output,
+			map[string]interface{}{
+				"id": "allowed",
+				"processors": []interface{}{
+					map[string]interface{}{"add_fields": map[string]interface{}{}},
+				},
+			},
+			map[string]interface{}{
+				"id": "stream-processor",
+				"streams": []interface{}{
+					map[string]interface{}{
+						"processors": []interface{}{
+							map[string]interface{}{
+								"if":   map[string]interface{}{"has_fields": []interface{}{"message"}},
+								"then": map[string]interface{}{"script": map[string]interface{}{}},
+							},
+						},
+					},
+				},
+			},
+		)
+		units := comp.Units
+		assert.True(t, ApplyToComponent(caps, &comp))
+		require.NoError(t, comp.Err)
+		assert.NoError(t, comp.Units[0].Err)
+		assert.EqualError(t, comp.Units[1].Err, "processor 'script' is denied by capabilities")
+		assert.NoError(t, comp.Units[2].Err)
+		assert.NoError(t, units[1].Err, "the units of the original component must not be modified")
+	})
+
+	t.Run("output setting exceeded", func(t *testing.T) {
+		comp := newComponent("filestream", "filebeat", map[string]interface{}{"type": "elasticsearch", "worker": 8},
+			map[string]interface{}{"id": "input"})
+		assert.True(t, ApplyToComponent(caps, &comp))
+		require.NoError(t, comp.Err)
+		for _, unit := range comp.Units {
+			assert.ErrorContains(t, unit.Err, "'worker' value 8 exceeds the maximum of 4")
+		}
+	})
+
+	t.Run("output processor denied", func(t *testing.T) {
+		comp := newComponent("filestream", "filebeat", map[string]interface{}{
+			"type": "elasticsearch",
+			"processors": []interface{}{
+				map[string]interface{}{"script": map[string]interface{}{}},
+			},
+		}, map[string]interface{}{"id": "input"})
+		assert.True(t, ApplyToComponent(caps, &comp))
+		require.NoError(t, comp.Err)
+		for _, unit := range comp.Units {
+			assert.EqualError(t, unit.Err, "output processor 'script' is denied by capabilities")
+		}
+	})
+
+	t.Run("nothing blocked", func(t *testing.T) {
+		comp := newComponent("filestream", "filebeat", output, map[string]interface{}{"id": "input"})
+		assert.False(t, ApplyToComponent(caps, &comp))
+		assert.NoError(t, comp.Err)
+		for _, unit := range comp.Units {
+			assert.NoError(t, unit.Err)
+		}
+	})
+}
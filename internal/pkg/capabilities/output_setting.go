@@ -0,0 +1,98 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package capabilities
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type outputSettingCapability struct {
+	// The name of the output setting, nested settings use dots
+	// (e.g. "queue.mem.events").
+	setting string
+
+	// The output types the capability applies to, a pattern with the same
+	// syntax as the output capabilities.
+	output string
+
+	// The maximum value allowed for the setting.
+	max float64
+}
+
+func newOutputSettingCapability(setting string, output string, max *float64) (*outputSettingCapability, error) {
+	if setting == "" {
+		return nil, errors.New("output_setting cannot be empty")
+	}
+	if max == nil {
+		return nil, fmt.Errorf("output_setting '%s' requires a max value", setting)
+	}
+	if output == "" {
+		output = wild
+	}
+	return &outputSettingCapability{
+		setting: setting,
+		output:  output,
+		max:     *max,
+	}, nil
+}
+
+// checkOutputSettings returns an error describing the first output setting that
+// exceeds its maximum. Settings that are not present are not checked.
+func checkOutputSettings(outputType string, settings map[string]interface{}, caps []*outputSettingCapability) error {
+	for _, cap := range caps {
+		if cap == nil || !matchesExpr(cap.output, outputType) {
+			continue
+		}
+		val, ok := lookupSetting(settings, cap.setting)
+		if !ok {
+			continue
+		}
+		n, ok := toFloat(val)
+		if !ok {
+			return fmt.Errorf("output setting '%s' must be a number to be checked against capabilities; received %v", cap.setting, val)
+		}
+		if n > cap.max {
+			return fmt.Errorf("output setting '%s' value %v exceeds the maximum of %v allowed by capabilities", cap.setting, val, cap.max)
+		}
+	}
+	return nil
+}
+
+// lookupSetting returns the value of the setting either as a flat dotted key or
+// as nested dictionaries.
+func lookupSetting(settings map[string]interface{}, name string) (interface{}, bool) {
+	if val, ok := settings[name]; ok {
+		return val, true
+	}
+	head, rest, found := strings.Cut(name, ".")
+	if !found {
+		return nil, false
+	}
+	nested, ok := settings[head].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookupSetting(nested, rest)
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
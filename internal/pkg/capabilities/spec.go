@@ -15,6 +15,9 @@ import (
 type capabilitiesList struct {
 	inputChecks         []*stringMatcher
 	outputChecks        []*stringMatcher
+	processorChecks     []*stringMatcher
+	componentChecks     []*stringMatcher
+	outputSettingChecks []*outputSettingCapability
 	upgradeChecks       []*upgradeCapability
 	fleetOverrideChecks []*fleetOverrideCapability
 }
@@ -57,6 +60,22 @@ func (r *capabilitiesList) UnmarshalYAML(unmarshal func(interface{}) error) erro
 			}
 			r.inputChecks = append(r.inputChecks,
 				&stringMatcher{pattern: spec.Input, rule: spec.Type})
+		} else if _, found = mm["output_setting"]; found {
+			// checked before output, output_setting capabilities can
+			// restrict the output types they apply to
+			spec := struct {
+				Setting string   `yaml:"output_setting"`
+				Output  string   `yaml:"output"`
+				Max     *float64 `yaml:"max"`
+			}{}
+			if err := yaml.Unmarshal(partialYaml, &spec); err != nil {
+				return err
+			}
+			cap, err := newOutputSettingCapability(spec.Setting, spec.Output, spec.Max)
+			if err != nil {
+				return fmt.Errorf("invalid output_setting capability for definition number '%d': %w", i, err)
+			}
+			r.outputSettingChecks = append(r.outputSettingChecks, cap)
 		} else if _, found = mm["output"]; found {
 			spec := struct {
 				Type   allowOrDeny `yaml:"rule"`
@@ -67,6 +86,26 @@ func (r *capabilitiesList) UnmarshalYAML(unmarshal func(interface{}) error) erro
 			}
 			r.outputChecks = append(r.outputChecks,
 				&stringMatcher{pattern: spec.Output, rule: spec.Type})
+		} else if _, found = mm["processor"]; found {
+			spec := struct {
+				Type      allowOrDeny `yaml:"rule"`
+				Processor string      `yaml:"processor"`
+			}{}
+			if err := yaml.Unmarshal(partialYaml, &spec); err != nil {
+				return err
+			}
+			r.processorChecks = append(r.processorChecks,
+				&stringMatcher{pattern: spec.Processor, rule: spec.Type})
+		} else if _, found = mm["component"]; found {
+			spec := struct {
+				Type      allowOrDeny `yaml:"rule"`
+				Component string      `yaml:"component"`
+			}{}
+			if err := yaml.Unmarshal(partialYaml, &spec); err != nil {
+				return err
+			}
+			r.componentChecks = append(r.componentChecks,
+				&stringMatcher{pattern: spec.Component, rule: spec.Type})
 		} else if _, found = mm["upgrade"]; found {
 			// Serialize upgrade constraints to a temporary struct so we can
 			// safely assemble the associated EQL expression
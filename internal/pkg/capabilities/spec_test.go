@@ -23,6 +23,9 @@ func TestUnmarshal(t *testing.T) {
 		assert.Equal(t, 1, len(rr.Capabilities.inputChecks))
 		assert.Equal(t, 1, len(rr.Capabilities.outputChecks))
 		assert.Equal(t, 1, len(rr.Capabilities.upgradeChecks))
+		assert.Equal(t, 1, len(rr.Capabilities.processorChecks))
+		assert.Equal(t, 1, len(rr.Capabilities.componentChecks))
+		assert.Equal(t, 1, len(rr.Capabilities.outputSettingChecks))
 	})
 
 	t.Run("invalid yaml", func(t *testing.T) {
@@ -44,6 +47,16 @@ var yamlDefinitionValid = []byte(`capabilities:
 -
   output: "elasticsearch"
   rule: "allow"
+-
+  processor: "script"
+  rule: "deny"
+-
+  component: "endpoint-security"
+  rule: "deny"
+-
+  output_setting: "bulk_max_size"
+  output: "elasticsearch"
+  max: 1600
 `)
 
 var yamlDefinitionInvalid = []byte(`
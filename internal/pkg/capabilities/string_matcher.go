@@ -25,3 +25,15 @@ func matchString(str string, matchers []*stringMatcher) bool {
 	// If nothing blocked it, default to allow.
 	return true
 }
+
+// matchAnyString is matchString where a matcher applies when it matches any of the strings.
+func matchAnyString(strs []string, matchers []*stringMatcher) bool {
+	for _, matcher := range matchers {
+		for _, str := range strs {
+			if str != "" && matchesExpr(matcher.pattern, str) {
+				return matcher.rule == ruleTypeAllow
+			}
+		}
+	}
+	return true
+}
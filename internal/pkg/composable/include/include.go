@@ -23,6 +23,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/composable/providers/local"
 	"github.com/elastic/elastic-agent/internal/pkg/composable/providers/localdynamic"
 	"github.com/elastic/elastic-agent/internal/pkg/composable/providers/path"
+	"github.com/elastic/elastic-agent/internal/pkg/composable/providers/secret"
 )
 
 var once sync.Once
@@ -42,5 +43,6 @@ func Providers() {
 		composable.Providers.MustAddContextProvider("local", local.ContextProviderBuilder)
 		composable.Providers.MustAddDynamicProvider("local_dynamic", localdynamic.DynamicProviderBuilder)
 		composable.Providers.MustAddContextProvider("path", path.ContextProviderBuilder)
+		composable.Providers.MustAddContextProvider("secret", secret.ContextProviderBuilder)
 	})
 }
@@ -0,0 +1,62 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// agentVaultKeyPrefix prefixes the vault keys of the secrets, it keeps the policies from reading
+// the other keys of the vault such as the key of the encrypted configuration.
+const agentVaultKeyPrefix = "composable/secret/"
+
+// agentVaultBackend reads the secrets from the vault of the Elastic Agent. The secret of a path is
+// stored under the vault key agentVaultKeyPrefix + path as a JSON object of its fields.
+type agentVaultBackend struct {
+	vaultPath    string
+	unprivileged bool
+}
+
+func newAgentVaultBackend(cfg AgentVaultConfig) (*agentVaultBackend, error) {
+	isRoot, err := utils.HasRoot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for root/Administrator privileges: %w", err)
+	}
+	vaultPath := cfg.VaultPath
+	if vaultPath == "" {
+		vaultPath = paths.AgentVaultPath()
+	}
+	return &agentVaultBackend{vaultPath: vaultPath, unprivileged: !isRoot}, nil
+}
+
+func (b *agentVaultBackend) fetch(ctx context.Context, path string) (map[string]string, error) {
+	v, err := vault.New(ctx, vault.WithVaultPath(b.vaultPath), vault.WithReadonly(true), vault.WithUnprivileged(b.unprivileged))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the vault: %w", err)
+	}
+	defer v.Close()
+
+	data, err := v.Get(ctx, agentVaultKeyPrefix+path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errSecretNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from the vault: %w", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode the secret: %w", err)
+	}
+	return stringFields(fields)
+}
@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/internal/pkg/crypto"
+)
+
+// encryptedFileMaxSize limits the size of the decrypted content of an encrypted file.
+const encryptedFileMaxSize = 1024 * 1024 // 1MiB
+
+// encryptedFileBackend reads the secrets from a local file encrypted with the password stored in
+// the password file. Once decrypted the file is a YAML document mapping each path to the fields of
+// its secret:
+//
+//	db/production:
+//	  username: elastic
+//	  password: changeme
+//
+// The file is read on each fetch so changes are picked up by the refresh of the cache.
+type encryptedFileBackend struct {
+	cfg EncryptedFileConfig
+}
+
+func newEncryptedFileBackend(cfg EncryptedFileConfig) *encryptedFileBackend {
+	return &encryptedFileBackend{cfg: cfg}
+}
+
+func (b *encryptedFileBackend) fetch(_ context.Context, path string) (map[string]string, error) {
+	password, err := os.ReadFile(b.cfg.PasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password file: %w", err)
+	}
+	password = bytes.TrimSpace(password)
+
+	f, err := os.Open(b.cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open encrypted file: %w", err)
+	}
+	defer f.Close()
+
+	r, err := crypto.NewReaderWithDefaults(f, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt encrypted file: %w", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, encryptedFileMaxSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt encrypted file: %w", err)
+	}
+
+	var secrets map[string]map[string]any
+	if err := yaml.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted file: %w", err)
+	}
+	fields, ok := secrets[path]
+	if !ok {
+		return nil, errSecretNotFound
+	}
+	return stringFields(fields)
+}
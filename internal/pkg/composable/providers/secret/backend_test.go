@@ -0,0 +1,157 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/crypto"
+)
+
+// vaultKVStandIn is a local HTTP stand-in of the KV secrets engines of HashiCorp Vault.
+func vaultKVStandIn(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/db/production":
+			_, _ = w.Write([]byte(`{"data":{"data":{"username":"elastic","password":"changeme","port":5432},"metadata":{"version":3}}}`))
+		case "/v1/secret/data/db/deleted":
+			_, _ = w.Write([]byte(`{"data":{"data":null,"metadata":{"version":2,"deletion_time":"2026-01-01T00:00:00Z"}}}`))
+		case "/v1/kv/db/production":
+			if r.Header.Get("X-Vault-Namespace") != "team" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"password":"v1-changeme"},"lease_duration":2764800}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVaultKVBackend(t *testing.T) {
+	ctx := t.Context()
+	srv := vaultKVStandIn(t)
+
+	cfg := defaultVaultKVConfig()
+	cfg.Address = srv.URL
+	cfg.Token = "s.token"
+	require.NoError(t, cfg.Validate())
+	b, err := newVaultKVBackend(cfg)
+	require.NoError(t, err)
+
+	fields, err := b.fetch(ctx, "db/production")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"username": "elastic", "password": "changeme", "port": "5432"}, fields)
+	_, err = b.fetch(ctx, "db/missing")
+	assert.ErrorIs(t, err, errSecretNotFound)
+	_, err = b.fetch(ctx, "db/deleted")
+	assert.ErrorIs(t, err, errSecretNotFound)
+
+	// the token file is read on each request
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("s.expired\n"), 0600))
+	cfg.Token = ""
+	cfg.TokenFile = tokenFile
+	_, err = b.fetch(ctx, "db/production")
+	assert.ErrorContains(t, err, "permission denied")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("s.token\n"), 0600))
+	_, err = b.fetch(ctx, "db/production")
+	assert.NoError(t, err)
+
+	// version 1 of the KV secrets engine
+	cfg = defaultVaultKVConfig()
+	cfg.Address = srv.URL
+	cfg.Token = "s.token"
+	cfg.Mount = "kv"
+	cfg.KVVersion = 1
+	cfg.Namespace = "team"
+	b, err = newVaultKVBackend(cfg)
+	require.NoError(t, err)
+	fields, err = b.fetch(ctx, "db/production")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"password": "v1-changeme"}, fields)
+}
+
+func TestVaultKVConfigValidate(t *testing.T) {
+	cfg := defaultVaultKVConfig()
+	assert.EqualError(t, cfg.Validate(), "address is required")
+	cfg.Address = "https://vault:8200"
+	assert.EqualError(t, cfg.Validate(), "exactly one of token or token_file is required")
+	cfg.Token = "s.token"
+	cfg.TokenFile = "/etc/vault-token"
+	assert.EqualError(t, cfg.Validate(), "exactly one of token or token_file is required")
+	cfg.TokenFile = ""
+	cfg.KVVersion = 3
+	assert.EqualError(t, cfg.Validate(), "unsupported kv_version 3, must be 1 or 2")
+}
+
+func TestEncryptedFileBackend(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	password := []byte("a-long-enough-password")
+	passwordFile := filepath.Join(dir, "secrets.key")
+	require.NoError(t, os.WriteFile(passwordFile, append(password, '\n'), 0600))
+
+	var buf bytes.Buffer
+	w, err := crypto.NewWriterWithDefaults(&buf, password)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("db/production:\n  username: elastic\n  password: changeme\n  options:\n    ssl: true\n"))
+	require.NoError(t, err)
+	path := filepath.Join(dir, "secrets.enc")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+
+	b := newEncryptedFileBackend(EncryptedFileConfig{Path: path, PasswordFile: passwordFile})
+	fields, err := b.fetch(ctx, "db/production")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"username": "elastic", "password": "changeme", "options": `{"ssl":true}`}, fields)
+	_, err = b.fetch(ctx, "db/staging")
+	assert.ErrorIs(t, err, errSecretNotFound)
+
+	// a wrong password cannot decrypt the file
+	require.NoError(t, os.WriteFile(passwordFile, []byte("another-long-password"), 0600))
+	_, err = b.fetch(ctx, "db/production")
+	assert.ErrorContains(t, err, "failed to decrypt encrypted file")
+}
+
+func TestAgentVaultBackend(t *testing.T) {
+	ctx := t.Context()
+	vaultPath := filepath.Join(t.TempDir(), "vault")
+
+	b, err := newAgentVaultBackend(AgentVaultConfig{VaultPath: vaultPath})
+	require.NoError(t, err)
+
+	v, err := vault.New(ctx, vault.WithVaultPath(vaultPath), vault.WithUnprivileged(b.unprivileged))
+	require.NoError(t, err)
+	require.NoError(t, v.Set(ctx, agentVaultKeyPrefix+"db/production", []byte(`{"username":"elastic","password":"changeme"}`)))
+	require.NoError(t, v.Set(ctx, "secret", []byte(`{"v":"a2V5"}`)))
+	require.NoError(t, v.Close())
+
+	fields, err := b.fetch(ctx, "db/production")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"username": "elastic", "password": "changeme"}, fields)
+
+	// the keys of the vault outside of the prefix cannot be read
+	_, err = b.fetch(ctx, "../../secret")
+	assert.ErrorIs(t, err, errSecretNotFound)
+	_, err = b.fetch(ctx, "db/staging")
+	assert.ErrorIs(t, err, errSecretNotFound)
+}
@@ -0,0 +1,125 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+)
+
+// vaultKVMaxResponseSize limits the size of a response of the Vault API.
+const vaultKVMaxResponseSize = 1024 * 1024 // 1MiB
+
+// vaultKVBackend reads the secrets from a HashiCorp Vault KV secrets engine, version 1 or 2, through
+// the Vault HTTP API. The path of a reference is relative to the mount of the secrets engine.
+type vaultKVBackend struct {
+	cfg    *VaultKVConfig
+	client *http.Client
+}
+
+func newVaultKVBackend(cfg *VaultKVConfig) (*vaultKVBackend, error) {
+	client, err := cfg.Transport.Client(httpcommon.WithAPMHTTPInstrumentation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	return &vaultKVBackend{cfg: cfg, client: client}, nil
+}
+
+func (b *vaultKVBackend) fetch(ctx context.Context, path string) (map[string]string, error) {
+	token, err := b.token()
+	if err != nil {
+		return nil, err
+	}
+
+	elems := []string{"v1", b.cfg.Mount}
+	if b.cfg.KVVersion == 2 {
+		elems = append(elems, "data")
+	}
+	u, err := url.JoinPath(b.cfg.Address, append(elems, strings.Split(path, "/")...)...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if b.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", b.cfg.Namespace)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errSecretNotFound
+	default:
+		return nil, fmt.Errorf("unexpected response status %q: %s", resp.Status, vaultKVErrors(resp.Body))
+	}
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, vaultKVMaxResponseSize)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	data := body.Data
+	if b.cfg.KVVersion == 2 {
+		// the version 2 wraps the data of the secret with its metadata
+		var v2 struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &v2); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		data = v2.Data
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if fields == nil {
+		// a deleted version of a version 2 secret has no data
+		return nil, errSecretNotFound
+	}
+	return stringFields(fields)
+}
+
+// token returns the Vault token, the token file is read on each request so the token can be
+// rotated without restarting the Elastic Agent.
+func (b *vaultKVBackend) token() (string, error) {
+	if b.cfg.TokenFile == "" {
+		return b.cfg.Token, nil
+	}
+	data, err := os.ReadFile(b.cfg.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// vaultKVErrors returns the errors reported in the body of a failed response.
+func vaultKVErrors(r io.Reader) string {
+	var body struct {
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r, vaultKVMaxResponseSize)).Decode(&body); err != nil || len(body.Errors) == 0 {
+		return "no error reported"
+	}
+	return strings.Join(body.Errors, "; ")
+}
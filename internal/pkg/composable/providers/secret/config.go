@@ -0,0 +1,95 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+const (
+	backendVaultKV       = "vault_kv"
+	backendEncryptedFile = "encrypted_file"
+	backendAgentVault    = "agent_vault"
+)
+
+// Config for secret provider
+type Config struct {
+	// Backends are the named backends a secret reference resolves through.
+	Backends map[string]*config.Config `config:"backends"`
+
+	RefreshInterval time.Duration `config:"cache_refresh_interval" validate:"positive,nonzero"`
+	TTLDelete       time.Duration `config:"cache_ttl"`
+	RequestTimeout  time.Duration `config:"cache_request_timeout" validate:"positive,nonzero"`
+	DisableCache    bool          `config:"cache_disable"`
+}
+
+// defaultConfig returns default configuration for secret provider
+func defaultConfig() *Config {
+	return &Config{
+		RefreshInterval: 60 * time.Second,
+		TTLDelete:       1 * time.Hour,
+		RequestTimeout:  5 * time.Second,
+		DisableCache:    false,
+	}
+}
+
+// backendTypeConfig selects the type of a backend.
+type backendTypeConfig struct {
+	Type string `config:"type"`
+}
+
+// VaultKVConfig for a backend reading the secrets from a HashiCorp Vault KV secrets engine
+type VaultKVConfig struct {
+	Address   string `config:"address"`
+	Token     string `config:"token"`
+	TokenFile string `config:"token_file"`
+	Namespace string `config:"namespace"`
+	Mount     string `config:"mount"`
+	KVVersion int    `config:"kv_version"`
+
+	Transport httpcommon.HTTPTransportSettings `config:",inline"`
+}
+
+func defaultVaultKVConfig() *VaultKVConfig {
+	return &VaultKVConfig{
+		Mount:     "secret",
+		KVVersion: 2,
+		Transport: httpcommon.DefaultHTTPTransportSettings(),
+	}
+}
+
+// Validate validates the configuration.
+func (c *VaultKVConfig) Validate() error {
+	if c.Address == "" {
+		return errors.New("address is required")
+	}
+	if (c.Token == "") == (c.TokenFile == "") {
+		return errors.New("exactly one of token or token_file is required")
+	}
+	if c.Mount == "" {
+		return errors.New("mount cannot be empty")
+	}
+	if c.KVVersion != 1 && c.KVVersion != 2 {
+		return fmt.Errorf("unsupported kv_version %d, must be 1 or 2", c.KVVersion)
+	}
+	return nil
+}
+
+// EncryptedFileConfig for a backend reading the secrets from a local file encrypted with a password
+type EncryptedFileConfig struct {
+	Path         string `config:"path" validate:"required"`
+	PasswordFile string `config:"password_file" validate:"required"`
+}
+
+// AgentVaultConfig for a backend reading the secrets from the vault of the Elastic Agent
+type AgentVaultConfig struct {
+	// VaultPath overrides the path of the vault, defaults to the vault of the Elastic Agent.
+	VaultPath string `config:"vault_path"`
+}
@@ -0,0 +1,120 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// secret is a secret fetched from a backend that is stored in the cache
+type secret struct {
+	// backend is the name of the backend the secret is fetched from
+	backend string
+	// path is the path of the secret in the backend
+	path string
+	// fields are the fields of the secret, nil when it does not exist or could not be fetched
+	fields map[string]string
+	// failed is true when the fetch failed with an error other than errSecretNotFound, the
+	// secret is never served and is fetched again until it succeeds
+	failed bool
+	// fetchTime is the time the secret was fetched from the backend
+	fetchTime time.Time
+}
+
+// expirationCache is a store that expires items after time.Now - secret.lastAccess > ttl (if ttl > 0) at Get.
+type expirationCache struct {
+	sync.Mutex
+	// ttl is the time-to-live for items in the cache
+	ttl time.Duration
+	// items is the underlying cache store.
+	items map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	s          secret
+	lastAccess time.Time
+}
+
+// newExpirationCache creates and returns an expirationCache
+func newExpirationCache(ttl time.Duration) *expirationCache {
+	return &expirationCache{
+		items: make(map[string]*cacheEntry),
+		ttl:   ttl,
+	}
+}
+
+// Get returns the secret associated with the given key from the store if it exists and is not expired. If updateAccess is true
+// and the secret exists, the expiration check is skipped and the lastAccess timestamp is updated to time.Now().
+func (c *expirationCache) Get(key string, updateAccess bool) (secret, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, exists := c.items[key]
+	if !exists {
+		return secret{}, false
+	}
+	if updateAccess {
+		entry.lastAccess = time.Now()
+	} else if c.isExpired(entry.lastAccess) {
+		delete(c.items, key)
+		return secret{}, false
+	}
+	return entry.s, true
+}
+
+// Add adds the secret to the store, unless the store already holds a secret fetched after it, and
+// returns the secret now held by the store. The lastAccess timestamp is updated to time.Now().
+func (c *expirationCache) Add(key string, s secret) secret {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, exists := c.items[key]
+	if !exists {
+		c.items[key] = &cacheEntry{s, time.Now()}
+		return s
+	}
+	if !entry.s.fetchTime.After(s.fetchTime) {
+		entry.s = s
+	}
+	entry.lastAccess = time.Now()
+	return entry.s
+}
+
+// Update replaces the secret of an existing key when its fields or its failed state have changed
+// and the secret in the store was not fetched after it. It returns true when the secret was replaced.
+func (c *expirationCache) Update(key string, s secret) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, exists := c.items[key]
+	if !exists || entry.s.fetchTime.After(s.fetchTime) || (entry.s.failed == s.failed && maps.Equal(entry.s.fields, s.fields)) {
+		return false
+	}
+	entry.s = s
+	return true
+}
+
+// ListKeys returns a list of all the keys of the secrets in the store without checking for expiration
+func (c *expirationCache) ListKeys() []string {
+	c.Lock()
+	defer c.Unlock()
+
+	list := make([]string, 0, len(c.items))
+	for key := range c.items {
+		list = append(list, key)
+	}
+	return list
+}
+
+// isExpired returns true if the item has expired based on the ttl
+func (c *expirationCache) isExpired(lastAccess time.Time) bool {
+	if c.ttl <= 0 {
+		// no expiration
+		return false
+	}
+	return time.Since(lastAccess) > c.ttl
+}
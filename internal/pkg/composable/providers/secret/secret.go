@@ -0,0 +1,270 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	corecomp "github.com/elastic/elastic-agent/internal/pkg/core/composable"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+var _ corecomp.FetchContextProvider = (*contextProviderSecret)(nil)
+
+const secretProviderName = "secret"
+
+// errSecretNotFound is returned by a backend when no secret is stored at the path.
+var errSecretNotFound = errors.New("secret not found")
+
+// backend retrieves the secrets from a secret store.
+type backend interface {
+	// fetch returns the fields of the secret stored at path, errSecretNotFound if there is none.
+	fetch(ctx context.Context, path string) (map[string]string, error)
+}
+
+// reference is a parsed secret reference of the format secret.<backend>/<path>#<field>.
+type reference struct {
+	backend string
+	path    string
+	field   string
+}
+
+// parseReference parses the variable key of a secret reference.
+func parseReference(key string) (reference, error) {
+	ref, ok := strings.CutPrefix(key, secretProviderName+".")
+	if !ok {
+		return reference{}, fmt.Errorf("not a %s reference", secretProviderName)
+	}
+	backendPath, field, ok := strings.Cut(ref, "#")
+	if !ok || field == "" {
+		return reference{}, errors.New("missing the field after '#'")
+	}
+	name, path, ok := strings.Cut(backendPath, "/")
+	if !ok || name == "" || path == "" {
+		return reference{}, errors.New("missing the backend or the path")
+	}
+	return reference{backend: name, path: path, field: field}, nil
+}
+
+type contextProviderSecret struct {
+	logger   *logger.Logger
+	config   *Config
+	backends map[string]backend
+	store    *expirationCache
+}
+
+// ContextProviderBuilder builds the secret context provider. The provider resolves the references
+// ${secret.<backend>/<path>#<field>} through the backends defined in Config.Backends. Like the
+// kubernetes_secrets provider, it employs a cache refreshed every Config.RefreshInterval where each
+// secret expires once it was not referenced for Config.TTLDelete. On expiration or when the value
+// of a secret changes the provider calls ContextProviderComm.Signal() so the components are
+// reconfigured. The cache can be disabled by setting Config.DisableCache to true.
+func ContextProviderBuilder(logger *logger.Logger, c *config.Config, _ bool) (corecomp.ContextProvider, error) {
+	cfg := defaultConfig()
+
+	if c == nil {
+		c = config.New()
+	}
+
+	err := c.UnpackTo(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack configuration: %w", err)
+	}
+
+	backends := make(map[string]backend, len(cfg.Backends))
+	for name, backendCfg := range cfg.Backends {
+		if strings.ContainsAny(name, "/#") {
+			return nil, fmt.Errorf("backend name %q cannot contain '/' or '#'", name)
+		}
+		b, err := newBackend(backendCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create backend %q: %w", name, err)
+		}
+		backends[name] = b
+	}
+
+	return &contextProviderSecret{
+		logger:   logger,
+		config:   cfg,
+		backends: backends,
+		store:    newExpirationCache(cfg.TTLDelete),
+	}, nil
+}
+
+// newBackend creates the backend of the given configuration.
+func newBackend(c *config.Config) (backend, error) {
+	if c == nil {
+		c = config.New()
+	}
+	var typeCfg backendTypeConfig
+	if err := c.UnpackTo(&typeCfg); err != nil {
+		return nil, err
+	}
+	switch typeCfg.Type {
+	case backendVaultKV:
+		cfg := defaultVaultKVConfig()
+		if err := c.UnpackTo(cfg); err != nil {
+			return nil, err
+		}
+		return newVaultKVBackend(cfg)
+	case backendEncryptedFile:
+		var cfg EncryptedFileConfig
+		if err := c.UnpackTo(&cfg); err != nil {
+			return nil, err
+		}
+		return newEncryptedFileBackend(cfg), nil
+	case backendAgentVault:
+		var cfg AgentVaultConfig
+		if err := c.UnpackTo(&cfg); err != nil {
+			return nil, err
+		}
+		return newAgentVaultBackend(cfg)
+	case "":
+		return nil, errors.New("type is required")
+	default:
+		return nil, fmt.Errorf("unknown type %q, must be one of %s, %s or %s", typeCfg.Type, backendVaultKV, backendEncryptedFile, backendAgentVault)
+	}
+}
+
+// Run runs the secret context provider.
+func (p *contextProviderSecret) Run(ctx context.Context, comm corecomp.ContextProviderComm) error {
+	if !p.config.DisableCache {
+		go p.refreshCache(ctx, comm)
+	}
+	<-comm.Done()
+	return comm.Err()
+}
+
+// Fetch returns the secret value for the given key
+func (p *contextProviderSecret) Fetch(key string) (string, bool) {
+	ref, err := parseReference(key)
+	if err != nil {
+		p.logger.Warnf(`Invalid secret reference %q: %s. Secrets should be of the format secret.backend/path#field`, key, err)
+		return "", false
+	}
+	if _, ok := p.backends[ref.backend]; !ok {
+		p.logger.Warnf(`Invalid secret reference %q: unknown backend %q`, key, ref.backend)
+		return "", false
+	}
+
+	ctx := context.Background()
+	cacheKey := ref.backend + "/" + ref.path
+
+	if p.config.DisableCache {
+		// cache disabled - fetch secret from the backend
+		fields, err := p.fetchFromBackend(ctx, ref.backend, ref.path)
+		if err != nil {
+			p.logger.Warnf(`Could not retrieve secret %q from backend %q: %s`, ref.path, ref.backend, err)
+			return "", false
+		}
+		value, ok := fields[ref.field]
+		return value, ok
+	}
+
+	// cache enabled
+	entry, exists := p.store.Get(cacheKey, true)
+	if !exists || entry.failed {
+		// cache miss or failed fetch - fetch secret from the backend
+		now := time.Now()
+		fields, err := p.fetchFromBackend(ctx, ref.backend, ref.path)
+		if err != nil {
+			p.logger.Warnf(`Could not retrieve secret %q from backend %q: %s`, ref.path, ref.backend, err)
+		}
+		// a missing secret and a failed fetch are cached, the refresh signals when the secret
+		// becomes available
+		failed := err != nil && !errors.Is(err, errSecretNotFound)
+		entry = p.store.Add(cacheKey, secret{backend: ref.backend, path: ref.path, fields: fields, failed: failed, fetchTime: now})
+	}
+	if entry.failed {
+		return "", false
+	}
+	value, ok := entry.fields[ref.field]
+	if !ok {
+		p.logger.Warnf(`Could not retrieve field %q of secret %q from backend %q because it does not exist`, ref.field, ref.path, ref.backend)
+	}
+	return value, ok
+}
+
+// refreshCache refreshes the secrets in the cache every p.config.RefreshInterval
+func (p *contextProviderSecret) refreshCache(ctx context.Context, comm corecomp.ContextProviderComm) {
+	timer := time.NewTimer(p.config.RefreshInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			p.logger.Debug("Cache: refresh started")
+			if p.updateSecrets(ctx) {
+				p.logger.Info("Cache: refresh ended with updates, agent will be notified")
+				comm.Signal()
+			} else {
+				p.logger.Debug("Cache: refresh ended without updates")
+			}
+			timer.Reset(p.config.RefreshInterval)
+		}
+	}
+}
+
+// updateSecrets re-fetches all the non-expired secrets from their backends and returns true if any
+// of the secrets has expired or has changed, including a failed fetch that now succeeds. A secret
+// that cannot be fetched because of an error other than errSecretNotFound keeps its cached value.
+func (p *contextProviderSecret) updateSecrets(ctx context.Context) bool {
+	hasUpdates := false
+
+	for _, key := range p.store.ListKeys() {
+		entry, exists := p.store.Get(key, false)
+		if !exists {
+			p.logger.Infof(`Cache: %q expired`, key)
+			hasUpdates = true
+			continue
+		}
+
+		now := time.Now()
+		fields, err := p.fetchFromBackend(ctx, entry.backend, entry.path)
+		if err != nil && !errors.Is(err, errSecretNotFound) {
+			p.logger.Warnf(`Cache: could not refresh secret %q from backend %q: %s`, entry.path, entry.backend, err)
+			continue
+		}
+		if p.store.Update(key, secret{backend: entry.backend, path: entry.path, fields: fields, fetchTime: now}) {
+			p.logger.Infof(`Cache: %q updated`, key)
+			hasUpdates = true
+		}
+	}
+
+	return hasUpdates
+}
+
+// fetchFromBackend fetches the fields of the secret from the backend
+func (p *contextProviderSecret) fetchFromBackend(ctx context.Context, name string, path string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.config.RequestTimeout)
+	defer cancel()
+
+	return p.backends[name].fetch(ctx, path)
+}
+
+// stringFields converts the decoded fields of a secret into strings, the values that are not
+// strings are JSON encoded.
+func stringFields(data map[string]any) (map[string]string, error) {
+	fields := make(map[string]string, len(data))
+	for k, v := range data {
+		if s, ok := v.(string); ok {
+			fields[k] = s
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field %q: %w", k, err)
+		}
+		fields[k] = string(b)
+	}
+	return fields, nil
+}
@@ -0,0 +1,334 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ctesting "github.com/elastic/elastic-agent/internal/pkg/composable/testing"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestParseReference(t *testing.T) {
+	for key, tc := range map[string]struct {
+		expected reference
+		err      string
+	}{
+		"secret.vault/db/production#password": {expected: reference{backend: "vault", path: "db/production", field: "password"}},
+		"secret.local/app.token#value":        {expected: reference{backend: "local", path: "app.token", field: "value"}},
+		"secret.vault/db/production":          {err: "missing the field after '#'"},
+		"secret.vault/db/production#":         {err: "missing the field after '#'"},
+		"secret.vault#password":               {err: "missing the backend or the path"},
+		"secret./db#password":                 {err: "missing the backend or the path"},
+		"kubernetes_secrets.ns.name.value":    {err: "not a secret reference"},
+	} {
+		t.Run(key, func(t *testing.T) {
+			ref, err := parseReference(key)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ref)
+		})
+	}
+}
+
+func TestContextProviderBuilder(t *testing.T) {
+	log, _ := loggertest.New("secret")
+
+	for name, tc := range map[string]struct {
+		cfg map[string]interface{}
+		err string
+	}{
+		"no backends": {},
+		"all backends": {
+			cfg: map[string]interface{}{
+				"backends": map[string]interface{}{
+					"vault": map[string]interface{}{"type": "vault_kv", "address": "http://localhost:8200", "token": "root"},
+					"local": map[string]interface{}{"type": "encrypted_file", "path": "/etc/secrets.enc", "password_file": "/etc/secrets.key"},
+					"agent": map[string]interface{}{"type": "agent_vault"},
+				},
+			},
+		},
+		"missing type": {
+			cfg: map[string]interface{}{"backends": map[string]interface{}{"vault": map[string]interface{}{"address": "http://localhost:8200"}}},
+			err: `failed to create backend "vault": type is required`,
+		},
+		"unknown type": {
+			cfg: map[string]interface{}{"backends": map[string]interface{}{"vault": map[string]interface{}{"type": "aws"}}},
+			err: `failed to create backend "vault": unknown type "aws"`,
+		},
+		"invalid vault_kv": {
+			cfg: map[string]interface{}{"backends": map[string]interface{}{"vault": map[string]interface{}{"type": "vault_kv", "address": "http://localhost:8200"}}},
+			err: "exactly one of token or token_file is required",
+		},
+		"invalid encrypted_file": {
+			cfg: map[string]interface{}{"backends": map[string]interface{}{"local": map[string]interface{}{"type": "encrypted_file", "path": "/etc/secrets.enc"}}},
+			err: "password_file",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ContextProviderBuilder(log, config.MustNewConfigFrom(tc.cfg), false)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	b := newFakeBackend(map[string]map[string]string{
+		"db/production": {"username": "elastic", "password": "changeme"},
+	})
+	p := newTestProvider(t, defaultConfig(), b)
+
+	for key, expected := range map[string]struct {
+		value string
+		found bool
+	}{
+		"secret.fake/db/production#password": {"changeme", true},
+		"secret.fake/db/production#username": {"elastic", true},
+		"secret.fake/db/production#missing":  {"", false},
+		"secret.fake/db/staging#password":    {"", false},
+		"secret.other/db/production#value":   {"", false},
+		"secret.fake/db/production":          {"", false},
+	} {
+		value, found := p.Fetch(key)
+		assert.Equal(t, expected.value, value, key)
+		assert.Equal(t, expected.found, found, key)
+	}
+	assert.Equal(t, 2, b.fetchCount(), "the fields of a path are fetched once")
+
+	// the cache keeps the values
+	b.set("db/production", map[string]string{"password": "updated"})
+	value, found := p.Fetch("secret.fake/db/production#password")
+	assert.True(t, found)
+	assert.Equal(t, "changeme", value)
+
+	// a failed fetch is not served and is fetched again on the next reference
+	b.setErr(errors.New("connection refused"))
+	_, found = p.Fetch("secret.fake/db/development#password")
+	assert.False(t, found)
+	b.set("db/development", map[string]string{"password": "created"})
+	_, found = p.Fetch("secret.fake/db/development#password")
+	assert.False(t, found)
+	b.setErr(nil)
+	b.set("db/development", map[string]string{"password": "created"})
+	value, found = p.Fetch("secret.fake/db/development#password")
+	assert.True(t, found)
+	assert.Equal(t, "created", value)
+
+	// unless it is disabled
+	cfg := defaultConfig()
+	cfg.DisableCache = true
+	p = newTestProvider(t, cfg, b)
+	value, found = p.Fetch("secret.fake/db/production#password")
+	assert.True(t, found)
+	assert.Equal(t, "updated", value)
+}
+
+func TestUpdateSecrets(t *testing.T) {
+	ctx := t.Context()
+	b := newFakeBackend(map[string]map[string]string{
+		"db/production": {"password": "changeme"},
+		"db/staging":    {"password": "changeme"},
+	})
+	p := newTestProvider(t, defaultConfig(), b)
+
+	_, found := p.Fetch("secret.fake/db/production#password")
+	require.True(t, found)
+	_, found = p.Fetch("secret.fake/db/staging#password")
+	require.True(t, found)
+	_, found = p.Fetch("secret.fake/db/missing#password")
+	require.False(t, found)
+	assert.False(t, p.updateSecrets(ctx), "nothing changed")
+
+	// a changed secret is updated
+	b.set("db/production", map[string]string{"password": "updated"})
+	assert.True(t, p.updateSecrets(ctx))
+	value, _ := p.Fetch("secret.fake/db/production#password")
+	assert.Equal(t, "updated", value)
+
+	// a secret that becomes available is updated
+	b.set("db/missing", map[string]string{"password": "created"})
+	assert.True(t, p.updateSecrets(ctx))
+	value, found = p.Fetch("secret.fake/db/missing#password")
+	assert.True(t, found)
+	assert.Equal(t, "created", value)
+
+	// a secret that could not be fetched is updated once the backend recovers
+	b.setErr(errors.New("connection refused"))
+	_, found = p.Fetch("secret.fake/db/failing#password")
+	require.False(t, found)
+	assert.False(t, p.updateSecrets(ctx))
+	b.setErr(nil)
+	b.set("db/failing", map[string]string{"password": "recovered"})
+	fetches := b.fetchCount()
+	assert.True(t, p.updateSecrets(ctx))
+	value, found = p.Fetch("secret.fake/db/failing#password")
+	assert.True(t, found)
+	assert.Equal(t, "recovered", value)
+	assert.Equal(t, fetches+4, b.fetchCount(), "the recovered secret is served from the cache")
+
+	// a backend failure keeps the cached values
+	b.setErr(errors.New("connection refused"))
+	assert.False(t, p.updateSecrets(ctx))
+	value, found = p.Fetch("secret.fake/db/production#password")
+	assert.True(t, found)
+	assert.Equal(t, "updated", value)
+	b.setErr(nil)
+
+	// a removed secret is removed
+	b.set("db/staging", nil)
+	assert.True(t, p.updateSecrets(ctx))
+	_, found = p.Fetch("secret.fake/db/staging#password")
+	assert.False(t, found)
+
+	// a secret not referenced for the ttl expires
+	p.store.ttl = time.Millisecond
+	time.Sleep(5 * time.Millisecond)
+	assert.True(t, p.updateSecrets(ctx))
+	assert.Empty(t, p.store.ListKeys())
+}
+
+func TestRunSignalsChanges(t *testing.T) {
+	b := newFakeBackend(map[string]map[string]string{
+		"db/production": {"password": "changeme"},
+	})
+	cfg := defaultConfig()
+	cfg.RefreshInterval = 10 * time.Millisecond
+	p := newTestProvider(t, cfg, b)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	signaled := make(chan struct{}, 1)
+	comm := ctesting.NewContextComm(ctx)
+	comm.CallOnSignal(func() {
+		select {
+		case signaled <- struct{}{}:
+		default:
+		}
+	})
+	done := make(chan error)
+	go func() {
+		done <- p.Run(ctx, comm)
+	}()
+
+	value, _ := p.Fetch("secret.fake/db/production#password")
+	require.Equal(t, "changeme", value)
+	b.set("db/production", map[string]string{"password": "updated"})
+
+	select {
+	case <-signaled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the provider did not signal the update")
+	}
+	value, _ = p.Fetch("secret.fake/db/production#password")
+	assert.Equal(t, "updated", value)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestFetchFromVaultKV(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/elasticsearch" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"api_key":"c2VjcmV0"},"metadata":{"version":1}}}`))
+	}))
+	defer srv.Close()
+
+	log, _ := loggertest.New("secret")
+	p, err := ContextProviderBuilder(log, config.MustNewConfigFrom(map[string]interface{}{
+		"backends": map[string]interface{}{
+			"vault": map[string]interface{}{"type": "vault_kv", "address": srv.URL, "token": "root"},
+		},
+	}), false)
+	require.NoError(t, err)
+
+	fp, ok := p.(*contextProviderSecret)
+	require.True(t, ok)
+	value, found := fp.Fetch("secret.vault/elasticsearch#api_key")
+	assert.True(t, found)
+	assert.Equal(t, "c2VjcmV0", value)
+	_, found = fp.Fetch("secret.vault/missing#api_key")
+	assert.False(t, found)
+}
+
+func newTestProvider(t *testing.T, cfg *Config, b backend) *contextProviderSecret {
+	log, _ := loggertest.New("secret")
+	return &contextProviderSecret{
+		logger:   log,
+		config:   cfg,
+		backends: map[string]backend{"fake": b},
+		store:    newExpirationCache(cfg.TTLDelete),
+	}
+}
+
+type fakeBackend struct {
+	mx      sync.Mutex
+	secrets map[string]map[string]string
+	err     error
+	fetches int
+}
+
+func newFakeBackend(secrets map[string]map[string]string) *fakeBackend {
+	return &fakeBackend{secrets: secrets}
+}
+
+func (b *fakeBackend) fetch(_ context.Context, path string) (map[string]string, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.fetches++
+	if b.err != nil {
+		return nil, b.err
+	}
+	fields, ok := b.secrets[path]
+	if !ok {
+		return nil, errSecretNotFound
+	}
+	return fields, nil
+}
+
+func (b *fakeBackend) set(path string, fields map[string]string) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if fields == nil {
+		delete(b.secrets, path)
+		return
+	}
+	b.secrets[path] = fields
+}
+
+func (b *fakeBackend) setErr(err error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.err = err
+}
+
+func (b *fakeBackend) fetchCount() int {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.fetches
+}
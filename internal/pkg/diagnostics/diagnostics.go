@@ -6,6 +6,7 @@ package diagnostics
 
 import (
 	"archive/zip"
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
@@ -15,6 +16,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime/pprof"
+	"slices"
 	"strings"
 	"time"
 
@@ -27,6 +29,7 @@ import (
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics/redaction"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/version"
@@ -38,6 +41,9 @@ const (
 	agentName             = "elastic-agent"
 	redactionMarkerPrefix = "__mark_redact_"
 	redactionRouteKey     = "routekey"
+
+	// secretProviderReference starts the variables resolved by the secret provider.
+	secretProviderReference = "${secret."
 )
 
 var redactionHeaderValueKeys = []string{
@@ -198,6 +204,10 @@ func CreateCPUProfile(ctx context.Context, period time.Duration) ([]byte, error)
 
 // ZipArchive creates a zipped diagnostics bundle using the passed writer with the passed diagnostics and local logs.
 // If any error is encountered when writing the contents of the archive it is returned.
+//
+// When a profile or a size budget is passed in opts only the matching content is included and a manifest describing
+// what was skipped is added to the bundle. When redaction rules are passed in opts they are applied to the YAML files
+// and a report listing the rules that fired is added to the bundle.
 func ZipArchive(
 	errOut,
 	w io.Writer,
@@ -205,13 +215,30 @@ func ZipArchive(
 	agentDiag []client.DiagnosticFileResult,
 	unitDiags []client.DiagnosticUnitResult,
 	compDiags []client.DiagnosticComponentResult,
-	excludeEvents bool) error {
+	excludeEvents bool,
+	opts ...ArchiveOption) error {
 
 	ts := time.Now().UTC()
-	zw := zip.NewWriter(w)
+	o := archiveOptions{}
+	o.profile, _ = GetProfile(ProfileDefault)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	manifest := &Manifest{Profile: o.profile.Name, MaxSize: o.maxSize, Generated: ts}
+	redactions, err := o.redactor.NewBundle()
+	if err != nil {
+		return err
+	}
+
+	cw := &countingWriter{w: w}
+	zw := zip.NewWriter(cw)
 	defer zw.Close()
 	// Write agent diagnostics content
 	for _, ad := range agentDiag {
+		if !o.profile.IncludesHook(ad.Name) {
+			manifest.skip(ad.Filename, int64(len(ad.Content)), SkipReasonProfile)
+			continue
+		}
 		zf, err := zw.CreateHeader(&zip.FileHeader{
 			Name:     ad.Filename,
 			Method:   zip.Deflate,
@@ -220,17 +247,108 @@ func ZipArchive(
 		if err != nil {
 			return fmt.Errorf("error creating header for agent diagnostics: %w", err)
 		}
-		err = writeRedacted(errOut, zf, ad.Filename, ad)
+		err = writeRedactedWithRules(errOut, zf, ad.Filename, ad, redactions)
 		if err != nil {
 			return fmt.Errorf("error writing file for agent diagnostics: %w", err)
 		}
 	}
 
+	if err := zipComponents(errOut, zw, ts, o.profile, manifest, redactions, unitDiags, compDiags); err != nil {
+		return err
+	}
+
+	// Gather Logs:
+	if o.profile.Logs {
+		var budget func([]logFile) *logBudget
+		if o.maxSize > 0 {
+			budget = func(files []logFile) *logBudget {
+				// flush to count the content written so far
+				_ = zw.Flush()
+				return newLogBudget(files, o.maxSize-cw.n-manifestReserve, manifest)
+			}
+		}
+		if err := zipLogsWithBudget(zw, ts, topPath, excludeEvents, errOut, budget); err != nil {
+			return err
+		}
+	} else {
+		manifest.skip("logs/", 0, SkipReasonProfile)
+	}
+
+	if redactions != nil {
+		if err := writeRedactionReport(zw, ts, redactions); err != nil {
+			return err
+		}
+	}
+	if !o.manifest {
+		return nil
+	}
+	return writeManifest(zw, ts, manifest)
+}
+
+// writeRedactionReport adds the report of the redaction rules that fired to the bundle.
+func writeRedactionReport(zw *zip.Writer, ts time.Time, redactions *redaction.Bundle) error {
+	b, err := yaml.Marshal(redactions.Report())
+	if err != nil {
+		return fmt.Errorf("failed to marshal redaction report: %w", err)
+	}
+	zf, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     redaction.ReportFilename,
+		Method:   zip.Deflate,
+		Modified: ts,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating header for the redaction report: %w", err)
+	}
+	_, err = zf.Write(b)
+	return err
+}
+
+// writeManifest adds the manifest to the bundle.
+func writeManifest(zw *zip.Writer, ts time.Time, manifest *Manifest) error {
+	out, err := yaml.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("error marshalling diagnostics manifest: %w", err)
+	}
+	mw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     ManifestFilename,
+		Method:   zip.Deflate,
+		Modified: ts,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating .zip header for %s: %w", ManifestFilename, err)
+	}
+	_, err = mw.Write(out)
+	return err
+}
+
+// zipComponents writes the component and unit diagnostics included in the profile.
+func zipComponents(
+	errOut io.Writer,
+	zw *zip.Writer,
+	ts time.Time,
+	profile Profile,
+	manifest *Manifest,
+	redactions *redaction.Bundle,
+	unitDiags []client.DiagnosticUnitResult,
+	compDiags []client.DiagnosticComponentResult) error {
+
+	if !profile.Components && !profile.Units {
+		manifest.skip("components/", 0, SkipReasonProfile)
+		return nil
+	}
+
 	// Handle unit diagnostics
 	// structure each unit into its own component directory
 	compDirs := make(map[string][]client.DiagnosticUnitResult)
 	for _, ud := range unitDiags {
 		compDir := strings.ReplaceAll(ud.ComponentID, "/", "-")
+		if !profile.Units {
+			if ud.Err == nil && len(ud.Results) > 0 {
+				unitDir := strings.ReplaceAll(strings.TrimPrefix(ud.UnitID, ud.ComponentID+"-"), "/", "-")
+				manifest.skip(fmt.Sprintf("components/%s/%s/", compDir, unitDir), 0, SkipReasonProfile)
+			}
+			continue
+		}
 		compDirs[compDir] = append(compDirs[compDir], ud)
 	}
 
@@ -238,6 +356,12 @@ func ZipArchive(
 	// handle component diagnostics
 	for _, comp := range compDiags {
 		compDir := strings.ReplaceAll(comp.ComponentID, "/", "-")
+		if !profile.Components {
+			for _, res := range comp.Results {
+				manifest.skip(fmt.Sprintf("components/%s/%s", compDir, res.Filename), int64(len(res.Content)), SkipReasonProfile)
+			}
+			comp.Results = nil
+		}
 		componentResults[compDir] = comp
 	}
 	// write each units diagnostics into its own directory
@@ -278,7 +402,7 @@ func ZipArchive(
 				if err != nil {
 					return fmt.Errorf("error creating .zip header for %s: %w", res.Filename, err)
 				}
-				err = writeRedacted(errOut, resFileWriter, filePath, res)
+				err = writeRedactedWithRules(errOut, resFileWriter, filePath, res, redactions)
 				if err != nil {
 					return fmt.Errorf("error writing %s in zip file: %w", res.Filename, err)
 				}
@@ -319,7 +443,7 @@ func ZipArchive(
 					if err != nil {
 						return err
 					}
-					err = writeRedacted(errOut, w, filePath, fr)
+					err = writeRedactedWithRules(errOut, w, filePath, fr, redactions)
 					if err != nil {
 						return err
 					}
@@ -327,9 +451,7 @@ func ZipArchive(
 			}
 		}
 	}
-
-	// Gather Logs:
-	return zipLogs(zw, ts, topPath, excludeEvents, errOut)
+	return nil
 }
 
 func writeErrorResult(zw *zip.Writer, path string, errBody string) error {
@@ -361,6 +483,12 @@ func RedactOpts(w io.Writer) []redact.RedactOption {
 }
 
 func writeRedacted(errOut, resultWriter io.Writer, fullFilePath string, fileResult client.DiagnosticFileResult) error {
+	return writeRedactedWithRules(errOut, resultWriter, fullFilePath, fileResult, nil)
+}
+
+// writeRedactedWithRules writes the redacted content of fileResult, the redaction rules of redactions, if not nil,
+// are applied after the default redaction.
+func writeRedactedWithRules(errOut, resultWriter io.Writer, fullFilePath string, fileResult client.DiagnosticFileResult, redactions *redaction.Bundle) error {
 	out := &fileResult.Content
 
 	// Should we support json too?
@@ -374,6 +502,10 @@ func writeRedacted(errOut, resultWriter io.Writer, fullFilePath string, fileResu
 			switch t := unmarshalled.(type) { // could be a plain string, we only redact if this is a proper map
 			case map[string]any:
 				redact.Redact(t, RedactOpts(errOut)...)
+				if err := redactions.Redact(fullFilePath, t); err != nil {
+					// Best effort, the values that could not be hashed are redacted
+					fmt.Fprintf(errOut, "[WARNING] Could not hash values of %s: %s\n", fullFilePath, err)
+				}
 				redacted, err := yaml.Marshal(t)
 				if err != nil {
 					// Best effort, output a warning but still include the file
@@ -391,10 +523,12 @@ func writeRedacted(errOut, resultWriter io.Writer, fullFilePath string, fileResu
 }
 
 func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool, errOut io.Writer) error {
-	homePath := paths.HomeFrom(topPath)
-	dataPath := paths.DataFrom(topPath)
-	currentDir := filepath.Base(homePath)
+	return zipLogsWithBudget(zw, ts, topPath, excludeEvents, errOut, nil)
+}
 
+// zipLogsWithBudget copies the logs into zw. When budget is not nil it is
+// called with the log files found and returns the parts of them to copy.
+func zipLogsWithBudget(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool, errOut io.Writer, budget func([]logFile) *logBudget) error {
 	_, err := zw.CreateHeader(&zip.FileHeader{
 		Name:     "logs/",
 		Method:   zip.Deflate,
@@ -404,14 +538,31 @@ func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool, e
 		return err
 	}
 
-	if err := collectServiceComponentsLogs(zw, errOut); err != nil {
+	sink := &zipLogSink{zw: zw, ts: ts, errOut: errOut}
+	if budget != nil {
+		scan := &scanLogSink{}
+		if err := collectLogs(scan, topPath, excludeEvents, io.Discard); err != nil {
+			return err
+		}
+		sink.budget = budget(scan.files)
+	}
+	return collectLogs(sink, topPath, excludeEvents, errOut)
+}
+
+// collectLogs passes the log directories and files of the agent to sink.
+func collectLogs(sink logSink, topPath string, excludeEvents bool, errOut io.Writer) error {
+	homePath := paths.HomeFrom(topPath)
+	dataPath := paths.DataFrom(topPath)
+	currentDir := filepath.Base(homePath)
+
+	if err := collectServiceComponentsLogs(sink, errOut); err != nil {
 		fmt.Fprintf(errOut, "[WARNING] failed to collect endpoint-security logs: %s\n", err)
 	}
 
 	if !paths.IsVersionHome() {
 		// running in a container with custom top path set
 		// logs are directly under top path
-		return zipLogsWithPath(homePath, currentDir, excludeEvents, zw, ts, errOut)
+		return zipLogsWithPath(homePath, currentDir, excludeEvents, sink, errOut)
 	}
 
 	dataDir, err := os.Open(dataPath)
@@ -431,7 +582,7 @@ func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool, e
 			continue
 		}
 		path := filepath.Join(dataPath, dir)
-		if err := zipLogsWithPath(path, dir, excludeEvents, zw, ts, errOut); err != nil {
+		if err := zipLogsWithPath(path, dir, excludeEvents, sink, errOut); err != nil {
 			return err
 		}
 	}
@@ -439,46 +590,38 @@ func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool, e
 	return nil
 }
 
-// zipLogsWithPath walks {pathsHome}/logs and {pathsHome}/components/logs and copies them into zw
+// zipLogsWithPath walks {pathsHome}/logs and {pathsHome}/components/logs and passes them to sink
 // under "logs/<commitName>/" and "logs/<commitName>/components/" respectively.
-func zipLogsWithPath(pathsHome, commitName string, excludeEvents bool, zw *zip.Writer, ts time.Time, errOut io.Writer) error {
-	if _, err := zw.CreateHeader(&zip.FileHeader{
-		Name:     "logs/" + commitName + "/",
-		Method:   zip.Deflate,
-		Modified: ts,
-	}); err != nil {
+func zipLogsWithPath(pathsHome, commitName string, excludeEvents bool, sink logSink, errOut io.Writer) error {
+	if err := sink.addDir(commitName); err != nil {
 		return fmt.Errorf("failed to create logs dir entry for %s: %w", commitName, err)
 	}
 
-	if err := walkLogPath(filepath.Join(pathsHome, "logs"), commitName, excludeEvents, zw, ts, errOut); err != nil {
+	if err := walkLogPath(filepath.Join(pathsHome, "logs"), commitName, excludeEvents, sink, errOut); err != nil {
 		return fmt.Errorf("failed to collect logs from %s: %w", pathsHome, err)
 	}
 
-	if _, err := zw.CreateHeader(&zip.FileHeader{
-		Name:     "logs/" + commitName + "/components/",
-		Method:   zip.Deflate,
-		Modified: ts,
-	}); err != nil {
+	if err := sink.addDir(filepath.Join(commitName, "components")); err != nil {
 		return fmt.Errorf("failed to create components logs dir entry for %s: %w", commitName, err)
 	}
 
 	// Components may write logs under {pathsHome}/components/logs.
 	// Mirror that structure under logs/<commitName>/components/ to reflect the source layout.
-	if err := walkLogPath(filepath.Join(pathsHome, "components", "logs"), filepath.Join(commitName, "components"), excludeEvents, zw, ts, errOut); err != nil {
+	if err := walkLogPath(filepath.Join(pathsHome, "components", "logs"), filepath.Join(commitName, "components"), excludeEvents, sink, errOut); err != nil {
 		return fmt.Errorf("failed to collect component logs from %s: %w", pathsHome, err)
 	}
 
 	return nil
 }
 
-func walkLogPath(logRoot, commitName string, excludeEvents bool, zw *zip.Writer, ts time.Time, errOut io.Writer) error {
+func walkLogPath(logRoot, commitName string, excludeEvents bool, sink logSink, errOut io.Writer) error {
 	// Trailing separator is required: zipLogWalkFunc uses logPath as a TrimPrefix argument,
 	// so it must end with a separator to avoid a leading slash on relative names.
 	logPath := logRoot + string(filepath.Separator)
-	return filepath.WalkDir(logPath, zipLogWalkFunc(logPath, commitName, excludeEvents, zw, ts, errOut))
+	return filepath.WalkDir(logPath, zipLogWalkFunc(logPath, commitName, excludeEvents, sink, errOut))
 }
 
-func zipLogWalkFunc(logPath, commitName string, excludeEvents bool, zw *zip.Writer, ts time.Time, errOut io.Writer) func(path string, d fs.DirEntry, fErr error) error {
+func zipLogWalkFunc(logPath, commitName string, excludeEvents bool, sink logSink, errOut io.Writer) func(path string, d fs.DirEntry, fErr error) error {
 	return func(path string, d fs.DirEntry, fErr error) error {
 		if errors.Is(fErr, fs.ErrNotExist) {
 			return nil
@@ -506,21 +649,17 @@ func zipLogWalkFunc(logPath, commitName string, excludeEvents bool, zw *zip.Writ
 		name = filepath.Join(commitName, name)
 
 		if d.IsDir() {
-			if _, err := zw.CreateHeader(&zip.FileHeader{
-				Name:     "logs/" + filepath.ToSlash(name) + "/",
-				Method:   zip.Deflate,
-				Modified: ts,
-			}); err != nil {
+			if err := sink.addDir(name); err != nil {
 				return fmt.Errorf("unable to create log directory in archive %s: %w", name, err)
 			}
 			return nil
 		}
 
-		return saveLogs(name, path, zw, errOut)
+		return sink.addFile(name, path)
 	}
 }
 
-func collectServiceComponentsLogs(zw *zip.Writer, errOut io.Writer) error {
+func collectServiceComponentsLogs(sink logSink, errOut io.Writer) error {
 	platform, err := component.LoadPlatformDetail()
 	if err != nil {
 		return fmt.Errorf("failed to gather system information: %w", err)
@@ -554,7 +693,7 @@ func collectServiceComponentsLogs(zw *zip.Writer, errOut io.Writer) error {
 				return nil
 			}
 
-			return saveLogs("services/"+name, path, zw, errOut)
+			return sink.addFile("services/"+name, path)
 		})
 		if err != nil {
 			return err
@@ -569,6 +708,12 @@ func collectServiceComponentsLogs(zw *zip.Writer, errOut io.Writer) error {
 // Only zip-writer errors (CreateHeader, io.Copy to the zip) are returned so the caller
 // knows to stop writing to the archive.
 func saveLogs(name string, logPath string, zw *zip.Writer, errOut io.Writer) error {
+	return saveLogPart(name, logPath, logPart{length: -1}, zw, errOut)
+}
+
+// saveLogPart copies the part of the log file at logPath into the zip under "logs/<name>", see saveLogs.
+// When the part does not start at the beginning of the file the first, partial, line is skipped.
+func saveLogPart(name string, logPath string, part logPart, zw *zip.Writer, errOut io.Writer) error {
 	ts := time.Now().UTC()
 	lf, err := os.Open(logPath)
 	if err != nil {
@@ -581,6 +726,21 @@ func saveLogs(name string, logPath string, zw *zip.Writer, errOut io.Writer) err
 	if li, err := lf.Stat(); err == nil {
 		ts = li.ModTime()
 	}
+	var r io.Reader = lf
+	if part.offset > 0 {
+		if _, err := lf.Seek(part.offset, io.SeekStart); err != nil {
+			fmt.Fprintf(errOut, "[WARNING] unable to seek in log file %s: %s\n", logPath, err)
+			return nil
+		}
+		br := bufio.NewReader(io.LimitReader(lf, part.length))
+		if _, err := br.ReadBytes('\n'); err != nil {
+			// no complete line in the part
+			return nil
+		}
+		r = br
+	} else if part.length >= 0 {
+		r = io.LimitReader(lf, part.length)
+	}
 	zf, err := zw.CreateHeader(&zip.FileHeader{
 		Name:     "logs/" + filepath.ToSlash(name),
 		Method:   zip.Deflate,
@@ -589,11 +749,12 @@ func saveLogs(name string, logPath string, zw *zip.Writer, errOut io.Writer) err
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(zf, lf)
+	_, err = io.Copy(zf, r)
 	return err
 }
 
-// AddSecretMarkers adds secret redaction markers to the config by looking at the secret_paths field.
+// AddSecretMarkers adds secret redaction markers to the config by looking at the secret_paths field
+// and at the inputs and outputs fields referencing the secret provider.
 // It will add a marker to the config for each secret path.
 // The marker is added to the config as a boolean field with the name of the
 // secret path prefixed with "__mark_redact_".
@@ -604,6 +765,17 @@ func AddSecretMarkers(logger *logger.Logger, cfg *config.Config) error {
 		return err
 	}
 
+	providerPaths, err := getSecretProviderPaths(cfg)
+	if err != nil {
+		logger.Errorf("failed to get the fields referencing the secret provider: %v", err)
+		return err
+	}
+	for _, p := range providerPaths {
+		if !slices.Contains(secretPaths, p) {
+			secretPaths = append(secretPaths, p)
+		}
+	}
+
 	return addSecretMarkers(cfg, secretPaths)
 }
 
@@ -630,6 +802,54 @@ func getSecretPaths(logger *logger.Logger, cfg *config.Config) ([]string, error)
 	return res, nil
 }
 
+// getSecretProviderPaths returns the paths of the inputs and outputs fields whose value references
+// the secret provider, the value of these fields is a secret once the variables are substituted.
+func getSecretProviderPaths(cfg *config.Config) ([]string, error) {
+	var res []string
+	for _, key := range []string{"inputs", "outputs"} {
+		if !cfg.Agent.HasField(key) {
+			continue
+		}
+		child, err := cfg.Agent.Child(key, -1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", key, err)
+		}
+		var unpacked interface{}
+		if child.IsArray() {
+			var arr []interface{}
+			err = child.Unpack(&arr, config.NoResolveOptions...)
+			unpacked = arr
+		} else {
+			var dict map[string]interface{}
+			err = child.Unpack(&dict, config.NoResolveOptions...)
+			unpacked = dict
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack %s: %w", key, err)
+		}
+		res = appendSecretProviderPaths(res, key, unpacked)
+	}
+	return res, nil
+}
+
+func appendSecretProviderPaths(res []string, path string, value interface{}) []string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if s, ok := field.(string); ok && strings.Contains(s, secretProviderReference) {
+				res = append(res, path+"."+k)
+				continue
+			}
+			res = appendSecretProviderPaths(res, path+"."+k, field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			res = appendSecretProviderPaths(res, fmt.Sprintf("%s.%d", path, i), item)
+		}
+	}
+	return res
+}
+
 func addSecretMarkers(cfg *config.Config, secretPaths []string) error {
 	var aggregateError error
 
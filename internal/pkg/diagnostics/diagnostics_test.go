@@ -1087,6 +1087,57 @@ func TestAddRedactionMarkers(t *testing.T) {
 			},
 			expectedErrMsg: "secret path 2 does not exist",
 		},
+		{
+			name: "secret provider references",
+			input: map[string]interface{}{
+				"secret_paths": []interface{}{
+					"outputs.default.api_key",
+				},
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{
+						"type":    "elasticsearch",
+						"api_key": "${secret.vault/elasticsearch#api_key}",
+					},
+				},
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"type": "example",
+						"streams": []interface{}{
+							map[string]interface{}{
+								"password": "prefix-${secret.local/db/production#password}",
+								"hosts":    []interface{}{"${secret.local/db/production#host}"},
+								"username": "${env.USER}",
+							},
+						},
+					},
+				},
+			},
+			expectedConfig: map[string]interface{}{
+				"secret_paths": []interface{}{
+					"outputs.default.api_key",
+				},
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{
+						"type":                            "elasticsearch",
+						"api_key":                         "${secret.vault/elasticsearch#api_key}",
+						redactionMarkerPrefix + "api_key": true,
+					},
+				},
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"type": "example",
+						"streams": []interface{}{
+							map[string]interface{}{
+								"password":                         "prefix-${secret.local/db/production#password}",
+								redactionMarkerPrefix + "password": true,
+								"hosts":                            []interface{}{"${secret.local/db/production#host}"},
+								"username":                         "${env.USER}",
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	log, _ := loggertest.New("test")
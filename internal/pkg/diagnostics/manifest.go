@@ -0,0 +1,220 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagnostics
+
+import (
+	"archive/zip"
+	"cmp"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics/redaction"
+)
+
+// ManifestFilename is the name of the file describing the content of a
+// diagnostics bundle created with a profile or a size budget.
+const ManifestFilename = "manifest.yaml"
+
+const (
+	// SkipReasonProfile is the reason of the content not collected because it
+	// is not part of the profile.
+	SkipReasonProfile = "not part of the profile"
+	// SkipReasonSizeBudget is the reason of the logs not collected, or
+	// truncated, because the size budget of the bundle is exceeded.
+	SkipReasonSizeBudget = "size budget exceeded"
+)
+
+// manifestReserve is the part of the size budget reserved for the manifest.
+const manifestReserve = 64 * 1024
+
+// zipEntryOverhead is the approximate size of the zip headers of an entry,
+// without its name.
+const zipEntryOverhead = 128
+
+// Manifest describes the content of a diagnostics bundle.
+type Manifest struct {
+	// Profile is the name of the profile used to collect the bundle.
+	Profile string `yaml:"profile"`
+	// MaxSize is the size budget of the bundle in bytes, 0 if unlimited.
+	MaxSize int64 `yaml:"max_size,omitempty"`
+	// Generated is the time the bundle was created.
+	Generated time.Time `yaml:"generated"`
+	// Skipped is the content not included in the bundle.
+	Skipped []ManifestEntry `yaml:"skipped,omitempty"`
+	// Truncated are the logs only partially included in the bundle.
+	Truncated []ManifestEntry `yaml:"truncated,omitempty"`
+}
+
+// ManifestEntry is a file, or directory, of the bundle that was skipped or truncated.
+type ManifestEntry struct {
+	Path string `yaml:"path"`
+	// Size is the size of the file in bytes.
+	Size int64 `yaml:"size,omitempty"`
+	// Included is the number of bytes of a truncated file included in the bundle.
+	Included int64  `yaml:"included,omitempty"`
+	Reason   string `yaml:"reason"`
+}
+
+func (m *Manifest) skip(path string, size int64, reason string) {
+	m.Skipped = append(m.Skipped, ManifestEntry{Path: path, Size: size, Reason: reason})
+}
+
+// ArchiveOption is an option of ZipArchive.
+type ArchiveOption func(*archiveOptions)
+
+type archiveOptions struct {
+	profile  Profile
+	maxSize  int64
+	manifest bool
+	redactor *redaction.Redactor
+}
+
+// WithProfile only includes in the bundle the content of the profile.
+func WithProfile(p Profile) ArchiveOption {
+	return func(o *archiveOptions) {
+		o.profile = p
+		o.manifest = true
+	}
+}
+
+// WithMaxSize sets the size budget of the bundle in bytes. The logs are
+// counted with their uncompressed size and are skipped, oldest first, to keep
+// the bundle under the budget; the newest log skipped is truncated to keep its
+// last lines. The other content is always included.
+func WithMaxSize(size int64) ArchiveOption {
+	return func(o *archiveOptions) {
+		o.maxSize = size
+		o.manifest = true
+	}
+}
+
+// WithRedactor applies the redaction rules of r to the YAML files of the
+// bundle and adds a report of the rules that fired. It has no effect if r is nil.
+func WithRedactor(r *redaction.Redactor) ArchiveOption {
+	return func(o *archiveOptions) {
+		o.redactor = r
+	}
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// logSink receives the log directories and files collected in a bundle, names
+// are relative to the logs directory of the bundle.
+type logSink interface {
+	addDir(name string) error
+	addFile(name, path string) error
+}
+
+// logFile is a log file found when listing the logs.
+type logFile struct {
+	name    string
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// logPart is the part of a log file copied in the bundle, length is -1 to
+// copy up to the end of the file.
+type logPart struct {
+	offset int64
+	length int64
+}
+
+// logBudget holds the parts of the log files copied in the bundle, log files
+// missing from parts are skipped.
+type logBudget struct {
+	parts map[string]logPart
+}
+
+// newLogBudget selects the log files, newest first, fitting in available bytes.
+// The skipped and truncated files are recorded in m.
+func newLogBudget(files []logFile, available int64, m *Manifest) *logBudget {
+	files = slices.Clone(files)
+	slices.SortStableFunc(files, func(a, b logFile) int {
+		if c := b.modTime.Compare(a.modTime); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.name, b.name)
+	})
+
+	b := &logBudget{parts: make(map[string]logPart, len(files))}
+	for _, f := range files {
+		entry := "logs/" + filepath.ToSlash(f.name)
+		cost := f.size + zipEntryOverhead + int64(len(entry))
+		switch {
+		case cost <= available:
+			b.parts[f.path] = logPart{length: f.size}
+			available -= cost
+		case available > cost-f.size:
+			included := available - (cost - f.size)
+			b.parts[f.path] = logPart{offset: f.size - included, length: included}
+			m.Truncated = append(m.Truncated, ManifestEntry{Path: entry, Size: f.size, Included: included, Reason: SkipReasonSizeBudget})
+			available = 0
+		default:
+			m.skip(entry, f.size, SkipReasonSizeBudget)
+		}
+	}
+	return b
+}
+
+// zipLogSink copies the logs into a zip.
+type zipLogSink struct {
+	zw     *zip.Writer
+	ts     time.Time
+	errOut io.Writer
+	budget *logBudget
+}
+
+func (s *zipLogSink) addDir(name string) error {
+	_, err := s.zw.CreateHeader(&zip.FileHeader{
+		Name:     "logs/" + filepath.ToSlash(name) + "/",
+		Method:   zip.Deflate,
+		Modified: s.ts,
+	})
+	return err
+}
+
+func (s *zipLogSink) addFile(name, path string) error {
+	if s.budget == nil {
+		return saveLogs(name, path, s.zw, s.errOut)
+	}
+	part, ok := s.budget.parts[path]
+	if !ok {
+		return nil
+	}
+	return saveLogPart(name, path, part, s.zw, s.errOut)
+}
+
+// scanLogSink lists the log files.
+type scanLogSink struct {
+	files []logFile
+}
+
+func (s *scanLogSink) addDir(string) error {
+	return nil
+}
+
+func (s *scanLogSink) addFile(name, path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		// unreadable files are reported when copied
+		return nil
+	}
+	s.files = append(s.files, logFile{name: name, path: path, size: fi.Size(), modTime: fi.ModTime()})
+	return nil
+}
@@ -0,0 +1,188 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagnostics
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func TestGetProfile(t *testing.T) {
+	p, err := GetProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, ProfileDefault, p.Name)
+	assert.True(t, p.IncludesHook("heap"))
+
+	for _, name := range ProfileNames() {
+		p, err := GetProfile(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, p.Name)
+		assert.True(t, p.IncludesHook("version"), "profile %s must include the version", name)
+	}
+
+	p, err = GetProfile(ProfileMinimal)
+	require.NoError(t, err)
+	assert.False(t, p.IncludesHook("heap"))
+	assert.False(t, p.IncludesHook(DiagCPUName))
+
+	p, err = GetProfile(ProfilePerformance)
+	require.NoError(t, err)
+	assert.True(t, p.IncludesHook(DiagCPUName), "the CPU profile is part of the profiles collecting it")
+
+	_, err = GetProfile("unknown")
+	assert.ErrorContains(t, err, "minimal, default, full, network, performance")
+}
+
+// readZip returns the content of the files of the zip, and the names of the
+// directories with an empty content.
+func readZip(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+	files := make(map[string]string, len(r.File))
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func readManifest(t *testing.T, files map[string]string) Manifest {
+	t.Helper()
+	require.Contains(t, files, ManifestFilename)
+	var m Manifest
+	require.NoError(t, yaml.Unmarshal([]byte(files[ManifestFilename]), &m))
+	return m
+}
+
+func TestZipArchiveProfile(t *testing.T) {
+	topPath := t.TempDir()
+	logsDir := filepath.Join(paths.HomeFrom(topPath), "logs")
+	require.NoError(t, os.MkdirAll(logsDir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(logsDir, "elastic-agent.ndjson"), []byte("log\n"), 0o600))
+
+	agentDiag := []client.DiagnosticFileResult{
+		{Name: "version", Filename: "version.txt", ContentType: "text/plain", Content: []byte("9.1.0")},
+		{Name: "heap", Filename: "heap.pprof.gz", ContentType: "application/octet-stream", Content: []byte("heap")},
+	}
+	unitDiags := []client.DiagnosticUnitResult{{
+		ComponentID: "comp",
+		UnitID:      "comp-input",
+		Results:     []client.DiagnosticFileResult{{Filename: "unit.txt", ContentType: "text/plain", Content: []byte("unit")}},
+	}}
+	compDiags := []client.DiagnosticComponentResult{{
+		ComponentID: "comp",
+		Results:     []client.DiagnosticFileResult{{Filename: "comp.txt", ContentType: "text/plain", Content: []byte("comp")}},
+	}}
+
+	t.Run("no options", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		require.NoError(t, ZipArchive(io.Discard, buf, topPath, agentDiag, unitDiags, compDiags, true))
+		files := readZip(t, buf.Bytes())
+		assert.NotContains(t, files, ManifestFilename)
+		assert.Contains(t, files, "heap.pprof.gz")
+		assert.Contains(t, files, "components/comp/input/unit.txt")
+	})
+
+	t.Run("minimal", func(t *testing.T) {
+		p, err := GetProfile(ProfileMinimal)
+		require.NoError(t, err)
+		buf := new(bytes.Buffer)
+		require.NoError(t, ZipArchive(io.Discard, buf, topPath, agentDiag, unitDiags, compDiags, true, WithProfile(p)))
+		files := readZip(t, buf.Bytes())
+
+		assert.Equal(t, "9.1.0", files["version.txt"])
+		assert.NotContains(t, files, "heap.pprof.gz")
+		assert.NotContains(t, files, "components/")
+		assert.Contains(t, files, "logs/"+filepath.Base(paths.HomeFrom(topPath))+"/elastic-agent.ndjson")
+
+		m := readManifest(t, files)
+		assert.Equal(t, ProfileMinimal, m.Profile)
+		assert.Zero(t, m.MaxSize)
+		assert.Equal(t, []ManifestEntry{
+			{Path: "heap.pprof.gz", Size: 4, Reason: SkipReasonProfile},
+			{Path: "components/", Reason: SkipReasonProfile},
+		}, m.Skipped)
+	})
+
+	t.Run("network", func(t *testing.T) {
+		p, err := GetProfile(ProfileNetwork)
+		require.NoError(t, err)
+		buf := new(bytes.Buffer)
+		require.NoError(t, ZipArchive(io.Discard, buf, topPath, agentDiag, unitDiags, compDiags, true, WithProfile(p)))
+		files := readZip(t, buf.Bytes())
+
+		assert.Equal(t, "comp", files["components/comp/comp.txt"])
+		assert.NotContains(t, files, "components/comp/input/unit.txt")
+		m := readManifest(t, files)
+		assert.Contains(t, m.Skipped, ManifestEntry{Path: "components/comp/input/", Reason: SkipReasonProfile})
+	})
+}
+
+func TestZipArchiveMaxSize(t *testing.T) {
+	topPath := t.TempDir()
+	logsDir := filepath.Join(paths.HomeFrom(topPath), "logs")
+	require.NoError(t, os.MkdirAll(logsDir, 0o700))
+
+	line := strings.Repeat("x", 99) + "\n"
+	now := time.Now()
+	// 3 log files of 100 KiB, the oldest first
+	for i, name := range []string{"elastic-agent-1.ndjson", "elastic-agent-2.ndjson", "elastic-agent-3.ndjson"} {
+		var content strings.Builder
+		for l := 0; l < 1024; l++ {
+			fmt.Fprintf(&content, "%04d%s", l, line[4:])
+		}
+		path := filepath.Join(logsDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content.String()), 0o600))
+		modTime := now.Add(time.Duration(i-3) * time.Hour)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	logPrefix := "logs/" + filepath.Base(paths.HomeFrom(topPath)) + "/"
+
+	// the budget fits the manifest reserve, the newest log and half of the second newest
+	maxSize := int64(manifestReserve + 150*1024)
+	buf := new(bytes.Buffer)
+	require.NoError(t, ZipArchive(io.Discard, buf, topPath, nil, nil, nil, true, WithMaxSize(maxSize)))
+	assert.LessOrEqual(t, int64(buf.Len()), maxSize)
+
+	files := readZip(t, buf.Bytes())
+	assert.Len(t, files[logPrefix+"elastic-agent-3.ndjson"], 100*1024, "the newest log must be complete")
+	assert.NotContains(t, files, logPrefix+"elastic-agent-1.ndjson", "the oldest log must be skipped")
+
+	truncated := files[logPrefix+"elastic-agent-2.ndjson"]
+	require.NotEmpty(t, truncated)
+	assert.Less(t, len(truncated), 50*1024)
+	assert.True(t, strings.HasSuffix(truncated, "1023"+line[4:]), "the last lines of the log must be kept")
+	for _, l := range strings.Split(strings.TrimSuffix(truncated, "\n"), "\n") {
+		assert.Len(t, l, 99, "the truncated log must only contain complete lines")
+	}
+
+	m := readManifest(t, files)
+	assert.Equal(t, ProfileDefault, m.Profile)
+	assert.Equal(t, maxSize, m.MaxSize)
+	assert.Equal(t, []ManifestEntry{{Path: logPrefix + "elastic-agent-1.ndjson", Size: 100 * 1024, Reason: SkipReasonSizeBudget}}, m.Skipped)
+	require.Len(t, m.Truncated, 1)
+	assert.Equal(t, logPrefix+"elastic-agent-2.ndjson", m.Truncated[0].Path)
+	assert.Equal(t, int64(100*1024), m.Truncated[0].Size)
+	assert.Greater(t, m.Truncated[0].Included, int64(len(truncated)))
+}
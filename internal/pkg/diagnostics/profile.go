@@ -0,0 +1,119 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagnostics
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Names of the diagnostics profiles.
+const (
+	ProfileMinimal     = "minimal"
+	ProfileDefault     = "default"
+	ProfileFull        = "full"
+	ProfileNetwork     = "network"
+	ProfilePerformance = "performance"
+)
+
+// Profile selects the content of a diagnostics bundle.
+type Profile struct {
+	// Name is the name of the profile.
+	Name string
+	// Description is a short description of the profile.
+	Description string
+	// Hooks are the names of the agent diagnostics hooks included in the
+	// bundle, all of them are included when empty.
+	Hooks []string
+	// Units includes the diagnostics of the units.
+	Units bool
+	// Components includes the diagnostics of the components.
+	Components bool
+	// Logs includes the logs.
+	Logs bool
+	// CPU collects a CPU profile of the agent and of the components.
+	CPU bool
+	// Conn collects the connection request diagnostics.
+	Conn bool
+}
+
+var profiles = []Profile{
+	{
+		Name:        ProfileMinimal,
+		Description: "version, agent information, state and logs",
+		Hooks:       []string{"version", "package version", "agent-info", "state"},
+		Logs:        true,
+	},
+	{
+		Name:        ProfileDefault,
+		Description: "all the agent, component and unit diagnostics, connection request diagnostics and logs",
+		Units:       true,
+		Components:  true,
+		Logs:        true,
+		Conn:        true,
+	},
+	{
+		Name:        ProfileFull,
+		Description: "the default profile with a CPU profile",
+		Units:       true,
+		Components:  true,
+		Logs:        true,
+		CPU:         true,
+		Conn:        true,
+	},
+	{
+		Name:        ProfileNetwork,
+		Description: "configuration, component diagnostics, connection request diagnostics and logs",
+		Hooks:       []string{"version", "package version", "agent-info", "state", "environment", "local-config", "computed-config"},
+		Components:  true,
+		Logs:        true,
+		Conn:        true,
+	},
+	{
+		Name:        ProfilePerformance,
+		Description: "Go profiles and CPU profile of the agent and the components, unit diagnostics and logs",
+		Hooks:       []string{"version", "package version", "agent-info", "state", "components-actual", "goroutine", "heap", "allocs", "threadcreate", "block", "mutex"},
+		Units:       true,
+		Components:  true,
+		Logs:        true,
+		CPU:         true,
+	},
+}
+
+// GetProfile returns the diagnostics profile with the given name, the default
+// profile when name is empty.
+func GetProfile(name string) (Profile, error) {
+	if name == "" {
+		name = ProfileDefault
+	}
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown diagnostics profile %q, valid profiles are: %s", name, strings.Join(ProfileNames(), ", "))
+}
+
+// ProfileNames returns the names of the diagnostics profiles.
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// IncludesHook returns true if the result of the agent diagnostics hook with
+// the given name is part of the profile.
+func (p Profile) IncludesHook(name string) bool {
+	if len(p.Hooks) == 0 {
+		return true
+	}
+	if p.CPU && name == DiagCPUName {
+		return true
+	}
+	return slices.Contains(p.Hooks, name)
+}
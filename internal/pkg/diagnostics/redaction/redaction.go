@@ -0,0 +1,312 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package redaction implements the user-supplied redaction rules applied to the
+// diagnostics, on top of the secrets redacted by default.
+package redaction
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/redact"
+)
+
+// HashPrefix prefixes the hash replacing a value redacted by a rule with Hash set.
+const HashPrefix = "hmac-sha256:"
+
+// ReportFilename is the name of the file listing the redaction rules that
+// fired in a diagnostics bundle.
+const ReportFilename = "redaction-report.yaml"
+
+// bundleKeySize is the size of the random key the values are hashed with.
+const bundleKeySize = 32
+
+// Rule is a user-supplied rule redacting the content of the diagnostics.
+type Rule struct {
+	// Name identifies the rule in the redaction report.
+	Name string `yaml:"name" config:"name" json:"name"`
+	// Key is a glob matching the path of the redacted keys, the path segments
+	// are separated by "." and the list items are matched by their index.
+	// "*" matches a single segment, or part of it, and "**" matches any number
+	// of segments.
+	Key string `yaml:"key" config:"key" json:"key"`
+	// Value is a regular expression matching the redacted part of the string
+	// values. When Key is also set only the values of the matching keys are
+	// redacted, when Key is set alone the whole values are redacted.
+	Value string `yaml:"value" config:"value" json:"value"`
+	// Hash replaces the redacted values with their HMAC-SHA256 instead of
+	// removing them, so equal values can still be correlated within a bundle.
+	// The key is random and unique to each bundle, the values cannot be
+	// guessed from their hash nor correlated across bundles.
+	Hash bool `yaml:"hash" config:"hash" json:"hash"`
+}
+
+type compiledRule struct {
+	name  string
+	key   []string
+	value *regexp.Regexp
+	hash  bool
+}
+
+// Redactor applies the redaction rules.
+type Redactor struct {
+	rules []compiledRule
+}
+
+// New compiles the redaction rules, the rules without a name are named after
+// their position. It returns nil when there is no rule.
+func New(rules ...Rule) (*Redactor, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	r := &Redactor{rules: make([]compiledRule, 0, len(rules))}
+	names := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		c := compiledRule{name: rule.Name, hash: rule.Hash}
+		if c.name == "" {
+			c.name = "rule-" + strconv.Itoa(i+1)
+		}
+		if names[c.name] {
+			return nil, fmt.Errorf("redaction rule %q is defined more than once", c.name)
+		}
+		names[c.name] = true
+
+		if rule.Key == "" && rule.Value == "" {
+			return nil, fmt.Errorf("redaction rule %q must have a key or a value", c.name)
+		}
+		if rule.Key != "" {
+			c.key = strings.Split(rule.Key, ".")
+			for _, segment := range c.key {
+				if _, err := path.Match(segment, ""); err != nil {
+					return nil, fmt.Errorf("redaction rule %q has an invalid key %q: %w", c.name, rule.Key, err)
+				}
+			}
+		}
+		if rule.Value != "" {
+			var err error
+			c.value, err = regexp.Compile(rule.Value)
+			if err != nil {
+				return nil, fmt.Errorf("redaction rule %q has an invalid value: %w", c.name, err)
+			}
+		}
+		r.rules = append(r.rules, c)
+	}
+	return r, nil
+}
+
+// Names returns the names of the rules, in the order they are defined.
+func (r *Redactor) Names() []string {
+	names := make([]string, 0, len(r.rules))
+	for _, rule := range r.rules {
+		names = append(names, rule.name)
+	}
+	return names
+}
+
+// Report lists the redaction rules that fired in a diagnostics bundle.
+type Report struct {
+	Rules []RuleReport `yaml:"rules"`
+}
+
+// RuleReport is the number of values redacted by a rule.
+type RuleReport struct {
+	Name    string       `yaml:"name"`
+	Matches int          `yaml:"matches"`
+	Files   []FileReport `yaml:"files"`
+}
+
+// FileReport is the number of values redacted by a rule in a file of the
+// bundle.
+type FileReport struct {
+	Path    string `yaml:"path"`
+	Matches int    `yaml:"matches"`
+}
+
+// Bundle applies the rules of a Redactor to the files of a diagnostics bundle
+// and collects the rules that fired in each file.
+type Bundle struct {
+	redactor *Redactor
+	// key is the key of the HMAC replacing the values of the rules with Hash
+	// set, unique to the bundle.
+	key []byte
+	// fired holds the number of matches by rule and file.
+	fired map[string]map[string]int
+}
+
+// NewBundle returns a Bundle applying the rules to a new diagnostics bundle.
+// It returns nil if r is nil.
+func (r *Redactor) NewBundle() (*Bundle, error) {
+	if r == nil {
+		return nil, nil
+	}
+	key := make([]byte, bundleKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate the redaction hash key: %w", err)
+	}
+	return &Bundle{redactor: r, key: key, fired: make(map[string]map[string]int)}, nil
+}
+
+// Redact applies the rules in place to obj, the content of the file at
+// filePath. The values that cannot be hashed are redacted and returned as an
+// error.
+func (b *Bundle) Redact(filePath string, obj map[string]any) error {
+	if b == nil {
+		return nil
+	}
+	run := &redaction{rules: b.redactor.rules, key: b.key, counts: make(map[string]int)}
+	for k, v := range obj {
+		obj[k] = run.redactValue(v, []string{k})
+	}
+	for name, n := range run.counts {
+		if b.fired[name] == nil {
+			b.fired[name] = make(map[string]int)
+		}
+		b.fired[name][filePath] += n
+	}
+	return errors.Join(run.errs...)
+}
+
+// Report returns the rules that fired, in the order they are defined.
+func (b *Bundle) Report() Report {
+	report := Report{Rules: []RuleReport{}}
+	for _, name := range b.redactor.Names() {
+		files, ok := b.fired[name]
+		if !ok {
+			continue
+		}
+		rr := RuleReport{Name: name}
+		for filePath, n := range files {
+			rr.Matches += n
+			rr.Files = append(rr.Files, FileReport{Path: filePath, Matches: n})
+		}
+		slices.SortFunc(rr.Files, func(a, b FileReport) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+		report.Rules = append(report.Rules, rr)
+	}
+	return report
+}
+
+// redaction is the application of the rules to a file.
+type redaction struct {
+	rules  []compiledRule
+	key    []byte
+	counts map[string]int
+	errs   []error
+}
+
+func (r *redaction) redactValue(v any, keyPath []string) any {
+	for _, rule := range r.rules {
+		if rule.value == nil && matchKeyPath(rule.key, keyPath) {
+			r.counts[rule.name]++
+			return r.replace(rule, v, keyPath)
+		}
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			t[k] = r.redactValue(val, append(slices.Clip(keyPath), k))
+		}
+	case map[any]any:
+		for k, val := range t {
+			t[k] = r.redactValue(val, append(slices.Clip(keyPath), fmt.Sprint(k)))
+		}
+	case []any:
+		for i, val := range t {
+			t[i] = r.redactValue(val, append(slices.Clip(keyPath), strconv.Itoa(i)))
+		}
+	case string:
+		for _, rule := range r.rules {
+			if rule.value == nil || (rule.key != nil && !matchKeyPath(rule.key, keyPath)) {
+				continue
+			}
+			t = rule.value.ReplaceAllStringFunc(t, func(match string) string {
+				r.counts[rule.name]++
+				return r.replace(rule, match, keyPath).(string)
+			})
+		}
+		return t
+	}
+	return v
+}
+
+// replace returns the value replacing v, the value of the key at keyPath.
+func (r *redaction) replace(rule compiledRule, v any, keyPath []string) any {
+	if !rule.hash {
+		return redact.REDACTED
+	}
+	s, ok := v.(string)
+	if !ok {
+		// json sorts the map keys, the hash of a map is stable
+		b, err := json.Marshal(stringKeys(v))
+		if err != nil {
+			r.errs = append(r.errs, fmt.Errorf("redaction rule %q could not hash the value of %s, it is redacted instead: %w",
+				rule.name, strings.Join(keyPath, "."), err))
+			return redact.REDACTED
+		}
+		s = string(b)
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(s))
+	return HashPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// stringKeys returns a copy of v where the map[any]any decoded from YAML are
+// replaced by map[string]any, so it can be encoded to JSON.
+func stringKeys(v any) any {
+	switch t := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = stringKeys(val)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, val := range t {
+			m[k] = stringKeys(val)
+		}
+		return m
+	case []any:
+		l := make([]any, len(t))
+		for i, val := range t {
+			l[i] = stringKeys(val)
+		}
+		return l
+	}
+	return v
+}
+
+// matchKeyPath returns true if keyPath matches the glob pattern.
+func matchKeyPath(pattern []string, keyPath []string) bool {
+	if len(pattern) == 0 {
+		return len(keyPath) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(keyPath); i++ {
+			if matchKeyPath(pattern[1:], keyPath[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(keyPath) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], keyPath[0]); !ok {
+		return false
+	}
+	return matchKeyPath(pattern[1:], keyPath[1:])
+}
@@ -0,0 +1,126 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package redaction
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/redact"
+)
+
+func TestNew(t *testing.T) {
+	tests := map[string]struct {
+		rules []Rule
+		err   string
+	}{
+		"valid": {
+			rules: []Rule{{Key: "inputs.*.processors.**.script.source"}, {Name: "customer", Value: "cust-[0-9]+"}},
+		},
+		"empty rule": {
+			rules: []Rule{{Name: "empty"}},
+			err:   `redaction rule "empty" must have a key or a value`,
+		},
+		"invalid key": {
+			rules: []Rule{{Key: "inputs.[.id"}},
+			err:   `redaction rule "rule-1" has an invalid key`,
+		},
+		"invalid value": {
+			rules: []Rule{{Value: "("}},
+			err:   `redaction rule "rule-1" has an invalid value`,
+		},
+		"duplicated name": {
+			rules: []Rule{{Name: "a", Key: "a"}, {Name: "a", Key: "b"}},
+			err:   `redaction rule "a" is defined more than once`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(test.rules...)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	r, err := New(
+		Rule{Name: "script", Key: "inputs.*.processors.**.source"},
+		Rule{Name: "customer", Value: "cust-[0-9]+", Hash: true},
+		Rule{Name: "tags", Key: "inputs.*.tags", Value: "^team-.*"},
+	)
+	require.NoError(t, err)
+
+	obj := map[string]any{
+		"inputs": []any{
+			map[string]any{
+				"id":   "cust-1234",
+				"tags": []any{"team-a", "prod"},
+				"processors": []any{
+					map[string]any{"script": map[string]any{"source": "function process(e) {}"}},
+				},
+			},
+		},
+		"outputs": map[string]any{"default": map[string]any{"tags": []any{"team-b"}}},
+	}
+	b, err := r.NewBundle()
+	require.NoError(t, err)
+	require.NoError(t, b.Redact("pre-config.yaml", obj))
+	assert.Equal(t, Report{Rules: []RuleReport{
+		{Name: "script", Matches: 1, Files: []FileReport{{Path: "pre-config.yaml", Matches: 1}}},
+		{Name: "customer", Matches: 1, Files: []FileReport{{Path: "pre-config.yaml", Matches: 1}}},
+	}}, b.Report(), "the tags rule only applies to the values of the matching keys")
+
+	input := obj["inputs"].([]any)[0].(map[string]any)
+	assert.Equal(t, redact.REDACTED, input["processors"].([]any)[0].(map[string]any)["script"].(map[string]any)["source"])
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte("cust-1234"))
+	assert.Equal(t, HashPrefix+hex.EncodeToString(mac.Sum(nil)), input["id"])
+	assert.Equal(t, []any{"team-a", "prod"}, input["tags"], "list items are matched by their index")
+
+	r, err = New(Rule{Name: "tags", Key: "inputs.*.tags.*", Value: "^team-.*"})
+	require.NoError(t, err)
+	b, err = r.NewBundle()
+	require.NoError(t, err)
+	require.NoError(t, b.Redact("pre-config.yaml", obj))
+	assert.Equal(t, Report{Rules: []RuleReport{
+		{Name: "tags", Matches: 1, Files: []FileReport{{Path: "pre-config.yaml", Matches: 1}}},
+	}}, b.Report())
+	assert.Equal(t, []any{redact.REDACTED, "prod"}, input["tags"])
+}
+
+func TestRedactHash(t *testing.T) {
+	r, err := New(Rule{Name: "ids", Key: "*.id", Hash: true})
+	require.NoError(t, err)
+
+	redactID := func(b *Bundle, id any) any {
+		obj := map[string]any{"a": map[string]any{"id": id}}
+		require.NoError(t, b.Redact("state.yaml", obj))
+		return obj["a"].(map[string]any)["id"]
+	}
+	b1, err := r.NewBundle()
+	require.NoError(t, err)
+	b2, err := r.NewBundle()
+	require.NoError(t, err)
+	assert.Equal(t, redactID(b1, "cust-1"), redactID(b1, "cust-1"), "equal values have the same hash in a bundle")
+	assert.NotEqual(t, redactID(b1, "cust-1"), redactID(b2, "cust-1"), "each bundle hashes with its own key")
+
+	// YAML decodes the maps with non string keys to map[any]any
+	hashed := redactID(b1, map[any]any{1: "cust-1", "nested": map[any]any{true: "cust-2"}})
+	assert.Contains(t, hashed, HashPrefix)
+	assert.Equal(t, hashed, redactID(b1, map[any]any{"nested": map[any]any{true: "cust-2"}, 1: "cust-1"}))
+
+	obj := map[string]any{"a": map[string]any{"id": func() {}}}
+	assert.ErrorContains(t, b1.Redact("state.yaml", obj), `redaction rule "ids" could not hash the value of a.id`)
+	assert.Equal(t, redact.REDACTED, obj["a"].(map[string]any)["id"])
+}
@@ -0,0 +1,69 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagnostics
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics/redaction"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func TestZipArchiveRedaction(t *testing.T) {
+	r, err := redaction.New(
+		redaction.Rule{Name: "customer", Value: "cust-[0-9]+"},
+		redaction.Rule{Name: "unused", Key: "unused"},
+		redaction.Rule{Name: "env", Key: "CUSTOMER_*"},
+	)
+	require.NoError(t, err)
+	topPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(paths.HomeFrom(topPath), "logs"), 0o700))
+
+	agentDiag := []client.DiagnosticFileResult{
+		{Name: "pre-config", Filename: "pre-config.yaml", ContentType: "application/yaml", Content: []byte("id: cust-1\nname: cust-2 and cust-3\n")},
+		{Name: "environment", Filename: "environment.yaml", ContentType: "application/yaml", Content: []byte("CUSTOMER_ID: abc\nPATH: /bin\n")},
+		{Name: "version", Filename: "version.txt", ContentType: "text/plain", Content: []byte("cust-4")},
+	}
+	compDiags := []client.DiagnosticComponentResult{{
+		ComponentID: "comp",
+		Results:     []client.DiagnosticFileResult{{Filename: "comp.yaml", ContentType: "application/yaml", Content: []byte("id: cust-5\n")}},
+	}}
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, ZipArchive(io.Discard, buf, topPath, agentDiag, nil, compDiags, true, WithRedactor(r)))
+	files := readZip(t, buf.Bytes())
+
+	assert.NotContains(t, files["pre-config.yaml"], "cust-")
+	assert.Equal(t, "cust-4", files["version.txt"], "only the YAML files are redacted")
+	assert.NotContains(t, files["environment.yaml"], "abc")
+	assert.Contains(t, files["environment.yaml"], "/bin")
+	assert.NotContains(t, files["components/comp/comp.yaml"], "cust-5")
+	assert.NotContains(t, files, ManifestFilename)
+
+	var report redaction.Report
+	require.NoError(t, yaml.Unmarshal([]byte(files[redaction.ReportFilename]), &report))
+	assert.Equal(t, redaction.Report{Rules: []redaction.RuleReport{
+		{Name: "customer", Matches: 4, Files: []redaction.FileReport{
+			{Path: "components/comp/comp.yaml", Matches: 1},
+			{Path: "pre-config.yaml", Matches: 3},
+		}},
+		{Name: "env", Matches: 1, Files: []redaction.FileReport{{Path: "environment.yaml", Matches: 1}}},
+	}}, report)
+
+	buf.Reset()
+	require.NoError(t, ZipArchive(io.Discard, buf, topPath, agentDiag, nil, compDiags, true, WithRedactor(nil)))
+	files = readZip(t, buf.Bytes())
+	assert.NotContains(t, files, redaction.ReportFilename)
+	assert.Contains(t, files["pre-config.yaml"], "cust-1")
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/antlr4-go/antlr/v4"
 	"github.com/stretchr/testify/assert"
@@ -277,6 +278,21 @@ func TestEql(t *testing.T) {
 		{expression: "hasKey(${data.dict})", err: true},
 		{expression: "hasKey(${data.array}, 'not present')", err: true},
 
+		// methods net
+		{expression: "cidrMatch('10.0.0.5', '10.0.0.0/8')", result: true},
+		{expression: "cidrMatch('192.168.1.5', '10.0.0.0/8')", result: false},
+		{expression: "cidrMatch('192.168.1.5', '10.0.0.0/8', '192.168.0.0/16')", result: true},
+		{expression: "cidrMatch('192.168.1.5/24', '192.168.1.0/24')", result: true},
+		{expression: "cidrMatch('fe80::1', 'fe80::/10')", result: true},
+		{expression: "cidrMatch(${host.ip}, '172.16.0.0/12')", result: true},
+		{expression: "cidrMatch(${host.ip}, '192.168.0.0/16')", result: false},
+		{expression: "cidrMatch('not an ip', '10.0.0.0/8')", result: false},
+		{expression: "cidrMatch(${null}, '10.0.0.0/8')", allowMissingVars: true, result: false},
+		{expression: "cidrMatch('10.0.0.5', 'not a cidr')", err: true},
+		{expression: "cidrMatch('10.0.0.5', 8)", err: true},
+		{expression: "cidrMatch(10, '10.0.0.0/8')", err: true},
+		{expression: "cidrMatch('10.0.0.5')", err: true},
+
 		// methods length
 		{expression: "length('hello') == 5", result: true},
 		{expression: "length([true, 1, 3.5, 'str']) == 4", result: true},
@@ -336,6 +352,15 @@ func TestEql(t *testing.T) {
 		{expression: "number('0xbeef', 16) == 48879", result: true},
 		{expression: "number('not a number') == 'not'", err: true},
 		{expression: "number('0xbeef', 16, 2) == 'too many args'", err: true},
+		{expression: "regexCapture('elastic-agent-8.15.0', '-([0-9.]+)$') == '8.15.0'", result: true},
+		{expression: "regexCapture('elastic-agent-8.15.0', '([a-z]+)-([a-z]+)', 2) == 'agent'", result: true},
+		{expression: "regexCapture('elastic-agent-8.15.0', '(?P<major>[0-9]+)\\.', 'major') == '8'", result: true},
+		{expression: "regexCapture('elastic-agent', '[a-z]+') == 'elastic'", result: true},
+		{expression: "regexCapture('elastic-agent', '([0-9]+)') == ${null}", allowMissingVars: true, result: true},
+		{expression: "regexCapture('elastic-agent', '([a-z]+)', 2) == 'elastic'", err: true},
+		{expression: "regexCapture('elastic-agent', '([a-z]+)', 'missing') == 'elastic'", err: true},
+		{expression: "regexCapture('elastic-agent', '([a-z')", err: true},
+		{expression: "regexCapture('not enough')", err: true},
 		{expression: "startsWith('hello world', 'hello')", result: true},
 		{expression: "startsWith('hello world', 'llo')", result: false},
 		{expression: "startsWith('hello world', 'hello', 'too many args')", err: true},
@@ -354,6 +379,16 @@ func TestEql(t *testing.T) {
 		{expression: "stringContains(0, 'o w', 'too many')", err: true},
 		{expression: "stringContains('hello world', 0)", result: false},
 
+		// methods version
+		{expression: "semverCompare('8.15.0', '8.14.3') == 1", result: true},
+		{expression: "semverCompare('8.15.0', '8.15.0') == 0", result: true},
+		{expression: "semverCompare('8.15.0-SNAPSHOT', '8.15.0') == -1", result: true},
+		{expression: "semverCompare(${agent.version}, '8.12.0') >= 0", result: true},
+		{expression: "semverCompare(${agent.version}, '9.0.0') < 0", result: true},
+		{expression: "semverCompare('not a version', '8.15.0') == 0", err: true},
+		{expression: "semverCompare(8, '8.15.0') == 0", err: true},
+		{expression: "semverCompare('8.15.0') == 0", err: true},
+
 		// Bad expression and malformed expression
 		{expression: "length('hello')", err: true},
 		{expression: "length()", err: true},
@@ -368,6 +403,8 @@ func TestEql(t *testing.T) {
 			"env.HOSTNAME":    "my-hostname",
 			"env.HOSTSAME":    "my-hostname",
 			"host.name":       "host-name",
+			"host.ip":         []interface{}{"127.0.0.1/8", "::1/128", "172.17.0.3/16"},
+			"agent.version":   "8.15.2",
 			"data.array":      []interface{}{"array1", "array2", "array3"},
 			"data.with-dash":  "dash-value",
 			"data.with/slash": "some/path",
@@ -404,6 +441,108 @@ func TestEql(t *testing.T) {
 	}
 }
 
+func TestEqlTimeFunctions(t *testing.T) {
+	// Wednesday 2024-01-10 22:30 UTC, Thursday 2024-01-11 07:30 in Tokyo
+	now := time.Date(2024, time.January, 10, 22, 30, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	testcases := []struct {
+		expression string
+		result     bool
+		err        bool
+	}{
+		{expression: "hour() == 22", result: true},
+		{expression: "hour('Asia/Tokyo') == 7", result: true},
+		{expression: "hour('Not/AZone') == 7", err: true},
+		{expression: "hour('UTC', 'too many') == 7", err: true},
+		{expression: "weekday() == 'wednesday'", result: true},
+		{expression: "weekday('Asia/Tokyo') == 'thursday'", result: true},
+		{expression: "weekday(1) == 'thursday'", err: true},
+		{expression: "timeOfDayBetween('09:00', '17:00')", result: false},
+		{expression: "timeOfDayBetween('22:30', '23:00')", result: true},
+		{expression: "timeOfDayBetween('22:00', '22:30')", result: false},
+		{expression: "timeOfDayBetween('22:00', '06:00')", result: true},
+		{expression: "timeOfDayBetween('09:00', '17:00', 'Asia/Tokyo')", result: false},
+		{expression: "timeOfDayBetween('07:00', '08:00', 'Asia/Tokyo')", result: true},
+		{expression: "timeOfDayBetween('9am', '17:00')", err: true},
+		{expression: "timeOfDayBetween('09:00', 17)", err: true},
+		{expression: "timeOfDayBetween('09:00')", err: true},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.expression, func(t *testing.T) {
+			r, err := Eval(test.expression, &testVarStore{}, false)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.result, r)
+		})
+	}
+}
+
+func TestUsesTimeFunctions(t *testing.T) {
+	assert.True(t, UsesTimeFunctions("hour() == 22"))
+	assert.True(t, UsesTimeFunctions("${host.name} == 'web' and timeOfDayBetween('09:00', '17:00')"))
+	assert.True(t, UsesTimeFunctions("weekday ('UTC') == 'monday'"))
+	assert.False(t, UsesTimeFunctions("${host.weekday} == 'monday'"))
+	assert.False(t, UsesTimeFunctions("semverCompare(${agent.version}, '>= 9.0.0')"))
+	assert.False(t, UsesTimeFunctions("${host.name} == 'hour()'"), "a string constant is not a call")
+	assert.False(t, UsesTimeFunctions("hour( =="), "an invalid expression does not call any function")
+
+	now := time.Date(2024, time.January, 10, 22, 30, 15, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, time.January, 10, 22, 31, 0, 0, time.UTC), NextTimeBoundary(now))
+}
+
+func TestRegisterFunction(t *testing.T) {
+	fn := func(args []interface{}) (interface{}, error) {
+		return len(args) == 2, nil
+	}
+	t.Cleanup(func() {
+		methodsMx.Lock()
+		delete(methods, "testTwoArgs")
+		methodsMx.Unlock()
+	})
+
+	require.NoError(t, RegisterFunction("testTwoArgs", fn))
+	assert.Error(t, RegisterFunction("testTwoArgs", fn), "registering the same name twice must fail")
+	assert.Error(t, RegisterFunction("length", fn), "built-in functions cannot be replaced")
+	assert.Error(t, RegisterFunction("invalid-name", fn))
+	assert.Error(t, RegisterFunction("nilFunction", nil))
+
+	r, err := Eval("testTwoArgs(1, 'two')", &testVarStore{}, false)
+	require.NoError(t, err)
+	assert.True(t, r)
+}
+
+func TestRegisterTimeFunction(t *testing.T) {
+	fn := func(args []interface{}) (interface{}, error) {
+		return timeNow().Minute() == 0, nil
+	}
+	t.Cleanup(func() {
+		methodsMx.Lock()
+		delete(methods, "testOnTheHour")
+		delete(timeMethods, "testOnTheHour")
+		methodsMx.Unlock()
+	})
+
+	require.NoError(t, RegisterFunction("testTwoArgs", func(args []interface{}) (interface{}, error) {
+		return len(args) == 2, nil
+	}))
+	t.Cleanup(func() {
+		methodsMx.Lock()
+		delete(methods, "testTwoArgs")
+		methodsMx.Unlock()
+	})
+	require.NoError(t, RegisterTimeFunction("testOnTheHour", fn))
+	assert.Error(t, RegisterTimeFunction("hour", fn), "built-in functions cannot be replaced")
+
+	assert.True(t, UsesTimeFunctions("testOnTheHour() and ${host.name} == 'web'"))
+	assert.False(t, UsesTimeFunctions("testTwoArgs(1, 2)"))
+}
+
 func debug(t *testing.T, expression string) {
 	raw := antlr.NewInputStream(expression)
 
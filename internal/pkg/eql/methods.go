@@ -4,15 +4,30 @@
 
 package eql
 
-// callFunc is a function called while the expression evaluation is done, the function is responsible
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// Function is a function called while the expression evaluation is done, the function is responsible
 // of doing the type conversion and allow checking the arity of the function.
-type callFunc func(args []interface{}) (interface{}, error)
+type Function func(args []interface{}) (interface{}, error)
+
+var functionNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// methodsMx protects methods, functions can be registered while expressions are evaluated.
+var methodsMx sync.RWMutex
 
 // methods are the methods enabled in EQL.
-var methods = map[string]callFunc{
+var methods = map[string]Function{
 	// array
 	"arrayContains": arrayContains,
 
+	// net
+	"cidrMatch": cidrMatch,
+
 	// dict
 	"hasKey": hasKey,
 
@@ -32,7 +47,74 @@ var methods = map[string]callFunc{
 	"indexOf":        indexOf,
 	"match":          match,
 	"number":         number,
+	"regexCapture":   regexCapture,
 	"startsWith":     startsWith,
 	"string":         str,
 	"stringContains": stringContains,
+
+	// time
+	"hour":             hour,
+	"timeOfDayBetween": timeOfDayBetween,
+	"weekday":          weekday,
+
+	// version
+	"semverCompare": semverCompare,
+}
+
+// timeMethods are the methods whose result depends on the current time.
+var timeMethods = map[string]bool{
+	"hour":             true,
+	"timeOfDayBetween": true,
+	"weekday":          true,
+}
+
+// RegisterFunction makes fn callable with the provided name from every EQL expression, this includes
+// conditions in the policy, capabilities rules and runtime preventions in the component specifications.
+// An error is returned when the name is not a valid function name or when a function with the same name
+// is already registered.
+func RegisterFunction(name string, fn Function) error {
+	return registerFunction(name, fn, false)
+}
+
+// RegisterTimeFunction registers fn like RegisterFunction for a function whose result depends on the
+// current time, the expressions calling it are evaluated again at NextTimeBoundary even when none of
+// their variables changed.
+func RegisterTimeFunction(name string, fn Function) error {
+	return registerFunction(name, fn, true)
+}
+
+func registerFunction(name string, fn Function, timeDependent bool) error {
+	if !functionNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid function name %q", name)
+	}
+	if fn == nil {
+		return errors.New("function cannot be nil")
+	}
+
+	methodsMx.Lock()
+	defer methodsMx.Unlock()
+	if _, ok := methods[name]; ok {
+		return fmt.Errorf("function %s is already registered", name)
+	}
+	methods[name] = fn
+	if timeDependent {
+		timeMethods[name] = true
+	}
+	return nil
+}
+
+// lookupFunction returns the function registered with the provided name.
+func lookupFunction(name string) (Function, bool) {
+	methodsMx.RLock()
+	defer methodsMx.RUnlock()
+	fn, ok := methods[name]
+	return fn, ok
+}
+
+// isTimeFunction returns true when the function registered with the provided name depends on the
+// current time.
+func isTimeFunction(name string) bool {
+	methodsMx.RLock()
+	defer methodsMx.RUnlock()
+	return timeMethods[name]
 }
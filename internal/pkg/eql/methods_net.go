@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package eql
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// cidrMatch returns true if the IP address, or any of the IP addresses when an array is provided, is contained
+// in any of the provided CIDR ranges. Addresses with a prefix length (like 10.0.0.1/24) are accepted, the prefix
+// length is ignored.
+func cidrMatch(args []interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("cidrMatch: accepts minimum 2 arguments; received %d", len(args))
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(args)-1)
+	for i, arg := range args[1:] {
+		c, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("cidrMatch: argument %d must be a string; received %T", i+1, arg)
+		}
+		prefix, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("cidrMatch: argument %d is not a valid CIDR: %w", i+1, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	var ips []string
+	switch a := args[0].(type) {
+	case *null:
+		return false, nil
+	case string:
+		ips = []string{a}
+	case []string:
+		ips = a
+	case []interface{}:
+		for _, item := range a {
+			if s, ok := item.(string); ok {
+				ips = append(ips, s)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("cidrMatch: first argument must be a string or an array; received %T", args[0])
+	}
+
+	for _, ip := range ips {
+		addr, ok := parseAddr(ip)
+		if !ok {
+			continue
+		}
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// parseAddr parses an IP address with or without a prefix length.
+func parseAddr(ip string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		prefix, err := netip.ParsePrefix(ip)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = prefix.Addr()
+	}
+	return addr.Unmap(), true
+}
@@ -93,6 +93,50 @@ func number(args []interface{}) (interface{}, error) {
 	return int(n), nil
 }
 
+// regexCapture returns the group captured by the regular expression, the group is referenced by its index or
+// by its name, the first group is returned by default. Null is returned when the regular expression doesn't match.
+func regexCapture(args []interface{}) (interface{}, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("regexCapture: accepts 2-3 arguments; received %d", len(args))
+	}
+	input := toString(args[0])
+	r, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("regexCapture: argument 1 must be a string; received %T", args[1])
+	}
+	exp, err := regexp.Compile(r)
+	if err != nil {
+		return nil, fmt.Errorf("regexCapture: failed to compile regexp: %w", err)
+	}
+
+	group := 1
+	if exp.NumSubexp() == 0 {
+		group = 0
+	}
+	if len(args) > 2 {
+		switch g := args[2].(type) {
+		case int:
+			group = g
+		case string:
+			group = exp.SubexpIndex(g)
+			if group < 0 {
+				return nil, fmt.Errorf("regexCapture: regexp has no group named '%s'", g)
+			}
+		default:
+			return nil, fmt.Errorf("regexCapture: argument 2 must be an integer or a string; received %T", args[2])
+		}
+	}
+	if group < 0 || group > exp.NumSubexp() {
+		return nil, fmt.Errorf("regexCapture: regexp has no group %d", group)
+	}
+
+	matches := exp.FindStringSubmatch(input)
+	if matches == nil {
+		return Null, nil
+	}
+	return matches[group], nil
+}
+
 // startsWith returns true if the string starts with given prefix
 func startsWith(args []interface{}) (interface{}, error) {
 	if len(args) != 2 {
@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package eql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/antlr4-go/antlr/v4"
+
+	"github.com/elastic/elastic-agent/internal/pkg/eql/parser"
+)
+
+// timeNow returns the current time, replaced in tests.
+var timeNow = time.Now
+
+// UsesTimeFunctions returns true when the expression calls a function whose result depends on the
+// current time, a built-in time function or one registered with RegisterTimeFunction. Such an
+// expression must be evaluated again at NextTimeBoundary, even when none of its variables changed.
+// An invalid expression does not use any function.
+func UsesTimeFunctions(expression string) bool {
+	e, err := New(expression)
+	if err != nil {
+		return false
+	}
+	return callsTimeFunction(e.tree)
+}
+
+// callsTimeFunction walks the parsed expression looking for a call to a time function, the names
+// inside string constants are not calls.
+func callsTimeFunction(tree antlr.Tree) bool {
+	if fn, ok := tree.(*parser.ExpFunctionContext); ok && isTimeFunction(fn.NAME().GetText()) {
+		return true
+	}
+	for _, child := range tree.GetChildren() {
+		if callsTimeFunction(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextTimeBoundary returns the next time the time functions can return a different result, the
+// start of the next minute as the time of the day is compared at the minute and every time zone
+// offset is a whole number of minutes.
+func NextTimeBoundary(now time.Time) time.Time {
+	return now.Truncate(time.Minute).Add(time.Minute)
+}
+
+// hour returns the current hour of the day (0-23), in the provided time zone or in UTC.
+func hour(args []interface{}) (interface{}, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("hour: accepts between 0-1 arguments; received %d", len(args))
+	}
+	now, err := nowIn("hour", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return now.Hour(), nil
+}
+
+// weekday returns the current day of the week in lowercase (sunday, monday, ...), in the provided time zone
+// or in UTC.
+func weekday(args []interface{}) (interface{}, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("weekday: accepts between 0-1 arguments; received %d", len(args))
+	}
+	now, err := nowIn("weekday", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToLower(now.Weekday().String()), nil
+}
+
+// timeOfDayBetween returns true if the current time of the day is within the start (inclusive) and end
+// (exclusive) times, in HH:MM format, in the provided time zone or in UTC. The range wraps around midnight
+// when start is after end.
+func timeOfDayBetween(args []interface{}) (interface{}, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("timeOfDayBetween: accepts 2-3 arguments; received %d", len(args))
+	}
+	start, err := parseTimeOfDay("timeOfDayBetween", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTimeOfDay("timeOfDayBetween", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	now, err := nowIn("timeOfDayBetween", args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	current := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	if start <= end {
+		return current >= start && current < end, nil
+	}
+	return current >= start || current < end, nil
+}
+
+// nowIn returns the current time in the time zone provided as argument idx, UTC is used when the argument
+// is not provided.
+func nowIn(name string, args []interface{}, idx int) (time.Time, error) {
+	now := timeNow().UTC()
+	if len(args) <= idx {
+		return now, nil
+	}
+	tz, ok := args[idx].(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%s: argument %d must be a string; received %T", name, idx, args[idx])
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: unknown time zone '%s': %w", name, tz, err)
+	}
+	return now.In(loc), nil
+}
+
+// parseTimeOfDay parses argument idx in HH:MM format into the duration since midnight.
+func parseTimeOfDay(name string, args []interface{}, idx int) (time.Duration, error) {
+	s, ok := args[idx].(string)
+	if !ok {
+		return 0, fmt.Errorf("%s: argument %d must be a string; received %T", name, idx, args[idx])
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: argument %d must be a time of the day in HH:MM format; received '%s'", name, idx, s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
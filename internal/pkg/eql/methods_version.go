@@ -0,0 +1,32 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package eql
+
+import (
+	"fmt"
+
+	"github.com/elastic/elastic-agent/pkg/version"
+)
+
+// semverCompare compares two semantic versions, it returns -1 when the first version is lower than the second,
+// 0 when both are equal and 1 when the first version is greater than the second.
+func semverCompare(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("semverCompare: accepts exactly 2 arguments; received %d", len(args))
+	}
+	versions := make([]*version.ParsedSemVer, 0, len(args))
+	for i, arg := range args {
+		v, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("semverCompare: argument %d must be a string; received %T", i, arg)
+		}
+		parsed, err := version.ParseVersion(v)
+		if err != nil {
+			return nil, fmt.Errorf("semverCompare: argument %d is not a valid version: %w", i, err)
+		}
+		versions = append(versions, parsed)
+	}
+	return version.CompareParsedSemVer(*versions[0], *versions[1]), nil
+}
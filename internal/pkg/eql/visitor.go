@@ -191,7 +191,7 @@ func (v *expVisitor) VisitExpBoolean(ctx *parser.ExpBooleanContext) interface{}
 
 func (v *expVisitor) VisitExpFunction(ctx *parser.ExpFunctionContext) interface{} {
 	name := ctx.NAME().GetText()
-	method, ok := methods[name]
+	method, ok := lookupFunction(name)
 	if !ok {
 		v.err = fmt.Errorf("call to unknown function %s", name)
 		return nil
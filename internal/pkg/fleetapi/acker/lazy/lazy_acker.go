@@ -6,7 +6,8 @@ package lazy
 
 import (
 	"context"
-	"net/http"
+	"errors"
+	"fmt"
 
 	"go.elastic.co/apm/v2"
 
@@ -22,12 +23,19 @@ type retrier interface {
 	Enqueue([]fleetapi.Action)
 }
 
+type ackStore interface {
+	Add(actions ...fleetapi.Action) error
+	Acked(actions ...fleetapi.Action) error
+	Failed(ackErr error, actions ...fleetapi.Action) error
+}
+
 // Acker is a lazy acker which performs HTTP communication on commit.
 type Acker struct {
 	log     *logger.Logger
 	acker   batchAcker
 	queue   []fleetapi.Action
 	retrier retrier
+	store   ackStore
 }
 
 // Option Acker option function
@@ -55,6 +63,14 @@ func WithRetrier(r retrier) Option {
 	}
 }
 
+// WithStore option allows to specify the store persisting the pending acks,
+// so they are not lost on restart
+func WithStore(s ackStore) Option {
+	return func(f *Acker) {
+		f.store = s
+	}
+}
+
 // Ack acknowledges action.
 func (f *Acker) Ack(ctx context.Context, action fleetapi.Action) (err error) {
 	span, ctx := apm.StartSpan(ctx, "ack", "app.internal")
@@ -86,6 +102,7 @@ func (f *Acker) Commit(ctx context.Context) (err error) {
 
 	// If request failed enqueue all actions with retrier if it is set
 	if err != nil {
+		f.storeFailed(err, actions...)
 		if f.retrier != nil {
 			f.log.Warnf("lazy acker: failed ack batch, enqueue for retry: %s", actions)
 			f.retrier.Enqueue(actions)
@@ -96,25 +113,39 @@ func (f *Acker) Commit(ctx context.Context) (err error) {
 	}
 
 	// If request succeeded check the errors on individual items
-	if f.retrier != nil && resp != nil && resp.Errors {
+	if resp != nil && resp.Errors {
 		f.log.Error("lazy acker: partially failed ack batch")
 		failed := make([]fleetapi.Action, 0)
-		for i, res := range resp.Items {
-			if res.Status >= http.StatusBadRequest {
-				if i < len(actions) {
-					failed = append(failed, actions[i])
-				}
+		acked := make([]fleetapi.Action, 0, len(actions))
+		for i, action := range actions {
+			if fleetapi.AckFailed(resp, i) {
+				f.storeFailed(ackItemError(resp, i), action)
+				failed = append(failed, action)
+			} else {
+				acked = append(acked, action)
 			}
 		}
-		if len(failed) > 0 {
+		f.storeAcked(acked...)
+		if f.retrier != nil && len(failed) > 0 {
 			f.log.Infof("lazy acker: partially failed ack batch, enqueue for retry: %s", failed)
 			f.retrier.Enqueue(failed)
 		}
+		return nil
 	}
 
+	f.storeAcked(actions...)
 	return nil
 }
 
+// ackItemError returns the error of the failed ack of the action at index i
+// of a partially failed batch.
+func ackItemError(resp *fleetapi.AckResponse, i int) error {
+	if i >= len(resp.Items) {
+		return errors.New("ack has no response")
+	}
+	return fmt.Errorf("ack failed with status %d", resp.Items[i].Status)
+}
+
 func (f *Acker) enqueue(action fleetapi.Action) {
 	for _, a := range f.queue {
 		if a.ID() == action.ID() {
@@ -124,4 +155,27 @@ func (f *Acker) enqueue(action fleetapi.Action) {
 	}
 	f.queue = append(f.queue, action)
 	f.log.Debugf("appending action with id '%s' to the queue", action.ID())
+	if f.store != nil {
+		if err := f.store.Add(action); err != nil {
+			f.log.Warnf("lazy acker: failed to persist pending ack of action '%s': %v", action.ID(), err)
+		}
+	}
+}
+
+func (f *Acker) storeAcked(actions ...fleetapi.Action) {
+	if f.store == nil || len(actions) == 0 {
+		return
+	}
+	if err := f.store.Acked(actions...); err != nil {
+		f.log.Warnf("lazy acker: failed to remove delivered acks from the store: %v", err)
+	}
+}
+
+func (f *Acker) storeFailed(ackErr error, actions ...fleetapi.Action) {
+	if f.store == nil {
+		return
+	}
+	if err := f.store.Failed(ackErr, actions...); err != nil {
+		f.log.Warnf("lazy acker: failed to persist failed acks: %v", err)
+	}
 }
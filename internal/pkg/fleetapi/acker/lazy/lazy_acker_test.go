@@ -206,3 +206,96 @@ func TestLazyAcker(t *testing.T) {
 		})
 	}
 }
+
+type testStore struct {
+	// failed attempts per pending action ID
+	pending map[string]int
+}
+
+func (s *testStore) Add(actions ...fleetapi.Action) error {
+	for _, a := range actions {
+		if _, ok := s.pending[a.ID()]; !ok {
+			s.pending[a.ID()] = 0
+		}
+	}
+	return nil
+}
+
+func (s *testStore) Acked(actions ...fleetapi.Action) error {
+	for _, a := range actions {
+		delete(s.pending, a.ID())
+	}
+	return nil
+}
+
+func (s *testStore) Failed(_ error, actions ...fleetapi.Action) error {
+	for _, a := range actions {
+		s.pending[a.ID()]++
+	}
+	return nil
+}
+
+func TestLazyAckerStore(t *testing.T) {
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+
+	log, _ := logger.New("", false)
+
+	tests := []struct {
+		name            string
+		acker           *testAcker
+		expectedPending map[string]int
+	}{
+		{
+			name:            "no error",
+			acker:           &testAcker{ackResponse: &fleetapi.AckResponse{}},
+			expectedPending: map[string]int{},
+		},
+		{
+			name:            "error",
+			acker:           &testAcker{errResponse: errFoo},
+			expectedPending: map[string]int{"1": 1, "2": 1},
+		},
+		{
+			name: "one item not found",
+			acker: &testAcker{ackResponse: &fleetapi.AckResponse{Errors: true, Items: []fleetapi.AckResponseItem{
+				{Status: http.StatusOK},
+				{Status: http.StatusNotFound},
+			}}},
+			expectedPending: map[string]int{"2": 1},
+		},
+		{
+			name: "one item missing",
+			acker: &testAcker{ackResponse: &fleetapi.AckResponse{Errors: true, Items: []fleetapi.AckResponseItem{
+				{Status: http.StatusNotFound},
+			}}},
+			expectedPending: map[string]int{"1": 1, "2": 1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &testStore{pending: make(map[string]int)}
+			acker := NewAcker(tc.acker, log, WithRetrier(&testRetrier{}), WithStore(store))
+			for _, action := range []fleetapi.Action{&fleetapi.ActionUnknown{ActionID: "1"}, &fleetapi.ActionUnknown{ActionID: "2"}} {
+				if err := acker.Ack(ctx, action); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// the acks are pending until committed
+			diff := cmp.Diff(map[string]int{"1": 0, "2": 0}, store.pending)
+			if diff != "" {
+				t.Fatal(diff)
+			}
+
+			if err := acker.Commit(ctx); err != nil {
+				t.Fatal(err)
+			}
+			diff = cmp.Diff(tc.expectedPending, store.pending)
+			if diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -6,7 +6,8 @@ package retrier
 
 import (
 	"context"
-	"net/http"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -23,6 +24,12 @@ type BatchAcker interface {
 	AckBatch(ctx context.Context, actions []fleetapi.Action) (*fleetapi.AckResponse, error)
 }
 
+// AckStore persists the acks that are not delivered to Fleet yet
+type AckStore interface {
+	Acked(actions ...fleetapi.Action) error
+	Failed(ackErr error, actions ...fleetapi.Action) error
+}
+
 // Option Retrier option function
 type Option func(*Retrier)
 
@@ -30,11 +37,13 @@ type Option func(*Retrier)
 type Retrier struct {
 	acker BatchAcker // AckBatch provider
 	log   *logger.Logger
+	store AckStore // optional store of the pending acks
 
 	doneCh chan struct{} // signal channel to kickoff retry loop if not running
 	kickCh chan struct{} // signal channel when retry loop is done
 
 	actions []fleetapi.Action // pending actions
+	parked  []fleetapi.Action // actions out of retries, retried again after maxRetryInterval when a store is set
 
 	maxRetryInterval     time.Duration // max retry interval
 	maxRetries           int           // configurable maxNumber of retries per action
@@ -82,6 +91,15 @@ func WithMaxAckRetries(maxRetries int) Option {
 	}
 }
 
+// WithStore configures retrier with the store persisting the pending acks.
+// Acks still failing after the max retries are kept in the store and retried
+// again after the max retry interval, instead of being dropped
+func WithStore(s AckStore) Option {
+	return func(f *Retrier) {
+		f.store = s
+	}
+}
+
 // Done signals when retry loop is done, useful for testing
 func (r *Retrier) Done() <-chan struct{} {
 	return r.doneCh
@@ -89,25 +107,67 @@ func (r *Retrier) Done() <-chan struct{} {
 
 // Run runs retrier loop
 func (r *Retrier) Run(ctx context.Context) {
+	var parkedTimer *time.Timer
+	defer func() {
+		if parkedTimer != nil {
+			parkedTimer.Stop()
+		}
+	}()
+	parkedC := func() <-chan time.Time {
+		if parkedTimer == nil {
+			return nil
+		}
+		return parkedTimer.C
+	}
+
 	for {
 		select {
 		case <-r.kickCh:
 			r.runRetries(ctx)
+		case <-parkedC():
+			parkedTimer = nil
+			r.mx.Lock()
+			parked := r.parked
+			r.parked = nil
+			r.mx.Unlock()
+			r.log.Infof("ack retrier: retrying %d acks out of retries", len(parked))
+			r.Enqueue(parked)
 		case <-ctx.Done():
 			r.log.Debugf("ack retrier: exit on %v", ctx.Err())
 			return
 		}
+
+		r.mx.Lock()
+		hasParked := len(r.parked) > 0
+		r.mx.Unlock()
+		if hasParked && parkedTimer == nil {
+			parkedTimer = time.NewTimer(r.maxRetryInterval)
+		}
 	}
 }
 
-// Enqueue enqueue provided actions for the next retry
+// Enqueue enqueue provided actions for the next retry, an action already
+// waiting for the next retry is replaced
 func (r *Retrier) Enqueue(actions []fleetapi.Action) {
 	if len(actions) == 0 {
 		return
 	}
 
 	r.mx.Lock()
-	r.actions = append(r.actions, actions...)
+	for _, action := range actions {
+		r.parked = removeAction(r.parked, action.ID())
+		queued := false
+		for i, a := range r.actions {
+			if a.ID() == action.ID() {
+				r.actions[i] = action
+				queued = true
+				break
+			}
+		}
+		if !queued {
+			r.actions = append(r.actions, action)
+		}
+	}
 	r.mx.Unlock()
 
 	// Signal to kick off retry loop, non blocking if the signal is already pending
@@ -131,18 +191,22 @@ func (r *Retrier) runRetries(ctx context.Context) {
 		r.actions = nil
 		r.mx.Unlock()
 
-		var failed []fleetapi.Action
+		var failed, exhausted []fleetapi.Action
 		r.log.Debug("ack retrier: before AckBatch")
 		resp, err := r.acker.AckBatch(ctx, actions)
 		r.log.Debugf("ack retrier: after AckBatch: %#v, %#v", resp, err)
 		if err != nil {
 			r.log.Errorf("ack retrier: commit failed with error: %v", err)
 			// Commit failed, update retry map from actions
-			failed = r.updateRetriesMap(retries, actions, nil)
+			failed, exhausted = r.updateRetriesMap(retries, actions, nil)
+			r.storeResult(actions, nil, err)
 		} else if resp != nil && resp.Errors {
 			// Commit partially failed, update retry map from failed actions
-			failed = r.updateRetriesMap(retries, actions, resp)
+			failed, exhausted = r.updateRetriesMap(retries, actions, resp)
 			r.log.Debugf("ack retrier: commit partially failed: %#v", failed)
+			r.storeResult(actions, resp, nil)
+		} else {
+			r.storeResult(actions, nil, nil)
 		}
 
 		r.log.Debugf("ack retrier: failed actions: %#v", failed)
@@ -153,6 +217,9 @@ func (r *Retrier) runRetries(ctx context.Context) {
 			b.Reset() // reset backoff if new actions came while committing
 		}
 		r.actions = append(failed, r.actions...)
+		if r.store != nil {
+			r.parked = append(r.parked, exhausted...)
+		}
 		r.log.Debugf("ack retrier: total actions: %#v", r.actions)
 		exit := (len(r.actions) == 0)
 
@@ -171,20 +238,10 @@ func (r *Retrier) runRetries(ctx context.Context) {
 	r.log.Debug("ack retrier: exit retry loop")
 }
 
-func (r *Retrier) updateRetriesMap(retries map[string]int, actions []fleetapi.Action, resp *fleetapi.AckResponse) (failed []fleetapi.Action) {
-	isFailed := func(pos int) bool {
-		// Response is nil when all actions fail, still need to update attempts bookkeeping
-		if resp == nil {
-			return true
-		}
-		if pos >= len(resp.Items) {
-			return true
-		}
-		return (resp.Items[pos].Status >= http.StatusBadRequest)
-	}
-
+func (r *Retrier) updateRetriesMap(retries map[string]int, actions []fleetapi.Action, resp *fleetapi.AckResponse) (failed []fleetapi.Action, exhausted []fleetapi.Action) {
 	for i, action := range actions {
-		if isFailed(i) {
+		// Response is nil when all actions fail, still need to update attempts bookkeeping
+		if fleetapi.AckFailed(resp, i) {
 			n, ok := retries[action.ID()]
 			if !ok {
 				n = r.maxRetries
@@ -195,11 +252,60 @@ func (r *Retrier) updateRetriesMap(retries map[string]int, actions []fleetapi.Ac
 				failed = append(failed, action)
 			} else {
 				delete(retries, action.ID())
+				exhausted = append(exhausted, action)
+				if r.store != nil {
+					r.log.Warnf("ack retrier: action '%s' failed after %d retries, the ack is kept in the store and retried in %s", action.ID(), r.maxRetries, r.maxRetryInterval)
+				}
 			}
 		} else {
 			delete(retries, action.ID())
 		}
 	}
 
-	return failed
+	return failed, exhausted
+}
+
+// removeAction removes the action with the ID from actions.
+func removeAction(actions []fleetapi.Action, id string) []fleetapi.Action {
+	for i, a := range actions {
+		if a.ID() == id {
+			return append(actions[:i], actions[i+1:]...)
+		}
+	}
+	return actions
+}
+
+// storeResult records the result of the ack of the actions in the store, if
+// any. err is set when the whole batch failed, resp when some acks failed.
+func (r *Retrier) storeResult(actions []fleetapi.Action, resp *fleetapi.AckResponse, ackErr error) {
+	if r.store == nil || len(actions) == 0 {
+		return
+	}
+	if ackErr != nil {
+		if err := r.store.Failed(ackErr, actions...); err != nil {
+			r.log.Warnf("ack retrier: failed to persist failed acks: %v", err)
+		}
+		return
+	}
+
+	acked := make([]fleetapi.Action, 0, len(actions))
+	for i, action := range actions {
+		if resp != nil && fleetapi.AckFailed(resp, i) {
+			ackErr := errors.New("ack has no response")
+			if i < len(resp.Items) {
+				ackErr = fmt.Errorf("ack failed with status %d", resp.Items[i].Status)
+			}
+			if err := r.store.Failed(ackErr, action); err != nil {
+				r.log.Warnf("ack retrier: failed to persist failed ack of action '%s': %v", action.ID(), err)
+			}
+			continue
+		}
+		acked = append(acked, action)
+	}
+	if len(acked) == 0 {
+		return
+	}
+	if err := r.store.Acked(acked...); err != nil {
+		r.log.Warnf("ack retrier: failed to remove delivered acks from the store: %v", err)
+	}
 }
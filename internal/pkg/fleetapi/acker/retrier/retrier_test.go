@@ -7,7 +7,9 @@ package retrier
 import (
 	"context"
 	"errors"
+	"maps"
 	"net/http"
+	"sync"
 	"testing"
 	"time"
 
@@ -136,3 +138,159 @@ func TestRetrier(t *testing.T) {
 		})
 	}
 }
+
+type testStore struct {
+	mx sync.Mutex
+	// failed attempts per pending action ID
+	pending map[string]int
+}
+
+func (s *testStore) Acked(actions ...fleetapi.Action) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for _, a := range actions {
+		delete(s.pending, a.ID())
+	}
+	return nil
+}
+
+func (s *testStore) Failed(_ error, actions ...fleetapi.Action) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for _, a := range actions {
+		s.pending[a.ID()]++
+	}
+	return nil
+}
+
+func (s *testStore) snapshot() map[string]int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return maps.Clone(s.pending)
+}
+
+func TestRetrierStore(t *testing.T) {
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+
+	log, _ := logger.New("", false)
+
+	tests := []struct {
+		name            string
+		acker           *testAcker
+		expectedPending map[string]int
+	}{
+		{
+			name: "no error",
+			acker: &testAcker{
+				responses: []*fleetapi.AckResponse{{}},
+			},
+			expectedPending: map[string]int{},
+		},
+		{
+			name:  "permanent error is kept in the store",
+			acker: &testAcker{errResponse: errBar},
+			expectedPending: map[string]int{
+				"1": 3,
+				"2": 3,
+			},
+		},
+		{
+			name: "partial error",
+			acker: &testAcker{
+				responses: []*fleetapi.AckResponse{
+					{
+						Errors: true,
+						Items: []fleetapi.AckResponseItem{
+							{Status: http.StatusNotFound},
+							{Status: http.StatusOK},
+						},
+					},
+					{},
+				},
+			},
+			expectedPending: map[string]int{},
+		},
+	}
+
+	const maxRetries = 3
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cn := context.WithCancel(ctx)
+			defer cn()
+
+			store := &testStore{pending: make(map[string]int)}
+			retrier := New(tc.acker, log,
+				WithInitialRetryInterval(50*time.Millisecond),
+				WithMaxRetryInterval(time.Minute),
+				WithMaxAckRetries(maxRetries),
+				WithStore(store),
+			)
+
+			go retrier.Run(ctx)
+
+			retrier.Enqueue([]fleetapi.Action{&fleetapi.ActionUnknown{ActionID: "1"}, &fleetapi.ActionUnknown{ActionID: "2"}})
+
+			// Wait until done
+			<-retrier.Done()
+
+			diff := cmp.Diff(tc.expectedPending, store.snapshot())
+			if diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+type flakyAcker struct {
+	mx       sync.Mutex
+	failures int
+	called   int
+}
+
+func (a *flakyAcker) AckBatch(_ context.Context, _ []fleetapi.Action) (*fleetapi.AckResponse, error) {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	a.called++
+	if a.called <= a.failures {
+		return nil, errBar
+	}
+	return &fleetapi.AckResponse{}, nil
+}
+
+func TestRetrierStoreRetriesExhaustedAcks(t *testing.T) {
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+
+	log, _ := logger.New("", false)
+
+	const maxRetries = 3
+	acker := &flakyAcker{failures: maxRetries}
+	store := &testStore{pending: make(map[string]int)}
+	retrier := New(acker, log,
+		WithInitialRetryInterval(10*time.Millisecond),
+		WithMaxRetryInterval(100*time.Millisecond),
+		WithMaxAckRetries(maxRetries),
+		WithStore(store),
+	)
+
+	go retrier.Run(ctx)
+
+	retrier.Enqueue([]fleetapi.Action{&fleetapi.ActionUnknown{ActionID: "1"}})
+
+	// the retries are exhausted, the ack is kept in the store
+	<-retrier.Done()
+	if diff := cmp.Diff(map[string]int{"1": maxRetries}, store.snapshot()); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// the ack is retried again after the max retry interval
+	select {
+	case <-retrier.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the ack out of retries was not retried")
+	}
+	if diff := cmp.Diff(map[string]int{}, store.snapshot()); diff != "" {
+		t.Fatal(diff)
+	}
+}
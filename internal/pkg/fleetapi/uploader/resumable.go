@@ -0,0 +1,154 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// UploadState is the state of a resumable upload. It is updated as fleet-server
+// confirms the chunks, so once persisted an interrupted upload can resume from
+// the last confirmed chunk.
+type UploadState struct {
+	// UploadID is the ID of the upload given by fleet-server, empty until the
+	// upload is started.
+	UploadID string `json:"upload_id,omitempty"`
+	// Name is the name of the uploaded file.
+	Name string `json:"name"`
+	// Size is the size of the uploaded file in bytes.
+	Size int64 `json:"size"`
+	// SHA256 is the hex encoded SHA-256 of the uploaded file.
+	SHA256 string `json:"sha256"`
+	// ChunkSize is the size of the chunks required by fleet-server.
+	ChunkSize int64 `json:"chunk_size,omitempty"`
+	// ChunkHashes are the hex encoded SHA-256 of the chunks confirmed by
+	// fleet-server, in order.
+	ChunkHashes []string `json:"chunk_hashes,omitempty"`
+}
+
+// Chunks returns the number of chunks of the upload, 0 until it is started.
+func (s *UploadState) Chunks() int {
+	if s.ChunkSize <= 0 {
+		return 0
+	}
+	return int((s.Size + s.ChunkSize - 1) / s.ChunkSize)
+}
+
+// Reset forgets the upload started on fleet-server, so the next upload starts
+// a new one.
+func (s *UploadState) Reset() {
+	s.UploadID = ""
+	s.ChunkSize = 0
+	s.ChunkHashes = nil
+}
+
+// BytesSent returns the number of bytes confirmed by fleet-server.
+func (s *UploadState) BytesSent() int64 {
+	return min(int64(len(s.ChunkHashes))*s.ChunkSize, s.Size)
+}
+
+// Progress is the progress of a diagnostics upload.
+type Progress struct {
+	ActionID   string    `json:"action_id" yaml:"action_id"`
+	UploadID   string    `json:"upload_id,omitempty" yaml:"upload_id,omitempty"`
+	ChunksSent int       `json:"chunks_sent" yaml:"chunks_sent"`
+	Chunks     int       `json:"chunks" yaml:"chunks"`
+	BytesSent  int64     `json:"bytes_sent" yaml:"bytes_sent"`
+	Size       int64     `json:"size" yaml:"size"`
+	Attempt    int       `json:"attempt" yaml:"attempt"`
+	LastError  string    `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// UploadDiagnosticsResumable uploads the diagnostics bundle of the action read
+// from r. Name, Size and SHA256 must be set in state. When state has an upload
+// ID the upload is resumed: only the chunks after the ones already confirmed
+// by fleet-server are sent.
+//
+// state is updated as the upload progresses and onProgress, if not nil, is
+// called with it once the upload is started and after every confirmed chunk,
+// so it can be persisted.
+//
+// When fleet-server rejects the upload with ErrUploadRejected the state is
+// reset, and a resumed upload is started again from the first chunk.
+func (c *Client) UploadDiagnosticsResumable(ctx context.Context, actionID string, state *UploadState, r io.ReadSeeker, onProgress func(*UploadState)) (string, error) {
+	if onProgress == nil {
+		onProgress = func(*UploadState) {}
+	}
+
+	resumed := state.UploadID != ""
+	uploadID, err := c.uploadResumable(ctx, actionID, state, r, onProgress)
+	if errors.Is(err, ErrUploadRejected) {
+		state.Reset()
+		onProgress(state)
+		if resumed {
+			return c.uploadResumable(ctx, actionID, state, r, onProgress)
+		}
+	}
+	return uploadID, err
+}
+
+func (c *Client) uploadResumable(ctx context.Context, actionID string, state *UploadState, r io.ReadSeeker, onProgress func(*UploadState)) (string, error) {
+	if state.UploadID == "" {
+		req := NewUploadRequest{
+			ActionID: actionID,
+			AgentID:  c.agentID,
+			Source:   "agent",
+			File: FileData{
+				Size:      state.Size,
+				Name:      state.Name,
+				Extension: "zip",
+				Mime:      "application/zip",
+			},
+		}
+		req.File.Hash.SHA256 = state.SHA256
+		upResp, err := c.New(ctx, &req)
+		if err != nil {
+			return "", err
+		}
+		state.UploadID = upResp.UploadID
+		state.ChunkSize = upResp.ChunkSize
+		state.ChunkHashes = nil
+		onProgress(state)
+	}
+	if state.ChunkSize <= 0 {
+		return state.UploadID, fmt.Errorf("invalid chunk size %d for upload %s", state.ChunkSize, state.UploadID)
+	}
+
+	if _, err := r.Seek(int64(len(state.ChunkHashes))*state.ChunkSize, io.SeekStart); err != nil {
+		return state.UploadID, fmt.Errorf("failed to seek to chunk %d: %w", len(state.ChunkHashes), err)
+	}
+	for chunk := len(state.ChunkHashes); chunk < state.Chunks(); chunk++ {
+		var data bytes.Buffer
+		if _, err := io.CopyN(&data, r, state.ChunkSize); err != nil && !errors.Is(err, io.EOF) {
+			return state.UploadID, fmt.Errorf("failed to read chunk %d: %w", chunk, err)
+		}
+		hash := sha256.Sum256(data.Bytes())
+		if err := c.Chunk(ctx, state.UploadID, chunk, hash[:], bytes.NewReader(data.Bytes())); err != nil {
+			return state.UploadID, err
+		}
+		state.ChunkHashes = append(state.ChunkHashes, hex.EncodeToString(hash[:]))
+		onProgress(state)
+	}
+
+	transitHash := sha256.New()
+	for i, h := range state.ChunkHashes {
+		hash, err := hex.DecodeString(h)
+		if err != nil {
+			return state.UploadID, fmt.Errorf("invalid hash of chunk %d: %w", i, err)
+		}
+		transitHash.Write(hash)
+	}
+	var fr FinishRequest
+	fr.TransitHash.SHA256 = fmt.Sprintf("%x", transitHash.Sum(nil))
+	return state.UploadID, c.Finish(ctx, state.UploadID, &fr)
+}
@@ -0,0 +1,192 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Client_UploadDiagnosticsResumable(t *testing.T) {
+	content := "abcde"
+	hashOf := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+	contentHash := hashOf(content)
+
+	okResponse := func() *http.Response {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(nil))}
+	}
+	expectChunk := func(sender *mockSender, chunk int, data *[]byte) *mock.Call {
+		return sender.On("Send", mock.Anything, "PUT", fmt.Sprintf(PathChunk, "test-upload", chunk), mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			b, err := io.ReadAll(args.Get(5).(io.Reader))
+			require.NoError(t, err)
+			*data = b
+		})
+	}
+	expectFinish := func(sender *mockSender, transitHash *string) {
+		sender.On("Send", mock.Anything, "POST", fmt.Sprintf(PathFinishUpload, "test-upload"), mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			var fr FinishRequest
+			require.NoError(t, json.NewDecoder(args.Get(5).(io.Reader)).Decode(&fr))
+			*transitHash = fr.TransitHash.SHA256
+		}).Return(okResponse(), nil).Once()
+	}
+	wantTransitHash := func() string {
+		h := sha256.New()
+		for _, chunk := range []string{"ab", "cd", "e"} {
+			sum := sha256.Sum256([]byte(chunk))
+			h.Write(sum[:])
+		}
+		return hex.EncodeToString(h.Sum(nil))
+	}()
+
+	t.Run("new upload", func(t *testing.T) {
+		var chunk0, chunk1, chunk2 []byte
+		var transitHash string
+		sender := &mockSender{}
+		sender.On("Send", mock.Anything, "POST", PathNewUpload, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			var req NewUploadRequest
+			require.NoError(t, json.NewDecoder(args.Get(5).(io.Reader)).Decode(&req))
+			assert.Equal(t, "test-id", req.ActionID)
+			assert.Equal(t, contentHash, req.File.Hash.SHA256)
+		}).Return(&http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"upload_id":"test-upload","chunk_size":2}`))),
+		}, nil).Once()
+		expectChunk(sender, 0, &chunk0).Return(okResponse(), nil).Once()
+		expectChunk(sender, 1, &chunk1).Return(okResponse(), nil).Once()
+		expectChunk(sender, 2, &chunk2).Return(okResponse(), nil).Once()
+		expectFinish(sender, &transitHash)
+
+		c := &Client{c: sender, agentID: "test-agent"}
+		state := &UploadState{Name: "diag.zip", Size: 5, SHA256: contentHash}
+		var progress []int
+		id, err := c.UploadDiagnosticsResumable(context.Background(), "test-id", state, strings.NewReader(content), func(s *UploadState) {
+			progress = append(progress, len(s.ChunkHashes))
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "test-upload", id)
+		assert.Equal(t, "ab", string(chunk0))
+		assert.Equal(t, "cd", string(chunk1))
+		assert.Equal(t, "e", string(chunk2))
+		assert.Equal(t, wantTransitHash, transitHash)
+		assert.Equal(t, []int{0, 1, 2, 3}, progress)
+		assert.Equal(t, 3, state.Chunks())
+		assert.Equal(t, int64(5), state.BytesSent())
+		sender.AssertExpectations(t)
+	})
+
+	t.Run("resumes from the last confirmed chunk", func(t *testing.T) {
+		var chunk1, chunk2 []byte
+		var transitHash string
+		sender := &mockSender{}
+		expectChunk(sender, 1, &chunk1).Return(okResponse(), nil).Once()
+		expectChunk(sender, 2, &chunk2).Return((*http.Response)(nil), errors.New("connection reset")).Once()
+
+		c := &Client{c: sender, agentID: "test-agent"}
+		state := &UploadState{
+			UploadID:    "test-upload",
+			Name:        "diag.zip",
+			Size:        5,
+			SHA256:      contentHash,
+			ChunkSize:   2,
+			ChunkHashes: []string{hashOf("ab")},
+		}
+		_, err := c.UploadDiagnosticsResumable(context.Background(), "test-id", state, strings.NewReader(content), nil)
+		require.Error(t, err)
+		assert.Equal(t, "cd", string(chunk1))
+		assert.Len(t, state.ChunkHashes, 2, "the confirmed chunk must be kept in the state")
+		assert.Equal(t, int64(4), state.BytesSent())
+
+		// retry with the same state
+		expectChunk(sender, 2, &chunk2).Return(okResponse(), nil).Once()
+		expectFinish(sender, &transitHash)
+		id, err := c.UploadDiagnosticsResumable(context.Background(), "test-id", state, strings.NewReader(content), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "test-upload", id)
+		assert.Equal(t, "e", string(chunk2))
+		assert.Equal(t, wantTransitHash, transitHash)
+		sender.AssertExpectations(t)
+	})
+
+	for _, status := range []int{http.StatusRequestTimeout, http.StatusTooManyRequests} {
+		t.Run(fmt.Sprintf("keeps the upload on status %d", status), func(t *testing.T) {
+			var chunk1 []byte
+			sender := &mockSender{}
+			expectChunk(sender, 1, &chunk1).Return(&http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"statusCode":%d,"error":"retry later"}`, status))),
+			}, nil).Once()
+
+			c := &Client{c: sender, agentID: "test-agent"}
+			state := &UploadState{
+				UploadID:    "test-upload",
+				Name:        "diag.zip",
+				Size:        5,
+				SHA256:      contentHash,
+				ChunkSize:   2,
+				ChunkHashes: []string{hashOf("ab")},
+			}
+			_, err := c.UploadDiagnosticsResumable(context.Background(), "test-id", state, strings.NewReader(content), nil)
+			require.Error(t, err)
+			assert.NotErrorIs(t, err, ErrUploadRejected)
+			assert.Equal(t, "test-upload", state.UploadID, "the upload must be resumed on the next attempt")
+			assert.Len(t, state.ChunkHashes, 1)
+			sender.AssertExpectations(t)
+		})
+	}
+
+	t.Run("starts a new upload when the resumed one is rejected", func(t *testing.T) {
+		var chunk0, chunk1, chunk2 []byte
+		var transitHash string
+		sender := &mockSender{}
+		expectChunk(sender, 1, &chunk1).Return(&http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(strings.NewReader(`{"statusCode":404,"error":"NotFound","message":"upload not found"}`)),
+		}, nil).Once()
+		sender.On("Send", mock.Anything, "POST", PathNewUpload, mock.Anything, mock.Anything, mock.Anything).Return(&http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"upload_id":"test-upload","chunk_size":2}`))),
+		}, nil).Once()
+		expectChunk(sender, 0, &chunk0).Return(okResponse(), nil).Once()
+		expectChunk(sender, 1, &chunk1).Return(okResponse(), nil).Once()
+		expectChunk(sender, 2, &chunk2).Return(okResponse(), nil).Once()
+		expectFinish(sender, &transitHash)
+
+		c := &Client{c: sender, agentID: "test-agent"}
+		state := &UploadState{
+			UploadID:    "test-upload",
+			Name:        "diag.zip",
+			Size:        5,
+			SHA256:      contentHash,
+			ChunkSize:   2,
+			ChunkHashes: []string{hashOf("ab")},
+		}
+		var uploadIDs []string
+		id, err := c.UploadDiagnosticsResumable(context.Background(), "test-id", state, strings.NewReader(content), func(s *UploadState) {
+			uploadIDs = append(uploadIDs, s.UploadID)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "test-upload", id)
+		assert.Equal(t, "", uploadIDs[0], "the rejected upload must be reset in the persisted state")
+		assert.Equal(t, "ab", string(chunk0))
+		assert.Equal(t, wantTransitHash, transitHash)
+		sender.AssertExpectations(t)
+	})
+}
@@ -29,6 +29,12 @@ const (
 	PathFinishUpload = "/api/fleet/uploads/%s"
 )
 
+// ErrUploadRejected is returned when fleet-server rejects a chunk or the
+// completion of an upload with a 400, 404 or 410 status, e.g. when the upload
+// is unknown to fleet-server or expired. The upload cannot be resumed and must
+// be started again.
+var ErrUploadRejected = errors.New("upload rejected by fleet-server")
+
 // FileData contains metadata about a file.
 type FileData struct {
 	Size      int64  `json:"size"`
@@ -157,10 +163,7 @@ func (c *Client) Chunk(ctx context.Context, uploadID string, chunkID int, sha256
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return client.ExtractError(resp.Body)
-	}
-	return nil
+	return uploadStatusError(resp)
 }
 
 // Finish calls the finalize endpoint for the file upload.
@@ -176,10 +179,23 @@ func (c *Client) Finish(ctx context.Context, id string, r *FinishRequest) error
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return client.ExtractError(resp.Body)
+	return uploadStatusError(resp)
+}
+
+// uploadStatusError returns the error of a response to a request for an
+// existing upload, wrapping ErrUploadRejected when the upload is unknown to
+// fleet-server. Other statuses, like 408 or 429, are transient and the upload
+// can be resumed.
+func uploadStatusError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	err := client.ExtractError(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusGone:
+		return fmt.Errorf("%w: %w", ErrUploadRejected, err)
 	}
-	return nil
+	return err
 }
 
 // UploadDiagnostics is a wrapper to upload a diagnostics request identified by the passed action id contained in the buffer to fleet-server.
@@ -619,7 +619,7 @@ func monitoringInputEventTemplate(monitoring *monitoringCfg.MonitoringConfig, ag
 func exporterIDToOutputNameLookup(components []component.Component) (map[string]string, error) {
 	lookup := map[string]string{}
 	for _, comp := range components {
-		exporterType, err := translate.OutputTypeToExporterType(comp.OutputType)
+		exporterType, err := translate.ComponentExporterType(&comp)
 		if err != nil {
 			return nil, err
 		}
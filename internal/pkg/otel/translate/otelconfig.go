@@ -78,11 +78,13 @@ type (
 )
 
 var (
-	OtelSupportedOutputTypes         = []string{"elasticsearch", "logstash", "kafka"}
+	OtelSupportedOutputTypes         = []string{"elasticsearch", "logstash", "kafka", OTLPOutputType}
 	configTranslationFuncForExporter = map[otelcomponent.Type]exporterConfigTranslationFunc{
 		otelcomponent.MustNewType("elasticsearch"): ESToOTelConfig,
 		otelcomponent.MustNewType("logstash"):      LogstashToOTelConfig,
 		otelcomponent.MustNewType("kafka"):         KafkaToOTelConfig,
+		otlpExporterType:                           OTLPToOTelConfig,
+		otlpHTTPExporterType:                       OTLPToOTelConfig,
 	}
 )
 
@@ -195,7 +197,7 @@ func VerifyOutputIsOtelSupported(outputType string, outputCfg map[string]any) er
 	if !slices.Contains(OtelSupportedOutputTypes, outputType) {
 		return fmt.Errorf("unsupported output type: %s", outputType)
 	}
-	exporterType, err := OutputTypeToExporterType(outputType)
+	exporterType, err := OutputToExporterType(outputType, outputCfg)
 	if err != nil {
 		return err
 	}
@@ -281,7 +283,7 @@ func getCollectorConfigForComponent(
 	info info.Agent,
 	logger *logp.Logger,
 ) (*confmap.Conf, error) {
-	exporterType, err := OutputTypeToExporterType(comp.OutputType)
+	exporterType, err := ComponentExporterType(comp)
 	if err != nil {
 		return nil, err
 	}
@@ -657,11 +659,32 @@ func OutputTypeToExporterType(outputType string) (otelcomponent.Type, error) {
 		return otelcomponent.MustNewType("logstash"), nil
 	case "kafka":
 		return otelcomponent.MustNewType("kafka"), nil
+	case OTLPOutputType:
+		return otlpExporterType, nil
 	default:
 		return otelcomponent.Type{}, fmt.Errorf("unknown otel exporter type for output type: %s", outputType)
 	}
 }
 
+// OutputToExporterType returns the exporter type for the given output type and configuration. Unlike
+// OutputTypeToExporterType it resolves the exporter of the outputs translated to different exporters depending on
+// their configuration, the otlp output uses the otlphttp exporter with the http protocol.
+func OutputToExporterType(outputType string, outputCfg map[string]any) (otelcomponent.Type, error) {
+	if outputType == OTLPOutputType {
+		return otlpExporterTypeForConfig(outputCfg)
+	}
+	return OutputTypeToExporterType(outputType)
+}
+
+// ComponentExporterType returns the exporter type for the output of the given component.
+func ComponentExporterType(comp *component.Component) (otelcomponent.Type, error) {
+	var outputCfg map[string]any
+	if outputUnit, ok := comp.OutputUnit(); ok && outputUnit.Config != nil {
+		outputCfg = outputUnit.Config.GetSource().AsMap()
+	}
+	return OutputToExporterType(comp.OutputType, outputCfg)
+}
+
 // unitToExporterConfig translates an output unit into OTel component configuration(s).
 // The returned configurations include:
 // - exportersCfg: OTel exporter configuration
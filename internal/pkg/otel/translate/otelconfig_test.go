@@ -3252,6 +3252,35 @@ func TestVerifyOutputIsOtelSupported(t *testing.T) {
 				"max_retries": 0,
 			},
 		},
+		{
+			name:       "supported output type - otlp",
+			outputType: "otlp",
+			outputCfg: map[string]any{
+				"type":     "otlp",
+				"hosts":    []any{"gateway:4318"},
+				"protocol": "http",
+			},
+		},
+		{
+			name:       "unsupported configuration - otlp loadbalance",
+			outputType: "otlp",
+			outputCfg: map[string]any{
+				"type":        "otlp",
+				"hosts":       []any{"gateway:4317", "gateway-2:4317"},
+				"loadbalance": true,
+			},
+			expectedError: "unsupported configuration for otlp:",
+		},
+		{
+			name:       "invalid otlp protocol",
+			outputType: "otlp",
+			outputCfg: map[string]any{
+				"type":     "otlp",
+				"hosts":    []any{"gateway:4317"},
+				"protocol": "udp",
+			},
+			expectedError: `unknown otlp protocol "udp"`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,151 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package translate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	otelcomponent "go.opentelemetry.io/collector/component"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+)
+
+const (
+	// OTLPOutputType is the type of the output sending the events to an OTLP endpoint, it can only
+	// be used by the components running in the OTel collector.
+	OTLPOutputType = "otlp"
+
+	otlpProtocolGRPC = "grpc"
+	otlpProtocolHTTP = "http"
+)
+
+var (
+	otlpExporterType     = otelcomponent.MustNewType("otlp")
+	otlpHTTPExporterType = otelcomponent.MustNewType("otlphttp")
+
+	otlpCompressions = []string{"gzip", "zstd", "snappy", "zlib", "deflate", "none"}
+)
+
+type otlpOutputConfig struct {
+	Hosts       []string          `config:"hosts" validate:"required"`
+	Protocol    string            `config:"protocol"`
+	Headers     map[string]string `config:"headers"`
+	Compression string            `config:"compression"`
+	Timeout     time.Duration     `config:"timeout"`
+	BulkMaxSize int               `config:"bulk_max_size"`
+	Backoff     struct {
+		Init time.Duration `config:"init"`
+		Max  time.Duration `config:"max"`
+	} `config:"backoff"`
+	TLS *tlscommon.Config `config:"ssl"`
+}
+
+func defaultOTLPOutputConfig() otlpOutputConfig {
+	c := otlpOutputConfig{
+		Protocol:    otlpProtocolGRPC,
+		Compression: "gzip",
+		Timeout:     30 * time.Second,
+		BulkMaxSize: 1600,
+	}
+	c.Backoff.Init = time.Second
+	c.Backoff.Max = 60 * time.Second
+	return c
+}
+
+func (c *otlpOutputConfig) Validate() error {
+	if len(c.Hosts) > 1 {
+		return fmt.Errorf("only one host is supported by the otlp output: %w", errors.ErrUnsupported)
+	}
+	if c.Protocol != otlpProtocolGRPC && c.Protocol != otlpProtocolHTTP {
+		return fmt.Errorf("unknown otlp protocol %q, must be either %s or %s", c.Protocol, otlpProtocolGRPC, otlpProtocolHTTP)
+	}
+	if !slices.Contains(otlpCompressions, c.Compression) {
+		return fmt.Errorf("unknown otlp compression %q", c.Compression)
+	}
+	if c.BulkMaxSize <= 0 {
+		return errors.New("bulk_max_size must be greater than 0")
+	}
+	return nil
+}
+
+// otlpExporterTypeForConfig returns the exporter type for the protocol of the otlp output.
+func otlpExporterTypeForConfig(outputCfg map[string]any) (otelcomponent.Type, error) {
+	protocol, _ := outputCfg["protocol"].(string)
+	switch protocol {
+	case "", otlpProtocolGRPC:
+		return otlpExporterType, nil
+	case otlpProtocolHTTP:
+		return otlpHTTPExporterType, nil
+	default:
+		return otelcomponent.Type{}, fmt.Errorf("unknown otlp protocol %q, must be either %s or %s", protocol, otlpProtocolGRPC, otlpProtocolHTTP)
+	}
+}
+
+// OTLPToOTelConfig translates the otlp output to the config of the otlp exporter, or of the otlphttp exporter
+// when the http protocol is used.
+func OTLPToOTelConfig(output *config.C, _ string, logger *logp.Logger) (map[string]any, map[string]any, error) {
+	if err := checkUnsupportedOTLPConfig(output); err != nil {
+		return nil, nil, err
+	}
+
+	otlpConfig := defaultOTLPOutputConfig()
+	if err := output.Unpack(&otlpConfig); err != nil {
+		return nil, nil, fmt.Errorf("failed unpacking otlp config: %w", err)
+	}
+
+	otlpExporter := map[string]any{
+		"endpoint":    otlpConfig.Hosts[0],
+		"compression": otlpConfig.Compression,
+		"timeout":     otlpConfig.Timeout,
+		"sending_queue": map[string]any{
+			"batch": map[string]any{
+				"flush_timeout": getFlushTimeout(logger, output),
+				"max_size":      otlpConfig.BulkMaxSize,                                         // bulk_max_size
+				"min_size":      min(getFlushMinEvents(logger, output), otlpConfig.BulkMaxSize), // queue.mem.flush.min_events, capped at max_size
+				"sizer":         "items",
+			},
+			"enabled":           true,
+			"queue_size":        getQueueSize(logger, output),
+			"block_on_overflow": true,
+		},
+		"retry_on_failure": map[string]any{
+			"enabled":          true,
+			"initial_interval": otlpConfig.Backoff.Init, // backoff.init
+			"max_interval":     otlpConfig.Backoff.Max,  // backoff.max
+		},
+	}
+
+	tlsCfg, err := TLSToOTel(otlpConfig.TLS, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error translating tls config: %w", err)
+	}
+	setIfNotNil(otlpExporter, "tls", tlsCfg)
+	setIfNotNil(otlpExporter, "headers", otlpConfig.Headers)
+
+	return otlpExporter, nil, nil
+}
+
+// checkUnsupportedOTLPConfig returns an error wrapping errors.ErrUnsupported for the settings that cannot be
+// translated to the otlp exporters.
+func checkUnsupportedOTLPConfig(cfg *config.C) error {
+	for _, field := range []string{"loadbalance", "proxy_url", "worker"} {
+		if cfg.HasField(field) {
+			return fmt.Errorf("%s is currently not supported: %w", field, errors.ErrUnsupported)
+		}
+	}
+	if value, err := cfg.Child("ssl", -1); err == nil {
+		if value.HasField("ca_trusted_fingerprint") {
+			return fmt.Errorf("ca_trusted_fingerprint is currently not supported: %w", errors.ErrUnsupported)
+		} else if value.HasField("ca_sha256") {
+			return fmt.Errorf("ca_sha256 is currently not supported: %w", errors.ErrUnsupported)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package translate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp/logptest"
+)
+
+func TestOTLPToOTelConfig(t *testing.T) {
+	defaultQueue := map[string]any{
+		"batch": map[string]any{
+			"flush_timeout": "10s",
+			"max_size":      1600,
+			"min_size":      1600,
+			"sizer":         "items",
+		},
+		"enabled":           true,
+		"queue_size":        3200,
+		"block_on_overflow": true,
+	}
+	defaultRetry := map[string]any{
+		"enabled":          true,
+		"initial_interval": time.Second,
+		"max_interval":     time.Minute,
+	}
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected map[string]any
+		err      string
+	}{
+		{
+			name: "grpc with defaults",
+			input: `
+hosts: [gateway:4317]
+`,
+			expected: map[string]any{
+				"endpoint":         "gateway:4317",
+				"compression":      "gzip",
+				"timeout":          30 * time.Second,
+				"sending_queue":    defaultQueue,
+				"retry_on_failure": defaultRetry,
+			},
+		},
+		{
+			name: "http with headers, compression and queue settings",
+			input: `
+hosts: [https://gateway:4318]
+protocol: http
+compression: zstd
+timeout: 5s
+headers:
+  X-Tenant: acme
+bulk_max_size: 500
+backoff.init: 2s
+backoff.max: 30s
+queue.mem.events: 4096
+queue.mem.flush.timeout: 5
+queue.mem.flush.min_events: 1000
+ssl.enabled: false
+`,
+			expected: map[string]any{
+				"endpoint":    "https://gateway:4318",
+				"compression": "zstd",
+				"timeout":     5 * time.Second,
+				"headers":     map[string]string{"X-Tenant": "acme"},
+				"tls":         map[string]any{"insecure": true},
+				"sending_queue": map[string]any{
+					"batch": map[string]any{
+						"flush_timeout": "5s",
+						"max_size":      500,
+						"min_size":      500,
+						"sizer":         "items",
+					},
+					"enabled":           true,
+					"queue_size":        4096,
+					"block_on_overflow": true,
+				},
+				"retry_on_failure": map[string]any{
+					"enabled":          true,
+					"initial_interval": 2 * time.Second,
+					"max_interval":     30 * time.Second,
+				},
+			},
+		},
+		{
+			name:  "missing host",
+			input: `protocol: grpc`,
+			err:   "missing required field",
+		},
+		{
+			name: "unknown compression",
+			input: `
+hosts: [gateway:4317]
+compression: lz4
+`,
+			err: `unknown otlp compression "lz4"`,
+		},
+		{
+			name: "several hosts",
+			input: `
+hosts: [gateway:4317, gateway-2:4317]
+`,
+			err: "only one host is supported",
+		},
+		{
+			name: "loadbalance",
+			input: `
+hosts: [gateway:4317]
+loadbalance: true
+`,
+			err: "loadbalance is currently not supported",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.MustNewConfigFrom(tc.input)
+			got, processors, err := OTLPToOTelConfig(cfg, "gateway", logptest.NewTestingLogger(t, ""))
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Nil(t, processors)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestOTLPUnsupportedConfig(t *testing.T) {
+	cfg := config.MustNewConfigFrom(`
+hosts: [gateway:4317]
+ssl.ca_trusted_fingerprint: abcd
+`)
+	_, _, err := OTLPToOTelConfig(cfg, "gateway", logptest.NewTestingLogger(t, ""))
+	assert.True(t, errors.Is(err, errors.ErrUnsupported))
+}
+
+func TestOutputToExporterType(t *testing.T) {
+	for _, tc := range []struct {
+		outputType string
+		cfg        map[string]any
+		expected   string
+		err        string
+	}{
+		{outputType: "elasticsearch", expected: "elasticsearch"},
+		{outputType: "otlp", expected: "otlp"},
+		{outputType: "otlp", cfg: map[string]any{"protocol": "grpc"}, expected: "otlp"},
+		{outputType: "otlp", cfg: map[string]any{"protocol": "http"}, expected: "otlphttp"},
+		{outputType: "otlp", cfg: map[string]any{"protocol": "udp"}, err: `unknown otlp protocol "udp"`},
+		{outputType: "redis", err: "unknown otel exporter type for output type: redis"},
+	} {
+		got, err := OutputToExporterType(tc.outputType, tc.cfg)
+		if tc.err != "" {
+			assert.ErrorContains(t, err, tc.err)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, got.String())
+	}
+}
@@ -223,7 +223,7 @@ func muteExporters(agg *status.AggregateStatus, components []component.Component
 		if comp.OutputStatusReporting == nil || comp.OutputStatusReporting.Enabled {
 			continue
 		}
-		exporterType, err := OutputTypeToExporterType(comp.OutputType)
+		exporterType, err := ComponentExporterType(&comp)
 		if err != nil {
 			return err
 		}
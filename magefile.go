@@ -335,6 +335,10 @@ func GolangCrossBuild(ctx context.Context) error {
 	// which pulls in the cgo runtime support and NSS resolver as opaque C
 	// objects that bypass the Go linker's dead code elimination entirely
 	// and produces a dynamically linked binary instead of a static one.
+	// The PKCS#11 vault backend on linux/amd64 and linux/arm64 loads the
+	// token module with dlopen through purego, which does not need cgo but
+	// makes the binary dynamically linked against the C library, the
+	// module is a C shared library that cannot be loaded otherwise.
 	if cfg.Platform().GOOS != "darwin" {
 		params.CGO = false
 	}
@@ -152,6 +152,10 @@ func (r *RuntimeConfig) BeatRuntimeConfig(beatName string) *BeatRuntimeConfig {
 }
 
 func (r *RuntimeConfig) RuntimeManagerForInputType(inputType string, beatName string, output outputI) RuntimeManager {
+	// the otlp output is only implemented by the exporters of the OTel collector
+	if output.OutputType == otlpType {
+		return OtelRuntimeManager
+	}
 	if r.Output != nil {
 		// Check if runtime is set for given output
 		if runtime, ok := r.Output[output.OutputType]; ok && output.Enabled {
@@ -182,6 +186,7 @@ const (
 	defaultUnitLogLevel                  = client.UnitLogLevelInfo
 	headersKey                           = "headers"
 	elasticsearchType                    = "elasticsearch"
+	otlpType                             = "otlp"
 	workDirPathMod                       = 0o770
 	ProcessRuntimeManager                = RuntimeManager("process")
 	OtelRuntimeManager                   = RuntimeManager("otel")
@@ -330,6 +335,13 @@ type Component struct {
 	// Component-level configuration
 	Component *proto.Component `yaml:"component,omitempty"`
 
+	// ResourceLimits are the memory and CPU limits of the component process, enforced by the
+	// command runtime on Linux.
+	ResourceLimits limits.ResourceLimits `yaml:"resource_limits,omitempty"`
+
+	// Quarantine configures when the command runtime stops restarting the crash-looping component.
+	Quarantine QuarantineConfig `yaml:"quarantine,omitempty"`
+
 	OutputStatusReporting *StatusReporting `yaml:"-"`
 
 	// LastConfiguredAt records when the component was last configured.
@@ -684,6 +696,8 @@ func (r *RuntimeSpecs) componentsForInputType(
 					Dynamic:               hasDynamicInputs,
 					Features:              featureFlags.AsProto(),
 					Component:             componentConfig.AsProto(),
+					ResourceLimits:        componentConfig.ResourceLimitsFor(componentID, inputSpec.CommandName()),
+					Quarantine:            componentConfig.Quarantine,
 					OutputStatusReporting: extractStatusReporting(output.Config),
 					LastConfiguredAt:      time.Now(),
 				})
@@ -731,6 +745,8 @@ func (r *RuntimeSpecs) componentsForInputType(
 					Dynamic:               input.dynamic,
 					Features:              featureFlags.AsProto(),
 					Component:             componentConfig.AsProto(),
+					ResourceLimits:        componentConfig.ResourceLimitsFor(componentID, inputSpec.CommandName()),
+					Quarantine:            componentConfig.Quarantine,
 					OutputStatusReporting: extractStatusReporting(output.Config),
 					LastConfiguredAt:      time.Now(),
 				})
@@ -797,10 +813,15 @@ func (r *RuntimeSpecs) PolicyToComponents(
 	if err != nil {
 		return nil, fmt.Errorf("could not parse limits from policy: %w", err)
 	}
+	quarantine, err := parseQuarantineConfig(policy)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse quarantine from policy: %w", err)
+	}
 	// for now it's a shared component configuration for all components
 	// subject to change in the future
 	componentConfig := &ComponentConfig{
-		Limits: ComponentLimits(*limits),
+		Limits:     ComponentLimits(*limits),
+		Quarantine: quarantine,
 	}
 
 	var components []Component
@@ -22,6 +22,7 @@ import (
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/eql"
+	"github.com/elastic/elastic-agent/pkg/limits"
 	"github.com/elastic/go-ucfg"
 
 	"github.com/go-viper/mapstructure/v2"
@@ -4264,6 +4265,25 @@ func TestRuntimeConfigRuntimeManagerForInputType(t *testing.T) {
 			beatName:  "packetbeat",
 			want:      OtelRuntimeManager,
 		},
+		{
+			name: "otlp output always uses otel runtime",
+			config: &RuntimeConfig{
+				Default: string(ProcessRuntimeManager),
+				Filebeat: BeatRuntimeConfig{
+					InputType: map[string]string{
+						"filestream": string(ProcessRuntimeManager),
+					},
+				},
+			},
+			output: outputI{
+				Name:       "gateway",
+				Enabled:    true,
+				OutputType: "otlp",
+			},
+			inputType: "filestream",
+			beatName:  "filebeat",
+			want:      OtelRuntimeManager,
+		},
 	}
 
 	for _, tt := range tests {
@@ -4478,6 +4498,62 @@ func TestToComponentsWithRuntimeConfig(t *testing.T) {
 	}
 }
 
+func TestToComponentsWithResourceLimits(t *testing.T) {
+	linuxAMD64Platform := PlatformDetail{
+		Platform: Platform{
+			OS:   Linux,
+			Arch: AMD64,
+			GOOS: Linux,
+		},
+	}
+	policy := map[string]interface{}{
+		"agent": map[string]interface{}{
+			"limits": map[string]interface{}{
+				"memory": "1GiB",
+				"components": map[string]interface{}{
+					"filebeat": map[string]interface{}{
+						"memory": "512MiB",
+						"cpu":    0.5,
+					},
+					"system/metrics-default": map[string]interface{}{
+						"cpu": 2,
+					},
+				},
+			},
+		},
+		"outputs": map[string]interface{}{
+			"default": map[string]interface{}{
+				"type":    "elasticsearch",
+				"enabled": true,
+			},
+		},
+		"inputs": []interface{}{
+			map[string]interface{}{
+				"type": "filestream",
+				"id":   "filestream-0",
+			},
+			map[string]interface{}{
+				"type": "system/metrics",
+				"id":   "system-metrics-0",
+			},
+		},
+	}
+
+	runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+	require.NoError(t, err)
+	result, err := runtime.ToComponents(policy, DefaultRuntimeConfig(), nil, nil, logp.InfoLevel, nil, map[string]uint64{}, map[string]bool{})
+	require.NoError(t, err)
+
+	resourceLimits := make(map[string]limits.ResourceLimits, len(result))
+	for _, comp := range result {
+		resourceLimits[comp.ID] = comp.ResourceLimits
+	}
+	assert.Equal(t, map[string]limits.ResourceLimits{
+		"filestream-default":     {Memory: 512 << 20, CPU: 0.5},
+		"system/metrics-default": {CPU: 2},
+	}, resourceLimits)
+}
+
 func TestToComponentsWithDynamicInputs(t *testing.T) {
 	linuxAMD64Platform := PlatformDetail{
 		Platform: Platform{
@@ -51,7 +51,13 @@ func (c ComponentLimits) AsProto() *proto.ComponentLimits {
 }
 
 type ComponentConfig struct {
-	Limits ComponentLimits
+	Limits     ComponentLimits
+	Quarantine QuarantineConfig
+}
+
+// ResourceLimitsFor returns the resource limits of the component with the provided ID and binary.
+func (c ComponentConfig) ResourceLimitsFor(id string, binaryName string) limits.ResourceLimits {
+	return limits.LimitsConfig(c.Limits).ResourceLimitsFor(id, binaryName)
 }
 
 func (c ComponentConfig) AsProto() *proto.Component {
@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package component
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/config"
+)
+
+// QuarantineConfig configures the quarantine of crash-looping components. A quarantined component is no
+// longer restarted until it is resumed with the `elastic-agent component resume` command.
+type QuarantineConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+
+	// MaxCrashes is the number of crashes during Period after which the component is quarantined.
+	MaxCrashes int           `yaml:"max_crashes" config:"max_crashes" json:"max_crashes"`
+	Period     time.Duration `yaml:"period" config:"period" json:"period"`
+
+	// CrashLogs is the number of crashes, with their exit code and last log lines, kept for a
+	// quarantined component.
+	CrashLogs int `yaml:"crash_logs" config:"crash_logs" json:"crash_logs"`
+}
+
+// DefaultQuarantineConfig returns the default quarantine configuration, quarantine is disabled.
+func DefaultQuarantineConfig() QuarantineConfig {
+	return QuarantineConfig{
+		Enabled:    false,
+		MaxCrashes: 10,
+		Period:     10 * time.Minute,
+		CrashLogs:  5,
+	}
+}
+
+// Validate validates the quarantine configuration.
+func (c *QuarantineConfig) Validate() error {
+	if c.MaxCrashes <= 0 {
+		return fmt.Errorf("max_crashes must be higher than zero; received %d", c.MaxCrashes)
+	}
+	if c.Period <= 0 {
+		return fmt.Errorf("period must be higher than zero; received %s", c.Period)
+	}
+	if c.CrashLogs < 0 {
+		return errors.New("crash_logs cannot be negative")
+	}
+	return nil
+}
+
+// parseQuarantineConfig parses the agent.process.quarantine section of the policy.
+func parseQuarantineConfig(policy map[string]interface{}) (QuarantineConfig, error) {
+	parsed := struct {
+		Agent struct {
+			Process struct {
+				Quarantine QuarantineConfig `config:"quarantine"`
+			} `config:"process"`
+		} `config:"agent"`
+	}{}
+	parsed.Agent.Process.Quarantine = DefaultQuarantineConfig()
+	if policy == nil {
+		return parsed.Agent.Process.Quarantine, nil
+	}
+	cfg, err := config.NewConfigFrom(policy)
+	if err != nil {
+		return QuarantineConfig{}, err
+	}
+	if err := cfg.Unpack(&parsed); err != nil {
+		return QuarantineConfig{}, err
+	}
+	return parsed.Agent.Process.Quarantine, nil
+}
@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package component
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuarantineConfig(t *testing.T) {
+	cfg, err := parseQuarantineConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultQuarantineConfig(), cfg)
+
+	cfg, err = parseQuarantineConfig(map[string]interface{}{
+		"agent": map[string]interface{}{
+			"process": map[string]interface{}{
+				"quarantine": map[string]interface{}{
+					"enabled":     true,
+					"max_crashes": 3,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, QuarantineConfig{
+		Enabled:    true,
+		MaxCrashes: 3,
+		Period:     10 * time.Minute,
+		CrashLogs:  5,
+	}, cfg)
+
+	_, err = parseQuarantineConfig(map[string]interface{}{
+		"agent": map[string]interface{}{
+			"process": map[string]interface{}{
+				"quarantine": map[string]interface{}{
+					"max_crashes": 0,
+				},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, "max_crashes must be higher than zero")
+}
@@ -126,12 +126,25 @@ type commandRuntime struct {
 	// handled by (*commandRuntime).Run.
 	actionCh chan actionMode
 
+	// resumeCh releases the component from quarantine, it is written by calls to
+	// (*commandRuntime).Resume and read by the run loop in (*commandRuntime).Run.
+	resumeCh chan struct{}
+
 	proc *process.Info
 
 	state          ComponentState
 	lastCheckin    time.Time
 	missedCheckins int
 	restartBucket  *rate.Limiter
+
+	// crashes are the last unexpected exits of the process, used to quarantine the component.
+	crashes []ComponentCrash
+
+	// resources is the cgroup enforcing the resource limits of the running process, nil when
+	// the component has no limits or they cannot be enforced.
+	resources     *resourceGroup
+	resourceStats resourceStats
+	throttled     bool
 }
 
 // newCommandRuntime creates a new command runtime for the provided component.
@@ -142,6 +155,7 @@ func newCommandRuntime(comp component.Component, log *logger.Logger, monitor Mon
 		monitor:     monitor,
 		ch:          make(chan ComponentState),
 		actionCh:    make(chan actionMode, 1),
+		resumeCh:    make(chan struct{}, 1),
 		procCh:      make(chan procState),
 		compCh:      make(chan component.Component, 1),
 		actionState: actionStop,
@@ -186,10 +200,22 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 				}
 				t.Reset(checkinPeriod)
 			case actionStop, actionTeardown:
+				// a stopped component is started again without quarantine
+				c.releaseQuarantine()
 				if err := c.stop(ctx); err != nil {
 					c.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
 				}
 			}
+		case <-c.resumeCh:
+			if c.releaseQuarantine() {
+				c.log.Infof("Component %s is released from quarantine", c.current.ID)
+				if c.actionState == actionStart {
+					if err := c.start(comm); err != nil {
+						c.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
+					}
+					t.Reset(checkinPeriod)
+				}
+			}
 		case ps := <-c.procCh:
 			// ignores old processes
 			if ps.proc == c.proc {
@@ -202,6 +228,7 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 		case newComp := <-c.compCh:
 			c.current = newComp
 			c.syncLogLevels()
+			c.syncResourceLimits()
 
 			sendExpected := c.state.syncExpected(&newComp)
 			changed := c.state.syncUnits(&newComp)
@@ -224,6 +251,9 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 					}
 				} else {
 					// running and should be running
+					if c.updateResources() {
+						c.sendObserved()
+					}
 					//
 					// Warning now must contain a
 					// monotonic clock.  Functions like Local(),
@@ -237,7 +267,12 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 						c.missedCheckins++
 						c.log.Debugf("Last check-in was: %s, now is: %s. The diff %s is higher than allowed %s.", c.lastCheckin.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano), now.Sub(c.lastCheckin), checkinPeriod)
 					}
-					if c.missedCheckins == 0 {
+					if c.missedCheckins == 0 && c.throttled {
+						msg := fmt.Sprintf("Degraded: pid '%d' is throttled by its CPU limit of %v CPUs", c.proc.PID, c.current.ResourceLimits.CPU)
+						if c.state.compState(client.UnitStateDegraded, msg) {
+							c.sendObserved()
+						}
+					} else if c.missedCheckins == 0 {
 						c.compState(client.UnitStateHealthy)
 					} else if c.missedCheckins > 0 && c.missedCheckins < maxCheckinMisses {
 						c.compState(client.UnitStateDegraded)
@@ -399,6 +434,10 @@ func (c *commandRuntime) start(comm Communicator) error {
 		// already running
 		return nil
 	}
+	if c.state.Quarantine != nil {
+		// not restarted until it is resumed
+		return nil
+	}
 	cmdSpec := c.getCommandSpec()
 	env := make([]string, 0, len(cmdSpec.Env)+4)
 	for _, e := range cmdSpec.Env {
@@ -438,18 +477,28 @@ func (c *commandRuntime) start(comm Communicator) error {
 	c.lastCheckin = time.Time{}
 	c.missedCheckins = 0
 
+	cmdOpts := []process.CmdOption{attachOutErr(c.logStd, c.logErr), dirPath(workDir)}
+	c.startResourceGroup()
+	if c.resources != nil {
+		cmdOpts = append(cmdOpts, c.resources.cmdOption())
+	}
+
 	startOpts := []process.StartOption{
 		process.WithArgs(args),
 		process.WithEnv(env),
-		process.WithCmdOptions(attachOutErr(c.logStd, c.logErr), dirPath(workDir)),
+		process.WithCmdOptions(cmdOpts...),
 	}
 	if !process.HasConsole() {
 		startOpts = append(startOpts, process.WithNewConsole())
 	}
 	proc, err := process.Start(path, startOpts...)
 	if err != nil {
+		c.stopResourceGroup()
 		return fmt.Errorf("failed to start process: %w", err)
 	}
+	if c.resources != nil {
+		c.resources.started()
+	}
 
 	c.proc = proc
 	c.forceCompState(client.UnitStateStarting, fmt.Sprintf("Starting: spawned pid '%d'", c.proc.PID))
@@ -481,6 +530,8 @@ func (c *commandRuntime) stop(ctx context.Context) error {
 	// While blocked, Run()'s select loop is paused in the actionStop case,
 	// so waitOrKill() is the sole reader of procCh — no deadlock.
 	ps := c.waitOrKill()
+	c.updateResources()
+	c.stopResourceGroup()
 
 	pid := c.proc.PID
 	c.proc = nil
@@ -558,9 +609,23 @@ func (c *commandRuntime) waitOrKill() *os.ProcessState {
 }
 
 func (c *commandRuntime) handleProc(state *os.ProcessState) bool {
+	prevOOMKills := c.resourceStats.oomKills
+	c.updateResources()
+	oomKilled := c.resourceStats.oomKills > prevOOMKills
+	c.stopResourceGroup()
+
 	switch c.actionState {
 	case actionStart:
-		if c.restartBucket != nil && c.restartBucket.Allow() {
+		if now := time.Now(); c.recordCrash(state.Pid(), state.ExitCode(), now) {
+			c.quarantine(now)
+			return false
+		}
+		c.state.Restarts++
+		if oomKilled {
+			// always reported, the process will keep being killed until its memory limit is raised
+			stopMsg := fmt.Sprintf("Failed: pid '%d' was killed for exceeding its memory limit of %d bytes", state.Pid(), c.current.ResourceLimits.Memory)
+			c.forceCompState(client.UnitStateFailed, stopMsg)
+		} else if c.restartBucket != nil && c.restartBucket.Allow() {
 			stopMsg := fmt.Sprintf("Suppressing FAILED state due to restart for '%d' exited with code '%d'", state.Pid(), state.ExitCode())
 			c.forceCompState(client.UnitStateStopped, stopMsg)
 		} else {
@@ -27,6 +27,9 @@ type logSource uint8
 const (
 	logSourceStdout logSource = 0
 	logSourceStderr logSource = 1
+
+	// tailLines is the number of last lines kept by the log writer.
+	tailLines = 20
 )
 
 // logWriter is an `io.Writer` that takes lines and passes them through the logger.
@@ -41,6 +44,10 @@ type logWriter struct {
 	unitLevels map[string]zapcore.Level
 	remainder  []byte
 
+	// tail is the ring buffer of the last lines written, next is the index the next line is written at.
+	tail []string
+	next int
+
 	// inheritLevel is the level that will be used for a log message in the case it doesn't define a log level
 	// for stdout it is INFO and for stderr it is ERROR.
 	inheritLevel zapcore.Level
@@ -121,6 +128,7 @@ func (r *logWriter) Write(p []byte) (int, error) {
 			// empty line after trim
 			continue
 		}
+		r.addTail(str)
 		// try to parse line as JSON
 		if str[0] == '{' && r.handleJSON(str) {
 			// handled as JSON
@@ -137,6 +145,25 @@ func (r *logWriter) Write(p []byte) (int, error) {
 	}
 }
 
+// addTail adds the line to the last lines written. Must be called with the lock held.
+func (r *logWriter) addTail(line string) {
+	if len(r.tail) < tailLines {
+		r.tail = append(r.tail, line)
+		return
+	}
+	r.tail[r.next] = line
+	r.next = (r.next + 1) % tailLines
+}
+
+// lastLines returns the last lines written, from the oldest to the latest.
+func (r *logWriter) lastLines() []string {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	lines := make([]string, 0, len(r.tail))
+	lines = append(lines, r.tail[r.next:]...)
+	return append(lines, r.tail[:r.next]...)
+}
+
 func (r *logWriter) handleJSON(line string) bool {
 	var evt map[string]interface{}
 	if err := json.Unmarshal([]byte(line), &evt); err != nil {
@@ -5,6 +5,7 @@
 package runtime
 
 import (
+	"fmt"
 	"sort"
 	"testing"
 	"time"
@@ -327,6 +328,25 @@ func TestLogWriter(t *testing.T) {
 	}
 }
 
+func TestLogWriterLastLines(t *testing.T) {
+	w := newLogWriter(&captureCore{}, component.CommandLogSpec{}, zapcore.ErrorLevel, nil, logSourceStderr)
+	assert.Empty(t, w.lastLines())
+
+	// lines are kept even when their level is not logged
+	_, err := w.Write([]byte("first line\nsecond line\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first line", "second line"}, w.lastLines())
+
+	for i := 0; i < tailLines; i++ {
+		_, err := w.Write([]byte(fmt.Sprintf("line %d\n", i)))
+		require.NoError(t, err)
+	}
+	lines := w.lastLines()
+	require.Len(t, lines, tailLines)
+	assert.Equal(t, "line 0", lines[0])
+	assert.Equal(t, fmt.Sprintf("line %d", tailLines-1), lines[tailLines-1])
+}
+
 type captureCore struct {
 	wrote []wrote
 }
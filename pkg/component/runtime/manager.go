@@ -356,6 +356,20 @@ func (m *Manager) Update(model component.Model) {
 	}
 }
 
+// ResumeComponent releases a quarantined component and starts it again.
+func (m *Manager) ResumeComponent(componentID string) error {
+	m.currentMx.RLock()
+	comp, ok := m.current[componentID]
+	m.currentMx.RUnlock()
+	if !ok {
+		return fmt.Errorf("component %s is not running", componentID)
+	}
+	if comp.getLatest().Quarantine == nil {
+		return fmt.Errorf("component %s is not quarantined", componentID)
+	}
+	return comp.resume()
+}
+
 // PerformAction executes an action on a unit.
 func (m *Manager) PerformAction(ctx context.Context, comp component.Component, unit component.Unit, name string, params map[string]interface{}) (map[string]interface{}, error) {
 	id, err := uuid.NewV4()
@@ -0,0 +1,98 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+)
+
+// ComponentCrash is a crash of a component process.
+type ComponentCrash struct {
+	Time     time.Time `yaml:"time"`
+	PID      int       `yaml:"pid"`
+	ExitCode int       `yaml:"exit_code"`
+
+	// Logs are the last lines the process wrote on stderr before exiting.
+	Logs []string `yaml:"logs,omitempty"`
+}
+
+// ComponentQuarantine is the quarantine of a component that crashed too many times. A quarantined
+// component is not restarted until it is resumed.
+type ComponentQuarantine struct {
+	Since time.Time `yaml:"since"`
+
+	// Crashes are the last crashes of the component, from the oldest to the latest.
+	Crashes []ComponentCrash `yaml:"crashes"`
+}
+
+// recordCrash records the unexpected exit of the process and returns true when the component
+// crashed too many times and must be quarantined.
+func (c *commandRuntime) recordCrash(pid int, exitCode int, now time.Time) bool {
+	cfg := c.current.Quarantine
+	c.crashes = append(c.crashes, ComponentCrash{
+		Time:     now,
+		PID:      pid,
+		ExitCode: exitCode,
+		Logs:     c.logErr.lastLines(),
+	})
+	if keep := max(cfg.MaxCrashes, cfg.CrashLogs); len(c.crashes) > keep {
+		c.crashes = c.crashes[len(c.crashes)-keep:]
+	}
+	if !cfg.Enabled {
+		return false
+	}
+
+	recent := 0
+	for _, crash := range c.crashes {
+		if now.Sub(crash.Time) <= cfg.Period {
+			recent++
+		}
+	}
+	return recent >= cfg.MaxCrashes
+}
+
+// quarantine stops restarting the component until it is resumed.
+func (c *commandRuntime) quarantine(now time.Time) {
+	cfg := c.current.Quarantine
+	crashes := c.crashes
+	if len(crashes) > cfg.CrashLogs {
+		crashes = crashes[len(crashes)-cfg.CrashLogs:]
+	}
+	c.state.Quarantine = &ComponentQuarantine{
+		Since:   now,
+		Crashes: append([]ComponentCrash(nil), crashes...),
+	}
+	msg := fmt.Sprintf("Quarantined: crashed %d times in %s, run 'elastic-agent component resume %s' to restart it", cfg.MaxCrashes, cfg.Period, c.current.ID)
+	c.log.Errorf("Component %s is quarantined after it crashed %d times in %s", c.current.ID, cfg.MaxCrashes, cfg.Period)
+	c.forceCompState(client.UnitStateFailed, msg)
+}
+
+// releaseQuarantine clears the quarantine and the recorded crashes of the component, returns
+// true when the component was quarantined.
+func (c *commandRuntime) releaseQuarantine() bool {
+	c.crashes = nil
+	if c.state.Quarantine == nil {
+		return false
+	}
+	c.state.Quarantine = nil
+	cmdSpec := c.getCommandSpec()
+	c.restartBucket = newRateLimiter(cmdSpec.RestartMonitoringPeriod, cmdSpec.MaxRestartsPerPeriod)
+	return true
+}
+
+// Resume releases the component from quarantine and starts it again.
+//
+// Non-blocking and never returns an error.
+func (c *commandRuntime) Resume() error {
+	select {
+	case c.resumeCh <- struct{}{}:
+	default:
+		// a resume is already pending
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func newQuarantineTestRuntime(t *testing.T, cfg component.QuarantineConfig) *commandRuntime {
+	log, _ := loggertest.New(t.Name())
+	comp := component.Component{
+		ID: "system/metrics-default",
+		InputSpec: &component.InputRuntimeSpec{
+			Spec: component.InputSpec{
+				Command: &component.CommandSpec{
+					RestartMonitoringPeriod: time.Minute,
+					MaxRestartsPerPeriod:    1,
+				},
+			},
+		},
+		Quarantine: cfg,
+	}
+	return &commandRuntime{
+		log:         log,
+		logErr:      newLogWriter(&captureCore{}, component.CommandLogSpec{}, zapcore.ErrorLevel, nil, logSourceStderr),
+		current:     comp,
+		ch:          make(chan ComponentState, 10),
+		state:       newComponentState(&comp),
+		actionState: actionStart,
+	}
+}
+
+func TestQuarantineAfterMaxCrashes(t *testing.T) {
+	c := newQuarantineTestRuntime(t, component.QuarantineConfig{
+		Enabled:    true,
+		MaxCrashes: 3,
+		Period:     time.Minute,
+		CrashLogs:  2,
+	})
+	now := time.Now()
+
+	// crashes older than the period are not counted
+	assert.False(t, c.recordCrash(1, 2, now.Add(-2*time.Minute)))
+	assert.False(t, c.recordCrash(2, 2, now.Add(-time.Second)))
+
+	_, err := c.logErr.Write([]byte("panic: runtime error\n"))
+	require.NoError(t, err)
+	assert.False(t, c.recordCrash(3, 2, now))
+	assert.True(t, c.recordCrash(4, 137, now))
+
+	c.quarantine(now)
+	require.NotNil(t, c.state.Quarantine)
+	assert.Equal(t, now, c.state.Quarantine.Since)
+	require.Len(t, c.state.Quarantine.Crashes, 2, "only the configured number of crashes is kept")
+	assert.Equal(t, ComponentCrash{Time: now, PID: 4, ExitCode: 137, Logs: []string{"panic: runtime error"}}, c.state.Quarantine.Crashes[1])
+	assert.Equal(t, client.UnitStateFailed, c.state.State)
+	assert.Contains(t, c.state.Message, "elastic-agent component resume system/metrics-default")
+
+	observed := <-c.ch
+	assert.NotNil(t, observed.Quarantine)
+
+	// a quarantined component is not started
+	require.NoError(t, c.start(nil))
+	assert.Nil(t, c.proc)
+
+	assert.True(t, c.releaseQuarantine())
+	assert.Nil(t, c.state.Quarantine)
+	assert.Empty(t, c.crashes)
+	assert.False(t, c.releaseQuarantine(), "already released")
+}
+
+func TestQuarantineDisabled(t *testing.T) {
+	cfg := component.DefaultQuarantineConfig()
+	c := newQuarantineTestRuntime(t, cfg)
+	now := time.Now()
+	for i := 0; i < cfg.MaxCrashes*2; i++ {
+		assert.False(t, c.recordCrash(i, 1, now))
+	}
+	assert.Len(t, c.crashes, cfg.MaxCrashes)
+}
@@ -0,0 +1,193 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/pkg/limits"
+)
+
+const (
+	// cpuPeriod is the period of the CPU bandwidth limit in microseconds.
+	cpuPeriod = 100000
+
+	// throttledRatio is the ratio of throttled periods above which the component is degraded.
+	throttledRatio = 0.5
+)
+
+// ComponentResources are the resource limits enforced on a component process and the events
+// caused by them.
+type ComponentResources struct {
+	Limits limits.ResourceLimits `yaml:"limits"`
+
+	// OOMKills is the number of times the process was killed for exceeding its memory limit.
+	OOMKills uint64 `yaml:"oom_kills"`
+
+	// ThrottledPeriods is the number of CPU periods the process was throttled in.
+	ThrottledPeriods uint64 `yaml:"throttled_periods"`
+
+	// ThrottledTime is the total time the process was throttled for.
+	ThrottledTime time.Duration `yaml:"throttled_time"`
+}
+
+// resourceStats are the counters read from the cgroup of a component.
+type resourceStats struct {
+	oomKills         uint64
+	periods          uint64
+	throttledPeriods uint64
+	throttledTime    time.Duration
+}
+
+// throttled returns true when the process was throttled in most of the periods since prev.
+func (s resourceStats) throttled(prev resourceStats) bool {
+	periods := s.periods - prev.periods
+	if s.periods < prev.periods || periods == 0 {
+		return false
+	}
+	return float64(s.throttledPeriods-prev.throttledPeriods)/float64(periods) > throttledRatio
+}
+
+// memoryMax returns the value of memory.max for the limit.
+func memoryMax(limit limits.ByteSize) string {
+	if limit == 0 {
+		return "max"
+	}
+	return strconv.FormatUint(uint64(limit), 10)
+}
+
+// cpuMax returns the value of cpu.max for the limit.
+func cpuMax(limit float64) string {
+	if limit <= 0 {
+		return fmt.Sprintf("max %d", cpuPeriod)
+	}
+	// the kernel rejects quotas lower than 1ms
+	quota := max(int64(limit*cpuPeriod), 1000)
+	return fmt.Sprintf("%d %d", quota, cpuPeriod)
+}
+
+// parseFlatKeyed parses a flat keyed cgroup file like memory.events or cpu.stat.
+func parseFlatKeyed(data []byte) (map[string]uint64, error) {
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line '%s'", scanner.Text())
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of '%s': %w", fields[0], err)
+		}
+		values[fields[0]] = v
+	}
+	return values, scanner.Err()
+}
+
+// parseResourceStats parses the content of the memory.events and cpu.stat files of a cgroup.
+func parseResourceStats(memoryEvents []byte, cpuStat []byte) (resourceStats, error) {
+	events, err := parseFlatKeyed(memoryEvents)
+	if err != nil {
+		return resourceStats{}, fmt.Errorf("failed to parse memory.events: %w", err)
+	}
+	stat, err := parseFlatKeyed(cpuStat)
+	if err != nil {
+		return resourceStats{}, fmt.Errorf("failed to parse cpu.stat: %w", err)
+	}
+	return resourceStats{
+		oomKills:         events["oom_kill"],
+		periods:          stat["nr_periods"],
+		throttledPeriods: stat["nr_throttled"],
+		throttledTime:    time.Duration(stat["throttled_usec"]) * time.Microsecond, //nolint:gosec // throttled time fits in a duration
+	}, nil
+}
+
+// cgroupName returns the name of the cgroup of the component.
+func cgroupName(componentID string) string {
+	return "component-" + strings.NewReplacer("/", "_", ".", "_").Replace(componentID)
+}
+
+// startResourceGroup creates the cgroup enforcing the resource limits of the component before its
+// process is started. The component runs without limits when they cannot be enforced.
+func (c *commandRuntime) startResourceGroup() {
+	l := c.current.ResourceLimits
+	if l.IsZero() {
+		c.state.Resources = nil
+		return
+	}
+	g, err := newResourceGroup(c.current.ID, l)
+	if err != nil {
+		c.log.Warnf("Resource limits of component %s are not enforced: %s", c.current.ID, err)
+		c.state.Resources = nil
+		return
+	}
+	c.resources = g
+	// the cgroup can be left over by a previous process, its counters are not reset
+	c.resourceStats, _ = g.stats()
+	if c.state.Resources == nil || c.state.Resources.Limits != l {
+		c.state.Resources = &ComponentResources{Limits: l}
+	}
+}
+
+// stopResourceGroup removes the cgroup of the component once its process exited.
+func (c *commandRuntime) stopResourceGroup() {
+	if c.resources == nil {
+		return
+	}
+	if err := c.resources.remove(); err != nil {
+		c.log.Warnf("Failed to remove the resource limits of component %s: %s", c.current.ID, err)
+	}
+	c.resources = nil
+}
+
+// syncResourceLimits applies changed resource limits to the running process. Limits added to a
+// component running without limits take effect when the process is restarted.
+func (c *commandRuntime) syncResourceLimits() {
+	l := c.current.ResourceLimits
+	if c.resources == nil || c.state.Resources == nil || c.state.Resources.Limits == l {
+		return
+	}
+	if err := c.resources.apply(l); err != nil {
+		c.log.Warnf("Failed to update the resource limits of component %s: %s", c.current.ID, err)
+		return
+	}
+	c.state.Resources.Limits = l
+}
+
+// updateResources reads the counters of the cgroup into the component state, returns true when
+// the reported counters changed. The component is throttled when it was throttled in most of the
+// CPU periods since the previous update.
+func (c *commandRuntime) updateResources() bool {
+	if c.resources == nil || c.state.Resources == nil {
+		return false
+	}
+	stats, err := c.resources.stats()
+	if err != nil {
+		c.log.Debugf("Failed to read the resource usage of component %s: %s", c.current.ID, err)
+		return false
+	}
+	prev := c.resourceStats
+	c.resourceStats = stats
+	c.throttled = false
+	if stats.oomKills < prev.oomKills || stats.throttledPeriods < prev.throttledPeriods || stats.throttledTime < prev.throttledTime {
+		// counters never decrease in the same cgroup
+		return false
+	}
+	r := c.state.Resources
+	changed := stats.oomKills != prev.oomKills || stats.throttledPeriods != prev.throttledPeriods
+	r.OOMKills += stats.oomKills - prev.oomKills
+	r.ThrottledPeriods += stats.throttledPeriods - prev.throttledPeriods
+	r.ThrottledTime += stats.throttledTime - prev.throttledTime
+	c.throttled = stats.throttled(prev)
+	return changed
+}
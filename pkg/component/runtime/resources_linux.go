@@ -0,0 +1,230 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/pkg/core/process"
+	"github.com/elastic/elastic-agent/pkg/limits"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+
+	// agentCgroup is the leaf cgroup the Elastic Agent processes are moved into, cgroup v2 does
+	// not allow processes in a cgroup that distributes resources to its children.
+	agentCgroup = "agent"
+)
+
+var (
+	cgroupSetupOnce sync.Once
+	cgroupParent    string
+	cgroupSetupErr  error
+)
+
+// resourceGroup is the cgroup v2 group a component process runs in to enforce its resource limits.
+type resourceGroup struct {
+	path string
+	dir  *os.File
+}
+
+// newResourceGroup creates the cgroup of the component and applies the limits to it.
+func newResourceGroup(componentID string, l limits.ResourceLimits) (*resourceGroup, error) {
+	cgroupSetupOnce.Do(func() {
+		cgroupParent, cgroupSetupErr = setupCgroupParent()
+	})
+	if cgroupSetupErr != nil {
+		return nil, cgroupSetupErr
+	}
+
+	path := filepath.Join(cgroupParent, cgroupName(componentID))
+	if err := os.Mkdir(path, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("failed to create cgroup %s: %w", path, err)
+	}
+	g := &resourceGroup{path: path}
+	if err := g.apply(l); err != nil {
+		return nil, err
+	}
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cgroup %s: %w", path, err)
+	}
+	g.dir = dir
+	return g, nil
+}
+
+// apply applies the limits to the cgroup, they take effect immediately on the running process.
+func (g *resourceGroup) apply(l limits.ResourceLimits) error {
+	if err := writeCgroupFile(g.path, "memory.max", memoryMax(l.Memory)); err != nil {
+		return err
+	}
+	return writeCgroupFile(g.path, "cpu.max", cpuMax(l.CPU))
+}
+
+// cmdOption starts the process directly inside the cgroup.
+func (g *resourceGroup) cmdOption() process.CmdOption {
+	return func(c *exec.Cmd) error {
+		if c.SysProcAttr == nil {
+			c.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		c.SysProcAttr.UseCgroupFD = true
+		c.SysProcAttr.CgroupFD = int(g.dir.Fd()) //nolint:gosec // file descriptors fit in an int
+		return nil
+	}
+}
+
+// started releases the resources only needed to start the process.
+func (g *resourceGroup) started() {
+	if g.dir != nil {
+		_ = g.dir.Close()
+		g.dir = nil
+	}
+}
+
+// stats reads the OOM kills and CPU throttling counters of the cgroup.
+func (g *resourceGroup) stats() (resourceStats, error) {
+	memoryEvents, err := os.ReadFile(filepath.Join(g.path, "memory.events"))
+	if err != nil {
+		return resourceStats{}, err
+	}
+	cpuStat, err := os.ReadFile(filepath.Join(g.path, "cpu.stat"))
+	if err != nil {
+		return resourceStats{}, err
+	}
+	return parseResourceStats(memoryEvents, cpuStat)
+}
+
+// remove removes the cgroup, it must not contain any process.
+func (g *resourceGroup) remove() error {
+	g.started()
+	if err := os.Remove(g.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove cgroup %s: %w", g.path, err)
+	}
+	return nil
+}
+
+// setupCgroupParent prepares the cgroup of the Elastic Agent to be the parent of the component
+// cgroups. The cgroup must be delegated to the Elastic Agent, with Delegate= in its systemd unit,
+// the Elastic Agent processes are then moved into a leaf cgroup and the memory and cpu controllers
+// are enabled for the children.
+func setupCgroupParent() (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", errors.New("resource limits require cgroup v2")
+	}
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", fmt.Errorf("failed to read the cgroup of the Elastic Agent: %w", err)
+	}
+	self, err := parseCgroupV2Path(data)
+	if err != nil {
+		return "", err
+	}
+	if filepath.Base(self) == agentCgroup {
+		// already moved by a previous run of the Elastic Agent
+		self = filepath.Dir(self)
+	}
+	if self == "/" {
+		return "", errors.New("resource limits cannot be enforced when the Elastic Agent runs in the root cgroup")
+	}
+	parent := filepath.Join(cgroupRoot, self)
+	if err := checkCgroupDelegated(parent); err != nil {
+		return "", err
+	}
+
+	leaf := filepath.Join(parent, agentCgroup)
+	if err := os.Mkdir(leaf, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", fmt.Errorf("failed to create cgroup %s: %w", leaf, err)
+	}
+	procs, err := os.ReadFile(filepath.Join(parent, "cgroup.procs"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the processes of cgroup %s: %w", parent, err)
+	}
+	for _, pid := range strings.Fields(string(procs)) {
+		// processes can exit while they are moved
+		if err := writeCgroupFile(leaf, "cgroup.procs", pid); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return "", err
+		}
+	}
+	if err := writeCgroupFile(parent, "cgroup.subtree_control", "+memory +cpu"); err != nil {
+		return "", err
+	}
+	return parent, nil
+}
+
+// checkCgroupDelegated returns an error when the cgroup is not delegated to the Elastic Agent or
+// when the memory and cpu controllers are not available to it. systemd marks the cgroup of a unit
+// with Delegate= with the trusted.delegate extended attribute, user.delegate for the units of the
+// user manager, since version 251. Without delegation systemd owns the cgroup tree and moving the
+// processes of the service would conflict with it.
+func checkCgroupDelegated(path string) error {
+	if !hasDelegateXattr(path) {
+		return fmt.Errorf("resource limits require the cgroup %s of the Elastic Agent to be delegated, set Delegate=memory cpu in its systemd unit and restart the service", path)
+	}
+	controllers, err := os.ReadFile(filepath.Join(path, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("failed to read the controllers of cgroup %s: %w", path, err)
+	}
+	if missing := missingControllers(controllers, "memory", "cpu"); len(missing) > 0 {
+		return fmt.Errorf("resource limits require the %s controllers to be delegated to the cgroup %s of the Elastic Agent", strings.Join(missing, " and "), path)
+	}
+	return nil
+}
+
+func hasDelegateXattr(path string) bool {
+	buf := make([]byte, 16)
+	for _, attr := range []string{"trusted.delegate", "user.delegate"} {
+		n, err := unix.Getxattr(path, attr, buf)
+		if err == nil && string(bytes.TrimSpace(buf[:n])) == "1" {
+			return true
+		}
+	}
+	return false
+}
+
+// missingControllers returns the required controllers missing from the content of a
+// cgroup.controllers file.
+func missingControllers(controllers []byte, required ...string) []string {
+	available := strings.Fields(string(controllers))
+	var missing []string
+	for _, c := range required {
+		if !slices.Contains(available, c) {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// parseCgroupV2Path returns the path of the cgroup v2 entry of /proc/<pid>/cgroup.
+func parseCgroupV2Path(data []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", errors.New("failed to find the cgroup v2 of the Elastic Agent")
+}
+
+func writeCgroupFile(path string, name string, value string) error {
+	if err := os.WriteFile(filepath.Join(path, name), []byte(value), 0o644); err != nil { //nolint:gosec // cgroup files are not secrets
+		return fmt.Errorf("failed to write %s of cgroup %s: %w", name, path, err)
+	}
+	return nil
+}
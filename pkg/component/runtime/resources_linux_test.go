@@ -0,0 +1,36 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingControllers(t *testing.T) {
+	assert.Empty(t, missingControllers([]byte("cpuset cpu io memory pids\n"), "memory", "cpu"))
+	assert.Equal(t, []string{"memory"}, missingControllers([]byte("cpu pids\n"), "memory", "cpu"))
+	assert.Equal(t, []string{"memory", "cpu"}, missingControllers([]byte("\n"), "memory", "cpu"))
+}
+
+func TestCheckCgroupDelegated(t *testing.T) {
+	// a directory without the delegate extended attribute is never delegated
+	err := checkCgroupDelegated(t.TempDir())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "set Delegate=memory cpu")
+}
+
+func TestParseCgroupV2Path(t *testing.T) {
+	path, err := parseCgroupV2Path([]byte("12:pids:/system.slice\n0::/system.slice/elastic-agent.service\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "/system.slice/elastic-agent.service", path)
+
+	_, err = parseCgroupV2Path([]byte("12:pids:/system.slice\n"))
+	assert.Error(t, err)
+}
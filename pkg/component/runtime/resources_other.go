@@ -0,0 +1,40 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux
+
+package runtime
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/elastic/elastic-agent/pkg/core/process"
+	"github.com/elastic/elastic-agent/pkg/limits"
+)
+
+// resourceGroup is not supported on this platform, resource limits are only enforced on Linux.
+type resourceGroup struct{}
+
+func newResourceGroup(_ string, _ limits.ResourceLimits) (*resourceGroup, error) {
+	return nil, errors.New("resource limits are only enforced on Linux")
+}
+
+func (g *resourceGroup) apply(_ limits.ResourceLimits) error {
+	return nil
+}
+
+func (g *resourceGroup) cmdOption() process.CmdOption {
+	return func(_ *exec.Cmd) error { return nil }
+}
+
+func (g *resourceGroup) started() {}
+
+func (g *resourceGroup) stats() (resourceStats, error) {
+	return resourceStats{}, nil
+}
+
+func (g *resourceGroup) remove() error {
+	return nil
+}
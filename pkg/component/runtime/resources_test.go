@@ -0,0 +1,62 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceLimitsFiles(t *testing.T) {
+	assert.Equal(t, "max", memoryMax(0))
+	assert.Equal(t, "536870912", memoryMax(512<<20))
+
+	assert.Equal(t, "max 100000", cpuMax(0))
+	assert.Equal(t, "50000 100000", cpuMax(0.5))
+	assert.Equal(t, "200000 100000", cpuMax(2))
+	assert.Equal(t, "1000 100000", cpuMax(0.001), "quota is at least 1ms")
+
+	assert.Equal(t, "component-system_metrics-default", cgroupName("system/metrics-default"))
+}
+
+func TestParseResourceStats(t *testing.T) {
+	memoryEvents := `low 0
+high 0
+max 12
+oom 3
+oom_kill 2
+oom_group_kill 0
+`
+	cpuStat := `usage_usec 8012345
+user_usec 6012345
+system_usec 2000000
+nr_periods 400
+nr_throttled 250
+throttled_usec 1500000
+`
+	stats, err := parseResourceStats([]byte(memoryEvents), []byte(cpuStat))
+	require.NoError(t, err)
+	assert.Equal(t, resourceStats{
+		oomKills:         2,
+		periods:          400,
+		throttledPeriods: 250,
+		throttledTime:    1500 * time.Millisecond,
+	}, stats)
+
+	_, err = parseResourceStats([]byte("oom_kill lots"), []byte(cpuStat))
+	assert.ErrorContains(t, err, "failed to parse memory.events")
+}
+
+func TestResourceStatsThrottled(t *testing.T) {
+	prev := resourceStats{periods: 100, throttledPeriods: 10}
+
+	assert.True(t, resourceStats{periods: 200, throttledPeriods: 70}.throttled(prev))
+	assert.False(t, resourceStats{periods: 200, throttledPeriods: 50}.throttled(prev))
+	assert.False(t, prev.throttled(prev), "no period elapsed")
+	assert.False(t, resourceStats{periods: 10, throttledPeriods: 10}.throttled(prev), "counters were reset")
+}
@@ -7,6 +7,7 @@ package runtime
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 
@@ -176,6 +177,22 @@ func (s *componentRuntimeState) start() error {
 	return s.runtime.Start()
 }
 
+// componentResumer is implemented by the runtimes able to quarantine a component.
+type componentResumer interface {
+	// Resume releases the component from quarantine and starts it again.
+	//
+	// Must be non-blocking and never return an error unless the whole Elastic Agent needs to exit.
+	Resume() error
+}
+
+func (s *componentRuntimeState) resume() error {
+	r, ok := s.runtime.(componentResumer)
+	if !ok {
+		return fmt.Errorf("component %s cannot be quarantined", s.id)
+	}
+	return r.Resume()
+}
+
 func (s *componentRuntimeState) stop(teardown bool, signed *component.Signed) error {
 	if !s.shuttingDown.CompareAndSwap(false, true) {
 		// already stopping
@@ -75,6 +75,16 @@ type ComponentState struct {
 
 	VersionInfo ComponentVersionInfo `yaml:"version_info"`
 
+	// Resources are the resource limits enforced on the component process, nil when the
+	// component runs without limits.
+	Resources *ComponentResources `yaml:"resources,omitempty"`
+
+	// Quarantine is set when the component crashed too many times and is no longer restarted.
+	Quarantine *ComponentQuarantine `yaml:"quarantine,omitempty"`
+
+	// Restarts is the number of times the component process exited unexpectedly and was restarted.
+	Restarts uint64 `yaml:"restarts,omitempty"`
+
 	// The PID of the process, as obtained from the *from the Protobuf API*
 	// As of now, this is only used by Endpoint, as agent doesn't know the PID
 	// of the endpoint service. If you need the PID for beats, use the coordinator/communicator
@@ -136,6 +146,11 @@ func (s *ComponentState) Copy() (c ComponentState) {
 	c.expectedComponent = s.expectedComponent
 	c.expectedComponentIdx = s.expectedComponentIdx
 
+	if s.Resources != nil {
+		resources := *s.Resources
+		c.Resources = &resources
+	}
+
 	return c
 }
 
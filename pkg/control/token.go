@@ -0,0 +1,16 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package control
+
+const (
+	// TokenMetadataKey is the gRPC metadata key carrying the token of a control protocol caller,
+	// its value is "Bearer <token>".
+	TokenMetadataKey = "authorization"
+
+	// TokenEnvVar is the environment variable holding the token presented by the control protocol
+	// client, it is required when the access control is enabled and the caller cannot be identified
+	// by its user or group, such as on Windows.
+	TokenEnvVar = "ELASTIC_AGENT_CONTROL_TOKEN" //nolint:gosec // G101: name of the variable, not a credential
+)
@@ -6,6 +6,8 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/elastic/elastic-agent/pkg/control"
 	"github.com/elastic/elastic-agent/pkg/control/v1/proto"
@@ -19,7 +21,7 @@ import (
 
 type serverV2 interface {
 	State(_ context.Context, _ *v2proto.Empty) (*v2proto.StateResponse, error)
-	Restart(_ context.Context, _ *v2proto.Empty) (*v2proto.RestartResponse, error)
+	Restart(_ context.Context, _ *v2proto.RestartRequest) (*v2proto.RestartResponse, error)
 	Upgrade(_ context.Context, _ *v2proto.UpgradeRequest) (*v2proto.UpgradeResponse, error)
 }
 
@@ -65,11 +67,26 @@ func (s *Server) Status(ctx context.Context, _ *proto.Empty) (*proto.StatusRespo
 	}, nil
 }
 
-// Restart performs re-exec.
+// Restart performs re-exec. The v1 protocol cannot report a restart deferred until the next maintenance window,
+// a deferred restart is reported as a failure to trigger the restart now.
 func (s *Server) Restart(ctx context.Context, _ *proto.Empty) (*proto.RestartResponse, error) {
-	_, err := s.v2Server.Restart(ctx, &v2proto.Empty{})
-	return &proto.RestartResponse{Status: proto.ActionStatus_V1_SUCCESS}, err
-
+	resp, err := s.v2Server.Restart(ctx, &v2proto.RestartRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status == v2proto.ActionStatus_FAILURE {
+		return &proto.RestartResponse{
+			Status: proto.ActionStatus_V1_FAILURE,
+			Error:  resp.Error,
+		}, nil
+	}
+	if resp.DeferredUntil != nil {
+		return &proto.RestartResponse{
+			Status: proto.ActionStatus_V1_FAILURE,
+			Error:  fmt.Sprintf("restart deferred until the next maintenance window at %s", resp.DeferredUntil.AsTime().Format(time.RFC3339)),
+		}, nil
+	}
+	return &proto.RestartResponse{Status: proto.ActionStatus_V1_SUCCESS}, nil
 }
 
 // Upgrade performs the upgrade operation.
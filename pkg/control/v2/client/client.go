@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"sync"
 	"time"
 
@@ -58,6 +59,8 @@ const (
 	Upgrading State = cproto.State_UPGRADING
 	// Rollback is when it is upgrading is rolling back.
 	Rollback State = cproto.State_ROLLBACK
+	// Quarantined is when a component crashed too many times and is no longer restarted.
+	Quarantined State = cproto.State_QUARANTINED
 )
 
 const (
@@ -118,6 +121,21 @@ type ComponentState struct {
 	Message     string               `json:"message" yaml:"message"`
 	Units       []ComponentUnitState `json:"units" yaml:"units"`
 	VersionInfo ComponentVersionInfo `json:"version_info" yaml:"version_info"`
+	Quarantine  *ComponentQuarantine `json:"quarantine,omitempty" yaml:"quarantine,omitempty"`
+}
+
+// ComponentCrash is a crash of a component process.
+type ComponentCrash struct {
+	Time     time.Time `json:"time" yaml:"time"`
+	PID      int64     `json:"pid" yaml:"pid"`
+	ExitCode int32     `json:"exit_code" yaml:"exit_code"`
+	Logs     []string  `json:"logs,omitempty" yaml:"logs,omitempty"`
+}
+
+// ComponentQuarantine is the quarantine of a component that crashed too many times.
+type ComponentQuarantine struct {
+	Since   time.Time        `json:"since" yaml:"since"`
+	Crashes []ComponentCrash `json:"crashes" yaml:"crashes"`
 }
 
 // CollectorComponent is a state of a collector component managed by the Elastic Agent.
@@ -142,14 +160,18 @@ type AgentStateInfo struct {
 
 // AgentState is the current state of the Elastic Agent.
 type AgentState struct {
-	Info           AgentStateInfo         `json:"info" yaml:"info"`
-	State          State                  `json:"state" yaml:"state"`
-	Message        string                 `json:"message" yaml:"message"`
-	Components     []ComponentState       `json:"components" yaml:"components"`
-	FleetState     State                  `yaml:"fleet_state"`
-	FleetMessage   string                 `yaml:"fleet_message"`
-	UpgradeDetails *cproto.UpgradeDetails `json:"upgrade_details,omitempty" yaml:"upgrade_details,omitempty"`
-	Collector      *CollectorComponent    `json:"collector,omitempty" yaml:"collector,omitempty"`
+	Info               AgentStateInfo         `json:"info" yaml:"info"`
+	State              State                  `json:"state" yaml:"state"`
+	Message            string                 `json:"message" yaml:"message"`
+	Components         []ComponentState       `json:"components" yaml:"components"`
+	FleetState         State                  `yaml:"fleet_state"`
+	FleetMessage       string                 `yaml:"fleet_message"`
+	UpgradeDetails     *cproto.UpgradeDetails `json:"upgrade_details,omitempty" yaml:"upgrade_details,omitempty"`
+	Collector          *CollectorComponent    `json:"collector,omitempty" yaml:"collector,omitempty"`
+	PolicyRollback     *PolicyRollback        `json:"policy_rollback,omitempty" yaml:"policy_rollback,omitempty"`
+	Maintenance        *MaintenanceState      `json:"maintenance,omitempty" yaml:"maintenance,omitempty"`
+	PendingAcks        []PendingAck           `json:"pending_acks,omitempty" yaml:"pending_acks,omitempty"`
+	DiagnosticsUploads []DiagnosticsUpload    `json:"diagnostics_uploads,omitempty" yaml:"diagnostics_uploads,omitempty"`
 }
 
 // DiagnosticFileResult is a diagnostic file result.
@@ -196,6 +218,80 @@ type AvailableRollback struct {
 	ValidUntil    time.Time `json:"valid_until" yaml:"valid_until"`
 }
 
+// UnitConfigDiff is a unit whose configuration changed between two component models.
+type UnitConfigDiff struct {
+	UnitID       string   `json:"unit_id" yaml:"unit_id"`
+	UnitType     UnitType `json:"unit_type" yaml:"unit_type"`
+	PreviousHash string   `json:"previous_hash" yaml:"previous_hash"`
+	CurrentHash  string   `json:"current_hash" yaml:"current_hash"`
+	ChangedKeys  []string `json:"changed_keys,omitempty" yaml:"changed_keys,omitempty"`
+}
+
+// ComponentDiff is a component present in both component models whose units changed.
+type ComponentDiff struct {
+	ComponentID  string           `json:"component_id" yaml:"component_id"`
+	UnitsAdded   []string         `json:"units_added,omitempty" yaml:"units_added,omitempty"`
+	UnitsRemoved []string         `json:"units_removed,omitempty" yaml:"units_removed,omitempty"`
+	UnitsChanged []UnitConfigDiff `json:"units_changed,omitempty" yaml:"units_changed,omitempty"`
+}
+
+// PolicyDiff is the difference between the previous and the current component model.
+type PolicyDiff struct {
+	ComputedAt        time.Time       `json:"computed_at" yaml:"computed_at"`
+	ComponentsAdded   []string        `json:"components_added,omitempty" yaml:"components_added,omitempty"`
+	ComponentsRemoved []string        `json:"components_removed,omitempty" yaml:"components_removed,omitempty"`
+	ComponentsChanged []ComponentDiff `json:"components_changed,omitempty" yaml:"components_changed,omitempty"`
+}
+
+// PolicyHistoryEntry is a policy applied by the Elastic Agent.
+type PolicyHistoryEntry struct {
+	ActionID  string    `json:"action_id" yaml:"action_id"`
+	PolicyID  string    `json:"policy_id" yaml:"policy_id"`
+	Revision  int64     `json:"revision" yaml:"revision"`
+	AppliedAt time.Time `json:"applied_at" yaml:"applied_at"`
+	Acked     bool      `json:"acked" yaml:"acked"`
+	Rollback  bool      `json:"rollback" yaml:"rollback"`
+}
+
+// PolicyRollback is a policy re-applied locally from the policy history.
+type PolicyRollback struct {
+	ActionID     string    `json:"action_id" yaml:"action_id"`
+	PolicyID     string    `json:"policy_id" yaml:"policy_id"`
+	Revision     int64     `json:"revision" yaml:"revision"`
+	FromRevision int64     `json:"from_revision" yaml:"from_revision"`
+	RolledBackAt time.Time `json:"rolled_back_at" yaml:"rolled_back_at"`
+}
+
+// MaintenanceState describes the operations deferred until the next maintenance window.
+type MaintenanceState struct {
+	DeferredUntil time.Time `json:"deferred_until" yaml:"deferred_until"`
+	Restart       bool      `json:"restart,omitempty" yaml:"restart,omitempty"`
+	PolicyChange  bool      `json:"policy_change,omitempty" yaml:"policy_change,omitempty"`
+}
+
+// PendingAck is the ack of a Fleet action not delivered to Fleet yet.
+type PendingAck struct {
+	ActionID      string    `json:"action_id" yaml:"action_id"`
+	ActionType    string    `json:"action_type" yaml:"action_type"`
+	Attempts      int       `json:"attempts" yaml:"attempts"`
+	LastError     string    `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+	EnqueuedAt    time.Time `json:"enqueued_at" yaml:"enqueued_at"`
+	LastAttemptAt time.Time `json:"last_attempt_at,omitempty" yaml:"last_attempt_at,omitempty"`
+}
+
+// DiagnosticsUpload is the progress of a diagnostics bundle being uploaded to Fleet.
+type DiagnosticsUpload struct {
+	ActionID   string    `json:"action_id" yaml:"action_id"`
+	UploadID   string    `json:"upload_id,omitempty" yaml:"upload_id,omitempty"`
+	ChunksSent int       `json:"chunks_sent" yaml:"chunks_sent"`
+	Chunks     int       `json:"chunks" yaml:"chunks"`
+	BytesSent  int64     `json:"bytes_sent" yaml:"bytes_sent"`
+	Size       int64     `json:"size" yaml:"size"`
+	Attempt    int       `json:"attempt" yaml:"attempt"`
+	LastError  string    `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
 // Client communicates to Elastic Agent through the control protocol.
 type Client interface {
 	// Connect connects to the running Elastic Agent.
@@ -211,8 +307,12 @@ type Client interface {
 	// the most recent state at the time of each read. Pass WithAllAvailable()
 	// (or no option) to receive all buffered transitions in order.
 	StateWatch(ctx context.Context, opts ...StateWatchOption) (ClientStateWatch, error)
-	// Restart triggers restarting the current running daemon.
+	// Restart triggers restarting the current running daemon immediately, even outside of the maintenance windows.
 	Restart(ctx context.Context) error
+	// RestartOrDefer triggers restarting the current running daemon. When maintenance windows are configured
+	// the restart is deferred until the next window, unless force is set, and the start of that window
+	// is returned.
+	RestartOrDefer(ctx context.Context, force bool) (time.Time, error)
 	// Upgrade triggers upgrade of the current running daemon.
 	Upgrade(ctx context.Context, version string, rollback bool, sourceURI string, skipVerify bool, skipDefaultPgp bool, pgpBytes ...string) (string, error)
 	// DiagnosticAgent gathers diagnostics information for the running Elastic Agent.
@@ -227,6 +327,16 @@ type Client interface {
 	Configure(ctx context.Context, config string) error
 	// AvailableRollbacks returns all the existing elastic-agent installs that can be used to rollback the agent
 	AvailableRollbacks(ctx context.Context) ([]AvailableRollback, error)
+	// PolicyDiff returns the difference between the previous and the current component model.
+	// Returns nil when the component model has not changed since the Elastic Agent started.
+	PolicyDiff(ctx context.Context) (*PolicyDiff, error)
+	// PolicyHistory returns the last policies applied by the Elastic Agent, from the oldest to the latest.
+	PolicyHistory(ctx context.Context) ([]PolicyHistoryEntry, error)
+	// PolicyRollback re-applies the most recently applied policy with the provided ID and revision from the policy
+	// history. An empty policy ID selects the currently applied policy.
+	PolicyRollback(ctx context.Context, policyID string, revision int64) (*PolicyRollback, error)
+	// ComponentResume releases a quarantined component and starts it again.
+	ComponentResume(ctx context.Context, componentID string) error
 }
 
 // ClientStateWatch allows the state of the running Elastic Agent to be watched.
@@ -283,6 +393,14 @@ func WithAddress(address string) Option {
 	}
 }
 
+// WithToken sets the token presented to the Elastic Agent to be granted a role when the access
+// control of the control protocol is enabled. Defaults to the value of control.TokenEnvVar.
+func WithToken(token string) Option {
+	return func(c *client) {
+		c.token = token
+	}
+}
+
 // client manages the state and communication to the Elastic Agent.
 type client struct {
 	ctx        context.Context
@@ -291,6 +409,7 @@ type client struct {
 	client     cproto.ElasticAgentControlClient
 	address    string
 	maxMsgSize int
+	token      string
 }
 
 // New creates a client connection to Elastic Agent.
@@ -299,6 +418,7 @@ func New(opts ...Option) Client {
 	c := &client{
 		address:    control.Address(),
 		maxMsgSize: cfg.MaxMsgSize,
+		token:      os.Getenv(control.TokenEnvVar),
 	}
 	for _, o := range opts {
 		o(c)
@@ -309,6 +429,9 @@ func New(opts ...Option) Client {
 // Connect connects to the running Elastic Agent.
 func (c *client) Connect(ctx context.Context, opts ...grpc.DialOption) error {
 	c.ctx, c.cancel = context.WithCancel(ctx)
+	if c.token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(c.token)))
+	}
 	conn, err := dialContext(ctx, c.address, c.maxMsgSize, opts...)
 	if err != nil {
 		return err
@@ -372,16 +495,26 @@ func (c *client) StateWatch(ctx context.Context, opts ...StateWatchOption) (Clie
 	return &stateWatcher{cli}, nil
 }
 
-// Restart triggers restarting the current running daemon.
+// Restart triggers restarting the current running daemon immediately.
 func (c *client) Restart(ctx context.Context) error {
-	res, err := c.client.Restart(ctx, &cproto.Empty{})
+	_, err := c.RestartOrDefer(ctx, true)
+	return err
+}
+
+// RestartOrDefer triggers restarting the current running daemon, deferred until the next maintenance window
+// unless force is set.
+func (c *client) RestartOrDefer(ctx context.Context, force bool) (time.Time, error) {
+	res, err := c.client.Restart(ctx, &cproto.RestartRequest{Force: force})
 	if err != nil {
-		return err
+		return time.Time{}, err
 	}
 	if res.Status == cproto.ActionStatus_FAILURE {
-		return errors.New(res.Error)
+		return time.Time{}, errors.New(res.Error)
 	}
-	return nil
+	if res.DeferredUntil == nil {
+		return time.Time{}, nil
+	}
+	return res.DeferredUntil.AsTime(), nil
 }
 
 // Upgrade triggers upgrade of the current running daemon.
@@ -557,6 +690,166 @@ func (c *client) AvailableRollbacks(ctx context.Context) ([]AvailableRollback, e
 	return rollbacks, err
 }
 
+// PolicyDiff returns the difference between the previous and the current component model.
+func (c *client) PolicyDiff(ctx context.Context) (*PolicyDiff, error) {
+	res, err := c.client.PolicyDiff(ctx, &cproto.Empty{})
+	if err != nil {
+		return nil, fmt.Errorf("failed retrieving policy diff: %w", err)
+	}
+	if !res.Available {
+		return nil, nil
+	}
+	diff := &PolicyDiff{
+		ComponentsAdded:   res.ComponentsAdded,
+		ComponentsRemoved: res.ComponentsRemoved,
+	}
+	if res.ComputedAt != nil {
+		diff.ComputedAt = res.ComputedAt.AsTime()
+	}
+	for _, comp := range res.ComponentsChanged {
+		compDiff := ComponentDiff{
+			ComponentID:  comp.ComponentId,
+			UnitsAdded:   comp.UnitsAdded,
+			UnitsRemoved: comp.UnitsRemoved,
+		}
+		for _, unit := range comp.UnitsChanged {
+			compDiff.UnitsChanged = append(compDiff.UnitsChanged, UnitConfigDiff{
+				UnitID:       unit.UnitId,
+				UnitType:     unit.UnitType,
+				PreviousHash: unit.PreviousHash,
+				CurrentHash:  unit.CurrentHash,
+				ChangedKeys:  unit.ChangedKeys,
+			})
+		}
+		diff.ComponentsChanged = append(diff.ComponentsChanged, compDiff)
+	}
+	return diff, nil
+}
+
+// PolicyHistory returns the last policies applied by the Elastic Agent, from the oldest to the latest.
+func (c *client) PolicyHistory(ctx context.Context) ([]PolicyHistoryEntry, error) {
+	res, err := c.client.PolicyHistory(ctx, &cproto.Empty{})
+	if err != nil {
+		return nil, fmt.Errorf("failed retrieving policy history: %w", err)
+	}
+	entries := make([]PolicyHistoryEntry, 0, len(res.Entries))
+	for _, entry := range res.Entries {
+		entries = append(entries, PolicyHistoryEntry{
+			ActionID:  entry.ActionId,
+			PolicyID:  entry.PolicyId,
+			Revision:  entry.Revision,
+			AppliedAt: entry.AppliedAt.AsTime(),
+			Acked:     entry.Acked,
+			Rollback:  entry.Rollback,
+		})
+	}
+	return entries, nil
+}
+
+// PolicyRollback re-applies the most recently applied policy with the provided ID and revision from the policy
+// history. An empty policy ID selects the currently applied policy.
+func (c *client) PolicyRollback(ctx context.Context, policyID string, revision int64) (*PolicyRollback, error) {
+	res, err := c.client.PolicyRollback(ctx, &cproto.PolicyRollbackRequest{PolicyId: policyID, Revision: revision})
+	if err != nil {
+		return nil, fmt.Errorf("failed rolling back policy: %w", err)
+	}
+	return policyRollbackFromProto(res.Rollback), nil
+}
+
+// ComponentResume releases a quarantined component and starts it again.
+func (c *client) ComponentResume(ctx context.Context, componentID string) error {
+	_, err := c.client.ComponentResume(ctx, &cproto.ComponentResumeRequest{ComponentId: componentID})
+	if err != nil {
+		return fmt.Errorf("failed resuming component %s: %w", componentID, err)
+	}
+	return nil
+}
+
+func quarantineFromProto(quarantine *cproto.ComponentQuarantine) *ComponentQuarantine {
+	if quarantine == nil {
+		return nil
+	}
+	crashes := make([]ComponentCrash, 0, len(quarantine.Crashes))
+	for _, crash := range quarantine.Crashes {
+		crashes = append(crashes, ComponentCrash{
+			Time:     crash.Time.AsTime(),
+			PID:      crash.Pid,
+			ExitCode: crash.ExitCode,
+			Logs:     crash.Logs,
+		})
+	}
+	return &ComponentQuarantine{
+		Since:   quarantine.Since.AsTime(),
+		Crashes: crashes,
+	}
+}
+
+func policyRollbackFromProto(rollback *cproto.PolicyRollback) *PolicyRollback {
+	if rollback == nil {
+		return nil
+	}
+	return &PolicyRollback{
+		ActionID:     rollback.ActionId,
+		PolicyID:     rollback.PolicyId,
+		Revision:     rollback.Revision,
+		FromRevision: rollback.FromRevision,
+		RolledBackAt: rollback.RolledBackAt.AsTime(),
+	}
+}
+
+func maintenanceFromProto(maintenance *cproto.MaintenanceState) *MaintenanceState {
+	if maintenance == nil {
+		return nil
+	}
+	return &MaintenanceState{
+		DeferredUntil: maintenance.DeferredUntil.AsTime(),
+		Restart:       maintenance.Restart,
+		PolicyChange:  maintenance.PolicyChange,
+	}
+}
+
+func pendingAcksFromProto(pendingAcks []*cproto.PendingAck) []PendingAck {
+	if len(pendingAcks) == 0 {
+		return nil
+	}
+	res := make([]PendingAck, 0, len(pendingAcks))
+	for _, ack := range pendingAcks {
+		pa := PendingAck{
+			ActionID:   ack.ActionId,
+			ActionType: ack.ActionType,
+			Attempts:   int(ack.Attempts),
+			LastError:  ack.LastError,
+			EnqueuedAt: ack.EnqueuedAt.AsTime(),
+		}
+		if ack.LastAttemptAt != nil {
+			pa.LastAttemptAt = ack.LastAttemptAt.AsTime()
+		}
+		res = append(res, pa)
+	}
+	return res
+}
+
+func diagnosticsUploadsFromProto(uploads []*cproto.DiagnosticsUpload) []DiagnosticsUpload {
+	if len(uploads) == 0 {
+		return nil
+	}
+	res := make([]DiagnosticsUpload, 0, len(uploads))
+	for _, upload := range uploads {
+		res = append(res, DiagnosticsUpload{
+			ActionID:   upload.ActionId,
+			UploadID:   upload.UploadId,
+			ChunksSent: int(upload.ChunksSent),
+			Chunks:     int(upload.Chunks),
+			BytesSent:  upload.BytesSent,
+			Size:       upload.Size,
+			Attempt:    int(upload.Attempt),
+			LastError:  upload.LastError,
+			UpdatedAt:  upload.UpdatedAt.AsTime(),
+		})
+	}
+	return res
+}
+
 type stateWatcher struct {
 	client cproto.ElasticAgentControl_StateWatchClient
 }
@@ -582,11 +875,15 @@ func toState(res *cproto.StateResponse) (*AgentState, error) {
 			Unprivileged: res.Info.Unprivileged,
 			IsManaged:    res.Info.IsManaged,
 		},
-		State:          res.State,
-		Message:        res.Message,
-		FleetState:     res.FleetState,
-		FleetMessage:   res.FleetMessage,
-		UpgradeDetails: res.UpgradeDetails,
+		State:              res.State,
+		Message:            res.Message,
+		FleetState:         res.FleetState,
+		FleetMessage:       res.FleetMessage,
+		UpgradeDetails:     res.UpgradeDetails,
+		PolicyRollback:     policyRollbackFromProto(res.PolicyRollback),
+		Maintenance:        maintenanceFromProto(res.Maintenance),
+		PendingAcks:        pendingAcksFromProto(res.PendingAcks),
+		DiagnosticsUploads: diagnosticsUploadsFromProto(res.DiagnosticsUploads),
 
 		Components: make([]ComponentState, 0, len(res.Components)),
 	}
@@ -609,11 +906,12 @@ func toState(res *cproto.StateResponse) (*AgentState, error) {
 			})
 		}
 		cs := ComponentState{
-			ID:      comp.Id,
-			Name:    comp.Name,
-			State:   comp.State,
-			Message: comp.Message,
-			Units:   units,
+			ID:         comp.Id,
+			Name:       comp.Name,
+			State:      comp.State,
+			Message:    comp.Message,
+			Units:      units,
+			Quarantine: quarantineFromProto(comp.Quarantine),
 		}
 		if comp.VersionInfo != nil {
 			cs.VersionInfo = ComponentVersionInfo{
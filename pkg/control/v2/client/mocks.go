@@ -10,6 +10,7 @@ package client
 
 import (
 	"context"
+	"time"
 
 	mock "github.com/stretchr/testify/mock"
 	"google.golang.org/grpc"
@@ -104,6 +105,63 @@ func (_c *MockClient_AvailableRollbacks_Call) RunAndReturn(run func(ctx context.
 	return _c
 }
 
+// ComponentResume provides a mock function for the type MockClient
+func (_mock *MockClient) ComponentResume(ctx context.Context, componentID string) error {
+	ret := _mock.Called(ctx, componentID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ComponentResume")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = returnFunc(ctx, componentID)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockClient_ComponentResume_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ComponentResume'
+type MockClient_ComponentResume_Call struct {
+	*mock.Call
+}
+
+// ComponentResume is a helper method to define mock.On call
+//   - ctx context.Context
+//   - componentID string
+func (_e *MockClient_Expecter) ComponentResume(ctx any, componentID any) *MockClient_ComponentResume_Call {
+	return &MockClient_ComponentResume_Call{Call: _e.mock.On("ComponentResume", ctx, componentID)}
+}
+
+func (_c *MockClient_ComponentResume_Call) Run(run func(ctx context.Context, componentID string)) *MockClient_ComponentResume_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockClient_ComponentResume_Call) Return(err error) *MockClient_ComponentResume_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockClient_ComponentResume_Call) RunAndReturn(run func(ctx context.Context, componentID string) error) *MockClient_ComponentResume_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Configure provides a mock function for the type MockClient
 func (_mock *MockClient) Configure(ctx context.Context, config string) error {
 	ret := _mock.Called(ctx, config)
@@ -500,6 +558,204 @@ func (_c *MockClient_Disconnect_Call) RunAndReturn(run func()) *MockClient_Disco
 	return _c
 }
 
+// PolicyDiff provides a mock function for the type MockClient
+func (_mock *MockClient) PolicyDiff(ctx context.Context) (*PolicyDiff, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PolicyDiff")
+	}
+
+	var r0 *PolicyDiff
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) (*PolicyDiff, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) *PolicyDiff); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PolicyDiff)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockClient_PolicyDiff_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PolicyDiff'
+type MockClient_PolicyDiff_Call struct {
+	*mock.Call
+}
+
+// PolicyDiff is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockClient_Expecter) PolicyDiff(ctx any) *MockClient_PolicyDiff_Call {
+	return &MockClient_PolicyDiff_Call{Call: _e.mock.On("PolicyDiff", ctx)}
+}
+
+func (_c *MockClient_PolicyDiff_Call) Run(run func(ctx context.Context)) *MockClient_PolicyDiff_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockClient_PolicyDiff_Call) Return(policyDiff *PolicyDiff, err error) *MockClient_PolicyDiff_Call {
+	_c.Call.Return(policyDiff, err)
+	return _c
+}
+
+func (_c *MockClient_PolicyDiff_Call) RunAndReturn(run func(ctx context.Context) (*PolicyDiff, error)) *MockClient_PolicyDiff_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PolicyHistory provides a mock function for the type MockClient
+func (_mock *MockClient) PolicyHistory(ctx context.Context) ([]PolicyHistoryEntry, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PolicyHistory")
+	}
+
+	var r0 []PolicyHistoryEntry
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) ([]PolicyHistoryEntry, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) []PolicyHistoryEntry); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]PolicyHistoryEntry)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockClient_PolicyHistory_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PolicyHistory'
+type MockClient_PolicyHistory_Call struct {
+	*mock.Call
+}
+
+// PolicyHistory is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockClient_Expecter) PolicyHistory(ctx any) *MockClient_PolicyHistory_Call {
+	return &MockClient_PolicyHistory_Call{Call: _e.mock.On("PolicyHistory", ctx)}
+}
+
+func (_c *MockClient_PolicyHistory_Call) Run(run func(ctx context.Context)) *MockClient_PolicyHistory_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockClient_PolicyHistory_Call) Return(policyHistoryEntrys []PolicyHistoryEntry, err error) *MockClient_PolicyHistory_Call {
+	_c.Call.Return(policyHistoryEntrys, err)
+	return _c
+}
+
+func (_c *MockClient_PolicyHistory_Call) RunAndReturn(run func(ctx context.Context) ([]PolicyHistoryEntry, error)) *MockClient_PolicyHistory_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PolicyRollback provides a mock function for the type MockClient
+func (_mock *MockClient) PolicyRollback(ctx context.Context, policyID string, revision int64) (*PolicyRollback, error) {
+	ret := _mock.Called(ctx, policyID, revision)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PolicyRollback")
+	}
+
+	var r0 *PolicyRollback
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, int64) (*PolicyRollback, error)); ok {
+		return returnFunc(ctx, policyID, revision)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, int64) *PolicyRollback); ok {
+		r0 = returnFunc(ctx, policyID, revision)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PolicyRollback)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = returnFunc(ctx, policyID, revision)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockClient_PolicyRollback_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PolicyRollback'
+type MockClient_PolicyRollback_Call struct {
+	*mock.Call
+}
+
+// PolicyRollback is a helper method to define mock.On call
+//   - ctx context.Context
+//   - policyID string
+//   - revision int64
+func (_e *MockClient_Expecter) PolicyRollback(ctx any, policyID any, revision any) *MockClient_PolicyRollback_Call {
+	return &MockClient_PolicyRollback_Call{Call: _e.mock.On("PolicyRollback", ctx, policyID, revision)}
+}
+
+func (_c *MockClient_PolicyRollback_Call) Run(run func(ctx context.Context, policyID string, revision int64)) *MockClient_PolicyRollback_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 int64
+		if args[2] != nil {
+			arg2 = args[2].(int64)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockClient_PolicyRollback_Call) Return(policyRollback *PolicyRollback, err error) *MockClient_PolicyRollback_Call {
+	_c.Call.Return(policyRollback, err)
+	return _c
+}
+
+func (_c *MockClient_PolicyRollback_Call) RunAndReturn(run func(ctx context.Context, policyID string, revision int64) (*PolicyRollback, error)) *MockClient_PolicyRollback_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Restart provides a mock function for the type MockClient
 func (_mock *MockClient) Restart(ctx context.Context) error {
 	ret := _mock.Called(ctx)
@@ -551,6 +807,72 @@ func (_c *MockClient_Restart_Call) RunAndReturn(run func(ctx context.Context) er
 	return _c
 }
 
+// RestartOrDefer provides a mock function for the type MockClient
+func (_mock *MockClient) RestartOrDefer(ctx context.Context, force bool) (time.Time, error) {
+	ret := _mock.Called(ctx, force)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestartOrDefer")
+	}
+
+	var r0 time.Time
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, bool) (time.Time, error)); ok {
+		return returnFunc(ctx, force)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, bool) time.Time); ok {
+		r0 = returnFunc(ctx, force)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, bool) error); ok {
+		r1 = returnFunc(ctx, force)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockClient_RestartOrDefer_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestartOrDefer'
+type MockClient_RestartOrDefer_Call struct {
+	*mock.Call
+}
+
+// RestartOrDefer is a helper method to define mock.On call
+//   - ctx context.Context
+//   - force bool
+func (_e *MockClient_Expecter) RestartOrDefer(ctx any, force any) *MockClient_RestartOrDefer_Call {
+	return &MockClient_RestartOrDefer_Call{Call: _e.mock.On("RestartOrDefer", ctx, force)}
+}
+
+func (_c *MockClient_RestartOrDefer_Call) Run(run func(ctx context.Context, force bool)) *MockClient_RestartOrDefer_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 bool
+		if args[1] != nil {
+			arg1 = args[1].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockClient_RestartOrDefer_Call) Return(time1 time.Time, err error) *MockClient_RestartOrDefer_Call {
+	_c.Call.Return(time1, err)
+	return _c
+}
+
+func (_c *MockClient_RestartOrDefer_Call) RunAndReturn(run func(ctx context.Context, force bool) (time.Time, error)) *MockClient_RestartOrDefer_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // State provides a mock function for the type MockClient
 func (_mock *MockClient) State(ctx context.Context) (*AgentState, error) {
 	ret := _mock.Called(ctx)
@@ -0,0 +1,24 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package client
+
+import (
+	"context"
+
+	"github.com/elastic/elastic-agent/pkg/control"
+)
+
+// tokenCredentials presents the token of the client on each call.
+type tokenCredentials string
+
+// GetRequestMetadata returns the metadata carrying the token.
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{control.TokenMetadataKey: "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity returns false, the control protocol runs over a local socket or named pipe.
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -34,6 +34,10 @@ const (
 	ElasticAgentControl_DiagnosticComponents_FullMethodName = "/cproto.ElasticAgentControl/DiagnosticComponents"
 	ElasticAgentControl_Configure_FullMethodName            = "/cproto.ElasticAgentControl/Configure"
 	ElasticAgentControl_AvailableRollbacks_FullMethodName   = "/cproto.ElasticAgentControl/AvailableRollbacks"
+	ElasticAgentControl_PolicyDiff_FullMethodName           = "/cproto.ElasticAgentControl/PolicyDiff"
+	ElasticAgentControl_PolicyHistory_FullMethodName        = "/cproto.ElasticAgentControl/PolicyHistory"
+	ElasticAgentControl_PolicyRollback_FullMethodName       = "/cproto.ElasticAgentControl/PolicyRollback"
+	ElasticAgentControl_ComponentResume_FullMethodName      = "/cproto.ElasticAgentControl/ComponentResume"
 )
 
 // ElasticAgentControlClient is the client API for ElasticAgentControl service.
@@ -52,7 +56,10 @@ type ElasticAgentControlClient interface {
 	// skipping intermediate transitions.
 	StateWatch(ctx context.Context, in *StateWatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StateResponse], error)
 	// Restart restarts the current running Elastic Agent.
-	Restart(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RestartResponse, error)
+	//
+	// The restart is deferred until the next maintenance window, when maintenance windows are configured,
+	// unless the restart is forced.
+	Restart(ctx context.Context, in *RestartRequest, opts ...grpc.CallOption) (*RestartResponse, error)
 	// Upgrade starts the upgrade process of Elastic Agent.
 	Upgrade(ctx context.Context, in *UpgradeRequest, opts ...grpc.CallOption) (*UpgradeResponse, error)
 	// Gather diagnostic information for the running Elastic Agent.
@@ -70,6 +77,19 @@ type ElasticAgentControlClient interface {
 	Configure(ctx context.Context, in *ConfigureRequest, opts ...grpc.CallOption) (*Empty, error)
 	// AvailableRollbacks returns any existing agent installs that can be used as a target for a manual rollback operation
 	AvailableRollbacks(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*AvailableRollbacksResponse, error)
+	// PolicyDiff returns the difference between the previous and the current component model
+	// computed from the policy, to identify which part of a policy change affected each component.
+	PolicyDiff(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PolicyDiffResponse, error)
+	// PolicyHistory returns the last policies applied by Elastic Agent.
+	//
+	// Only available when Elastic Agent is managed by Fleet.
+	PolicyHistory(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PolicyHistoryResponse, error)
+	// PolicyRollback re-applies a policy from the policy history locally, without communicating with Fleet.
+	//
+	// Only available when Elastic Agent is managed by Fleet.
+	PolicyRollback(ctx context.Context, in *PolicyRollbackRequest, opts ...grpc.CallOption) (*PolicyRollbackResponse, error)
+	// ComponentResume releases a component from quarantine and starts it again.
+	ComponentResume(ctx context.Context, in *ComponentResumeRequest, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type elasticAgentControlClient struct {
@@ -119,7 +139,7 @@ func (c *elasticAgentControlClient) StateWatch(ctx context.Context, in *StateWat
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ElasticAgentControl_StateWatchClient = grpc.ServerStreamingClient[StateResponse]
 
-func (c *elasticAgentControlClient) Restart(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RestartResponse, error) {
+func (c *elasticAgentControlClient) Restart(ctx context.Context, in *RestartRequest, opts ...grpc.CallOption) (*RestartResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(RestartResponse)
 	err := c.cc.Invoke(ctx, ElasticAgentControl_Restart_FullMethodName, in, out, cOpts...)
@@ -207,6 +227,46 @@ func (c *elasticAgentControlClient) AvailableRollbacks(ctx context.Context, in *
 	return out, nil
 }
 
+func (c *elasticAgentControlClient) PolicyDiff(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PolicyDiffResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PolicyDiffResponse)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_PolicyDiff_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) PolicyHistory(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PolicyHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PolicyHistoryResponse)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_PolicyHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) PolicyRollback(ctx context.Context, in *PolicyRollbackRequest, opts ...grpc.CallOption) (*PolicyRollbackResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PolicyRollbackResponse)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_PolicyRollback_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) ComponentResume(ctx context.Context, in *ComponentResumeRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_ComponentResume_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ElasticAgentControlServer is the server API for ElasticAgentControl service.
 // All implementations must embed UnimplementedElasticAgentControlServer
 // for forward compatibility.
@@ -223,7 +283,10 @@ type ElasticAgentControlServer interface {
 	// skipping intermediate transitions.
 	StateWatch(*StateWatchRequest, grpc.ServerStreamingServer[StateResponse]) error
 	// Restart restarts the current running Elastic Agent.
-	Restart(context.Context, *Empty) (*RestartResponse, error)
+	//
+	// The restart is deferred until the next maintenance window, when maintenance windows are configured,
+	// unless the restart is forced.
+	Restart(context.Context, *RestartRequest) (*RestartResponse, error)
 	// Upgrade starts the upgrade process of Elastic Agent.
 	Upgrade(context.Context, *UpgradeRequest) (*UpgradeResponse, error)
 	// Gather diagnostic information for the running Elastic Agent.
@@ -241,6 +304,19 @@ type ElasticAgentControlServer interface {
 	Configure(context.Context, *ConfigureRequest) (*Empty, error)
 	// AvailableRollbacks returns any existing agent installs that can be used as a target for a manual rollback operation
 	AvailableRollbacks(context.Context, *Empty) (*AvailableRollbacksResponse, error)
+	// PolicyDiff returns the difference between the previous and the current component model
+	// computed from the policy, to identify which part of a policy change affected each component.
+	PolicyDiff(context.Context, *Empty) (*PolicyDiffResponse, error)
+	// PolicyHistory returns the last policies applied by Elastic Agent.
+	//
+	// Only available when Elastic Agent is managed by Fleet.
+	PolicyHistory(context.Context, *Empty) (*PolicyHistoryResponse, error)
+	// PolicyRollback re-applies a policy from the policy history locally, without communicating with Fleet.
+	//
+	// Only available when Elastic Agent is managed by Fleet.
+	PolicyRollback(context.Context, *PolicyRollbackRequest) (*PolicyRollbackResponse, error)
+	// ComponentResume releases a component from quarantine and starts it again.
+	ComponentResume(context.Context, *ComponentResumeRequest) (*Empty, error)
 	mustEmbedUnimplementedElasticAgentControlServer()
 }
 
@@ -260,7 +336,7 @@ func (UnimplementedElasticAgentControlServer) State(context.Context, *Empty) (*S
 func (UnimplementedElasticAgentControlServer) StateWatch(*StateWatchRequest, grpc.ServerStreamingServer[StateResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method StateWatch not implemented")
 }
-func (UnimplementedElasticAgentControlServer) Restart(context.Context, *Empty) (*RestartResponse, error) {
+func (UnimplementedElasticAgentControlServer) Restart(context.Context, *RestartRequest) (*RestartResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Restart not implemented")
 }
 func (UnimplementedElasticAgentControlServer) Upgrade(context.Context, *UpgradeRequest) (*UpgradeResponse, error) {
@@ -281,6 +357,18 @@ func (UnimplementedElasticAgentControlServer) Configure(context.Context, *Config
 func (UnimplementedElasticAgentControlServer) AvailableRollbacks(context.Context, *Empty) (*AvailableRollbacksResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AvailableRollbacks not implemented")
 }
+func (UnimplementedElasticAgentControlServer) PolicyDiff(context.Context, *Empty) (*PolicyDiffResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PolicyDiff not implemented")
+}
+func (UnimplementedElasticAgentControlServer) PolicyHistory(context.Context, *Empty) (*PolicyHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PolicyHistory not implemented")
+}
+func (UnimplementedElasticAgentControlServer) PolicyRollback(context.Context, *PolicyRollbackRequest) (*PolicyRollbackResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PolicyRollback not implemented")
+}
+func (UnimplementedElasticAgentControlServer) ComponentResume(context.Context, *ComponentResumeRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ComponentResume not implemented")
+}
 func (UnimplementedElasticAgentControlServer) mustEmbedUnimplementedElasticAgentControlServer() {}
 func (UnimplementedElasticAgentControlServer) testEmbeddedByValue()                             {}
 
@@ -350,7 +438,7 @@ func _ElasticAgentControl_StateWatch_Handler(srv interface{}, stream grpc.Server
 type ElasticAgentControl_StateWatchServer = grpc.ServerStreamingServer[StateResponse]
 
 func _ElasticAgentControl_Restart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+	in := new(RestartRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -362,7 +450,7 @@ func _ElasticAgentControl_Restart_Handler(srv interface{}, ctx context.Context,
 		FullMethod: ElasticAgentControl_Restart_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ElasticAgentControlServer).Restart(ctx, req.(*Empty))
+		return srv.(ElasticAgentControlServer).Restart(ctx, req.(*RestartRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -461,6 +549,78 @@ func _ElasticAgentControl_AvailableRollbacks_Handler(srv interface{}, ctx contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ElasticAgentControl_PolicyDiff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).PolicyDiff(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_PolicyDiff_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).PolicyDiff(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_PolicyHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).PolicyHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_PolicyHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).PolicyHistory(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_PolicyRollback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PolicyRollbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).PolicyRollback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_PolicyRollback_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).PolicyRollback(ctx, req.(*PolicyRollbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_ComponentResume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ComponentResumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).ComponentResume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_ComponentResume_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).ComponentResume(ctx, req.(*ComponentResumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ElasticAgentControl_ServiceDesc is the grpc.ServiceDesc for ElasticAgentControl service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -496,6 +656,22 @@ var ElasticAgentControl_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AvailableRollbacks",
 			Handler:    _ElasticAgentControl_AvailableRollbacks_Handler,
 		},
+		{
+			MethodName: "PolicyDiff",
+			Handler:    _ElasticAgentControl_PolicyDiff_Handler,
+		},
+		{
+			MethodName: "PolicyHistory",
+			Handler:    _ElasticAgentControl_PolicyHistory_Handler,
+		},
+		{
+			MethodName: "PolicyRollback",
+			Handler:    _ElasticAgentControl_PolicyRollback_Handler,
+		},
+		{
+			MethodName: "ComponentResume",
+			Handler:    _ElasticAgentControl_ComponentResume_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -0,0 +1,272 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/control"
+	"github.com/elastic/elastic-agent/pkg/control/v1/proto"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// role is the role of a caller of the control protocol, a role includes the calls allowed to the
+// roles before it.
+type role int
+
+const (
+	roleNone role = iota
+	roleReadOnly
+	roleAdmin
+)
+
+func (r role) String() string {
+	switch r {
+	case roleReadOnly:
+		return "read_only"
+	case roleAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// readOnlyMethods are the calls that only read the state of the Elastic Agent, every other call
+// requires the admin role.
+var readOnlyMethods = map[string]bool{
+	cproto.ElasticAgentControl_Version_FullMethodName:    true,
+	cproto.ElasticAgentControl_State_FullMethodName:      true,
+	cproto.ElasticAgentControl_StateWatch_FullMethodName: true,
+	proto.ElasticAgentControl_Version_FullMethodName:     true,
+	proto.ElasticAgentControl_Status_FullMethodName:      true,
+}
+
+// requiredRole returns the role required by the call.
+func requiredRole(fullMethod string) role {
+	if readOnlyMethods[fullMethod] {
+		return roleReadOnly
+	}
+	return roleAdmin
+}
+
+// accessRole selects the callers granted a role.
+type accessRole struct {
+	uids  []uint32
+	gids  []uint32
+	token []byte
+}
+
+func newAccessRole(cfg configuration.ControlRoleConfig) (accessRole, error) {
+	r := accessRole{uids: cfg.UIDs, gids: cfg.GIDs}
+	if cfg.TokenFile != "" {
+		data, err := os.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return r, fmt.Errorf("failed to read token file: %w", err)
+		}
+		r.token = bytes.TrimSpace(data)
+		if len(r.token) == 0 {
+			return r, fmt.Errorf("token file %s is empty", cfg.TokenFile)
+		}
+	}
+	return r, nil
+}
+
+// grants returns true when the caller identified by its peer or by its token is granted the role.
+func (r accessRole) grants(id *peerIdentity, token []byte) bool {
+	if len(r.token) > 0 && len(token) > 0 && subtle.ConstantTimeCompare(r.token, token) == 1 {
+		return true
+	}
+	if id == nil {
+		return false
+	}
+	if slices.Contains(r.uids, id.uid) {
+		return true
+	}
+	return slices.ContainsFunc(id.groups(), func(gid uint32) bool {
+		return slices.Contains(r.gids, gid)
+	})
+}
+
+// accessControl authorizes the calls of the control protocol with the role of the caller.
+type accessControl struct {
+	log      *logger.Logger
+	agentUID int
+	readOnly accessRole
+	admin    accessRole
+}
+
+// newAccessControl creates the access control of the configuration, nil when it is not enabled.
+func newAccessControl(log *logger.Logger, cfg *configuration.ControlAccessConfig) (*accessControl, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+	readOnly, err := newAccessRole(cfg.ReadOnly)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read_only role: %w", err)
+	}
+	admin, err := newAccessRole(cfg.Admin)
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin role: %w", err)
+	}
+	return &accessControl{
+		log:      log,
+		agentUID: os.Getuid(),
+		readOnly: readOnly,
+		admin:    admin,
+	}, nil
+}
+
+// role returns the role of the caller.
+func (a *accessControl) role(id *peerIdentity, token []byte) role {
+	switch {
+	case id != nil && (id.uid == 0 || int(id.uid) == a.agentUID):
+		// root and the user running the Elastic Agent
+		return roleAdmin
+	case a.admin.grants(id, token):
+		return roleAdmin
+	case a.readOnly.grants(id, token):
+		return roleReadOnly
+	default:
+		return roleNone
+	}
+}
+
+// authorize returns a PermissionDenied error when the role of the caller does not allow the call.
+func (a *accessControl) authorize(ctx context.Context, fullMethod string) error {
+	id := peerIdentityFromContext(ctx)
+	callerRole := a.role(id, tokenFromContext(ctx))
+	required := requiredRole(fullMethod)
+	if callerRole >= required {
+		return nil
+	}
+	a.log.Warnf("denied %s to %s with role %s, it requires role %s", fullMethod, id, callerRole, required)
+	return status.Errorf(codes.PermissionDenied, "%s requires the %s role", fullMethod, required)
+}
+
+func (a *accessControl) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := a.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *accessControl) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// tokenFromContext returns the token presented by the caller.
+func tokenFromContext(ctx context.Context) []byte {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	for _, v := range md.Get(control.TokenMetadataKey) {
+		if token, ok := strings.CutPrefix(v, "Bearer "); ok {
+			return []byte(token)
+		}
+	}
+	return nil
+}
+
+// peerIdentity is the user and group of the process at the other end of the Unix domain socket.
+type peerIdentity struct {
+	uid uint32
+	gid uint32
+}
+
+// groups returns the primary group of the peer and the supplementary groups of its user.
+func (id *peerIdentity) groups() []uint32 {
+	gids := []uint32{id.gid}
+	u, err := user.LookupId(strconv.FormatUint(uint64(id.uid), 10))
+	if err != nil {
+		return gids
+	}
+	groupIDs, err := u.GroupIds()
+	if err != nil {
+		return gids
+	}
+	for _, g := range groupIDs {
+		gid, err := strconv.ParseUint(g, 10, 32)
+		if err == nil && !slices.Contains(gids, uint32(gid)) {
+			gids = append(gids, uint32(gid))
+		}
+	}
+	return gids
+}
+
+func (id *peerIdentity) String() string {
+	if id == nil {
+		return "unidentified caller"
+	}
+	return fmt.Sprintf("caller uid=%d gid=%d", id.uid, id.gid)
+}
+
+// peerIdentityFromContext returns the identity of the peer of the call, nil when it is unknown.
+func peerIdentityFromContext(ctx context.Context) *peerIdentity {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(peerAuthInfo)
+	if !ok {
+		return nil
+	}
+	return info.id
+}
+
+// peerAuthInfo records the identity of the peer of a connection.
+type peerAuthInfo struct {
+	credentials.AuthInfo
+	id *peerIdentity
+}
+
+// peerCredentials are transport credentials that keep the connections untouched but record the
+// identity of the peer of the connections over a Unix domain socket.
+type peerCredentials struct {
+	credentials.TransportCredentials
+	log *logger.Logger
+}
+
+// ServerHandshake records the identity of the peer of the connection.
+func (c peerCredentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	conn, info, err := c.TransportCredentials.ServerHandshake(rawConn)
+	if err != nil {
+		return nil, nil, err
+	}
+	authInfo := peerAuthInfo{AuthInfo: info}
+	if unixConn, ok := rawConn.(*net.UnixConn); ok {
+		authInfo.id, err = unixPeerIdentity(unixConn)
+		if err != nil {
+			c.log.Warnf("failed to get the credentials of the control socket peer: %s", err)
+		}
+	}
+	return conn, authInfo, nil
+}
+
+// Clone clones the credentials.
+func (c peerCredentials) Clone() credentials.TransportCredentials {
+	return peerCredentials{TransportCredentials: c.TransportCredentials.Clone(), log: c.log}
+}
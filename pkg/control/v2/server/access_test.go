@@ -0,0 +1,216 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/control"
+	"github.com/elastic/elastic-agent/pkg/control/v1/proto"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+// uids and gids of users and groups that do not exist, the groups are limited to the primary group.
+const (
+	readOnlyUID = 4000001
+	adminUID    = 4000002
+	otherUID    = 4000003
+	readOnlyGID = 4000101
+	otherGID    = 4000102
+)
+
+func newTestAccessControl(t *testing.T) *accessControl {
+	dir := t.TempDir()
+	readOnlyTokenFile := filepath.Join(dir, "read-only.token")
+	require.NoError(t, os.WriteFile(readOnlyTokenFile, []byte("read-only-token\n"), 0600))
+	adminTokenFile := filepath.Join(dir, "admin.token")
+	require.NoError(t, os.WriteFile(adminTokenFile, []byte("admin-token"), 0600))
+
+	log, _ := loggertest.New(t.Name())
+	a, err := newAccessControl(log, &configuration.ControlAccessConfig{
+		Enabled: true,
+		ReadOnly: configuration.ControlRoleConfig{
+			UIDs:      []uint32{readOnlyUID},
+			GIDs:      []uint32{readOnlyGID},
+			TokenFile: readOnlyTokenFile,
+		},
+		Admin: configuration.ControlRoleConfig{
+			UIDs:      []uint32{adminUID},
+			TokenFile: adminTokenFile,
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, a)
+	// the tests run as any user, the agent user is set to one that is never a caller
+	a.agentUID = 4000000
+	return a
+}
+
+func TestRequiredRole(t *testing.T) {
+	for _, method := range []string{
+		cproto.ElasticAgentControl_Version_FullMethodName,
+		cproto.ElasticAgentControl_State_FullMethodName,
+		cproto.ElasticAgentControl_StateWatch_FullMethodName,
+		proto.ElasticAgentControl_Version_FullMethodName,
+		proto.ElasticAgentControl_Status_FullMethodName,
+	} {
+		assert.Equal(t, roleReadOnly, requiredRole(method), method)
+	}
+	for _, method := range []string{
+		cproto.ElasticAgentControl_Restart_FullMethodName,
+		cproto.ElasticAgentControl_Upgrade_FullMethodName,
+		cproto.ElasticAgentControl_Configure_FullMethodName,
+		cproto.ElasticAgentControl_DiagnosticAgent_FullMethodName,
+		cproto.ElasticAgentControl_DiagnosticUnits_FullMethodName,
+		cproto.ElasticAgentControl_DiagnosticComponents_FullMethodName,
+		proto.ElasticAgentControl_Restart_FullMethodName,
+		proto.ElasticAgentControl_Upgrade_FullMethodName,
+		"/cproto.ElasticAgentControl/Unknown",
+	} {
+		assert.Equal(t, roleAdmin, requiredRole(method), method)
+	}
+}
+
+func TestAccessControlRole(t *testing.T) {
+	a := newTestAccessControl(t)
+
+	tests := map[string]struct {
+		id       *peerIdentity
+		token    string
+		expected role
+	}{
+		"root":                     {id: &peerIdentity{uid: 0, gid: otherGID}, expected: roleAdmin},
+		"agent user":               {id: &peerIdentity{uid: 4000000, gid: otherGID}, expected: roleAdmin},
+		"admin uid":                {id: &peerIdentity{uid: adminUID, gid: otherGID}, expected: roleAdmin},
+		"read only uid":            {id: &peerIdentity{uid: readOnlyUID, gid: otherGID}, expected: roleReadOnly},
+		"read only gid":            {id: &peerIdentity{uid: otherUID, gid: readOnlyGID}, expected: roleReadOnly},
+		"other user":               {id: &peerIdentity{uid: otherUID, gid: otherGID}, expected: roleNone},
+		"admin token":              {token: "admin-token", expected: roleAdmin},
+		"read only token":          {token: "read-only-token", expected: roleReadOnly},
+		"wrong token":              {token: "admin", expected: roleNone},
+		"unidentified caller":      {expected: roleNone},
+		"admin token of read only": {id: &peerIdentity{uid: readOnlyUID, gid: otherGID}, token: "admin-token", expected: roleAdmin},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var token []byte
+			if test.token != "" {
+				token = []byte(test.token)
+			}
+			assert.Equal(t, test.expected, a.role(test.id, token))
+		})
+	}
+}
+
+func TestAccessControlInterceptors(t *testing.T) {
+	a := newTestAccessControl(t)
+
+	callerContext := func(id *peerIdentity, token string) context.Context {
+		ctx := peer.NewContext(t.Context(), &peer.Peer{AuthInfo: peerAuthInfo{id: id}})
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(control.TokenMetadataKey, "Bearer "+token))
+		}
+		return ctx
+	}
+	unary := func(ctx context.Context, method string) error {
+		_, err := a.unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+			return nil, nil
+		})
+		return err
+	}
+
+	readOnly := callerContext(&peerIdentity{uid: readOnlyUID, gid: otherGID}, "")
+	assert.NoError(t, unary(readOnly, cproto.ElasticAgentControl_State_FullMethodName))
+	err := unary(readOnly, cproto.ElasticAgentControl_Restart_FullMethodName)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	admin := callerContext(&peerIdentity{uid: otherUID, gid: otherGID}, "admin-token")
+	assert.NoError(t, unary(admin, cproto.ElasticAgentControl_Restart_FullMethodName))
+
+	other := callerContext(nil, "")
+	err = unary(other, cproto.ElasticAgentControl_Version_FullMethodName)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	stream := func(ctx context.Context, method string) error {
+		return a.streamInterceptor(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: method}, func(srv any, ss grpc.ServerStream) error {
+			return nil
+		})
+	}
+	assert.NoError(t, stream(readOnly, cproto.ElasticAgentControl_StateWatch_FullMethodName))
+	err = stream(other, cproto.ElasticAgentControl_StateWatch_FullMethodName)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestNewAccessControl(t *testing.T) {
+	log, _ := loggertest.New(t.Name())
+
+	a, err := newAccessControl(log, configuration.DefaultControlConfig().Access)
+	require.NoError(t, err)
+	assert.Nil(t, a, "access control must be disabled by default")
+
+	_, err = newAccessControl(log, &configuration.ControlAccessConfig{
+		Enabled: true,
+		Admin:   configuration.ControlRoleConfig{TokenFile: filepath.Join(t.TempDir(), "missing.token")},
+	})
+	assert.ErrorContains(t, err, "invalid admin role: failed to read token file")
+
+	emptyTokenFile := filepath.Join(t.TempDir(), "empty.token")
+	require.NoError(t, os.WriteFile(emptyTokenFile, []byte("\n"), 0600))
+	_, err = newAccessControl(log, &configuration.ControlAccessConfig{
+		Enabled:  true,
+		ReadOnly: configuration.ControlRoleConfig{TokenFile: emptyTokenFile},
+	})
+	assert.ErrorContains(t, err, "is empty")
+}
+
+func TestUnixPeerIdentity(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skipf("peer credentials are not supported on %s", runtime.GOOS)
+	}
+
+	// unix socket paths are limited in length, t.TempDir can be too long on darwin
+	dir, err := os.MkdirTemp("", "peercred")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	lis, err := net.Listen("unix", filepath.Join(dir, "s.sock"))
+	require.NoError(t, err)
+	defer lis.Close()
+
+	client, err := net.Dial("unix", lis.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	conn, err := lis.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	id, err := unixPeerIdentity(conn.(*net.UnixConn))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(os.Getuid()), id.uid) //nolint:gosec // G115: uid of the test process
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
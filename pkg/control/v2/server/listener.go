@@ -6,6 +6,9 @@ package server
 
 import (
 	"net"
+	"os"
+	"runtime"
+	"strings"
 
 	"github.com/elastic/elastic-agent/pkg/control"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -19,3 +22,13 @@ func createListener(log *logger.Logger) (net.Listener, error) {
 func cleanupListener(log *logger.Logger) {
 	ipc.CleanupListener(log, control.Address())
 }
+
+// openListener lets every local user connect to the control socket, the access control then
+// authorizes each call with the role of the caller.
+func openListener() error {
+	address := control.Address()
+	if runtime.GOOS == "windows" || !ipc.IsLocal(address) {
+		return nil
+	}
+	return os.Chmod(strings.TrimPrefix(address, "unix://"), 0777) //nolint:gosec // G302: calls are authorized by role
+}
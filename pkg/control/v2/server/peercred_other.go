@@ -0,0 +1,18 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux && !darwin
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// unixPeerIdentity is not supported, the callers are only identified by their token.
+func unixPeerIdentity(_ *net.UnixConn) (*peerIdentity, error) {
+	return nil, fmt.Errorf("peer credentials are not supported on %s", runtime.GOOS)
+}
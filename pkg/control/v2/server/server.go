@@ -26,13 +26,16 @@ import (
 	"go.elastic.co/apm/module/apmgrpc/v2"
 	"go.elastic.co/apm/v2"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
 	"github.com/elastic/elastic-agent/internal/pkg/otel"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/component"
@@ -69,12 +72,14 @@ type Server struct {
 	diagHooks  diagnostics.Hooks
 	grpcConfig *configuration.GRPCConfig
 
+	controlConfig *configuration.ControlConfig
+
 	tmSetter       TestModeConfigSetter
 	rollbackSource ttl.ReadOnlySource
 }
 
 // New creates a new control protocol server.
-func New(log *logger.Logger, agentInfo info.Agent, coord *coordinator.Coordinator, tracer *apm.Tracer, diagHooks diagnostics.Hooks, grpcConfig *configuration.GRPCConfig, rollbackSource ttl.ReadOnlySource) *Server {
+func New(log *logger.Logger, agentInfo info.Agent, coord *coordinator.Coordinator, tracer *apm.Tracer, diagHooks diagnostics.Hooks, grpcConfig *configuration.GRPCConfig, controlConfig *configuration.ControlConfig, rollbackSource ttl.ReadOnlySource) *Server {
 	return &Server{
 		logger:         log,
 		agentInfo:      agentInfo,
@@ -83,6 +88,7 @@ func New(log *logger.Logger, agentInfo info.Agent, coord *coordinator.Coordinato
 		tracer:         tracer,
 		diagHooks:      diagHooks,
 		grpcConfig:     grpcConfig,
+		controlConfig:  controlConfig,
 		rollbackSource: rollbackSource,
 	}
 }
@@ -99,19 +105,45 @@ func (s *Server) Start() error {
 		return nil
 	}
 
+	var access *accessControl
+	if s.controlConfig != nil {
+		var err error
+		access, err = newAccessControl(s.logger, s.controlConfig.Access)
+		if err != nil {
+			s.logger.Errorf("unable to setup control protocol access control: %s", err)
+			return err
+		}
+	}
+
 	lis, err := createListener(s.logger)
 	if err != nil {
 		s.logger.Errorf("unable to create listener: %s", err)
 		return err
 	}
 	s.logger.With("address", control.Address()).Infof("GRPC control socket listening at %s", control.Address())
-	s.listener = lis
+
+	opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(s.grpcConfig.MaxMsgSize)}
+	var unaryInterceptors []grpc.UnaryServerInterceptor
 	if s.tracer != nil {
 		apmInterceptor := apmgrpc.NewUnaryServerInterceptor(apmgrpc.WithRecovery(), apmgrpc.WithTracer(s.tracer))
-		s.server = grpc.NewServer(grpc.UnaryInterceptor(apmInterceptor), grpc.MaxRecvMsgSize(s.grpcConfig.MaxMsgSize))
-	} else {
-		s.server = grpc.NewServer(grpc.MaxRecvMsgSize(s.grpcConfig.MaxMsgSize))
+		unaryInterceptors = append(unaryInterceptors, apmInterceptor)
+	}
+	if access != nil {
+		if err := openListener(); err != nil {
+			lis.Close()
+			s.logger.Errorf("unable to open control socket to the other users: %s", err)
+			return err
+		}
+		s.logger.Info("Control protocol access control is enabled")
+		unaryInterceptors = append(unaryInterceptors, access.unaryInterceptor)
+		opts = append(opts,
+			grpc.Creds(peerCredentials{TransportCredentials: insecure.NewCredentials(), log: s.logger}),
+			grpc.StreamInterceptor(access.streamInterceptor),
+		)
 	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(unaryInterceptors...))
+	s.listener = lis
+	s.server = grpc.NewServer(opts...)
 	cproto.RegisterElasticAgentControlServer(s.server, s)
 
 	v1Wrapper := v1server.New(s.logger, s, s.tracer)
@@ -180,12 +212,22 @@ func (s *Server) StateWatch(req *cproto.StateWatchRequest, srv cproto.ElasticAge
 	}
 }
 
-// Restart performs re-exec.
-func (s *Server) Restart(_ context.Context, _ *cproto.Empty) (*cproto.RestartResponse, error) {
-	s.coord.ReExec(nil)
-	return &cproto.RestartResponse{
+// Restart performs re-exec, deferred until the next maintenance window unless forced.
+func (s *Server) Restart(_ context.Context, request *cproto.RestartRequest) (*cproto.RestartResponse, error) {
+	deferredUntil, err := s.coord.Restart(request.GetForce())
+	if err != nil {
+		return &cproto.RestartResponse{
+			Status: cproto.ActionStatus_FAILURE,
+			Error:  err.Error(),
+		}, nil
+	}
+	res := &cproto.RestartResponse{
 		Status: cproto.ActionStatus_SUCCESS,
-	}, nil
+	}
+	if !deferredUntil.IsZero() {
+		res.DeferredUntil = timestamppb.New(deferredUntil)
+	}
+	return res, nil
 }
 
 // Upgrade performs the upgrade operation.
@@ -397,6 +439,166 @@ func (s *Server) AvailableRollbacks(context.Context, *cproto.Empty) (*cproto.Ava
 	}, nil
 }
 
+// PolicyDiff returns the difference between the previous and the current component model.
+func (s *Server) PolicyDiff(_ context.Context, _ *cproto.Empty) (*cproto.PolicyDiffResponse, error) {
+	diff := s.coord.ComponentModelDiff()
+	if diff == nil {
+		return &cproto.PolicyDiffResponse{Available: false}, nil
+	}
+	return componentModelDiffToProto(diff), nil
+}
+
+func componentModelDiffToProto(diff *coordinator.ComponentModelDiff) *cproto.PolicyDiffResponse {
+	changed := make([]*cproto.ComponentDiff, 0, len(diff.ComponentsChanged))
+	for _, comp := range diff.ComponentsChanged {
+		units := make([]*cproto.UnitConfigDiff, 0, len(comp.UnitsChanged))
+		for _, unit := range comp.UnitsChanged {
+			units = append(units, &cproto.UnitConfigDiff{
+				UnitId:       unit.ID,
+				UnitType:     cproto.UnitType(unit.Type),
+				PreviousHash: unit.PreviousHash,
+				CurrentHash:  unit.CurrentHash,
+				ChangedKeys:  unit.ChangedKeys,
+			})
+		}
+		changed = append(changed, &cproto.ComponentDiff{
+			ComponentId:  comp.ID,
+			UnitsAdded:   comp.UnitsAdded,
+			UnitsRemoved: comp.UnitsRemoved,
+			UnitsChanged: units,
+		})
+	}
+	return &cproto.PolicyDiffResponse{
+		Available:         true,
+		ComputedAt:        timestamppb.New(diff.Timestamp),
+		ComponentsAdded:   diff.ComponentsAdded,
+		ComponentsRemoved: diff.ComponentsRemoved,
+		ComponentsChanged: changed,
+	}
+}
+
+// PolicyHistory returns the last policies applied by the Elastic Agent.
+func (s *Server) PolicyHistory(_ context.Context, _ *cproto.Empty) (*cproto.PolicyHistoryResponse, error) {
+	history, err := s.coord.PolicyHistory()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*cproto.PolicyHistoryEntry, 0, len(history))
+	for _, entry := range history {
+		entries = append(entries, &cproto.PolicyHistoryEntry{
+			ActionId:  entry.ActionID,
+			PolicyId:  entry.PolicyID,
+			Revision:  entry.Revision,
+			AppliedAt: timestamppb.New(entry.AppliedAt),
+			Acked:     entry.Acked,
+			Rollback:  entry.Rollback,
+		})
+	}
+	return &cproto.PolicyHistoryResponse{Entries: entries}, nil
+}
+
+// PolicyRollback re-applies a policy from the policy history.
+func (s *Server) PolicyRollback(ctx context.Context, req *cproto.PolicyRollbackRequest) (*cproto.PolicyRollbackResponse, error) {
+	rollback, err := s.coord.RollbackPolicy(ctx, req.PolicyId, req.Revision)
+	if err != nil {
+		return nil, err
+	}
+	return &cproto.PolicyRollbackResponse{Rollback: policyRollbackToProto(rollback)}, nil
+}
+
+// ComponentResume releases a quarantined component and starts it again.
+func (s *Server) ComponentResume(_ context.Context, req *cproto.ComponentResumeRequest) (*cproto.Empty, error) {
+	if err := s.coord.ResumeComponent(req.ComponentId); err != nil {
+		return nil, err
+	}
+	return &cproto.Empty{}, nil
+}
+
+func quarantineToProto(quarantine *runtime.ComponentQuarantine) *cproto.ComponentQuarantine {
+	if quarantine == nil {
+		return nil
+	}
+	crashes := make([]*cproto.ComponentCrash, 0, len(quarantine.Crashes))
+	for _, crash := range quarantine.Crashes {
+		crashes = append(crashes, &cproto.ComponentCrash{
+			Time:     timestamppb.New(crash.Time),
+			Pid:      int64(crash.PID),
+			ExitCode: int32(crash.ExitCode), //nolint:gosec // exit codes fit in an int32
+			Logs:     crash.Logs,
+		})
+	}
+	return &cproto.ComponentQuarantine{
+		Since:   timestamppb.New(quarantine.Since),
+		Crashes: crashes,
+	}
+}
+
+func policyRollbackToProto(rollback *coordinator.PolicyRollback) *cproto.PolicyRollback {
+	if rollback == nil {
+		return nil
+	}
+	return &cproto.PolicyRollback{
+		ActionId:     rollback.ActionID,
+		PolicyId:     rollback.PolicyID,
+		Revision:     rollback.Revision,
+		FromRevision: rollback.FromRevision,
+		RolledBackAt: timestamppb.New(rollback.RolledBackAt),
+	}
+}
+
+func maintenanceToProto(maintenance *coordinator.MaintenanceState) *cproto.MaintenanceState {
+	if maintenance == nil {
+		return nil
+	}
+	return &cproto.MaintenanceState{
+		DeferredUntil: timestamppb.New(maintenance.DeferredUntil),
+		Restart:       maintenance.Restart,
+		PolicyChange:  maintenance.PolicyChange,
+	}
+}
+
+func pendingAcksToProto(pendingAcks []store.PendingAck) []*cproto.PendingAck {
+	if len(pendingAcks) == 0 {
+		return nil
+	}
+	res := make([]*cproto.PendingAck, 0, len(pendingAcks))
+	for _, ack := range pendingAcks {
+		pa := &cproto.PendingAck{
+			ActionId:   ack.ActionID,
+			ActionType: ack.ActionType,
+			Attempts:   int32(ack.Attempts), //nolint:gosec // attempts fit in an int32
+			LastError:  ack.LastError,
+			EnqueuedAt: timestamppb.New(ack.EnqueuedAt),
+		}
+		if !ack.LastAttemptAt.IsZero() {
+			pa.LastAttemptAt = timestamppb.New(ack.LastAttemptAt)
+		}
+		res = append(res, pa)
+	}
+	return res
+}
+
+func diagnosticsUploadsToProto(uploads []uploader.Progress) []*cproto.DiagnosticsUpload {
+	if len(uploads) == 0 {
+		return nil
+	}
+	res := make([]*cproto.DiagnosticsUpload, 0, len(uploads))
+	for _, upload := range uploads {
+		res = append(res, &cproto.DiagnosticsUpload{
+			ActionId:   upload.ActionID,
+			UploadId:   upload.UploadID,
+			ChunksSent: int32(upload.ChunksSent), //nolint:gosec // chunks fit in an int32
+			Chunks:     int32(upload.Chunks),     //nolint:gosec // chunks fit in an int32
+			BytesSent:  upload.BytesSent,
+			Size:       upload.Size,
+			Attempt:    int32(upload.Attempt), //nolint:gosec // attempts fit in an int32
+			LastError:  upload.LastError,
+			UpdatedAt:  timestamppb.New(upload.UpdatedAt),
+		})
+	}
+	return res
+}
+
 func stateToProto(state *coordinator.State, agentInfo info.Agent) (*cproto.StateResponse, error) {
 	var err error
 	components := make([]*cproto.ComponentState, 0, len(state.Components))
@@ -418,16 +620,21 @@ func stateToProto(state *coordinator.State, agentInfo info.Agent) (*cproto.State
 				Payload:  string(payload),
 			})
 		}
+		compState := cproto.State(comp.State.State)
+		if comp.State.Quarantine != nil {
+			compState = cproto.State_QUARANTINED
+		}
 		components = append(components, &cproto.ComponentState{
 			Id:      comp.Component.ID,
 			Name:    comp.Component.Type(),
-			State:   cproto.State(comp.State.State),
+			State:   compState,
 			Message: comp.State.Message,
 			Units:   units,
 			VersionInfo: &cproto.ComponentVersionInfo{
 				Name: comp.State.VersionInfo.Name,
 				Meta: comp.State.VersionInfo.Meta,
 			},
+			Quarantine: quarantineToProto(comp.State.Quarantine),
 		})
 	}
 
@@ -468,13 +675,17 @@ func stateToProto(state *coordinator.State, agentInfo info.Agent) (*cproto.State
 			Unprivileged: agentInfo.Unprivileged(),
 			IsManaged:    !agentInfo.IsStandalone(),
 		},
-		State:          state.State,
-		Message:        state.Message,
-		FleetState:     state.FleetState,
-		FleetMessage:   state.FleetMessage,
-		Components:     components,
-		UpgradeDetails: upgradeDetails,
-		Collector:      collectorToProto(state.Collector),
+		State:              state.State,
+		Message:            state.Message,
+		FleetState:         state.FleetState,
+		FleetMessage:       state.FleetMessage,
+		Components:         components,
+		UpgradeDetails:     upgradeDetails,
+		Collector:          collectorToProto(state.Collector),
+		PolicyRollback:     policyRollbackToProto(state.PolicyRollback),
+		Maintenance:        maintenanceToProto(state.Maintenance),
+		PendingAcks:        pendingAcksToProto(state.PendingAcks),
+		DiagnosticsUploads: diagnosticsUploadsToProto(state.DiagnosticsUploads),
 	}, nil
 }
 
@@ -4,7 +4,11 @@
 
 package fleetapi
 
-import api "github.com/elastic/fleet-server/pkg/api"
+import (
+	"net/http"
+
+	api "github.com/elastic/fleet-server/pkg/api"
+)
 
 // AckRequest is re-exported from fleet-server's pkg/api for callers that don't want
 // to import fleet-server directly.
@@ -17,3 +21,13 @@ type AckResponseItem = api.AckResponseItem
 // AckResponse is re-exported from fleet-server's pkg/api for callers that don't want
 // to import fleet-server directly.
 type AckResponse = api.AckResponse
+
+// AckFailed returns true when the ack of the action at index i of a batch
+// failed according to resp. An action without a response item is considered
+// failed, so is every action when resp is nil.
+func AckFailed(resp *AckResponse, i int) bool {
+	if resp == nil || i >= len(resp.Items) {
+		return true
+	}
+	return resp.Items[i].Status >= http.StatusBadRequest
+}
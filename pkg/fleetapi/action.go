@@ -232,6 +232,9 @@ type ActionPolicyChange struct {
 
 type ActionPolicyChangeData struct {
 	Policy map[string]interface{} `json:"policy" yaml:"policy,omitempty"`
+	// PolicyDelta is set instead of Policy when Fleet sends the changes
+	// relative to the policy the agent runs, see CheckinPolicyDelta.
+	PolicyDelta *PolicyDelta `json:"policy_delta,omitempty" yaml:"policy_delta,omitempty"`
 }
 
 func (a *ActionPolicyChange) String() string {
@@ -593,6 +596,11 @@ type ActionDiagnostics struct {
 type ActionDiagnosticsData struct {
 	AdditionalMetrics []string `json:"additional_metrics"`
 	ExcludeEventsLog  bool     `json:"exclude_events_log"`
+	// Profile is the name of the diagnostics profile selecting the content of
+	// the bundle, all the diagnostics are collected when empty.
+	Profile string `json:"profile,omitempty"`
+	// MaxSize is the size budget of the bundle in bytes, 0 if unlimited.
+	MaxSize int64 `json:"max_size,omitempty"`
 }
 
 // ID returns the ID of the action.
@@ -88,17 +88,28 @@ type CheckinUpgrade struct {
 	Rollbacks []CheckinRollback `json:"rollbacks,omitempty"`
 }
 
+// CheckinPolicyDelta negotiates the delivery of policies as deltas, its
+// presence tells Fleet the agent can rebuild a policy from a PolicyDelta.
+type CheckinPolicyDelta struct {
+	// PolicyHash is the hash of the policy the agent runs, the base of the deltas.
+	PolicyHash string `json:"policy_hash,omitempty"`
+	// FullPolicyRequired asks Fleet for a complete policy, it is set after a
+	// delta could not be applied.
+	FullPolicyRequired bool `json:"full_policy_required,omitempty"`
+}
+
 // CheckinRequest consists of multiple events reported to fleet ui.
 type CheckinRequest struct {
-	Status            string             `json:"status"`
-	AckToken          string             `json:"ack_token,omitempty"`
-	Metadata          *ecsmeta.ECSMeta   `json:"local_metadata,omitempty"`
-	Message           string             `json:"message"`    // V2 Agent message
-	Components        []CheckinComponent `json:"components"` // V2 Agent components
-	UpgradeDetails    *details.Details   `json:"upgrade_details,omitempty"`
-	AgentPolicyID     string             `json:"agent_policy_id,omitempty"`
-	PolicyRevisionIDX int64              `json:"policy_revision_idx,omitempty"`
-	Upgrade           CheckinUpgrade     `json:"upgrade,omitempty"`
+	Status            string              `json:"status"`
+	AckToken          string              `json:"ack_token,omitempty"`
+	Metadata          *ecsmeta.ECSMeta    `json:"local_metadata,omitempty"`
+	Message           string              `json:"message"`    // V2 Agent message
+	Components        []CheckinComponent  `json:"components"` // V2 Agent components
+	UpgradeDetails    *details.Details    `json:"upgrade_details,omitempty"`
+	AgentPolicyID     string              `json:"agent_policy_id,omitempty"`
+	PolicyRevisionIDX int64               `json:"policy_revision_idx,omitempty"`
+	Upgrade           CheckinUpgrade      `json:"upgrade,omitempty"`
+	PolicyDelta       *CheckinPolicyDelta `json:"policy_delta,omitempty"`
 }
 
 // Validate validates the enrollment request before sending it to the API.
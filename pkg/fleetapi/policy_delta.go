@@ -0,0 +1,192 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	policyInputsKey  = "inputs"
+	policyOutputsKey = "outputs"
+	policyInputIDKey = "id"
+)
+
+// ErrPolicyHashMismatch is returned when the policy rebuilt from a delta does
+// not match the hash computed by Fleet.
+var ErrPolicyHashMismatch = errors.New("policy hash mismatch")
+
+// PolicyDelta is a policy sent by Fleet as the changes relative to the policy
+// revision the agent runs. Inputs are matched by ID and outputs by name, the
+// other top-level keys of the policy are replaced as a whole.
+type PolicyDelta struct {
+	// BaseRevisionIDX is the revision of the policy the changes apply to.
+	BaseRevisionIDX int64 `json:"base_revision_idx" yaml:"base_revision_idx"`
+	// Hash is the hash of the complete policy once the changes are applied,
+	// see PolicyHash.
+	Hash string `json:"hash" yaml:"hash"`
+	// Set are the top-level keys, other than inputs and outputs, added or
+	// replaced in the policy.
+	Set map[string]interface{} `json:"set,omitempty" yaml:"set,omitempty"`
+	// Unset are the top-level keys, other than inputs and outputs, removed
+	// from the policy.
+	Unset []string `json:"unset,omitempty" yaml:"unset,omitempty"`
+	// Inputs are the inputs added or replaced. A replaced input keeps its
+	// position, added inputs are appended in order.
+	Inputs []map[string]interface{} `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	// RemovedInputs are the IDs of the removed inputs.
+	RemovedInputs []string `json:"removed_inputs,omitempty" yaml:"removed_inputs,omitempty"`
+	// Outputs are the outputs added or replaced, by name.
+	Outputs map[string]interface{} `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+	// RemovedOutputs are the names of the removed outputs.
+	RemovedOutputs []string `json:"removed_outputs,omitempty" yaml:"removed_outputs,omitempty"`
+}
+
+// Apply returns the complete policy obtained by applying the changes on the
+// base policy and verifies its hash, ErrPolicyHashMismatch is returned when
+// the hash does not match. The base policy is not modified.
+func (d *PolicyDelta) Apply(base map[string]interface{}) (map[string]interface{}, error) {
+	policy := make(map[string]interface{}, len(base)+len(d.Set))
+	for k, v := range base {
+		policy[k] = v
+	}
+
+	for _, k := range d.Unset {
+		if k == policyInputsKey || k == policyOutputsKey {
+			return nil, fmt.Errorf("policy delta cannot unset %q", k)
+		}
+		delete(policy, k)
+	}
+	for k, v := range d.Set {
+		if k == policyInputsKey || k == policyOutputsKey {
+			return nil, fmt.Errorf("policy delta cannot set %q", k)
+		}
+		policy[k] = v
+	}
+
+	if len(d.Inputs) > 0 || len(d.RemovedInputs) > 0 {
+		inputs, err := d.applyInputs(base[policyInputsKey])
+		if err != nil {
+			return nil, err
+		}
+		policy[policyInputsKey] = inputs
+	}
+	if len(d.Outputs) > 0 || len(d.RemovedOutputs) > 0 {
+		outputs, err := d.applyOutputs(base[policyOutputsKey])
+		if err != nil {
+			return nil, err
+		}
+		policy[policyOutputsKey] = outputs
+	}
+
+	hash, err := PolicyHash(policy)
+	if err != nil {
+		return nil, err
+	}
+	if hash != d.Hash {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrPolicyHashMismatch, d.Hash, hash)
+	}
+	return policy, nil
+}
+
+func (d *PolicyDelta) applyInputs(base interface{}) ([]interface{}, error) {
+	var baseInputs []interface{}
+	switch v := base.(type) {
+	case nil:
+	case []interface{}:
+		baseInputs = v
+	case []map[string]interface{}:
+		baseInputs = make([]interface{}, 0, len(v))
+		for _, input := range v {
+			baseInputs = append(baseInputs, input)
+		}
+	default:
+		return nil, fmt.Errorf("policy inputs must be a list, got %T", base)
+	}
+
+	changed := make(map[string]map[string]interface{}, len(d.Inputs))
+	for _, input := range d.Inputs {
+		id, ok := input[policyInputIDKey].(string)
+		if !ok || id == "" {
+			return nil, errors.New("policy delta input has no id")
+		}
+		changed[id] = input
+	}
+
+	inputs := make([]interface{}, 0, len(baseInputs)+len(d.Inputs))
+	for _, input := range baseInputs {
+		id := inputID(input)
+		if id != "" && slices.Contains(d.RemovedInputs, id) {
+			continue
+		}
+		if replacement, ok := changed[id]; ok && id != "" {
+			inputs = append(inputs, replacement)
+			delete(changed, id)
+			continue
+		}
+		inputs = append(inputs, input)
+	}
+	for _, input := range d.Inputs {
+		if _, added := changed[inputID(input)]; added {
+			inputs = append(inputs, input)
+		}
+	}
+	return inputs, nil
+}
+
+func (d *PolicyDelta) applyOutputs(base interface{}) (map[string]interface{}, error) {
+	baseOutputs, ok := base.(map[string]interface{})
+	if !ok && base != nil {
+		return nil, fmt.Errorf("policy outputs must be a map, got %T", base)
+	}
+
+	outputs := make(map[string]interface{}, len(baseOutputs)+len(d.Outputs))
+	for name, output := range baseOutputs {
+		outputs[name] = output
+	}
+	for _, name := range d.RemovedOutputs {
+		delete(outputs, name)
+	}
+	for name, output := range d.Outputs {
+		outputs[name] = output
+	}
+	return outputs, nil
+}
+
+func inputID(input interface{}) string {
+	m, ok := input.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	id, _ := m[policyInputIDKey].(string)
+	return id
+}
+
+// PolicyHash returns the hash of the policy used to verify a policy rebuilt
+// from a delta: the lowercase hex encoded SHA-256 of its canonical JSON form.
+//
+// The canonical form is the compact JSON encoding of the policy, without
+// whitespace nor trailing newline, where:
+//   - the keys of every object are sorted in byte order
+//   - strings are UTF-8, only '"', '\\', the control characters, U+2028 and
+//     U+2029 are escaped; '<', '>' and '&' are not escaped
+//   - numbers are encoded in the shortest form that round trips as a float64,
+//     without exponent for integers up to 1e21, so 1 and 1.0 are both 1
+func PolicyHash(policy map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(policy); err != nil {
+		return "", fmt.Errorf("failed to encode policy: %w", err)
+	}
+	sum := sha256.Sum256(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return hex.EncodeToString(sum[:]), nil
+}
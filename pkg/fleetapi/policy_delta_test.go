@@ -0,0 +1,154 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodePolicy(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var policy map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &policy))
+	return policy
+}
+
+func TestPolicyDeltaApply(t *testing.T) {
+	base := `{
+		"id": "policy-1",
+		"revision": 1,
+		"agent": {"logging": {"level": "info"}},
+		"fleet": {"hosts": ["https://fleet:8220"]},
+		"inputs": [
+			{"id": "system-1", "type": "system/metrics"},
+			{"id": "logs-1", "type": "logfile"},
+			{"id": "logs-2", "type": "filestream"}
+		],
+		"outputs": {
+			"default": {"type": "elasticsearch"},
+			"remote": {"type": "logstash"}
+		}
+	}`
+
+	tests := []struct {
+		name     string
+		delta    PolicyDelta
+		expected string
+		err      string
+	}{
+		{
+			name: "inputs and outputs changes",
+			delta: PolicyDelta{
+				Set: map[string]interface{}{"revision": 2},
+				Inputs: []map[string]interface{}{
+					{"id": "logs-3", "type": "filestream"},
+					{"id": "logs-1", "type": "filestream"},
+				},
+				RemovedInputs:  []string{"system-1"},
+				Outputs:        map[string]interface{}{"kafka": map[string]interface{}{"type": "kafka"}},
+				RemovedOutputs: []string{"remote"},
+			},
+			expected: `{
+				"id": "policy-1",
+				"revision": 2,
+				"agent": {"logging": {"level": "info"}},
+				"fleet": {"hosts": ["https://fleet:8220"]},
+				"inputs": [
+					{"id": "logs-1", "type": "filestream"},
+					{"id": "logs-2", "type": "filestream"},
+					{"id": "logs-3", "type": "filestream"}
+				],
+				"outputs": {
+					"default": {"type": "elasticsearch"},
+					"kafka": {"type": "kafka"}
+				}
+			}`,
+		},
+		{
+			name: "top-level keys changes",
+			delta: PolicyDelta{
+				Set:   map[string]interface{}{"revision": 2, "agent": map[string]interface{}{"logging": map[string]interface{}{"level": "debug"}}},
+				Unset: []string{"fleet"},
+			},
+			expected: `{
+				"id": "policy-1",
+				"revision": 2,
+				"agent": {"logging": {"level": "debug"}},
+				"inputs": [
+					{"id": "system-1", "type": "system/metrics"},
+					{"id": "logs-1", "type": "logfile"},
+					{"id": "logs-2", "type": "filestream"}
+				],
+				"outputs": {
+					"default": {"type": "elasticsearch"},
+					"remote": {"type": "logstash"}
+				}
+			}`,
+		},
+		{
+			name:  "inputs cannot be set",
+			delta: PolicyDelta{Set: map[string]interface{}{"inputs": []interface{}{}}},
+			err:   `policy delta cannot set "inputs"`,
+		},
+		{
+			name:  "inputs must have an id",
+			delta: PolicyDelta{Inputs: []map[string]interface{}{{"type": "filestream"}}},
+			err:   "policy delta input has no id",
+		},
+		{
+			name:  "hash mismatch",
+			delta: PolicyDelta{Set: map[string]interface{}{"revision": 2}, Hash: "not-the-hash"},
+			err:   "policy hash mismatch",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			basePolicy := decodePolicy(t, base)
+			if tc.expected != "" {
+				hash, err := PolicyHash(decodePolicy(t, tc.expected))
+				require.NoError(t, err)
+				tc.delta.Hash = hash
+			}
+
+			policy, err := tc.delta.Apply(basePolicy)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			actual, err := json.Marshal(policy)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, string(actual))
+			assert.Equal(t, decodePolicy(t, base), basePolicy, "the base policy must not be modified")
+		})
+	}
+}
+
+func TestPolicyHash(t *testing.T) {
+	h1, err := PolicyHash(decodePolicy(t, `{"id": "policy-1", "revision": 1, "outputs": {"a": {}, "b": {}}}`))
+	require.NoError(t, err)
+	h2, err := PolicyHash(map[string]interface{}{
+		"revision": 1,
+		"outputs":  map[string]interface{}{"b": map[string]interface{}{}, "a": map[string]interface{}{}},
+		"id":       "policy-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, h1, h2, "the hash must not depend on the order of the keys nor on the number types")
+	assert.Len(t, h1, 64)
+
+	// the hash is the SHA-256 of the canonical form, HTML characters are not escaped
+	h3, err := PolicyHash(map[string]interface{}{"b": "<a&b>", "a": 1.0})
+	require.NoError(t, err)
+	sum := sha256.Sum256([]byte(`{"a":1,"b":"<a&b>"}`))
+	assert.Equal(t, hex.EncodeToString(sum[:]), h3)
+}
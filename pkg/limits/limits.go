@@ -33,6 +33,25 @@ type LimitsConfig struct {
 	// Translates into the GOMAXPROCS runtime parameter for each Go process started by the agent and the agent itself.
 	// By default is set to `0` which means using all available CPUs.
 	GoMaxProcs int `yaml:"go_max_procs" config:"go_max_procs" json:"go_max_procs"`
+
+	// ResourceLimits are the memory and CPU limits applied to each component process.
+	ResourceLimits `yaml:",inline" config:",inline"`
+
+	// Components overrides the resource limits for specific components, by component ID
+	// (e.g. system/metrics-default) or by binary name (e.g. metricbeat).
+	Components map[string]ResourceLimits `yaml:"components,omitempty" config:"components" json:"components,omitempty"`
+}
+
+// ResourceLimitsFor returns the resource limits of a component, the limits for its ID take precedence
+// over the limits for its binary, which take precedence over the limits for all the components.
+func (c LimitsConfig) ResourceLimitsFor(id string, binaryName string) ResourceLimits {
+	if l, ok := c.Components[id]; ok {
+		return l
+	}
+	if l, ok := c.Components[binaryName]; ok {
+		return l
+	}
+	return c.ResourceLimits
 }
 
 type LimitsOnChangeCallback func(new, old LimitsConfig)
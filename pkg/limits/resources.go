@@ -0,0 +1,78 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package limits
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-units"
+)
+
+// ResourceLimits are the memory and CPU limits of a component process. They are enforced by the
+// command runtime on Linux using cgroup v2 and ignored on the other platforms.
+type ResourceLimits struct {
+	// Memory is the maximum memory the process can use, like 512MiB. The process is killed by the
+	// OOM killer when it exceeds the limit. 0 means no limit.
+	Memory ByteSize `yaml:"memory,omitempty" config:"memory" json:"memory,omitempty"`
+
+	// CPU is the maximum number of CPUs the process can use, like 0.5 for half of a CPU. The process
+	// is throttled when it exceeds the limit. 0 means no limit.
+	CPU float64 `yaml:"cpu,omitempty" config:"cpu" json:"cpu,omitempty"`
+}
+
+// IsZero returns true when no limit is set.
+func (r ResourceLimits) IsZero() bool {
+	return r.Memory == 0 && r.CPU == 0
+}
+
+// Validate validates the resource limits.
+func (r *ResourceLimits) Validate() error {
+	if r.CPU < 0 {
+		return fmt.Errorf("cpu limit must be a positive number of CPUs; received %v", r.CPU)
+	}
+	return nil
+}
+
+// ByteSize is a size in bytes, it is unpacked from a number of bytes or from a string with a unit
+// like 512MiB or 1g. Units are binary, 1KB and 1KiB are both 1024 bytes.
+type ByteSize uint64
+
+// ParseByteSize parses a size in bytes with an optional unit.
+func ParseByteSize(s string) (ByteSize, error) {
+	size, err := units.RAMInBytes(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s': %w", s, err)
+	}
+	return ByteSize(size), nil //nolint:gosec // G115: RAMInBytes never returns a negative size
+}
+
+// Unpack unpacks a size from the configuration.
+func (b *ByteSize) Unpack(v interface{}) error {
+	switch val := v.(type) {
+	case int64:
+		if val < 0 {
+			return errors.New("size must be positive")
+		}
+		*b = ByteSize(val)
+	case uint64:
+		*b = ByteSize(val)
+	case float64:
+		if val < 0 {
+			return errors.New("size must be positive")
+		}
+		*b = ByteSize(val)
+	case string:
+		size, err := ParseByteSize(val)
+		if err != nil {
+			return err
+		}
+		*b = size
+	default:
+		return fmt.Errorf("size must be a number or a string; received %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package limits
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := map[string]struct {
+		size     string
+		expected ByteSize
+		err      string
+	}{
+		"bytes":         {size: "1024", expected: 1024},
+		"bytes unit":    {size: "10b", expected: 10},
+		"kibibytes":     {size: "4KiB", expected: 4 << 10},
+		"kilobytes":     {size: "4kB", expected: 4 << 10},
+		"mebibytes":     {size: "512MiB", expected: 512 << 20},
+		"short unit":    {size: "512m", expected: 512 << 20},
+		"gibibytes":     {size: "1.5GiB", expected: 3 << 29},
+		"space":         {size: "2 GiB", expected: 2 << 30},
+		"unknown unit":  {size: "2 parsecs", err: "invalid suffix"},
+		"invalid value": {size: "1.2.3MiB", err: "invalid size"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			size, err := ParseByteSize(tc.size)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, size)
+		})
+	}
+}
+
+func TestParseResourceLimits(t *testing.T) {
+	parsed, err := Parse(config.MustNewConfigFrom(`
+agent.limits:
+  go_max_procs: 2
+  memory: 1GiB
+  cpu: 1.5
+  components:
+    metricbeat:
+      memory: 512MiB
+      cpu: 0.5
+    system/metrics-default:
+      memory: 268435456
+`))
+	require.NoError(t, err)
+	require.NotNil(t, parsed)
+
+	assert.Equal(t, 2, parsed.GoMaxProcs)
+	assert.Equal(t, ResourceLimits{Memory: 1 << 30, CPU: 1.5}, parsed.ResourceLimits)
+
+	// the component ID takes precedence over the binary name
+	assert.Equal(t, ResourceLimits{Memory: 256 << 20}, parsed.ResourceLimitsFor("system/metrics-default", "metricbeat"))
+	assert.Equal(t, ResourceLimits{Memory: 512 << 20, CPU: 0.5}, parsed.ResourceLimitsFor("http/metrics-default", "metricbeat"))
+	assert.Equal(t, ResourceLimits{Memory: 1 << 30, CPU: 1.5}, parsed.ResourceLimitsFor("filestream-default", "filebeat"))
+}
+
+func TestParseResourceLimitsErrors(t *testing.T) {
+	_, err := Parse(config.MustNewConfigFrom(`agent.limits.cpu: -1`))
+	assert.ErrorContains(t, err, "cpu limit must be a positive number of CPUs")
+
+	_, err = Parse(config.MustNewConfigFrom(`agent.limits.memory: lots`))
+	assert.ErrorContains(t, err, "invalid size")
+}
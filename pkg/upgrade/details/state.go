@@ -23,4 +23,5 @@ const (
 	// List of well-known reasons for state transitions
 	ReasonWatchFailed           = "watch failed"
 	ReasonManualRollbackPattern = "manual rollback requested to version %s"
+	ReasonProbeFailedPattern    = "watch failed: health probe %s failed: %s"
 )
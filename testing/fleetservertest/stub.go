@@ -0,0 +1,492 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetservertest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	// StubPolicyFile is the file, in the directory of the Stub, holding the
+	// policy sent to the agent. The policy is sent again with a new revision
+	// every time the file changes.
+	StubPolicyFile = "policy.json"
+	// StubActionsDir is the directory, in the directory of the Stub, holding
+	// the actions sent to the agent, one JSON encoded action per file. The
+	// actions are sent in the order of their file names and moved to
+	// StubDeliveredDir once sent.
+	StubActionsDir = "actions"
+	// StubDeliveredDir is the directory, in StubActionsDir, holding the
+	// actions already sent to the agent.
+	StubDeliveredDir = "delivered"
+	// StubAcksFile is the file, in the directory of the Stub, the acks sent by
+	// the agent are appended to, one JSON encoded ack event per line.
+	StubAcksFile = "acks.ndjson"
+	// StubUploadsDir is the directory, in the directory of the Stub, holding
+	// the files uploaded by the agent, e.g. diagnostics, in a directory per
+	// upload.
+	StubUploadsDir = "uploads"
+
+	stubStateFile = "stub.json"
+	// stubChunkSize is the size of the upload chunks, the maximum accepted by
+	// fleet-server.
+	stubChunkSize = 4 * 1024 * 1024
+)
+
+// Stub is a Fleet Server stand-in backed by a local directory. It implements
+// the enroll, checkin, ack and file upload endpoints so a real Elastic Agent
+// can be exercised without a Fleet Server:
+//   - the policy in StubPolicyFile is sent on checkin every time it changes,
+//   - the actions in StubActionsDir are sent once, on the next checkin,
+//   - the acks are appended to StubAcksFile,
+//   - the uploaded files are stored in StubUploadsDir.
+//
+// The enrolled agent, its API key and the policy revision are persisted in
+// the directory, so an enrolled agent keeps working when the Stub restarts.
+// Use Handlers with NewServer to serve it.
+type Stub struct {
+	dir   string
+	logFn func(format string, a ...any)
+
+	// mx protects state, uploads and the files of dir from the concurrent
+	// requests of the agent.
+	mx      sync.Mutex
+	state   stubState
+	uploads map[string]*stubUpload
+}
+
+type stubState struct {
+	AgentID         string `json:"agent_id"`
+	APIKeyID        string `json:"api_key_id"`
+	APIKey          string `json:"api_key"`
+	EnrollmentToken string `json:"enrollment_token"`
+	PolicyID        string `json:"policy_id"`
+	PolicyRevision  int64  `json:"policy_revision"`
+	// PolicyHash is the SHA-256 of the last policy file sent to the agent.
+	PolicyHash string `json:"policy_hash,omitempty"`
+	// AckToken is the sequence number of the last checkin response.
+	AckToken int64 `json:"ack_token"`
+}
+
+type stubUpload struct {
+	name      string
+	chunks    int32
+	hashes    map[int32][]byte
+	partsPath string
+}
+
+// NewStub returns a Stub backed by dir, creating it if needed. logFn, if not
+// nil, is used to log what the Stub sends to and receives from the agent.
+func NewStub(dir string, logFn func(format string, a ...any)) (*Stub, error) {
+	if logFn == nil {
+		logFn = func(format string, a ...any) {}
+	}
+	for _, d := range []string{dir, filepath.Join(dir, StubActionsDir, StubDeliveredDir), filepath.Join(dir, StubUploadsDir)} {
+		if err := os.MkdirAll(d, 0o750); err != nil {
+			return nil, fmt.Errorf("could not create stub directory %q: %w", d, err)
+		}
+	}
+
+	s := &Stub{
+		dir:     dir,
+		logFn:   logFn,
+		uploads: map[string]*stubUpload{},
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, stubStateFile))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		s.state = stubState{
+			AgentID:         uuid.Must(uuid.NewV4()).String(),
+			APIKeyID:        randomString(10),
+			APIKey:          randomString(22),
+			EnrollmentToken: randomString(22),
+			PolicyID:        "fleet-stub-policy",
+		}
+		if err := s.saveState(); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, fmt.Errorf("could not read stub state: %w", err)
+	default:
+		if err := json.Unmarshal(data, &s.state); err != nil {
+			return nil, fmt.Errorf("could not parse stub state: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// Dir returns the directory backing the Stub.
+func (s *Stub) Dir() string {
+	return s.dir
+}
+
+// AgentID returns the ID given to the agent on enroll.
+func (s *Stub) AgentID() string {
+	return s.state.AgentID
+}
+
+// EnrollmentToken returns the enrollment token the agent must enroll with.
+func (s *Stub) EnrollmentToken() string {
+	return s.state.EnrollmentToken
+}
+
+// Handlers returns the Handlers serving the Stub, to be used with NewServer or
+// NewRouter.
+func (s *Stub) Handlers() *Handlers {
+	apiKey := APIKey{ID: s.state.APIKeyID, Key: s.state.APIKey}
+	enroll := NewHandlerEnroll(s.state.AgentID, s.state.PolicyID, apiKey)
+
+	return &Handlers{
+		AgentID:         s.state.AgentID,
+		APIKey:          apiKey.Key,
+		EnrollmentToken: s.state.EnrollmentToken,
+		logFn:           func(format string, a ...any) {},
+		EnrollFn: func(ctx context.Context, h *Handlers, userAgent string, enrollmentToken string, enrollRequest EnrollRequest) (*EnrollResponse, *HTTPError) {
+			if strings.TrimPrefix(enrollmentToken, APIKeyPrefix) != s.state.EnrollmentToken {
+				return nil, &HTTPError{StatusCode: http.StatusUnauthorized, Message: "invalid enrollment token"}
+			}
+			resp, hErr := enroll(ctx, h, userAgent, enrollmentToken, enrollRequest)
+			if hErr != nil {
+				return nil, hErr
+			}
+			s.mx.Lock()
+			defer s.mx.Unlock()
+			// a newly enrolled agent has no policy, send it again.
+			s.state.PolicyHash = ""
+			if err := s.saveState(); err != nil {
+				return nil, internalError(err)
+			}
+			s.logFn("agent %s enrolled", s.state.AgentID)
+			return resp, nil
+		},
+		CheckinFn:        s.checkin,
+		AckFn:            s.ack,
+		StatusFn:         NewHandlerStatusHealthy(),
+		UploadBeginFn:    s.uploadBegin,
+		UploadChunkFn:    s.uploadChunk,
+		UploadCompleteFn: s.uploadComplete,
+	}
+}
+
+func (s *Stub) checkin(
+	_ context.Context,
+	h *Handlers,
+	agentID string,
+	_ string,
+	_ string,
+	checkinRequest CheckinRequest) (*CheckinResponse, *HTTPError) {
+	if agentID != h.AgentID {
+		return nil, &HTTPError{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("agent %q not found", agentID),
+		}
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var actions []Action
+	policy, err := s.nextPolicyChange()
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if policy != nil {
+		actions = append(actions, *policy)
+	}
+	pending, err := s.nextActions()
+	if err != nil {
+		return nil, internalError(err)
+	}
+	actions = append(actions, pending...)
+
+	if len(actions) > 0 {
+		s.state.AckToken++
+		if err := s.saveState(); err != nil {
+			return nil, internalError(err)
+		}
+		s.logFn("checkin: agent status %s, sent %d action(s)", checkinRequest.Status, len(actions))
+	}
+
+	return &CheckinResponse{
+		AckToken: strconv.FormatInt(s.state.AckToken, 10),
+		Action:   "checkin",
+		Actions:  actions,
+	}, nil
+}
+
+// nextPolicyChange returns a POLICY_CHANGE action if the policy file changed
+// since it was last sent, otherwise nil.
+func (s *Stub) nextPolicyChange() (*Action, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, StubPolicyFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read policy: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if hash == s.state.PolicyHash {
+		return nil, nil
+	}
+
+	var policy map[string]interface{}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		// keep the agent running the previous policy until the file is fixed
+		s.logFn("invalid policy %s, not sending it: %v", StubPolicyFile, err)
+		s.state.PolicyHash = hash
+		return nil, nil
+	}
+	if _, ok := policy["id"]; !ok {
+		policy["id"] = s.state.PolicyID
+	}
+	s.state.PolicyRevision++
+	policy["revision"] = s.state.PolicyRevision
+	s.state.PolicyHash = hash
+
+	var actionData interface{} = map[string]interface{}{"policy": policy}
+	s.logFn("sending policy revision %d", s.state.PolicyRevision)
+	return &Action{
+		AgentId:   s.state.AgentID,
+		CreatedAt: timeNow().Format(time.RFC3339),
+		Data:      &actionData,
+		Id:        fmt.Sprintf("policy:%v:%d:1", policy["id"], s.state.PolicyRevision),
+		Type:      "POLICY_CHANGE",
+	}, nil
+}
+
+// nextActions returns the actions in the actions directory and moves their
+// files to the delivered directory.
+func (s *Stub) nextActions() ([]Action, error) {
+	actionsDir := filepath.Join(s.dir, StubActionsDir)
+	entries, err := os.ReadDir(actionsDir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read actions directory: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var actions []Action
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(actionsDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read action %q: %w", entry.Name(), err)
+		}
+
+		var action Action
+		if err := json.Unmarshal(data, &action); err != nil {
+			// it may still be being written, try again on the next checkin
+			s.logFn("invalid action %s, skipping it: %v", entry.Name(), err)
+			continue
+		}
+		if action.Type == "" {
+			s.logFn("action %s has no type, skipping it", entry.Name())
+			continue
+		}
+		if action.Id == "" {
+			action.Id = strings.TrimSuffix(entry.Name(), ".json")
+		}
+		if action.CreatedAt == "" {
+			action.CreatedAt = timeNow().Format(time.RFC3339)
+		}
+		action.AgentId = s.state.AgentID
+
+		if err := os.Rename(path, filepath.Join(actionsDir, StubDeliveredDir, entry.Name())); err != nil {
+			return nil, fmt.Errorf("could not move delivered action %q: %w", entry.Name(), err)
+		}
+		s.logFn("sending %s action %s", action.Type, action.Id)
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+func (s *Stub) ack(
+	_ context.Context,
+	h *Handlers,
+	agentID string,
+	ackRequest AckRequest) (*AckResponse, *HTTPError) {
+	if agentID != h.AgentID {
+		return nil, &HTTPError{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("agent %q not found", agentID),
+		}
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(s.dir, StubAcksFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("could not open acks file: %w", err))
+	}
+	defer f.Close()
+
+	resp := AckResponse{Action: "acks"}
+	enc := json.NewEncoder(f)
+	for _, e := range ackRequest.Events {
+		if err := enc.Encode(e); err != nil {
+			return nil, internalError(fmt.Errorf("could not write ack: %w", err))
+		}
+		if e.Error != "" {
+			s.logFn("ack for action %s: error: %s", e.ActionId, e.Error)
+		} else {
+			s.logFn("ack for action %s", e.ActionId)
+		}
+		resp.Items = append(resp.Items, AckResponseItem{
+			Status:  http.StatusOK,
+			Message: http.StatusText(http.StatusOK),
+		})
+	}
+	return &resp, nil
+}
+
+func (s *Stub) uploadBegin(
+	_ context.Context,
+	h *Handlers,
+	req UploadBeginRequest) (*UploadBeginResponse, *HTTPError) {
+	if req.AgentId != h.AgentID {
+		return nil, &HTTPError{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("agent %q not found", req.AgentId),
+		}
+	}
+	name := filepath.Base(req.File.Name)
+	if name == "." || name == string(filepath.Separator) || req.File.Size <= 0 {
+		return nil, &HTTPError{StatusCode: http.StatusBadRequest, Message: "invalid file name or size"}
+	}
+
+	uploadID := uuid.Must(uuid.NewV4()).String()
+	dir := filepath.Join(s.dir, StubUploadsDir, uploadID)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, internalError(fmt.Errorf("could not create upload directory: %w", err))
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.uploads[uploadID] = &stubUpload{
+		name:      name,
+		chunks:    int32((req.File.Size + stubChunkSize - 1) / stubChunkSize),
+		hashes:    map[int32][]byte{},
+		partsPath: filepath.Join(dir, name+".part"),
+	}
+	s.logFn("upload %s of %s (%d bytes) for action %s started", uploadID, name, req.File.Size, req.ActionId)
+	return &UploadBeginResponse{UploadId: uploadID, ChunkSize: stubChunkSize}, nil
+}
+
+func (s *Stub) uploadChunk(
+	_ context.Context,
+	_ *Handlers,
+	uploadID string,
+	chunkNum int32,
+	xChunkSHA2 string,
+	body io.ReadCloser) *HTTPError {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return &HTTPError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("could not read chunk: %v", err)}
+	}
+	sum := sha256.Sum256(data)
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	upload, ok := s.uploads[uploadID]
+	if !ok {
+		return &HTTPError{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("upload %q not found", uploadID)}
+	}
+	if chunkNum < 0 || chunkNum >= upload.chunks {
+		return &HTTPError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("invalid chunk %d", chunkNum)}
+	}
+	if hex.EncodeToString(sum[:]) != strings.ToLower(xChunkSHA2) {
+		return &HTTPError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("chunk %d hash mismatch", chunkNum)}
+	}
+
+	f, err := os.OpenFile(upload.partsPath, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return internalError(fmt.Errorf("could not open upload file: %w", err))
+	}
+	defer f.Close()
+	if _, err := f.WriteAt(data, int64(chunkNum)*stubChunkSize); err != nil {
+		return internalError(fmt.Errorf("could not write chunk: %w", err))
+	}
+	upload.hashes[chunkNum] = sum[:]
+	return nil
+}
+
+func (s *Stub) uploadComplete(
+	_ context.Context,
+	_ *Handlers,
+	uploadID string,
+	req UploadCompleteRequest) *HTTPError {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	upload, ok := s.uploads[uploadID]
+	if !ok {
+		return &HTTPError{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("upload %q not found", uploadID)}
+	}
+
+	transitHash := sha256.New()
+	for i := int32(0); i < upload.chunks; i++ {
+		hash, ok := upload.hashes[i]
+		if !ok {
+			return &HTTPError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("chunk %d is missing", i)}
+		}
+		transitHash.Write(hash)
+	}
+	if hex.EncodeToString(transitHash.Sum(nil)) != strings.ToLower(req.Transithash.Sha256) {
+		return &HTTPError{StatusCode: http.StatusBadRequest, Message: "transit hash mismatch"}
+	}
+
+	path := filepath.Join(filepath.Dir(upload.partsPath), upload.name)
+	if err := os.Rename(upload.partsPath, path); err != nil {
+		return internalError(fmt.Errorf("could not complete upload: %w", err))
+	}
+	delete(s.uploads, uploadID)
+	s.logFn("upload %s completed: %s", uploadID, path)
+	return nil
+}
+
+func (s *Stub) saveState() error {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode stub state: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, stubStateFile), data, 0o600); err != nil {
+		return fmt.Errorf("could not save stub state: %w", err)
+	}
+	return nil
+}
+
+func internalError(err error) *HTTPError {
+	return &HTTPError{StatusCode: http.StatusInternalServerError, Message: err.Error()}
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)[:n]
+}
@@ -0,0 +1,130 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetservertest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStub(t *testing.T) {
+	dir := t.TempDir()
+	stub, err := NewStub(dir, t.Logf)
+	require.NoError(t, err)
+	srv := httptest.NewServer(NewRouter(stub.Handlers()))
+	defer srv.Close()
+
+	do := func(method, path string, headers map[string]string, body []byte, out any) int {
+		t.Helper()
+		req, err := http.NewRequestWithContext(t.Context(), method, srv.URL+path, bytes.NewReader(body))
+		require.NoError(t, err)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if out != nil && resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+	checkin := func() []Action {
+		t.Helper()
+		var resp CheckinResponse
+		status := do(http.MethodPost, NewPathCheckin(stub.AgentID()), nil, []byte(`{"status": "online"}`), &resp)
+		require.Equal(t, http.StatusOK, status)
+		return resp.Actions
+	}
+
+	// enroll
+	status := do(http.MethodPost, PathAgentEnroll,
+		map[string]string{HeaderAuthorization: APIKeyPrefix + "wrong"}, []byte(`{"type": "PERMANENT"}`), nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	var enrollResp EnrollResponse
+	status = do(http.MethodPost, PathAgentEnroll,
+		map[string]string{HeaderAuthorization: APIKeyPrefix + stub.EnrollmentToken()}, []byte(`{"type": "PERMANENT"}`), &enrollResp)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, stub.AgentID(), enrollResp.Item.AgentID)
+
+	// policy and actions
+	require.NoError(t, os.WriteFile(filepath.Join(dir, StubPolicyFile), []byte(`{"inputs": []}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, StubActionsDir, "02-upgrade.json"),
+		[]byte(`{"type": "UPGRADE", "data": {"version": "9.1.0"}}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, StubActionsDir, "01-diagnostics.json"),
+		[]byte(`{"id": "diag-1", "type": "REQUEST_DIAGNOSTICS"}`), 0o644))
+
+	actions := checkin()
+	require.Len(t, actions, 3)
+	assert.Equal(t, "POLICY_CHANGE", actions[0].Type)
+	policy := (*actions[0].Data).(map[string]interface{})["policy"].(map[string]interface{})
+	assert.Equal(t, "fleet-stub-policy", policy["id"])
+	assert.EqualValues(t, 1, policy["revision"])
+	assert.Equal(t, "diag-1", actions[1].Id)
+	assert.Equal(t, "02-upgrade", actions[2].Id)
+	assert.Equal(t, stub.AgentID(), actions[2].AgentId)
+	assert.FileExists(t, filepath.Join(dir, StubActionsDir, StubDeliveredDir, "02-upgrade.json"))
+
+	assert.Empty(t, checkin(), "actions and policy must be sent once")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, StubPolicyFile), []byte(`{"id": "my-policy"}`), 0o644))
+	actions = checkin()
+	require.Len(t, actions, 1)
+	policy = (*actions[0].Data).(map[string]interface{})["policy"].(map[string]interface{})
+	assert.Equal(t, "my-policy", policy["id"])
+	assert.EqualValues(t, 2, policy["revision"])
+
+	// acks
+	var ackResp AckResponse
+	status = do(http.MethodPost, NewPathAgentAcks(stub.AgentID()), nil,
+		[]byte(`{"events": [{"action_id": "diag-1"}, {"action_id": "02-upgrade", "error": "failed"}]}`), &ackResp)
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, ackResp.Items, 2)
+	acks, err := os.ReadFile(filepath.Join(dir, StubAcksFile))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(acks)), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], `"error":"failed"`)
+
+	// upload
+	content := []byte("diagnostics content")
+	var beginResp UploadBeginResponse
+	status = do(http.MethodPost, PathUploadBegin, nil, []byte(fmt.Sprintf(
+		`{"action_id": "diag-1", "agent_id": %q, "file": {"name": "diag.zip", "size": %d}}`, stub.AgentID(), len(content))), &beginResp)
+	require.Equal(t, http.StatusOK, status)
+
+	chunkHash := sha256.Sum256(content)
+	status = do(http.MethodPut, NewPathUploadChunk(beginResp.UploadId, "0"),
+		map[string]string{"X-Chunk-SHA2": "bad"}, content, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status = do(http.MethodPut, NewPathUploadChunk(beginResp.UploadId, "0"),
+		map[string]string{"X-Chunk-SHA2": fmt.Sprintf("%x", chunkHash)}, content, nil)
+	require.Equal(t, http.StatusOK, status)
+
+	transitHash := sha256.Sum256(chunkHash[:])
+	status = do(http.MethodPost, NewPathUploadComplete(beginResp.UploadId), nil,
+		[]byte(fmt.Sprintf(`{"transithash": {"sha256": "%x"}}`, transitHash)), nil)
+	require.Equal(t, http.StatusOK, status)
+	uploaded, err := os.ReadFile(filepath.Join(dir, StubUploadsDir, beginResp.UploadId, "diag.zip"))
+	require.NoError(t, err)
+	assert.Equal(t, content, uploaded)
+
+	// the enrolled agent is kept across restarts
+	restarted, err := NewStub(dir, t.Logf)
+	require.NoError(t, err)
+	assert.Equal(t, stub.AgentID(), restarted.AgentID())
+	assert.Equal(t, stub.EnrollmentToken(), restarted.EnrollmentToken())
+}